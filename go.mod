@@ -1,5 +1,7 @@
 module github.com/unidoc/unidoc
 
+go 1.27.1
+
 require (
 	github.com/boombuler/barcode v1.0.0
 	github.com/gunnsth/pkcs7 v0.0.0-20181213175627-3cffc6fbfe83
@@ -8,3 +10,10 @@ require (
 	golang.org/x/image v0.0.0-20181116024801-cd38e8056d9b
 	golang.org/x/text v0.3.0
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/objx v0.1.0 // indirect
+	golang.org/x/sys v0.0.0-20190215142949-d0b11bdaac8a // indirect
+)
@@ -183,9 +183,7 @@ func newLZWEncoderFromInlineImage(inlineImage *ContentStreamInlineImage, decodeP
 	// which LZW implementation to use.
 	// The default is 1 (one code early)
 	//
-	// The EarlyChange parameter is specified in the object stream dictionary for regular streams,
-	// but it is not specified explicitly where to check for it in the case of inline images.
-	// We will check in the decodeParms for now, we can adjust later if we come across cases of this.
+	// The EarlyChange parameter is specified in the decodeParms, as for the regular streams.
 	obj := decodeParams.Get("EarlyChange")
 	if obj != nil {
 		earlyChange, ok := obj.(*core.PdfObjectInteger)
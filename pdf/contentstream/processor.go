@@ -22,6 +22,14 @@ type GraphicsState struct {
 	ColorStroking         model.PdfColor
 	ColorNonStroking      model.PdfColor
 	CTM                   transform.Matrix
+
+	// Line parameters in user space units. See 8.4.3 Details of Graphics State Parameters.
+	LineWidth  float64
+	LineCap    int
+	LineJoin   int
+	MiterLimit float64
+	DashArray  []float64 // Empty for solid lines.
+	DashPhase  float64
 }
 
 // GraphicStateStack represents a stack of GraphicsState.
@@ -51,6 +59,9 @@ type ContentStreamProcessor struct {
 	graphicsStack GraphicStateStack
 	operations    []*ContentStreamOperation
 	graphicsState GraphicsState
+	// initialState is the graphics state at the start of the content stream, nil for the default
+	// graphics state.
+	initialState *GraphicsState
 
 	handlers     []handlerEntry
 	currentIndex int
@@ -211,15 +222,27 @@ func (proc *ContentStreamProcessor) getInitialColor(cs model.PdfColorspace) (mod
 	return nil, errors.New("unsupported colorspace")
 }
 
+// SetInitialGraphicsState sets the graphics state `gs` at the start of the content stream instead of
+// the default graphics state, e.g. the graphics state in which a form XObject is drawn.
+func (proc *ContentStreamProcessor) SetInitialGraphicsState(gs GraphicsState) {
+	proc.initialState = &gs
+}
+
 // Process processes the entire list of operations. Maintains the graphics state that is passed to any
 // handlers that are triggered during processing (either on specific operators or all).
 func (proc *ContentStreamProcessor) Process(resources *model.PdfPageResources) error {
 	// Initialize graphics state
-	proc.graphicsState.ColorspaceStroking = model.NewPdfColorspaceDeviceGray()
-	proc.graphicsState.ColorspaceNonStroking = model.NewPdfColorspaceDeviceGray()
-	proc.graphicsState.ColorStroking = model.NewPdfColorDeviceGray(0)
-	proc.graphicsState.ColorNonStroking = model.NewPdfColorDeviceGray(0)
-	proc.graphicsState.CTM = transform.IdentityMatrix()
+	if proc.initialState != nil {
+		proc.graphicsState = *proc.initialState
+	} else {
+		proc.graphicsState.ColorspaceStroking = model.NewPdfColorspaceDeviceGray()
+		proc.graphicsState.ColorspaceNonStroking = model.NewPdfColorspaceDeviceGray()
+		proc.graphicsState.ColorStroking = model.NewPdfColorDeviceGray(0)
+		proc.graphicsState.ColorNonStroking = model.NewPdfColorDeviceGray(0)
+		proc.graphicsState.CTM = transform.IdentityMatrix()
+		proc.graphicsState.LineWidth = 1
+		proc.graphicsState.MiterLimit = 10
+	}
 
 	for _, op := range proc.operations {
 		var err error
@@ -258,6 +281,12 @@ func (proc *ContentStreamProcessor) Process(resources *model.PdfPageResources) e
 			err = proc.handleCommand_k(op, resources)
 		case "cm":
 			err = proc.handleCommand_cm(op, resources)
+
+		// Line parameters (Table 57 p. 127)
+		case "w", "J", "j", "M", "d":
+			proc.handleLineParams(op.Operand, op.Params)
+		case "gs":
+			proc.handleCommand_gs(op, resources)
 		}
 		if err != nil {
 			common.Log.Debug("Processor handling error (%s): %v", op.Operand, err)
@@ -584,3 +613,85 @@ func (proc *ContentStreamProcessor) handleCommand_cm(op *ContentStreamOperation,
 
 	return nil
 }
+
+// handleLineParams sets the line parameter of the operator `operand` ("w", "J", "j", "M" or "d")
+// to `params`. The names of the corresponding ExtGState entries ("LW", "LC", "LJ", "ML" or "D")
+// may be used for `operand`. Invalid parameters are ignored.
+func (proc *ContentStreamProcessor) handleLineParams(operand string, params []core.PdfObject) {
+	gs := &proc.graphicsState
+	if operand == "d" || operand == "D" {
+		// The dash pattern is an array and a phase, which is also an array in ExtGState.
+		if len(params) == 1 {
+			if arr, ok := core.GetArray(params[0]); ok {
+				params = arr.Elements()
+			}
+		}
+		if len(params) != 2 {
+			common.Log.Debug("ERROR: Invalid dash pattern: %v", params)
+			return
+		}
+		arr, ok := core.GetArray(params[0])
+		if !ok {
+			common.Log.Debug("ERROR: Invalid dash array: %v", params[0])
+			return
+		}
+		dashes, err := arr.ToFloat64Array()
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid dash array: %v", err)
+			return
+		}
+		phase, err := core.GetNumberAsFloat(params[1])
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid dash phase: %v", err)
+			return
+		}
+		gs.DashArray, gs.DashPhase = dashes, phase
+		return
+	}
+
+	if len(params) != 1 {
+		common.Log.Debug("ERROR: Invalid number of parameters for %s: %d", operand, len(params))
+		return
+	}
+	val, err := core.GetNumberAsFloat(params[0])
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid parameter for %s: %v", operand, err)
+		return
+	}
+	switch operand {
+	case "w", "LW":
+		gs.LineWidth = val
+	case "J", "LC":
+		gs.LineCap = int(val)
+	case "j", "LJ":
+		gs.LineJoin = int(val)
+	case "M", "ML":
+		gs.MiterLimit = val
+	}
+}
+
+// gs: sets the line parameters of the graphics state from an ExtGState resource.
+func (proc *ContentStreamProcessor) handleCommand_gs(op *ContentStreamOperation,
+	resources *model.PdfPageResources) {
+	if len(op.Params) != 1 || resources == nil {
+		return
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		return
+	}
+	obj, ok := resources.GetExtGState(*name)
+	if !ok {
+		common.Log.Debug("ERROR: ExtGState %s not found", *name)
+		return
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	for _, key := range []core.PdfObjectName{"LW", "LC", "LJ", "ML", "D"} {
+		if val := dict.Get(key); val != nil {
+			proc.handleLineParams(string(key), []core.PdfObject{val})
+		}
+	}
+}
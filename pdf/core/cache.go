@@ -0,0 +1,221 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"container/list"
+
+	"github.com/unidoc/unidoc/common"
+)
+
+// objectSizeOverhead is the estimated memory overhead of each object in bytes.
+const objectSizeOverhead = 16
+
+// cacheKey identifies an entry of the bounded cache: a parsed object or a decoded object stream.
+type cacheKey struct {
+	objstm bool
+	num    int
+}
+
+// cacheEntry is an entry of the bounded cache with the estimated size of the cached data.
+type cacheEntry struct {
+	key  cacheKey
+	size int64
+}
+
+// boundedCache tracks the objects cached by the parser and evicts the least recently used ones
+// when their estimated size exceeds the limit.
+type boundedCache struct {
+	limit int64
+	size  int64
+	// lru contains the entries with the most recently used first.
+	lru   *list.List
+	elems map[cacheKey]*list.Element
+}
+
+func newBoundedCache(limit int64) *boundedCache {
+	return &boundedCache{
+		limit: limit,
+		lru:   list.New(),
+		elems: make(map[cacheKey]*list.Element),
+	}
+}
+
+// touch marks the entry 'key' as the most recently used.
+func (c *boundedCache) touch(key cacheKey) {
+	if elem, ok := c.elems[key]; ok {
+		c.lru.MoveToFront(elem)
+	}
+}
+
+// add adds an entry of the specified 'size' and returns the keys of the evicted entries.
+func (c *boundedCache) add(key cacheKey, size int64) []cacheKey {
+	c.remove(key)
+	c.elems[key] = c.lru.PushFront(&cacheEntry{key: key, size: size})
+	c.size += size
+
+	var evicted []cacheKey
+	for c.size > c.limit && c.lru.Len() > 1 {
+		elem := c.lru.Back()
+		e := elem.Value.(*cacheEntry)
+		c.remove(e.key)
+		evicted = append(evicted, e.key)
+	}
+	return evicted
+}
+
+// remove removes the entry 'key' if present.
+func (c *boundedCache) remove(key cacheKey) {
+	elem, ok := c.elems[key]
+	if !ok {
+		return
+	}
+	c.size -= elem.Value.(*cacheEntry).size
+	c.lru.Remove(elem)
+	delete(c.elems, key)
+}
+
+// SetCacheLimit bounds the estimated memory size of the objects cached by the parser to 'limit' bytes.
+// The least recently used objects and object streams are evicted from the cache and parsed again
+// from the file when needed, the objects larger than the limit are not cached at all.
+// With a limit, the data of the streams parsed afterwards stays in the file until needed: Stream is
+// nil until loaded by LoadStream, and DecodeStream reads the data without keeping it in memory.
+// Note that an object looked up again after its eviction is a new object: it is not the same pointer
+// as the one returned before, and the changes made to the previous object are lost. Callers that
+// modify objects should not set a limit and callers comparing objects should compare their object
+// numbers.
+// Zero or negative limit disables the limit, which is the default.
+func (parser *PdfParser) SetCacheLimit(limit int64) {
+	if limit <= 0 {
+		parser.cache = nil
+		return
+	}
+	parser.cache = newBoundedCache(limit)
+	for num, obj := range parser.ObjCache {
+		parser.cacheObject(num, obj)
+	}
+	for num, objstm := range parser.objstms {
+		parser.cacheObjectStream(num, objstm)
+	}
+}
+
+// getCachedObject returns the cached object with the object number 'num'.
+func (parser *PdfParser) getCachedObject(num int) (PdfObject, bool) {
+	obj, ok := parser.ObjCache[num]
+	if ok && parser.cache != nil {
+		parser.cache.touch(cacheKey{num: num})
+	}
+	return obj, ok
+}
+
+// cacheObject adds the object 'obj' with the object number 'num' to the cache.
+func (parser *PdfParser) cacheObject(num int, obj PdfObject) {
+	if parser.cache == nil {
+		parser.ObjCache[num] = obj
+		return
+	}
+	size := estimateSize(obj)
+	if size > parser.cache.limit {
+		common.Log.Trace("Object %d too large to be cached (%d bytes)", num, size)
+		delete(parser.ObjCache, num)
+		parser.cache.remove(cacheKey{num: num})
+		return
+	}
+	parser.ObjCache[num] = obj
+	parser.evict(parser.cache.add(cacheKey{num: num}, size))
+}
+
+// getCachedObjectStream returns the cached decoded object stream with the object number 'num'.
+func (parser *PdfParser) getCachedObjectStream(num int) (objectStream, bool) {
+	objstm, ok := parser.objstms[num]
+	if ok && parser.cache != nil {
+		parser.cache.touch(cacheKey{objstm: true, num: num})
+	}
+	return objstm, ok
+}
+
+// cacheObjectStream adds the decoded object stream 'objstm' with the object number 'num' to the cache.
+func (parser *PdfParser) cacheObjectStream(num int, objstm objectStream) {
+	parser.objstms[num] = objstm
+	if parser.cache == nil {
+		return
+	}
+	size := int64(objectSizeOverhead + len(objstm.ds) + 2*objectSizeOverhead*len(objstm.offsets))
+	if size > parser.cache.limit {
+		common.Log.Trace("Object stream %d too large to be cached (%d bytes)", num, size)
+		delete(parser.objstms, num)
+		parser.cache.remove(cacheKey{objstm: true, num: num})
+		return
+	}
+	parser.evict(parser.cache.add(cacheKey{objstm: true, num: num}, size))
+}
+
+// resetCache empties the object cache.
+func (parser *PdfParser) resetCache() {
+	parser.ObjCache = objectCache{}
+	if parser.cache != nil {
+		parser.cache = newBoundedCache(parser.cache.limit)
+		for num, objstm := range parser.objstms {
+			parser.cacheObjectStream(num, objstm)
+		}
+	}
+}
+
+// evict removes the entries with the specified 'keys' from the cache.
+func (parser *PdfParser) evict(keys []cacheKey) {
+	for _, key := range keys {
+		if key.objstm {
+			delete(parser.objstms, key.num)
+			continue
+		}
+		obj := parser.ObjCache[key.num]
+		delete(parser.ObjCache, key.num)
+		if parser.crypter != nil {
+			// The object is decrypted again when parsed.
+			delete(parser.crypter.decryptedObjects, obj)
+		}
+		common.Log.Trace("Evicted object %d from cache", key.num)
+	}
+}
+
+// untrackUncached stops tracking the object 'obj' with the object number 'num' if it is not
+// retained by the cache, so that the memory can be released. The object is parsed and decrypted
+// again on the next lookup.
+func (parser *PdfParser) untrackUncached(num int, obj PdfObject) {
+	if cached, ok := parser.ObjCache[num]; ok && cached == obj {
+		return
+	}
+	if parser.crypter != nil {
+		delete(parser.crypter.decryptedObjects, obj)
+	}
+}
+
+// estimateSize returns the approximate memory size of the object 'obj' in bytes.
+func estimateSize(obj PdfObject) int64 {
+	size := int64(objectSizeOverhead)
+	switch t := obj.(type) {
+	case *PdfIndirectObject:
+		size += estimateSize(t.PdfObject)
+	case *PdfObjectStream:
+		size += int64(len(t.Stream))
+		if t.PdfObjectDictionary != nil {
+			size += estimateSize(t.PdfObjectDictionary)
+		}
+	case *PdfObjectDictionary:
+		for _, key := range t.Keys() {
+			size += objectSizeOverhead + int64(len(key)) + estimateSize(t.Get(key))
+		}
+	case *PdfObjectArray:
+		for _, o := range t.Elements() {
+			size += estimateSize(o)
+		}
+	case *PdfObjectString:
+		size += int64(len(t.val))
+	case *PdfObjectName:
+		size += int64(len(*t))
+	}
+	return size
+}
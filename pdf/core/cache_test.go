@@ -0,0 +1,107 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"os"
+	"testing"
+)
+
+// Tests that the parser with the limited cache keeps the cached objects within the limit and
+// loads the evicted objects again.
+func TestParserCacheLimit(t *testing.T) {
+	open := func(limit int64) *PdfParser {
+		f, err := os.Open("./testdata/i-9.pdf")
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		parser, err := NewParser(f)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		parser.SetCacheLimit(limit)
+		// Encrypted with an empty user password.
+		if _, err := parser.IsEncrypted(); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if ok, err := parser.Decrypt(nil); err != nil || !ok {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		return parser
+	}
+	const limit = 4096
+	parser, bounded := open(0), open(limit)
+	defer parser.rs.(*os.File).Close()
+	defer bounded.rs.(*os.File).Close()
+
+	objNums := parser.GetObjectNums()
+	if len(objNums) < 50 {
+		t.Fatalf("Too few objects: %d", len(objNums))
+	}
+	for pass := 0; pass < 2; pass++ {
+		for _, num := range objNums {
+			exp, err := parser.LookupByNumber(num)
+			if err != nil {
+				t.Fatalf("Error: %v", err)
+			}
+			obj, err := bounded.LookupByNumber(num)
+			if err != nil {
+				t.Fatalf("Error: %v", err)
+			}
+			if obj.WriteString() != exp.WriteString() {
+				t.Errorf("Object %d mismatch: %s, expected %s", num, obj.WriteString(), exp.WriteString())
+			}
+			if stream, ok := exp.(*PdfObjectStream); ok {
+				if pass == 0 {
+					checkLazyStream(t, obj.(*PdfObjectStream), stream)
+				} else if err := obj.(*PdfObjectStream).LoadStream(); err != nil {
+					t.Fatalf("Error: %v", err)
+				} else if string(obj.(*PdfObjectStream).Stream) != string(stream.Stream) {
+					t.Errorf("Stream %d data mismatch", num)
+				}
+			}
+			if bounded.cache.size > limit {
+				t.Fatalf("Cache size %d exceeds the limit %d", bounded.cache.size, limit)
+			}
+		}
+	}
+	if len(bounded.ObjCache) >= len(parser.ObjCache) {
+		t.Errorf("Objects not evicted: %d cached, %d in total", len(bounded.ObjCache), len(parser.ObjCache))
+	}
+	if len(bounded.ObjCache)+len(bounded.objstms) != bounded.cache.lru.Len() {
+		t.Errorf("Cache entries mismatch: %d objects, %d object streams, %d entries",
+			len(bounded.ObjCache), len(bounded.objstms), bounded.cache.lru.Len())
+	}
+}
+
+// checkLazyStream checks that the data of the stream 'stream' parsed with a limited cache is read
+// on demand and matches the data of the stream 'exp'.
+func checkLazyStream(t *testing.T, stream, exp *PdfObjectStream) {
+	if stream.Stream != nil {
+		t.Fatalf("Stream %d data loaded before needed", stream.ObjectNumber)
+	}
+	decoded, err := DecodeStream(stream)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	expDecoded, err := DecodeStream(exp)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if string(decoded) != string(expDecoded) {
+		t.Errorf("Stream %d decoded data mismatch", stream.ObjectNumber)
+	}
+	if stream.Stream != nil {
+		t.Fatalf("Stream %d data kept after decoding", stream.ObjectNumber)
+	}
+
+	if err := stream.LoadStream(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if string(stream.Stream) != string(exp.Stream) {
+		t.Errorf("Stream %d data mismatch", stream.ObjectNumber)
+	}
+}
@@ -45,9 +45,6 @@ type XrefObject struct {
 // where to access within the PDF file.
 type XrefTable struct {
 	ObjectMap map[int]XrefObject // Maps object number to XrefObject
-
-	// List of objects sorted by offset (only objects with offsets, not ones in streams).
-	sortedObjects []XrefObject
 }
 
 // objectStream represents an object stream's information which can contain multiple indirect objects.
@@ -72,7 +69,7 @@ func (parser *PdfParser) lookupObjectViaOS(sobjNumber int, objNum int) (PdfObjec
 	var objstm objectStream
 	var cached bool
 
-	objstm, cached = parser.objstms[sobjNumber]
+	objstm, cached = parser.getCachedObjectStream(sobjNumber)
 	if !cached {
 		soi, err := parser.LookupByNumber(sobjNumber)
 		if err != nil {
@@ -85,7 +82,8 @@ func (parser *PdfParser) lookupObjectViaOS(sobjNumber int, objNum int) (PdfObjec
 			return nil, errors.New("invalid object stream")
 		}
 
-		if parser.crypter != nil && !parser.crypter.isDecrypted(so) {
+		// The uncached objects are not tracked by the crypter with the bounded cache (see untrackUncached).
+		if parser.crypter != nil && parser.cache == nil && !parser.crypter.isDecrypted(so) {
 			return nil, errors.New("need to decrypt the stream")
 		}
 
@@ -158,7 +156,7 @@ func (parser *PdfParser) lookupObjectViaOS(sobjNumber int, objNum int) (PdfObjec
 		}
 
 		objstm = objectStream{N: int(*N), ds: ds, offsets: offsets}
-		parser.objstms[sobjNumber] = objstm
+		parser.cacheObjectStream(sobjNumber, objstm)
 	} else {
 		// Temporarily change the reader object to this decoded buffer.
 		// Point back afterwards.
@@ -218,6 +216,9 @@ func (parser *PdfParser) lookupByNumberWrapper(objNumber int, attemptRepairs boo
 			return nil, inObjStream, err
 		}
 	}
+	if parser.cache != nil {
+		parser.untrackUncached(objNumber, obj)
+	}
 
 	return obj, inObjStream, nil
 }
@@ -237,7 +238,7 @@ func getObjectNumber(obj PdfObject) (int64, int64, error) {
 // lookupByNumber is used by LookupByNumber.
 // attemptRepairs signals whether to attempt repair if broken.
 func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (PdfObject, bool, error) {
-	obj, ok := parser.ObjCache[objNumber]
+	obj, ok := parser.getCachedObject(objNumber)
 	if ok {
 		common.Log.Trace("Returning cached object %d", objNumber)
 		return obj, false, nil
@@ -273,6 +274,8 @@ func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (Pdf
 					common.Log.Debug("ERROR Failed repair (%s)", err)
 					return nil, false, err
 				}
+				parser.addRepair(RepairXrefRebuild, int64(objNumber), xref.Offset,
+					"offset not pointing to an object, rebuilt %d xref entries", len(xrefTable.ObjectMap))
 				parser.xrefs = *xrefTable
 				return parser.lookupByNumber(objNumber, false)
 			}
@@ -286,19 +289,31 @@ func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (Pdf
 			realObjNum, _, _ := getObjectNumber(obj)
 			if int(realObjNum) != objNumber {
 				common.Log.Debug("Invalid xrefs: Rebuilding")
+				parser.addRepair(RepairXrefObjectNumber, int64(objNumber), xref.Offset,
+					"xref entry pointing to object %d", realObjNum)
 				err := parser.rebuildXrefTable()
 				if err != nil {
 					return nil, false, err
 				}
+				if _, ok := parser.xrefs.ObjectMap[objNumber]; !ok && !parser.repairsAttempted {
+					// No entry points to the object, locate the objects by scanning the file.
+					xrefTable, err := parser.repairRebuildXrefsTopDown()
+					if err != nil {
+						return nil, false, err
+					}
+					parser.addRepair(RepairXrefRebuild, int64(objNumber), xref.Offset,
+						"object not found, rebuilt %d xref entries", len(xrefTable.ObjectMap))
+					parser.xrefs = *xrefTable
+				}
 				// Empty the cache.
-				parser.ObjCache = objectCache{}
+				parser.resetCache()
 				// Try looking up again and return.
 				return parser.lookupByNumberWrapper(objNumber, false)
 			}
 		}
 
 		common.Log.Trace("Returning obj")
-		parser.ObjCache[objNumber] = obj
+		parser.cacheObject(objNumber, obj)
 		return obj, false, nil
 	} else if xref.XType == XrefTypeObjectStream {
 		common.Log.Trace("xref from object stream!")
@@ -317,7 +332,7 @@ func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (Pdf
 				return nil, true, err
 			}
 			common.Log.Trace("<Loaded via OS")
-			parser.ObjCache[objNumber] = optr
+			parser.cacheObject(objNumber, optr)
 			if parser.crypter != nil {
 				// Mark as decrypted (inside object stream) for caching.
 				// and avoid decrypting decrypted object.
@@ -6,8 +6,10 @@
 package core
 
 import (
+	gocrypto "crypto"
 	"crypto/md5"
 	"crypto/rand"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"time"
@@ -28,8 +30,27 @@ type EncryptInfo struct {
 	ID0, ID1 string
 }
 
+// CryptFilterOptions select the objects of the document encrypted by the crypt filter (V>=4).
+// The objects not selected use the Identity crypt filter and are stored unencrypted. At least one
+// kind of objects must be encrypted.
+type CryptFilterOptions struct {
+	// IdentityStreams leaves the streams unencrypted, except for the embedded file streams.
+	IdentityStreams bool
+	// IdentityStrings leaves the strings unencrypted.
+	IdentityStrings bool
+	// IdentityEmbeddedFiles leaves the embedded file streams unencrypted.
+	IdentityEmbeddedFiles bool
+}
+
 // PdfCryptNewEncrypt makes the document crypt handler based on a specified crypt filter.
 func PdfCryptNewEncrypt(cf crypto.Filter, userPass, ownerPass []byte, perm security.Permissions) (*PdfCrypt, *EncryptInfo, error) {
+	return PdfCryptNewEncryptWithOptions(cf, userPass, ownerPass, perm, nil)
+}
+
+// PdfCryptNewEncryptWithOptions makes the document crypt handler based on a specified crypt filter.
+// The options 'opts' select the objects encrypted by the crypt filter, nil options encrypt all of them.
+func PdfCryptNewEncryptWithOptions(cf crypto.Filter, userPass, ownerPass []byte, perm security.Permissions,
+	opts *CryptFilterOptions) (*PdfCrypt, *EncryptInfo, error) {
 	crypter := &PdfCrypt{
 		encryptedObjects: make(map[PdfObject]bool),
 		cryptFilters:     make(cryptFilters),
@@ -49,28 +70,13 @@ func PdfCryptNewEncrypt(cf crypto.Filter, userPass, ownerPass []byte, perm secur
 
 		crypter.encrypt.Length = cf.KeyLength() * 8
 	}
-	const (
-		defaultFilter = stdCryptFilter
-	)
-	crypter.cryptFilters[defaultFilter] = cf
-	if crypter.encrypt.V >= 4 {
-		crypter.streamFilter = defaultFilter
-		crypter.stringFilter = defaultFilter
+	if err := crypter.setCryptFilters(stdCryptFilter, cf, opts); err != nil {
+		return nil, nil, err
 	}
 	ed := crypter.newEncryptDict()
 
-	// Prepare the ID object for the trailer.
-	hashcode := md5.Sum([]byte(time.Now().Format(time.RFC850)))
-	id0 := string(hashcode[:])
-	b := make([]byte, 100)
-	rand.Read(b)
-	hashcode = md5.Sum(b)
-	id1 := string(hashcode[:])
-	common.Log.Trace("Random b: % x", b)
-
-	common.Log.Trace("Gen Id 0: % x", id0)
-
-	crypter.id0 = string(id0)
+	id0, id1 := makeDocumentIDs()
+	crypter.id0 = id0
 
 	err := crypter.generateParams(userPass, ownerPass)
 	if err != nil {
@@ -91,12 +97,103 @@ func PdfCryptNewEncrypt(cf crypto.Filter, userPass, ownerPass []byte, perm secur
 	}, nil
 }
 
+// PdfCryptNewEncryptPubKey makes the document crypt handler of the public-key security handler
+// based on a specified crypt filter. The document can be decrypted by the 'recipients' with their
+// private keys, each recipient is granted the specified permissions. The options 'opts' select
+// the objects encrypted by the crypt filter, nil options encrypt all of them.
+func PdfCryptNewEncryptPubKey(cf crypto.Filter, recipients []security.Recipient,
+	opts *CryptFilterOptions) (*PdfCrypt, *EncryptInfo, error) {
+	if cf == nil {
+		return nil, nil, errors.New("crypt filter required")
+	}
+	crypter := &PdfCrypt{
+		encryptedObjects: make(map[PdfObject]bool),
+		cryptFilters:     make(cryptFilters),
+		encryptPubKey: security.PubKeyEncryptDict{
+			EncryptMetadata: true,
+		},
+	}
+	var vers Version
+	v := cf.PDFVersion()
+	vers.Major, vers.Minor = v[0], v[1]
+
+	crypter.encrypt.Filter = pubKeyFilter
+	crypter.encrypt.V, _ = cf.HandlerVersion()
+	crypter.encrypt.Length = cf.KeyLength() * 8
+	crypter.encrypt.SubFilter = security.SubFilterPKCS7S4
+	filterName := stdCryptFilter
+	if crypter.encrypt.V >= 4 {
+		// The recipients are stored in the default crypt filter (adbe.pkcs7.s5).
+		crypter.encrypt.SubFilter = security.SubFilterPKCS7S5
+		filterName = pubKeyCryptFilter
+	}
+	if err := crypter.setCryptFilters(filterName, cf, opts); err != nil {
+		return nil, nil, err
+	}
+
+	h := security.NewPubKeyHandler(cf.KeyLength())
+	ekey, err := h.GenerateParams(&crypter.encryptPubKey, recipients)
+	if err != nil {
+		return nil, nil, err
+	}
+	crypter.encryptionKey = ekey
+	crypter.pubKeyPerms = security.PermOwner
+	crypter.authenticated = true
+
+	ed := MakeDict()
+	ed.Set("Filter", MakeName(pubKeyFilter))
+	ed.Set("SubFilter", MakeName(crypter.encrypt.SubFilter))
+	ed.Set("V", MakeInteger(int64(crypter.encrypt.V)))
+	ed.Set("Length", MakeInteger(int64(crypter.encrypt.Length)))
+	recipientsArr := MakeArray()
+	for _, envelope := range crypter.encryptPubKey.Recipients {
+		recipientsArr.Append(MakeStringFromBytes(envelope))
+	}
+	if crypter.encrypt.V >= 4 {
+		if err := crypter.saveCryptFilters(ed); err != nil {
+			return nil, nil, err
+		}
+		filter, _ := GetDict(ed.Get("CF").(*PdfObjectDictionary).Get(pubKeyCryptFilter))
+		filter.Set("Recipients", recipientsArr)
+		filter.Set("EncryptMetadata", MakeBool(crypter.encryptPubKey.EncryptMetadata))
+	} else {
+		ed.Set("Recipients", recipientsArr)
+	}
+
+	id0, id1 := makeDocumentIDs()
+	crypter.id0 = id0
+
+	return crypter, &EncryptInfo{
+		Version: vers,
+		Encrypt: ed,
+		ID0:     id0, ID1: id1,
+	}, nil
+}
+
+// makeDocumentIDs prepares the ID object for the trailer.
+func makeDocumentIDs() (id0, id1 string) {
+	hashcode := md5.Sum([]byte(time.Now().Format(time.RFC850)))
+	id0 = string(hashcode[:])
+	b := make([]byte, 100)
+	rand.Read(b)
+	hashcode = md5.Sum(b)
+	id1 = string(hashcode[:])
+	common.Log.Trace("Random b: % x", b)
+
+	common.Log.Trace("Gen Id 0: % x", id0)
+	return id0, id1
+}
+
 // PdfCrypt provides PDF encryption/decryption support.
 // The PDF standard supports encryption of strings and streams (Section 7.6).
 type PdfCrypt struct {
 	encrypt    encryptDict
 	encryptStd security.StdEncryptDict
 
+	// Public-key security handler parameters and the permissions granted to the recipient.
+	encryptPubKey security.PubKeyEncryptDict
+	pubKeyPerms   security.Permissions
+
 	id0              string
 	encryptionKey    []byte
 	decryptedObjects map[PdfObject]bool
@@ -106,6 +203,8 @@ type PdfCrypt struct {
 	cryptFilters cryptFilters
 	streamFilter string
 	stringFilter string
+	// embeddedFileFilter is used for the embedded file streams, the streamFilter by default.
+	embeddedFileFilter string
 
 	parser *PdfParser
 
@@ -246,7 +345,7 @@ func decodeCryptFilter(cf *crypto.FilterDict, d *PdfObjectDictionary) error {
 func (crypt *PdfCrypt) newEncryptDict() *PdfObjectDictionary {
 	// Generate the encryption dictionary.
 	ed := MakeDict()
-	ed.Set("Filter", MakeName("Standard"))
+	ed.Set("Filter", MakeName(stdFilter))
 	ed.Set("V", MakeInteger(int64(crypt.encrypt.V)))
 	ed.Set("Length", MakeInteger(int64(crypt.encrypt.Length)))
 	return ed
@@ -300,6 +399,15 @@ type encryptDict struct {
 // stdCryptFilter is a default name for a standard crypt filter.
 const stdCryptFilter = "StdCF"
 
+// pubKeyCryptFilter is a default name for a crypt filter of the public-key security handler.
+const pubKeyCryptFilter = "DefaultCryptFilter"
+
+// Names of the supported security handlers.
+const (
+	stdFilter    = "Standard"
+	pubKeyFilter = "Adobe.PubSec"
+)
+
 func newCryptFiltersV2(length int) cryptFilters {
 	return cryptFilters{
 		stdCryptFilter: crypto.NewFilterV2(length),
@@ -383,9 +491,122 @@ func (crypt *PdfCrypt) loadCryptFilters(ed *PdfObjectDictionary) error {
 		crypt.streamFilter = string(*stmf)
 	}
 
+	// EFF embedded file streams filter.
+	crypt.embeddedFileFilter = crypt.streamFilter
+	if eff, ok := ed.Get("EFF").(*PdfObjectName); ok {
+		if _, exists := crypt.cryptFilters[string(*eff)]; !exists {
+			return fmt.Errorf("crypt filter for EFF not specified in CF dictionary (%s)", *eff)
+		}
+		crypt.embeddedFileFilter = string(*eff)
+	}
+
+	return nil
+}
+
+// setCryptFilters sets the crypt filter 'cf' with the specified 'name' as the document crypt filter.
+// With V>=4, the filter is used for the strings, streams and embedded files, unless the options
+// 'opts' select the Identity filter for them.
+func (crypt *PdfCrypt) setCryptFilters(name string, cf crypto.Filter, opts *CryptFilterOptions) error {
+	crypt.cryptFilters[name] = cf
+	if crypt.encrypt.V < 4 {
+		if opts != nil && *opts != (CryptFilterOptions{}) {
+			return errors.New("crypt filter options require V>=4")
+		}
+		return nil
+	}
+	crypt.cryptFilters["Identity"] = crypto.NewIdentity()
+	crypt.streamFilter = name
+	crypt.stringFilter = name
+	crypt.embeddedFileFilter = name
+	if opts != nil {
+		if opts.IdentityStreams && opts.IdentityStrings && opts.IdentityEmbeddedFiles {
+			return errors.New("crypt filter options leave all objects unencrypted")
+		}
+		if opts.IdentityStreams {
+			crypt.streamFilter = "Identity"
+		}
+		if opts.IdentityStrings {
+			crypt.stringFilter = "Identity"
+		}
+		if opts.IdentityEmbeddedFiles {
+			crypt.embeddedFileFilter = "Identity"
+		}
+	}
 	return nil
 }
 
+// documentFilter returns the name of the crypt filter used for the streams, the strings or
+// the embedded files, in this order, skipping the Identity filter.
+func (crypt *PdfCrypt) documentFilter() string {
+	for _, name := range []string{crypt.streamFilter, crypt.stringFilter, crypt.embeddedFileFilter} {
+		if name != "" && name != "Identity" {
+			return name
+		}
+	}
+	return crypt.streamFilter
+}
+
+// encryptMetadata returns true if the document-level metadata stream shall be encrypted.
+func (crypt *PdfCrypt) encryptMetadata() bool {
+	if crypt.isPubKey() {
+		return crypt.encryptPubKey.EncryptMetadata
+	}
+	return crypt.encryptStd.EncryptMetadata
+}
+
+// streamCryptFilter returns the name of the crypt filter of the stream with the dictionary 'dict'.
+// The Crypt filter of the stream (V>=4) overrides the default filters of the document.
+func (crypt *PdfCrypt) streamCryptFilter(dict *PdfObjectDictionary) string {
+	if crypt.encrypt.V < 4 {
+		return stdCryptFilter // Default RC4.
+	}
+
+	// The Crypt filter shall be the first filter in the Filter array entry.
+	var (
+		first  PdfObject
+		params = dict.Get("DecodeParms")
+	)
+	switch filters := TraceToDirectObject(dict.Get("Filter")).(type) {
+	case *PdfObjectName:
+		first = filters
+	case *PdfObjectArray:
+		first = filters.Get(0)
+		if arr, ok := GetArray(params); ok {
+			params = arr.Get(0)
+		}
+	}
+	if name, ok := GetName(first); ok && *name == StreamEncodingFilterNameCrypt {
+		// Crypt filter overriding the default.
+		// Default option is Identity.
+		filter := "Identity"
+		if decodeParams, ok := GetDict(params); ok {
+			if filterName, ok := GetName(decodeParams.Get("Name")); ok {
+				if _, ok := crypt.cryptFilters[string(*filterName)]; ok {
+					common.Log.Trace("Using stream filter %s", *filterName)
+					filter = string(*filterName)
+				} else {
+					common.Log.Debug("ERROR: Unknown crypt filter %s - using Identity", *filterName)
+				}
+			}
+		}
+		return filter
+	}
+
+	if t, ok := GetName(dict.Get("Type")); ok {
+		switch *t {
+		case "EmbeddedFile":
+			if crypt.embeddedFileFilter != "" {
+				return crypt.embeddedFileFilter
+			}
+		case "Metadata":
+			if !crypt.encryptMetadata() {
+				return "Identity"
+			}
+		}
+	}
+	return crypt.streamFilter
+}
+
 func encodeCryptFilter(cf crypto.Filter, event security.AuthEvent) *PdfObjectDictionary {
 	if event == "" {
 		event = security.EventDocOpen
@@ -410,11 +631,19 @@ func (crypt *PdfCrypt) saveCryptFilters(ed *PdfObjectDictionary) error {
 		if name == "Identity" {
 			continue
 		}
-		v := encodeCryptFilter(filter, "")
+		var event security.AuthEvent
+		if name != crypt.streamFilter && name != crypt.stringFilter {
+			// The filter of the embedded files only is applied when the embedded file is opened.
+			event = security.EventEFOpen
+		}
+		v := encodeCryptFilter(filter, event)
 		cf.Set(PdfObjectName(name), v)
 	}
 	ed.Set("StrF", MakeName(crypt.stringFilter))
 	ed.Set("StmF", MakeName(crypt.streamFilter))
+	if crypt.embeddedFileFilter != "" && crypt.embeddedFileFilter != crypt.streamFilter {
+		ed.Set("EFF", MakeName(crypt.embeddedFileFilter))
+	}
 	return nil
 }
 
@@ -434,13 +663,17 @@ func PdfCryptNewDecrypt(parser *PdfParser, ed, trailer *PdfObjectDictionary) (*P
 		common.Log.Debug("ERROR Crypt dictionary missing required Filter field!")
 		return crypter, errors.New("required crypt field Filter missing")
 	}
-	if *filter != "Standard" {
+	if *filter != stdFilter && *filter != pubKeyFilter {
 		common.Log.Debug("ERROR Unsupported filter (%s)", *filter)
 		return crypter, errors.New("unsupported Filter")
 	}
 	crypter.encrypt.Filter = string(*filter)
 
-	if subfilter, ok := ed.Get("SubFilter").(*PdfObjectString); ok {
+	switch subfilter := ed.Get("SubFilter").(type) {
+	case *PdfObjectName:
+		crypter.encrypt.SubFilter = string(*subfilter)
+		common.Log.Debug("Using subfilter %s", subfilter)
+	case *PdfObjectString:
 		crypter.encrypt.SubFilter = subfilter.Str()
 		common.Log.Debug("Using subfilter %s", subfilter)
 	}
@@ -472,8 +705,13 @@ func PdfCryptNewDecrypt(parser *PdfParser, ed, trailer *PdfObjectDictionary) (*P
 		}
 	}
 
-	// decode Standard security handler parameters
-	if err := decodeEncryptStd(&crypter.encryptStd, ed); err != nil {
+	if crypter.isPubKey() {
+		// decode public-key security handler parameters
+		if err := crypter.decodeEncryptPubKey(ed); err != nil {
+			return crypter, err
+		}
+	} else if err := decodeEncryptStd(&crypter.encryptStd, ed); err != nil {
+		// decode Standard security handler parameters
 		return crypter, err
 	}
 
@@ -495,11 +733,87 @@ func PdfCryptNewDecrypt(parser *PdfParser, ed, trailer *PdfObjectDictionary) (*P
 	return crypter, nil
 }
 
+// decodeEncryptPubKey decodes fields of the public-key security handler from an Encrypt dictionary
+// or from its default crypt filter dictionary (V>=4).
+func (crypt *PdfCrypt) decodeEncryptPubKey(ed *PdfObjectDictionary) error {
+	d := ed
+	if crypt.encrypt.V >= 4 {
+		cf, err := crypt.resolveDict(ed.Get("CF"))
+		if err != nil {
+			return err
+		}
+		name := crypt.documentFilter()
+		if d, err = crypt.resolveDict(cf.Get(PdfObjectName(name))); err != nil {
+			return fmt.Errorf("invalid crypt filter %s: %v", name, err)
+		}
+	}
+
+	var recipients []PdfObject
+	switch obj := TraceToDirectObject(d.Get("Recipients")).(type) {
+	case *PdfObjectArray:
+		recipients = obj.Elements()
+	case *PdfObjectString:
+		recipients = []PdfObject{obj}
+	default:
+		return errors.New("encrypt dictionary missing Recipients")
+	}
+	crypt.encryptPubKey.Recipients = nil
+	for _, obj := range recipients {
+		envelope, ok := GetString(obj)
+		if !ok {
+			return errors.New("invalid Recipients")
+		}
+		crypt.encryptPubKey.Recipients = append(crypt.encryptPubKey.Recipients, envelope.Bytes())
+	}
+
+	if em, ok := d.Get("EncryptMetadata").(*PdfObjectBool); ok {
+		crypt.encryptPubKey.EncryptMetadata = bool(*em)
+	} else {
+		crypt.encryptPubKey.EncryptMetadata = true // True by default.
+	}
+	return nil
+}
+
+// resolveDict returns the dictionary 'obj', resolving the reference if needed.
+func (crypt *PdfCrypt) resolveDict(obj PdfObject) (*PdfObjectDictionary, error) {
+	if ref, isRef := obj.(*PdfObjectReference); isRef {
+		o, err := crypt.parser.LookupByReference(*ref)
+		if err != nil {
+			return nil, err
+		}
+		obj = o
+	}
+	dict, ok := TraceToDirectObject(obj).(*PdfObjectDictionary)
+	if !ok {
+		return nil, fmt.Errorf("not a dictionary but %T", obj)
+	}
+	return dict, nil
+}
+
+// isPubKey checks whether the document is encrypted by the public-key security handler.
+func (crypt *PdfCrypt) isPubKey() bool {
+	return crypt.encrypt.Filter == pubKeyFilter
+}
+
 // GetAccessPermissions returns the PDF access permissions as an AccessPermissions object.
 func (crypt *PdfCrypt) GetAccessPermissions() security.Permissions {
+	if crypt.isPubKey() {
+		return crypt.pubKeyPerms
+	}
 	return crypt.encryptStd.P
 }
 
+// pubKeyHandler returns the public-key security handler with the key length of the document.
+func (crypt *PdfCrypt) pubKeyHandler() security.PubKeyHandler {
+	length := crypt.encrypt.Length / 8
+	if crypt.encrypt.V >= 4 {
+		if f, ok := crypt.cryptFilters[crypt.documentFilter()]; ok {
+			length = f.KeyLength()
+		}
+	}
+	return security.NewPubKeyHandler(length)
+}
+
 func (crypt *PdfCrypt) securityHandler() security.StdHandler {
 	if crypt.encryptStd.R >= 5 {
 		return security.NewHandlerR6()
@@ -511,6 +825,10 @@ func (crypt *PdfCrypt) securityHandler() security.StdHandler {
 // Also build the encryption/decryption key.
 func (crypt *PdfCrypt) authenticate(password []byte) (bool, error) {
 	crypt.authenticated = false
+	if crypt.isPubKey() {
+		common.Log.Debug("Public-key encrypted document cannot be decrypted with a password")
+		return false, nil
+	}
 	h := crypt.securityHandler()
 	fkey, perm, err := h.Authenticate(&crypt.encryptStd, password)
 	if err != nil {
@@ -523,6 +841,26 @@ func (crypt *PdfCrypt) authenticate(password []byte) (bool, error) {
 	return true, nil
 }
 
+// Check whether the recipient's certificate and private key can be used to decrypt the document
+// encrypted by the public-key security handler. Also build the encryption/decryption key.
+func (crypt *PdfCrypt) authenticatePubKey(cert *x509.Certificate, pkey gocrypto.PrivateKey) (bool, error) {
+	crypt.authenticated = false
+	if !crypt.isPubKey() {
+		return false, errors.New("document not encrypted with a public key")
+	}
+	h := crypt.pubKeyHandler()
+	fkey, perm, err := h.Authenticate(&crypt.encryptPubKey, cert, pkey)
+	if err != nil {
+		return false, err
+	} else if len(fkey) == 0 {
+		return false, nil
+	}
+	crypt.authenticated = true
+	crypt.encryptionKey = fkey
+	crypt.pubKeyPerms = perm
+	return true, nil
+}
+
 // Check access rights and permissions for a specified password.  If either user/owner password is specified,
 // full rights are granted, otherwise the access rights are specified by the Permissions flag.
 //
@@ -530,6 +868,10 @@ func (crypt *PdfCrypt) authenticate(password []byte) (bool, error) {
 // The AccessPermissions shows what access the user has for editing etc.
 // An error is returned if there was a problem performing the authentication.
 func (crypt *PdfCrypt) checkAccessRights(password []byte) (bool, security.Permissions, error) {
+	if crypt.isPubKey() {
+		// The access rights are granted to the recipients only.
+		return false, 0, nil
+	}
 	h := crypt.securityHandler()
 	// TODO(dennwc): it computes an encryption key as well; if necessary, define a new interface method to optimize this
 	fkey, perm, err := h.Authenticate(&crypt.encryptStd, password)
@@ -646,40 +988,11 @@ func (crypt *PdfCrypt) Decrypt(obj PdfObject, parentObjNum, parentGenNum int64)
 		genNum := obj.GenerationNumber
 		common.Log.Trace("Decrypting stream %d %d !", objNum, genNum)
 
-		// TODO: Check for crypt filter (V4).
-		// The Crypt filter shall be the first filter in the Filter array entry.
-
-		streamFilter := stdCryptFilter // Default RC4.
-		if crypt.encrypt.V >= 4 {
-			streamFilter = crypt.streamFilter
-			common.Log.Trace("this.streamFilter = %s", crypt.streamFilter)
-
-			if filters, ok := dict.Get("Filter").(*PdfObjectArray); ok {
-				// Crypt filter can only be the first entry.
-				if firstFilter, ok := GetName(filters.Get(0)); ok {
-					if *firstFilter == "Crypt" {
-						// Crypt filter overriding the default.
-						// Default option is Identity.
-						streamFilter = "Identity"
-
-						// Check if valid crypt filter specified in the decode params.
-						if decodeParams, ok := dict.Get("DecodeParms").(*PdfObjectDictionary); ok {
-							if filterName, ok := decodeParams.Get("Name").(*PdfObjectName); ok {
-								if _, ok := crypt.cryptFilters[string(*filterName)]; ok {
-									common.Log.Trace("Using stream filter %s", *filterName)
-									streamFilter = string(*filterName)
-								}
-							}
-						}
-					}
-				}
-			}
-
-			common.Log.Trace("with %s filter", streamFilter)
-			if streamFilter == "Identity" {
-				// Identity: pass unchanged.
-				return nil
-			}
+		streamFilter := crypt.streamCryptFilter(dict)
+		common.Log.Trace("with %s filter", streamFilter)
+		if streamFilter == "Identity" {
+			// Identity: pass unchanged.
+			return nil
 		}
 
 		err := crypt.Decrypt(dict, objNum, genNum)
@@ -692,6 +1005,13 @@ func (crypt *PdfCrypt) Decrypt(obj PdfObject, parentObjNum, parentGenNum int64)
 			return err
 		}
 
+		if obj.lazy != nil {
+			// The data is decrypted when read from the file.
+			obj.lazy.filter = streamFilter
+			obj.lazy.key = okey
+			return nil
+		}
+
 		obj.Stream, err = crypt.decryptBytes(obj.Stream, streamFilter, okey)
 		if err != nil {
 			return err
@@ -829,42 +1149,11 @@ func (crypt *PdfCrypt) Encrypt(obj PdfObject, parentObjNum, parentGenNum int64)
 		genNum := obj.GenerationNumber
 		common.Log.Trace("Encrypting stream %d %d !", objNum, genNum)
 
-		// TODO: Check for crypt filter (V4).
-		// The Crypt filter shall be the first filter in the Filter array entry.
-
-		streamFilter := stdCryptFilter // Default RC4.
-		if crypt.encrypt.V >= 4 {
-			// For now.  Need to change when we add support for more than
-			// Identity / RC4.
-			streamFilter = crypt.streamFilter
-			common.Log.Trace("this.streamFilter = %s", crypt.streamFilter)
-
-			if filters, ok := dict.Get("Filter").(*PdfObjectArray); ok {
-				// Crypt filter can only be the first entry.
-				if firstFilter, ok := GetName(filters.Get(0)); ok {
-					if *firstFilter == "Crypt" {
-						// Crypt filter overriding the default.
-						// Default option is Identity.
-						streamFilter = "Identity"
-
-						// Check if valid crypt filter specified in the decode params.
-						if decodeParams, ok := dict.Get("DecodeParms").(*PdfObjectDictionary); ok {
-							if filterName, ok := decodeParams.Get("Name").(*PdfObjectName); ok {
-								if _, ok := crypt.cryptFilters[string(*filterName)]; ok {
-									common.Log.Trace("Using stream filter %s", *filterName)
-									streamFilter = string(*filterName)
-								}
-							}
-						}
-					}
-				}
-			}
-
-			common.Log.Trace("with %s filter", streamFilter)
-			if streamFilter == "Identity" {
-				// Identity: pass unchanged.
-				return nil
-			}
+		streamFilter := crypt.streamCryptFilter(dict)
+		common.Log.Trace("with %s filter", streamFilter)
+		if streamFilter == "Identity" {
+			// Identity: pass unchanged.
+			return nil
 		}
 
 		err := crypt.Encrypt(obj.PdfObjectDictionary, objNum, genNum)
@@ -8,10 +8,12 @@
 package core
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/unidoc/unidoc/common"
 	"github.com/unidoc/unidoc/pdf/core/security"
+	crypto "github.com/unidoc/unidoc/pdf/core/security/crypt"
 )
 
 func init() {
@@ -95,3 +97,134 @@ func TestDecryption1(t *testing.T) {
 		return
 	}
 }
+
+// Test encrypting the embedded files only with the crypt filters and decrypting them.
+func TestCryptFiltersEmbeddedFiles(t *testing.T) {
+	opts := &CryptFilterOptions{IdentityStreams: true, IdentityStrings: true, IdentityEmbeddedFiles: true}
+	if _, _, err := PdfCryptNewEncryptWithOptions(crypto.NewFilterAESV2(), []byte("user"), []byte("owner"),
+		security.PermOwner, opts); err == nil {
+		t.Errorf("Options leaving all objects unencrypted should be rejected")
+	}
+
+	opts = &CryptFilterOptions{IdentityStreams: true, IdentityStrings: true}
+	crypter, info, err := PdfCryptNewEncryptWithOptions(crypto.NewFilterAESV2(), []byte("user"), []byte("owner"),
+		security.PermOwner, opts)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	ed := info.Encrypt
+	for key, exp := range map[PdfObjectName]string{"StmF": "Identity", "StrF": "Identity", "EFF": stdCryptFilter} {
+		if name, ok := GetNameVal(ed.Get(key)); !ok || name != exp {
+			t.Errorf("Wrong %s: %v, expected %s", key, ed.Get(key), exp)
+		}
+	}
+	cf, _ := GetDict(ed.Get("CF"))
+	stdCF, _ := GetDict(cf.Get(stdCryptFilter))
+	if event, _ := GetNameVal(stdCF.Get("AuthEvent")); event != string(security.EventEFOpen) {
+		t.Errorf("Wrong AuthEvent: %s", event)
+	}
+
+	data := []byte("embedded file data")
+	makeStreams := func() (*PdfObjectStream, *PdfObjectStream, *PdfObjectString) {
+		content, _ := MakeStream(data, nil)
+		content.ObjectNumber = 1
+		embedded, _ := MakeStream(data, nil)
+		embedded.ObjectNumber = 2
+		embedded.Set("Type", MakeName("EmbeddedFile"))
+		embedded.Set("Desc", MakeString(string(data)))
+		return content, embedded, MakeString(string(data))
+	}
+	content, embedded, str := makeStreams()
+	for _, obj := range []PdfObject{content, embedded, str} {
+		if err := crypter.Encrypt(obj, 3, 0); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	if !bytes.Equal(content.Stream, data) || str.Str() != string(data) {
+		t.Errorf("Content stream and string should not be encrypted")
+	}
+	if bytes.Equal(embedded.Stream, data) {
+		t.Errorf("Embedded file should be encrypted")
+	}
+	if desc, _ := GetStringVal(embedded.Get("Desc")); desc != string(data) {
+		t.Errorf("String of the embedded file dictionary should not be encrypted")
+	}
+
+	trailer := MakeDict()
+	trailer.Set("ID", MakeArray(MakeString(info.ID0), MakeString(info.ID1)))
+	decrypter, err := PdfCryptNewDecrypt(nil, ed, trailer)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if ok, err := decrypter.authenticate([]byte("user")); err != nil || !ok {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+	for _, obj := range []PdfObject{content, embedded, str} {
+		if err := decrypter.Decrypt(obj, 3, 0); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	if !bytes.Equal(content.Stream, data) || !bytes.Equal(embedded.Stream, data) || str.Str() != string(data) {
+		t.Errorf("Decryption failed")
+	}
+}
+
+// Test the Crypt filter of a stream overriding the stream filter of the document.
+func TestCryptFilterStream(t *testing.T) {
+	crypter, info, err := PdfCryptNewEncrypt(crypto.NewFilterAESV2(), []byte("user"), []byte("owner"), security.PermOwner)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	data := []byte("<x:xmpmeta/>")
+	makeStream := func(num int64, filter PdfObject, params PdfObject) *PdfObjectStream {
+		stream, _ := MakeStream(data, nil)
+		stream.ObjectNumber = num
+		stream.Set("Filter", filter)
+		if params != nil {
+			stream.Set("DecodeParms", params)
+		}
+		return stream
+	}
+	named := MakeDict()
+	named.Set("Type", MakeName("CryptFilterDecodeParms"))
+	named.Set("Name", MakeName(stdCryptFilter))
+	streams := []*PdfObjectStream{
+		makeStream(1, MakeName("Crypt"), nil),
+		makeStream(2, MakeArray(MakeName("Crypt")), MakeArray(MakeNull())),
+		makeStream(3, MakeArray(MakeName("Crypt")), MakeArray(named)),
+	}
+	for _, stream := range streams {
+		if err := crypter.Encrypt(stream, 0, 0); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	if !bytes.Equal(streams[0].Stream, data) || !bytes.Equal(streams[1].Stream, data) {
+		t.Errorf("Streams with the Identity crypt filter should not be encrypted")
+	}
+	if bytes.Equal(streams[2].Stream, data) {
+		t.Errorf("Stream with the named crypt filter should be encrypted")
+	}
+
+	trailer := MakeDict()
+	trailer.Set("ID", MakeArray(MakeString(info.ID0), MakeString(info.ID1)))
+	decrypter, err := PdfCryptNewDecrypt(nil, info.Encrypt, trailer)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if ok, err := decrypter.authenticate([]byte("user")); err != nil || !ok {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+	for i, stream := range streams {
+		if err := decrypter.Decrypt(stream, 0, 0); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		decoded, err := DecodeStream(stream)
+		if err != nil {
+			t.Fatalf("Stream %d: %v", i, err)
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Errorf("Stream %d: wrong data %q", i, decoded)
+		}
+	}
+}
@@ -14,8 +14,8 @@ package core
 // - ASCII Hex
 // - ASCII85
 // - CCITT Fax (dummy)
-// - JBIG2 (dummy)
-// - JPX (dummy)
+// - JBIG2 (generic region and symbol encoding)
+// - JPX (decoding only)
 
 import (
 	"bytes"
@@ -36,6 +36,9 @@ import (
 
 	"github.com/unidoc/unidoc/common"
 	"github.com/unidoc/unidoc/pdf/internal/ccittfax"
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/bitmap"
+	jbig2enc "github.com/unidoc/unidoc/pdf/internal/jbig2/encoder"
+	"github.com/unidoc/unidoc/pdf/internal/jpeg2000"
 )
 
 // Stream encoding filter names.
@@ -49,6 +52,7 @@ const (
 	StreamEncodingFilterNameCCITTFax  = "CCITTFaxDecode"
 	StreamEncodingFilterNameJBIG2     = "JBIG2Decode"
 	StreamEncodingFilterNameJPX       = "JPXDecode"
+	StreamEncodingFilterNameCrypt     = "Crypt"
 	StreamEncodingFilterNameRaw       = "Raw"
 )
 
@@ -97,8 +101,8 @@ func NewFlateEncoder() *FlateEncoder {
 // SetPredictor sets the predictor function.  Specify the number of columns per row.
 // The columns indicates the number of samples per row.
 // Used for grouping data together for compression.
+// Sets the PNG sub predictor, the other predictors can be set with the Predictor field.
 func (enc *FlateEncoder) SetPredictor(columns int) {
-	// Only supporting PNG sub predictor for encoding.
 	enc.Predictor = 11
 	enc.Columns = columns
 }
@@ -285,125 +289,19 @@ const (
 	pfPaeth = 4 // Paeth algorithm prediction.
 )
 
-// Apply predictor to decoded `outData` to get final output data.
-func (enc *FlateEncoder) postDecodePredict(outData []byte) ([]byte, error) {
-	if enc.Predictor > 1 {
-		if enc.Predictor == 2 { // TIFF encoding.
-			// TODO(gunnsth): Needs test cases.
-			common.Log.Trace("Tiff encoding")
-			common.Log.Trace("Colors: %d", enc.Colors)
-
-			rowLength := int(enc.Columns) * enc.Colors
-			if rowLength < 1 {
-				// No data. Return empty set.
-				return []byte{}, nil
-			}
-			rows := len(outData) / rowLength
-			if len(outData)%rowLength != 0 {
-				common.Log.Debug("ERROR: TIFF encoding: Invalid row length...")
-				return nil, fmt.Errorf("invalid row length (%d/%d)", len(outData), rowLength)
-			}
-			if rowLength%enc.Colors != 0 {
-				return nil, fmt.Errorf("invalid row length (%d) for colors %d", rowLength, enc.Colors)
-			}
-			if rowLength > len(outData) {
-				common.Log.Debug("Row length cannot be longer than data length (%d/%d)", rowLength, len(outData))
-				return nil, errors.New("range check error")
-			}
-			common.Log.Trace("inp outData (%d): % x", len(outData), outData)
-
-			pOutBuffer := bytes.NewBuffer(nil)
-
-			// 0-255  -255 255 ; 0-255=-255;
-			for i := 0; i < rows; i++ {
-				rowData := outData[rowLength*i : rowLength*(i+1)]
-				// Predicts the same as the sample to the left.
-				// Interleaved by colors.
-				for j := enc.Colors; j < rowLength; j++ {
-					rowData[j] += rowData[j-enc.Colors]
-				}
-				pOutBuffer.Write(rowData)
-			}
-			pOutData := pOutBuffer.Bytes()
-			common.Log.Trace("POutData (%d): % x", len(pOutData), pOutData)
-			return pOutData, nil
-		} else if enc.Predictor >= 10 && enc.Predictor <= 15 {
-			common.Log.Trace("PNG Encoding")
-			// Columns represents the number of samples per row; Each sample can contain multiple color
-			// components.
-			rowLength := int(enc.Columns*enc.Colors + 1) // 1 byte to specify predictor algorithms per row.
-			rows := len(outData) / rowLength
-			if len(outData)%rowLength != 0 {
-				return nil, fmt.Errorf("invalid row length (%d/%d)", len(outData), rowLength)
-			}
-			if rowLength > len(outData) {
-				common.Log.Debug("Row length cannot be longer than data length (%d/%d)", rowLength, len(outData))
-				return nil, errors.New("range check error")
-			}
-
-			pOutBuffer := bytes.NewBuffer(nil)
-
-			common.Log.Trace("Predictor columns: %d", enc.Columns)
-			common.Log.Trace("Length: %d / %d = %d rows", len(outData), rowLength, rows)
-			prevRowData := make([]byte, rowLength)
-			for i := 0; i < rowLength; i++ {
-				prevRowData[i] = 0
-			}
-
-			bytesPerPixel := enc.Colors // Assuming BPC = 8.
-
-			for i := 0; i < rows; i++ {
-				rowData := outData[rowLength*i : rowLength*(i+1)]
-
-				fb := rowData[0]
-				switch fb {
-				case pfNone:
-				case pfSub:
-					for j := 1 + bytesPerPixel; j < rowLength; j++ {
-						rowData[j] += rowData[j-bytesPerPixel]
-					}
-				case pfUp:
-					// Up: Predicts the same as the sample above
-					for j := 1; j < rowLength; j++ {
-						rowData[j] += prevRowData[j]
-					}
-				case pfAvg:
-					// Avg: Predicts the same as the average of the sample to the left and above.
-					for j := 1; j < bytesPerPixel+1; j++ {
-						rowData[j] += prevRowData[j] / 2
-					}
-					for j := bytesPerPixel + 1; j < rowLength; j++ {
-						rowData[j] += byte((int(rowData[j-bytesPerPixel]) + int(prevRowData[j])) / 2)
-					}
-				case pfPaeth:
-					// Paeth: a nonlinear function of the sample to the left (a), sample above (b)
-					// and the upper left (c).
-					for j := 1; j < rowLength; j++ {
-						var a, b, c byte
-						b = prevRowData[j] // above.
-						if j >= bytesPerPixel+1 {
-							a = rowData[j-bytesPerPixel]
-							c = prevRowData[j-bytesPerPixel]
-						}
-						rowData[j] += paeth(a, b, c)
-					}
-				default:
-					common.Log.Debug("ERROR: Invalid filter byte (%d) @row %d", fb, i)
-					return nil, fmt.Errorf("invalid filter byte (%d)", fb)
-				}
-
-				copy(prevRowData, rowData)
-				pOutBuffer.Write(rowData[1:])
-			}
-			pOutData := pOutBuffer.Bytes()
-			return pOutData, nil
-		} else {
-			common.Log.Debug("ERROR: Unsupported predictor (%d)", enc.Predictor)
-			return nil, fmt.Errorf("unsupported predictor (%d)", enc.Predictor)
-		}
+// predictorParams returns the parameters of the predictor function of the encoder.
+func (enc *FlateEncoder) predictorParams() predictorParams {
+	return predictorParams{
+		predictor: enc.Predictor,
+		bpc:       enc.BitsPerComponent,
+		columns:   enc.Columns,
+		colors:    enc.Colors,
 	}
+}
 
-	return outData, nil
+// Apply predictor to decoded `outData` to get final output data.
+func (enc *FlateEncoder) postDecodePredict(outData []byte) ([]byte, error) {
+	return enc.predictorParams().decode(outData)
 }
 
 // DecodeStream decodes a FlateEncoded stream object and give back decoded bytes.
@@ -412,9 +310,6 @@ func (enc *FlateEncoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, error
 
 	common.Log.Trace("FlateDecode stream")
 	common.Log.Trace("Predictor: %d", enc.Predictor)
-	if enc.BitsPerComponent != 8 {
-		return nil, fmt.Errorf("invalid BitsPerComponent=%d (only 8 supported)", enc.BitsPerComponent)
-	}
 
 	outData, err := enc.DecodeBytes(streamObj.Stream)
 	if err != nil {
@@ -425,44 +320,14 @@ func (enc *FlateEncoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, error
 }
 
 // EncodeBytes encodes a bytes array and return the encoded value based on the encoder parameters.
+// The data is compressed after applying the TIFF or PNG predictor function if set.
 func (enc *FlateEncoder) EncodeBytes(data []byte) ([]byte, error) {
-	if enc.Predictor != 1 && enc.Predictor != 11 {
-		common.Log.Debug("Encoding error: FlateEncoder Predictor = 1, 11 only supported")
+	data, err := enc.predictorParams().encode(data)
+	if err != nil {
+		common.Log.Debug("Encoding error: FlateEncoder Predictor = %d: %v", enc.Predictor, err)
 		return nil, ErrUnsupportedEncodingParameters
 	}
 
-	if enc.Predictor == 11 {
-		// The length of each output row in number of samples.
-		// N.B. Each output row has one extra sample as compared to the input to indicate the
-		// predictor type.
-		rowLength := int(enc.Columns)
-		rows := len(data) / rowLength
-		if len(data)%rowLength != 0 {
-			common.Log.Error("Invalid column length")
-			return nil, errors.New("invalid row length")
-		}
-
-		pOutBuffer := bytes.NewBuffer(nil)
-
-		tmpData := make([]byte, rowLength)
-
-		for i := 0; i < rows; i++ {
-			rowData := data[rowLength*i : rowLength*(i+1)]
-
-			// PNG SUB method.
-			// Sub: Predicts the same as the sample to the left.
-			tmpData[0] = rowData[0]
-			for j := 1; j < rowLength; j++ {
-				tmpData[j] = byte(int(rowData[j]-rowData[j-1]) % 256)
-			}
-
-			pOutBuffer.WriteByte(1) // sub method
-			pOutBuffer.Write(tmpData)
-		}
-
-		data = pOutBuffer.Bytes()
-	}
-
 	var b bytes.Buffer
 	w := zlib.NewWriter(&b)
 	w.Write(data)
@@ -507,8 +372,12 @@ func (enc *LZWEncoder) GetFilterName() string {
 // MakeDecodeParams makes a new instance of an encoding dictionary based on
 // the current encoder settings.
 func (enc *LZWEncoder) MakeDecodeParams() PdfObject {
+	if enc.Predictor <= 1 && enc.EarlyChange == 1 {
+		return nil
+	}
+
+	decodeParams := MakeDict()
 	if enc.Predictor > 1 {
-		decodeParams := MakeDict()
 		decodeParams.Set("Predictor", MakeInteger(int64(enc.Predictor)))
 
 		// Only add if not default option.
@@ -521,9 +390,11 @@ func (enc *LZWEncoder) MakeDecodeParams() PdfObject {
 		if enc.Colors != 1 {
 			decodeParams.Set("Colors", MakeInteger(int64(enc.Colors)))
 		}
-		return decodeParams
 	}
-	return nil
+	if enc.EarlyChange != 1 {
+		decodeParams.Set("EarlyChange", MakeInteger(int64(enc.EarlyChange)))
+	}
+	return decodeParams
 }
 
 // MakeStreamDict makes a new instance of an encoding dictionary for a stream object.
@@ -538,8 +409,6 @@ func (enc *LZWEncoder) MakeStreamDict() *PdfObjectDictionary {
 		dict.Set("DecodeParms", decodeParams)
 	}
 
-	dict.Set("EarlyChange", MakeInteger(int64(enc.EarlyChange)))
-
 	return dict
 }
 
@@ -607,7 +476,12 @@ func newLZWEncoderFromStream(streamObj *PdfObjectStream, decodeParams *PdfObject
 	// implementations use a different mechanisms. Essentially this chooses
 	// which LZW implementation to use.
 	// The default is 1 (one code early)
+	// The EarlyChange is an entry of the DecodeParms, the stream dictionary is checked as well
+	// for the streams written by the earlier versions.
 	obj := encDict.Get("EarlyChange")
+	if decodeParams != nil && decodeParams.Get("EarlyChange") != nil {
+		obj = decodeParams.Get("EarlyChange")
+	}
 	if obj != nil {
 		earlyChange, ok := obj.(*PdfObjectInteger)
 		if !ok {
@@ -706,11 +580,6 @@ func (enc *LZWEncoder) DecodeBytes(encoded []byte) ([]byte, error) {
 // DecodeStream decodes a LZW encoded stream and returns the result as a
 // slice of bytes.
 func (enc *LZWEncoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, error) {
-	// Revamp this support to handle TIFF predictor (2).
-	// Also handle more filter bytes and check
-	// BitsPerComponent.  Default value is 8, currently we are only
-	// supporting that one.
-
 	common.Log.Trace("LZW Decoding")
 	common.Log.Trace("Predictor: %d", enc.Predictor)
 
@@ -722,133 +591,33 @@ func (enc *LZWEncoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, error)
 	common.Log.Trace(" IN: (%d) % x", len(streamObj.Stream), streamObj.Stream)
 	common.Log.Trace("OUT: (%d) % x", len(outData), outData)
 
-	if enc.Predictor > 1 {
-		if enc.Predictor == 2 { // TIFF encoding: Needs some tests.
-			common.Log.Trace("Tiff encoding")
-
-			rowLength := int(enc.Columns) * enc.Colors
-			if rowLength < 1 {
-				// No data. Return empty set.
-				return []byte{}, nil
-			}
-
-			rows := len(outData) / rowLength
-			if len(outData)%rowLength != 0 {
-				common.Log.Debug("ERROR: TIFF encoding: Invalid row length...")
-				return nil, fmt.Errorf("invalid row length (%d/%d)", len(outData), rowLength)
-			}
-
-			if rowLength%enc.Colors != 0 {
-				return nil, fmt.Errorf("invalid row length (%d) for colors %d", rowLength, enc.Colors)
-			}
-
-			if rowLength > len(outData) {
-				common.Log.Debug("Row length cannot be longer than data length (%d/%d)", rowLength, len(outData))
-				return nil, errors.New("range check error")
-			}
-			common.Log.Trace("inp outData (%d): % x", len(outData), outData)
-
-			pOutBuffer := bytes.NewBuffer(nil)
-
-			// 0-255  -255 255 ; 0-255=-255;
-			for i := 0; i < rows; i++ {
-				rowData := outData[rowLength*i : rowLength*(i+1)]
-				// Predicts the same as the sample to the left.
-				// Interleaved by colors.
-				for j := enc.Colors; j < rowLength; j++ {
-					rowData[j] = byte(int(rowData[j]+rowData[j-enc.Colors]) % 256)
-				}
-				// GH: Appears that this is not working as expected...
-
-				pOutBuffer.Write(rowData)
-			}
-			pOutData := pOutBuffer.Bytes()
-			common.Log.Trace("POutData (%d): % x", len(pOutData), pOutData)
-			return pOutData, nil
-		} else if enc.Predictor >= 10 && enc.Predictor <= 15 {
-			common.Log.Trace("PNG Encoding")
-			// Columns represents the number of samples per row; Each sample can contain multiple color
-			// components.
-			rowLength := int(enc.Columns*enc.Colors + 1) // 1 byte to specify predictor algorithms per row.
-			if rowLength < 1 {
-				// No data. Return empty set.
-				return []byte{}, nil
-			}
-			rows := len(outData) / rowLength
-			if len(outData)%rowLength != 0 {
-				return nil, fmt.Errorf("invalid row length (%d/%d)", len(outData), rowLength)
-			}
-			if rowLength > len(outData) {
-				common.Log.Debug("Row length cannot be longer than data length (%d/%d)", rowLength, len(outData))
-				return nil, errors.New("range check error")
-			}
-
-			pOutBuffer := bytes.NewBuffer(nil)
-
-			common.Log.Trace("Predictor columns: %d", enc.Columns)
-			common.Log.Trace("Length: %d / %d = %d rows", len(outData), rowLength, rows)
-			prevRowData := make([]byte, rowLength)
-			for i := 0; i < rowLength; i++ {
-				prevRowData[i] = 0
-			}
-
-			for i := 0; i < rows; i++ {
-				rowData := outData[rowLength*i : rowLength*(i+1)]
-
-				fb := rowData[0]
-				switch fb {
-				case 0:
-					// No prediction. (No operation).
-				case 1:
-					// Sub: Predicts the same as the sample to the left.
-					for j := 2; j < rowLength; j++ {
-						rowData[j] = byte(int(rowData[j]+rowData[j-1]) % 256)
-					}
-				case 2:
-					// Up: Predicts the same as the sample above
-					for j := 1; j < rowLength; j++ {
-						rowData[j] = byte(int(rowData[j]+prevRowData[j]) % 256)
-					}
-				default:
-					common.Log.Debug("ERROR: Invalid filter byte (%d)", fb)
-					return nil, fmt.Errorf("invalid filter byte (%d)", fb)
-				}
+	return enc.predictorParams().decode(outData)
+}
 
-				for i := 0; i < rowLength; i++ {
-					prevRowData[i] = rowData[i]
-				}
-				pOutBuffer.Write(rowData[1:])
-			}
-			pOutData := pOutBuffer.Bytes()
-			return pOutData, nil
-		} else {
-			common.Log.Debug("ERROR: Unsupported predictor (%d)", enc.Predictor)
-			return nil, fmt.Errorf("unsupported predictor (%d)", enc.Predictor)
-		}
+// predictorParams returns the parameters of the predictor function of the encoder.
+func (enc *LZWEncoder) predictorParams() predictorParams {
+	return predictorParams{
+		predictor: enc.Predictor,
+		bpc:       enc.BitsPerComponent,
+		columns:   enc.Columns,
+		colors:    enc.Colors,
 	}
-
-	return outData, nil
 }
 
-// EncodeBytes implements support for LZW encoding.  Currently not supporting predictors (raw compressed data only).
-// Only supports the Early change = 1 algorithm (compress/lzw) as the other implementation
-// does not have a write method.
-// TODO: Consider refactoring compress/lzw to allow both.
+// EncodeBytes implements support for LZW encoding.  The data is compressed after applying
+// the TIFF or PNG predictor function if set. The code length increases one code early
+// if the EarlyChange is 1 and is postponed if 0.
 func (enc *LZWEncoder) EncodeBytes(data []byte) ([]byte, error) {
-	if enc.Predictor != 1 {
-		return nil, fmt.Errorf("LZW Predictor = 1 only supported yet")
+	if enc.EarlyChange != 0 && enc.EarlyChange != 1 {
+		return nil, fmt.Errorf("invalid EarlyChange value (not 0 or 1)")
 	}
 
-	if enc.EarlyChange == 1 {
-		return nil, fmt.Errorf("LZW Early Change = 0 only supported yet")
+	data, err := enc.predictorParams().encode(data)
+	if err != nil {
+		return nil, err
 	}
 
-	var b bytes.Buffer
-	w := lzw0.NewWriter(&b, lzw0.MSB, 8)
-	w.Write(data)
-	w.Close()
-
-	return b.Bytes(), nil
+	return lzwEncode(data, enc.EarlyChange), nil
 }
 
 // DCTEncoder provides a DCT (JPG) encoding/decoding functionality for images.
@@ -937,7 +706,10 @@ func newDCTEncoderFromStream(streamObj *PdfObjectStream, multiEnc *MultiEncoder)
 	}
 
 	// If using DCTDecode in combination with other filters, make sure to decode that first...
-	encoded := streamObj.Stream
+	encoded, err := streamObj.encodedData()
+	if err != nil {
+		return nil, err
+	}
 	if multiEnc != nil {
 		e, err := multiEnc.DecodeBytes(encoded)
 		if err != nil {
@@ -1981,8 +1753,16 @@ const (
 	jbig2Globals = "JBIG2Globals"
 )
 
-// JBIG2Encoder implements JBIG2 encoder/decoder (dummy, for now)
-// FIXME: implement
+// JBIG2Image is a 1 bit per component image encoded with the JBIG2Encoder. Each row of the
+// image data starts at a byte boundary.
+type JBIG2Image struct {
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// JBIG2Encoder implements JBIG2 encoder/decoder. The encoding produces a single page
+// with one lossless generic region or, in the symbol mode, with one text region.
 type JBIG2Encoder struct {
 	// Globals are the JBIG2 global segments
 	Globals jbig2.Globals
@@ -1990,11 +1770,36 @@ type JBIG2Encoder struct {
 	// IsChocolateData defines if the data is encoded such that one means when the binary data '1' means black and '0' white
 	// otherwise the data is called vanilla
 	IsChocolateData bool
+
+	// Width and Height of the encoded 1 bit per component image, required for encoding.
+	Width  int
+	Height int
+
+	// DuplicateLineRemoval enables the typical prediction of the generic region encoding,
+	// which codes the rows identical to the row above with a single bit.
+	DuplicateLineRemoval bool
+
+	// SymbolMode enables the symbol coding. The connected components of the image are stored
+	// in a symbol dictionary and placed by a text region, which is much smaller for the scanned
+	// text pages. Enabled by EncodeGlobals.
+	SymbolMode bool
+
+	// SymbolThreshold is the maximal fraction of the differing pixels of the same sized components
+	// coded with the same symbol. Zero keeps the symbol coding lossless.
+	SymbolThreshold float64
+
+	// globalsStream is the JBIG2Globals stream of the encoded or decoded images.
+	globalsStream *PdfObjectStream
+
+	// encoder keeps the global symbol dictionary used by the encoded images.
+	encoder *jbig2enc.Encoder
 }
 
 // NewJBIG2Encoder returns a new instance of JBIG2Encoder.
 func NewJBIG2Encoder() *JBIG2Encoder {
-	return &JBIG2Encoder{}
+	return &JBIG2Encoder{
+		DuplicateLineRemoval: true,
+	}
 }
 
 func (enc *JBIG2Encoder) setChocolateData(decode PdfObject) {
@@ -2064,13 +1869,19 @@ func newJBIG2EncoderFromStream(streamObj *PdfObjectStream, decodeParams *PdfObje
 	if decodeParams != nil {
 
 		if globals := decodeParams.Get("JBIG2Globals"); globals != nil {
-			globalsStream, ok := globals.(*PdfObjectStream)
+			globalsStream, ok := GetStream(globals)
 			if !ok {
 				err := errors.New("the Globals stream should be an Object Stream")
 				common.Log.Debug("ERROR: %s", err.Error())
 				return nil, err
 			}
-			gdoc, err := jbig2.NewDocument(globalsStream.Stream)
+			data, err := DecodeStream(globalsStream)
+			if err != nil {
+				err = fmt.Errorf("decoding global stream failed. %s", err.Error())
+				common.Log.Debug("ERROR: %s", err)
+				return nil, err
+			}
+			gdoc, err := jbig2.NewDocument(data)
 			if err != nil {
 				err = fmt.Errorf("decoding global stream failed. %s", err.Error())
 				common.Log.Debug("ERROR: %s", err)
@@ -2078,12 +1889,20 @@ func newJBIG2EncoderFromStream(streamObj *PdfObjectStream, decodeParams *PdfObje
 			}
 
 			encoder.Globals = gdoc.GlobalSegments
+			encoder.globalsStream = globalsStream
 		}
 	}
 	if decode := streamObj.Get("Decode"); decode != nil {
 		encoder.setChocolateData(decode)
 	}
 
+	if width, err := GetNumberAsInt64(encDict.Get("Width")); err == nil {
+		encoder.Width = int(width)
+	}
+	if height, err := GetNumberAsInt64(encDict.Get("Height")); err == nil {
+		encoder.Height = int(height)
+	}
+
 	return encoder, nil
 }
 
@@ -2093,9 +1912,14 @@ func (enc *JBIG2Encoder) GetFilterName() string {
 }
 
 // MakeDecodeParams makes a new instance of an encoding dictionary based on
-// the current encoder settings.
+// the current encoder settings. The dictionary refers to the JBIG2Globals stream if any.
 func (enc *JBIG2Encoder) MakeDecodeParams() PdfObject {
-	return MakeDict()
+	if enc.globalsStream == nil {
+		return nil
+	}
+	decodeParams := MakeDict()
+	decodeParams.Set(jbig2Globals, enc.globalsStream)
+	return decodeParams
 }
 
 // MakeStreamDict makes a new instance of an encoding dictionary for a stream object.
@@ -2107,6 +1931,11 @@ func (enc *JBIG2Encoder) MakeStreamDict() *PdfObjectDictionary {
 	}
 	dict.Set("Filter", MakeName(enc.GetFilterName()))
 
+	decodeParams := enc.MakeDecodeParams()
+	if decodeParams != nil {
+		dict.Set("DecodeParms", decodeParams)
+	}
+
 	return dict
 }
 
@@ -2115,6 +1944,16 @@ func (enc *JBIG2Encoder) UpdateParams(params *PdfObjectDictionary) {
 	if decode := params.Get("Decode"); decode != nil {
 		enc.setChocolateData(decode)
 	}
+
+	width, err := GetNumberAsInt64(params.Get("Width"))
+	if err == nil {
+		enc.Width = int(width)
+	}
+
+	height, err := GetNumberAsInt64(params.Get("Height"))
+	if err == nil {
+		enc.Height = int(height)
+	}
 }
 
 // DecodeBytes decode the jbig2 raw 'encoded' data
@@ -2157,19 +1996,212 @@ func (enc *JBIG2Encoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, error
 	return enc.DecodeBytes(streamObj.Stream)
 }
 
-// EncodeBytes encodes the raw data bytes into the jbig2 encoded data.
+// EncodeBytes encodes the 1 bit per component image 'data' into the jbig2 encoded data.
+// Each row of the image should start at a byte boundary. The data is interpreted as chocolate
+// if IsChocolateData is set and as vanilla otherwise. In the symbol mode the image refers
+// to the global symbol dictionary created by EncodeGlobals.
 func (enc *JBIG2Encoder) EncodeBytes(data []byte) ([]byte, error) {
-	common.Log.Debug("Error: Attempting to use unsupported encoding %s", enc.GetFilterName())
-	return data, ErrNoJBIG2Decode
+	bm, err := enc.makeBitmap(enc.Width, enc.Height, data)
+	if err != nil {
+		return nil, err
+	}
+
+	e := enc.getEncoder()
+	if enc.SymbolMode {
+		return e.EncodeText(bm)
+	}
+	return e.EncodeGeneric(bm)
 }
 
-// JPXEncoder implements JPX encoder/decoder (dummy, for now)
-// FIXME: implement
-type JPXEncoder struct{}
+// EncodeGlobals creates the global symbol dictionary from the symbols repeated within the
+// 'images' and enables the symbol mode. The images are usually the pages of a scanned document,
+// encoded afterwards one by one with EncodeBytes. The dictionary is stored in the JBIG2Globals
+// stream referred by the DecodeParms of the encoded images, so that all of them share it.
+func (enc *JBIG2Encoder) EncodeGlobals(images ...JBIG2Image) error {
+	bms := make([]*bitmap.Bitmap, len(images))
+	for i, img := range images {
+		bm, err := enc.makeBitmap(img.Width, img.Height, img.Data)
+		if err != nil {
+			return err
+		}
+		bms[i] = bm
+	}
+
+	e := enc.getEncoder()
+	data, err := e.EncodeGlobals(bms...)
+	if err != nil {
+		return err
+	}
+	enc.SymbolMode = true
+	enc.Globals = nil
+	enc.globalsStream = nil
+	if data == nil {
+		common.Log.Debug("JBIG2 images have no shared symbols")
+		return nil
+	}
+
+	gdoc, err := jbig2.NewDocument(data)
+	if err != nil {
+		return err
+	}
+	globalsStream, err := MakeStream(data, nil)
+	if err != nil {
+		return err
+	}
+	enc.Globals = gdoc.GlobalSegments
+	enc.globalsStream = globalsStream
+	return nil
+}
+
+// getEncoder returns the JBIG2 encoder keeping the global symbol dictionary.
+func (enc *JBIG2Encoder) getEncoder() *jbig2enc.Encoder {
+	if enc.encoder == nil {
+		enc.encoder = &jbig2enc.Encoder{}
+	}
+	enc.encoder.DuplicateLineRemoval = enc.DuplicateLineRemoval
+	enc.encoder.ClassThreshold = enc.SymbolThreshold
+	return enc.encoder
+}
+
+// makeBitmap converts the 1 bit per component image 'data' of the size 'width' x 'height'
+// into the JBIG2 bitmap.
+func (enc *JBIG2Encoder) makeBitmap(width, height int, data []byte) (*bitmap.Bitmap, error) {
+	if width <= 0 || height <= 0 {
+		common.Log.Debug("ERROR: JBIG2 encoding requires the image dimensions (%dx%d)", width, height)
+		return nil, errors.New("invalid JBIG2 image dimensions")
+	}
+
+	bm := bitmap.New(width, height)
+	if len(data) < len(bm.Data) {
+		common.Log.Debug("ERROR: JBIG2 image data too short (%d < %d)", len(data), len(bm.Data))
+		return nil, ErrRangeError
+	}
+
+	// JBIG2 bitmaps are chocolate: the vanilla data has to be inverted.
+	copy(bm.Data, data)
+	if !enc.IsChocolateData {
+		for i := range bm.Data {
+			bm.Data[i] = ^bm.Data[i]
+		}
+	}
+	return bm, nil
+}
+
+// JPXEncoder implements JPX (JPEG 2000) decoding. Encoding is not supported.
+type JPXEncoder struct {
+	ColorComponents  int // Number of colour components of the decoded image, without opacity.
+	BitsPerComponent int // 8 or 16 bit, determined by the precision of the JPEG 2000 data.
+	// Width and Height are the image size. When set, JPEG 2000 data of a different size is rejected.
+	Width  int
+	Height int
+
+	// SMaskInData specifies how the opacity channel of the image data is used (0, 1 or 2 as the
+	// SMaskInData entry of the image dictionary). When nonzero, the opacity is returned by
+	// DecodeBytesWithAlpha and premultiplied data (2) is converted to straight colours.
+	SMaskInData int
+
+	// ColorSpaceInDict is set when the image dictionary specifies a ColorSpace, which overrides
+	// any colour space specification of the JPEG 2000 data.
+	ColorSpaceInDict bool
+
+	// Indexed is set when the ColorSpace of the image dictionary is Indexed. The palette of the
+	// JPEG 2000 data is not applied then and the decoded samples are the palette indices.
+	Indexed bool
+}
 
 // NewJPXEncoder returns a new instance of JPXEncoder.
 func NewJPXEncoder() *JPXEncoder {
-	return &JPXEncoder{}
+	encoder := &JPXEncoder{}
+
+	encoder.ColorComponents = 3
+	encoder.BitsPerComponent = 8
+
+	return encoder
+}
+
+// newJPXEncoderFromStream creates a new JPX encoder/decoder from a stream object, getting the
+// parameters from the stream object dictionary entry and the image data itself.
+func newJPXEncoderFromStream(streamObj *PdfObjectStream, multiEnc *MultiEncoder) (*JPXEncoder, error) {
+	// Start with default settings.
+	encoder := NewJPXEncoder()
+
+	encDict := streamObj.PdfObjectDictionary
+	if encDict == nil {
+		// No encoding dictionary.
+		return encoder, nil
+	}
+
+	if smask, err := GetNumberAsInt64(TraceToDirectObject(encDict.Get("SMaskInData"))); err == nil {
+		encoder.SMaskInData = int(smask)
+	}
+	if csObj := TraceToDirectObject(encDict.Get("ColorSpace")); csObj != nil {
+		encoder.ColorSpaceInDict = true
+		encoder.Indexed = isIndexedColorSpace(csObj)
+	}
+	// The size of the image dictionary is checked against the JPEG 2000 data before decoding.
+	if width, err := GetNumberAsInt64(TraceToDirectObject(encDict.Get("Width"))); err == nil {
+		encoder.Width = int(width)
+	}
+	if height, err := GetNumberAsInt64(TraceToDirectObject(encDict.Get("Height"))); err == nil {
+		encoder.Height = int(height)
+	}
+
+	if bpc, err := GetNumberAsInt64(TraceToDirectObject(encDict.Get("BitsPerComponent"))); err == nil {
+		encoder.BitsPerComponent = int(bpc)
+	}
+
+	// The image header is read to determine the parameters of the decoded image. If it cannot be
+	// read, the encoder is still returned so that the image can be copied without decoding it.
+	// Decoding the image fails then.
+	encoded, err := streamObj.encodedData()
+	if err != nil {
+		common.Log.Debug("Error reading JPX image data: %v", err)
+		return encoder, nil
+	}
+	if multiEnc != nil {
+		// If used in combination with other filters, decode those first.
+		e, err := multiEnc.DecodeBytes(encoded)
+		if err != nil {
+			common.Log.Debug("Error decoding JPX image filters: %v", err)
+			return encoder, nil
+		}
+		encoded = e
+	}
+
+	cfg, err := jpeg2000.DecodeConfig(encoded, encoder.decodeOptions())
+	if err != nil {
+		common.Log.Debug("Error decoding JPX image header: %v", err)
+		return encoder, nil
+	}
+	encoder.Width = cfg.Width
+	encoder.Height = cfg.Height
+	encoder.ColorComponents = cfg.NumChannels
+	encoder.BitsPerComponent = 8
+	if cfg.Precision > 8 && !encoder.Indexed {
+		encoder.BitsPerComponent = 16
+	}
+	common.Log.Trace("JPX Encoder: %+v", encoder)
+
+	return encoder, nil
+}
+
+// isIndexedColorSpace checks whether the colorspace object `csObj` is an Indexed colorspace.
+func isIndexedColorSpace(csObj PdfObject) bool {
+	if arr, ok := csObj.(*PdfObjectArray); ok && arr.Len() > 0 {
+		csObj = TraceToDirectObject(arr.Get(0))
+	}
+	name, ok := csObj.(*PdfObjectName)
+	return ok && (*name == "Indexed" || *name == "I")
+}
+
+// decodeOptions returns the options of the JPEG 2000 decoder corresponding to the encoder settings.
+func (enc *JPXEncoder) decodeOptions() *jpeg2000.DecodeOptions {
+	return &jpeg2000.DecodeOptions{
+		IgnorePalette:    enc.Indexed,
+		IgnoreColorSpace: enc.ColorSpaceInDict,
+		Width:            enc.Width,
+		Height:           enc.Height,
+	}
 }
 
 // GetFilterName returns the name of the encoding filter.
@@ -2180,29 +2212,150 @@ func (enc *JPXEncoder) GetFilterName() string {
 // MakeDecodeParams makes a new instance of an encoding dictionary based on
 // the current encoder settings.
 func (enc *JPXEncoder) MakeDecodeParams() PdfObject {
+	// Does not have decode params.
 	return nil
 }
 
 // MakeStreamDict makes a new instance of an encoding dictionary for a stream object.
 func (enc *JPXEncoder) MakeStreamDict() *PdfObjectDictionary {
-	return MakeDict()
+	dict := MakeDict()
+
+	dict.Set("Filter", MakeName(enc.GetFilterName()))
+
+	return dict
 }
 
 // UpdateParams updates the parameter values of the encoder.
 func (enc *JPXEncoder) UpdateParams(params *PdfObjectDictionary) {
+	colorComponents, err := GetNumberAsInt64(params.Get("ColorComponents"))
+	if err == nil {
+		enc.ColorComponents = int(colorComponents)
+	}
+
+	bpc, err := GetNumberAsInt64(params.Get("BitsPerComponent"))
+	if err == nil {
+		enc.BitsPerComponent = int(bpc)
+	}
+
+	width, err := GetNumberAsInt64(params.Get("Width"))
+	if err == nil {
+		enc.Width = int(width)
+	}
+
+	height, err := GetNumberAsInt64(params.Get("Height"))
+	if err == nil {
+		enc.Height = int(height)
+	}
+
+	smask, err := GetNumberAsInt64(params.Get("SMaskInData"))
+	if err == nil {
+		enc.SMaskInData = int(smask)
+	}
 }
 
 // DecodeBytes decodes a slice of JPX encoded bytes and returns the result.
+// The samples of the colour components are interleaved with BitsPerComponent bits each.
 func (enc *JPXEncoder) DecodeBytes(encoded []byte) ([]byte, error) {
-	common.Log.Debug("Error: Attempting to use unsupported encoding %s", enc.GetFilterName())
-	return encoded, ErrNoJPXDecode
+	decoded, _, err := enc.DecodeBytesWithAlpha(encoded)
+	return decoded, err
+}
+
+// DecodeBytesWithAlpha decodes a slice of JPX encoded bytes. Besides the colour samples, it returns
+// the samples of the opacity channel if SMaskInData is set and the image has one (nil otherwise).
+func (enc *JPXEncoder) DecodeBytesWithAlpha(encoded []byte) ([]byte, []byte, error) {
+	img, err := jpeg2000.Decode(encoded, enc.decodeOptions())
+	if err != nil {
+		common.Log.Debug("Error decoding JPX image: %v", err)
+		return nil, nil, err
+	}
+	enc.Width = img.Width
+	enc.Height = img.Height
+	enc.ColorComponents = len(img.Channels)
+	if enc.BitsPerComponent != 16 {
+		enc.BitsPerComponent = 8
+	}
+
+	alpha := img.Alpha
+	if enc.SMaskInData == 0 {
+		alpha = nil
+	} else if alpha != nil && (img.PremultipliedAlpha || enc.SMaskInData == 2) {
+		unpremultiplyJPX(img.Channels, alpha)
+	}
+
+	samples := func(ch *jpeg2000.Channel) []uint16 {
+		if enc.Indexed {
+			// Palette indices are not scaled.
+			return ch.Data
+		}
+		return scaleJPXSamples(ch, enc.BitsPerComponent)
+	}
+
+	bytesPerSample := enc.BitsPerComponent / 8
+	numPixels := img.Width * img.Height
+	decoded := make([]byte, numPixels*len(img.Channels)*bytesPerSample)
+	for c, ch := range img.Channels {
+		data := samples(ch)
+		for i, v := range data {
+			putJPXSample(decoded, (i*len(img.Channels)+c)*bytesPerSample, v, bytesPerSample)
+		}
+	}
+	var alphaData []byte
+	if alpha != nil {
+		alphaData = make([]byte, numPixels*bytesPerSample)
+		for i, v := range scaleJPXSamples(alpha, enc.BitsPerComponent) {
+			putJPXSample(alphaData, i*bytesPerSample, v, bytesPerSample)
+		}
+	}
+	return decoded, alphaData, nil
+}
+
+// scaleJPXSamples scales the samples of `ch` to `bpc` bits.
+func scaleJPXSamples(ch *jpeg2000.Channel, bpc int) []uint16 {
+	if ch.Precision == bpc {
+		return ch.Data
+	}
+	inMax := uint32(1)<<uint(ch.Precision) - 1
+	outMax := uint32(1)<<uint(bpc) - 1
+	scaled := make([]uint16, len(ch.Data))
+	for i, v := range ch.Data {
+		scaled[i] = uint16((uint32(v)*outMax + inMax/2) / inMax)
+	}
+	return scaled
+}
+
+// putJPXSample stores the sample `v` at `pos` in `data` with `size` bytes.
+func putJPXSample(data []byte, pos int, v uint16, size int) {
+	if size == 2 {
+		data[pos] = byte(v >> 8)
+		data[pos+1] = byte(v)
+		return
+	}
+	data[pos] = byte(v)
+}
+
+// unpremultiplyJPX converts colour channels premultiplied with the opacity `alpha` to straight colours.
+func unpremultiplyJPX(channels []*jpeg2000.Channel, alpha *jpeg2000.Channel) {
+	alphaMax := uint32(1)<<uint(alpha.Precision) - 1
+	for _, ch := range channels {
+		max := uint32(1)<<uint(ch.Precision) - 1
+		for i, v := range ch.Data {
+			a := uint32(alpha.Data[i])
+			if a == 0 {
+				continue
+			}
+			c := (uint32(v)*alphaMax + a/2) / a
+			if c > max {
+				c = max
+			}
+			ch.Data[i] = uint16(c)
+		}
+	}
 }
 
 // DecodeStream decodes a JPX encoded stream and returns the result as a
 // slice of bytes.
 func (enc *JPXEncoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, error) {
-	common.Log.Debug("Error: Attempting to use unsupported encoding %s", enc.GetFilterName())
-	return streamObj.Stream, ErrNoJPXDecode
+	return enc.DecodeBytes(streamObj.Stream)
 }
 
 // EncodeBytes JPX encodes the passed in slice of bytes.
@@ -2325,6 +2478,15 @@ func newMultiEncoderFromStream(streamObj *PdfObjectStream) (*MultiEncoder, error
 			mencoder.AddEncoder(encoder)
 			common.Log.Trace("Added DCT encoder...")
 			common.Log.Trace("Multi encoder: %#v", mencoder)
+		} else if *name == StreamEncodingFilterNameJPX {
+			encoder, err := newJPXEncoderFromStream(streamObj, mencoder)
+			if err != nil {
+				return nil, err
+			}
+			mencoder.AddEncoder(encoder)
+		} else if *name == StreamEncodingFilterNameCrypt {
+			// The stream is decrypted by the security handler, see PdfCrypt.
+			common.Log.Trace("Skipping Crypt filter")
 		} else {
 			common.Log.Error("Unsupported filter %s", *name)
 			return nil, fmt.Errorf("invalid filter in multi filter array")
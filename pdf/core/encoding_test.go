@@ -6,7 +6,11 @@
 package core
 
 import (
+	"bytes"
+	lzw0 "compress/lzw"
 	"encoding/base64"
+	"encoding/hex"
+	"strings"
 	"testing"
 
 	"github.com/unidoc/unidoc/common"
@@ -165,31 +169,149 @@ func TestPostDecodingPredictors(t *testing.T) {
 	}
 }
 
+// Test encoding with the TIFF and PNG predictors through the stream dictionary.
+func TestPredictorEncoding(t *testing.T) {
+	// Gradient image data of 3 rows with 7 samples with 3 colour components each.
+	data := make([]byte, 3*7*3*2)
+	for i := range data {
+		data[i] = byte(i*5 + (i%3)*40)
+	}
+
+	testcases := []struct {
+		Predictor        int
+		BitsPerComponent int
+		Colors           int
+		Columns          int
+	}{
+		{2, 8, 3, 7},
+		{2, 16, 3, 7},
+		{2, 4, 3, 14},
+		{2, 1, 1, 56},
+		{10, 8, 3, 7},
+		{11, 8, 3, 7},
+		{12, 8, 3, 7},
+		{13, 8, 3, 7},
+		{14, 8, 3, 7},
+		{15, 8, 3, 7},
+		{15, 16, 3, 7},
+		{15, 2, 1, 84},
+		{14, 8, 1, 126},
+	}
+
+	for i, tcase := range testcases {
+		flate := NewFlateEncoder()
+		flate.Predictor = tcase.Predictor
+		flate.BitsPerComponent = tcase.BitsPerComponent
+		flate.Colors = tcase.Colors
+		flate.Columns = tcase.Columns
+
+		lzw := NewLZWEncoder()
+		lzw.Predictor = tcase.Predictor
+		lzw.BitsPerComponent = tcase.BitsPerComponent
+		lzw.Colors = tcase.Colors
+		lzw.Columns = tcase.Columns
+
+		for _, encoder := range []StreamEncoder{flate, lzw} {
+			stream, err := MakeStream(data, encoder)
+			if err != nil {
+				t.Fatalf("%d %s: failed to encode data: %v", i, encoder.GetFilterName(), err)
+			}
+			decoded, err := DecodeStream(stream)
+			if err != nil {
+				t.Fatalf("%d %s: failed to decode data: %v", i, encoder.GetFilterName(), err)
+			}
+			if !compareSlices(decoded, data) {
+				t.Errorf("%d %s: slices not matching", i, encoder.GetFilterName())
+				t.Errorf("Decoded  (%d): % x", len(decoded), decoded)
+				t.Errorf("Expected (%d): % x", len(data), data)
+			}
+		}
+	}
+
+	// The sample differences of the TIFF predictor with 4 bits per component.
+	encoder := &FlateEncoder{Predictor: 2, BitsPerComponent: 4, Colors: 1, Columns: 4}
+	predicted, err := encoder.postDecodePredict([]byte{0x11, 0x11, 0xf1, 0x11})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if expected := []byte{0x12, 0x34, 0xf0, 0x12}; !compareSlices(predicted, expected) {
+		t.Errorf("Predicted % x, expected % x", predicted, expected)
+	}
+
+	// The data not filling whole rows cannot be predicted.
+	encoder = &FlateEncoder{Predictor: 15, BitsPerComponent: 8, Colors: 3, Columns: 7}
+	if _, err := encoder.EncodeBytes(data[:20]); err == nil {
+		t.Errorf("Encoding of incomplete rows should fail")
+	}
+}
+
 // Test LZW encoding.
 func TestLZWEncoding(t *testing.T) {
 	rawStream := []byte("this is a dummy text with some \x01\x02\x03 binary data")
 
+	// Long data of low redundancy fill the code table several times.
+	var long []byte
+	for i := 0; i < 30000; i++ {
+		long = append(long, byte(i*i>>3), byte(i%251))
+	}
+
+	for _, earlyChange := range []int{0, 1} {
+		for _, data := range [][]byte{rawStream, long, nil} {
+			encoder := NewLZWEncoder()
+			encoder.EarlyChange = earlyChange
+
+			encoded, err := encoder.EncodeBytes(data)
+			if err != nil {
+				t.Errorf("Failed to encode data: %v", err)
+				return
+			}
+
+			decoded, err := encoder.DecodeBytes(encoded)
+			if err != nil {
+				t.Errorf("Failed to decode data (EarlyChange %d): %v", earlyChange, err)
+				return
+			}
+
+			if !compareSlices(decoded, data) && len(decoded)+len(data) > 0 {
+				t.Errorf("Slices not matching (EarlyChange %d)", earlyChange)
+				t.Errorf("Decoded (%d)", len(decoded))
+				t.Errorf("Raw     (%d)", len(data))
+				return
+			}
+
+			// The postponed code length increases are the same as of the standard library encoder.
+			if earlyChange == 0 {
+				var b bytes.Buffer
+				w := lzw0.NewWriter(&b, lzw0.MSB, 8)
+				w.Write(data)
+				w.Close()
+				if !compareSlices(encoded, b.Bytes()) {
+					t.Errorf("Encoded data differ from compress/lzw (%d/%d)", len(encoded), b.Len())
+				}
+			}
+		}
+	}
+
+	// The EarlyChange is stored in the DecodeParms if not default.
 	encoder := NewLZWEncoder()
-	// Only supporitng early change 0 for encoding at the moment.
 	encoder.EarlyChange = 0
-
-	encoded, err := encoder.EncodeBytes(rawStream)
+	stream, err := MakeStream(rawStream, encoder)
 	if err != nil {
-		t.Errorf("Failed to encode data: %v", err)
-		return
+		t.Fatalf("Failed to encode data: %v", err)
 	}
-
-	decoded, err := encoder.DecodeBytes(encoded)
+	decodeParams, ok := GetDict(stream.Get("DecodeParms"))
+	if !ok {
+		t.Fatalf("Missing DecodeParms")
+	}
+	if earlyChange, ok := GetIntVal(decodeParams.Get("EarlyChange")); !ok || earlyChange != 0 {
+		t.Errorf("Invalid EarlyChange %v", decodeParams.Get("EarlyChange"))
+	}
+	decoded, err := DecodeStream(stream)
 	if err != nil {
-		t.Errorf("Failed to decode data: %v", err)
-		return
+		t.Fatalf("Failed to decode data: %v", err)
 	}
-
 	if !compareSlices(decoded, rawStream) {
-		t.Errorf("Slices not matching")
-		t.Errorf("Decoded (%d): % x", len(encoded), encoded)
-		t.Errorf("Raw     (%d): % x", len(rawStream), rawStream)
-		return
+		t.Errorf("Slices not matching: %q", decoded)
 	}
 }
 
@@ -367,3 +489,291 @@ func TestMultiEncoder(t *testing.T) {
 		return
 	}
 }
+
+// jpxTestData returns the bytes of the hexadecimal dump `dump`.
+func jpxTestData(t *testing.T, dump string) []byte {
+	data, err := hex.DecodeString(strings.Replace(dump, " ", "", -1))
+	if err != nil {
+		t.Fatalf("Invalid test data: %v", err)
+	}
+	return data
+}
+
+// newJPXTestStream returns a JPX encoded image stream and its encoder.
+func newJPXTestStream(t *testing.T, encoded []byte, smaskInData int64) (*PdfObjectStream, *JPXEncoder) {
+	stream := &PdfObjectStream{PdfObjectDictionary: MakeDict(), Stream: encoded}
+	stream.Set("Filter", MakeName(StreamEncodingFilterNameJPX))
+	if smaskInData != 0 {
+		stream.Set("SMaskInData", MakeInteger(smaskInData))
+	}
+	encoder, err := NewEncoderFromStream(stream)
+	if err != nil {
+		t.Fatalf("Failed to create encoder: %v", err)
+	}
+	jpx, ok := encoder.(*JPXEncoder)
+	if !ok {
+		t.Fatalf("Unexpected encoder type %T", encoder)
+	}
+	return stream, jpx
+}
+
+// Test JPX decoding of a lossless RGB codestream (4x2, 8 bit, multiple component transformation).
+func TestJPXDecoding(t *testing.T) {
+	encoded := jpxTestData(t, "ff 4f ff 51 00 2f 00 00 00 00 00 04 00 00 00 02 00 00 00 00 00 00 00 00 "+
+		"00 00 00 04 00 00 00 02 00 00 00 00 00 00 00 00 00 03 07 01 01 07 01 01 "+
+		"07 01 01 ff 52 00 0c 00 00 00 01 01 01 02 02 00 01 ff 5c 00 07 80 40 48 "+
+		"48 50 ff 90 00 0a 00 00 00 00 00 57 00 01 ff 93 c1 f5 01 80 08 27 9f c3 "+
+		"ed 03 09 f7 df c3 ed 03 0c 4a f5 c0 fa 80 e0 7d 40 70 3e d0 30 02 72 3f "+
+		"06 15 7f 05 eb 5f c3 f0 03 87 e0 09 0f cc 08 08 f5 7b 03 80 8a 7f 03 7f "+
+		"c3 f0 03 83 ed 03 87 e6 04 07 ff 7f 05 7f 57 05 5f ff d9")
+	expected := []byte{
+		255, 0, 0, 0, 255, 0, 0, 0, 255, 128, 128, 128,
+		10, 50, 90, 20, 60, 100, 30, 70, 110, 40, 80, 120,
+	}
+
+	stream, encoder := newJPXTestStream(t, encoded, 0)
+	if encoder.Width != 4 || encoder.Height != 2 || encoder.ColorComponents != 3 || encoder.BitsPerComponent != 8 {
+		t.Errorf("Unexpected encoder parameters: %+v", encoder)
+	}
+
+	decoded, err := encoder.DecodeStream(stream)
+	if err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+	if !compareSlices(decoded, expected) {
+		t.Errorf("Slices not matching")
+		t.Errorf("Decoded  (%d): % x", len(decoded), decoded)
+		t.Errorf("Expected (%d): % x", len(expected), expected)
+	}
+
+	if _, err := encoder.EncodeBytes(decoded); err == nil {
+		t.Errorf("JPX encoding should not be supported")
+	}
+}
+
+// Test JPX decoding of a JP2 file with a gray and an opacity channel (3x2, 8 bit).
+func TestJPXDecodingSMaskInData(t *testing.T) {
+	encoded := jpxTestData(t, "00 00 00 0c 6a 50 20 20 0d 0a 87 0a 00 00 00 2d 6a 70 32 68 00 00 00 16 "+
+		"69 68 64 72 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 0f 63 6f "+
+		"6c 72 01 00 00 00 00 00 11 00 00 00 71 6a 70 32 63 ff 4f ff 51 00 2c 00 "+
+		"00 00 00 00 03 00 00 00 02 00 00 00 00 00 00 00 00 00 00 00 03 00 00 00 "+
+		"02 00 00 00 00 00 00 00 00 00 02 07 01 01 07 01 01 ff 52 00 0c 00 00 00 "+
+		"01 00 00 02 02 00 01 ff 5c 00 04 80 40 ff 90 00 0a 00 00 00 00 00 23 00 "+
+		"01 ff 93 c7 e0 10 07 ab c8 05 da 34 23 c5 c7 e0 0e 12 b8 ac 05 50 28 a7 "+
+		"ff d9")
+	expected := []byte{0, 100, 200, 50, 150, 250}
+	expectedAlpha := []byte{255, 128, 0, 255, 64, 32}
+
+	// Without SMaskInData, the opacity channel is ignored.
+	_, encoder := newJPXTestStream(t, encoded, 0)
+	if encoder.ColorComponents != 1 {
+		t.Errorf("Unexpected number of color components: %d", encoder.ColorComponents)
+	}
+	decoded, alpha, err := encoder.DecodeBytesWithAlpha(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+	if !compareSlices(decoded, expected) || alpha != nil {
+		t.Errorf("Unexpected decoded data: % x, alpha: % x", decoded, alpha)
+	}
+
+	_, encoder = newJPXTestStream(t, encoded, 1)
+	decoded, alpha, err = encoder.DecodeBytesWithAlpha(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+	if !compareSlices(decoded, expected) || !compareSlices(alpha, expectedAlpha) {
+		t.Errorf("Unexpected decoded data: % x, alpha: % x", decoded, alpha)
+	}
+
+	// Premultiplied colors are converted to straight colors.
+	_, encoder = newJPXTestStream(t, encoded, 2)
+	decoded, _, err = encoder.DecodeBytesWithAlpha(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+	if decoded[0] != 0 || decoded[1] != 199 || decoded[3] != 50 || decoded[5] != 255 {
+		t.Errorf("Unexpected unpremultiplied data: % x", decoded)
+	}
+}
+
+// Test JPX decoding of a 12 bit gray codestream, which is decoded with 16 bits per component.
+func TestJPXDecoding12Bit(t *testing.T) {
+	encoded := jpxTestData(t, "ff 4f ff 51 00 29 00 00 00 00 00 02 00 00 00 02 00 00 00 00 00 00 00 00 "+
+		"00 00 00 02 00 00 00 02 00 00 00 00 00 00 00 00 00 01 0b 01 01 ff 52 00 "+
+		"0c 00 00 00 01 00 00 02 02 00 01 ff 5c 00 04 80 60 ff 90 00 0a 00 00 00 "+
+		"00 00 18 00 01 ff 93 c7 f8 07 07 98 b2 74 90 6a 3f ff d9")
+	// Samples 0, 4095, 2048 and 1000 scaled to 16 bits.
+	expected := []byte{0x00, 0x00, 0xff, 0xff, 0x80, 0x08, 0x3e, 0x84}
+
+	stream, encoder := newJPXTestStream(t, encoded, 0)
+	if encoder.BitsPerComponent != 16 {
+		t.Errorf("Unexpected bits per component: %d", encoder.BitsPerComponent)
+	}
+	decoded, err := encoder.DecodeStream(stream)
+	if err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+	if !compareSlices(decoded, expected) {
+		t.Errorf("Slices not matching: % x", decoded)
+	}
+}
+
+// Test that encoders are created for JPX streams whose image header cannot be read, so that the
+// images can be copied, and that decoding them fails.
+func TestJPXInvalidHeader(t *testing.T) {
+	encoded := jpxTestData(t, "ff 4f ff 51 00 29 00 00 00 00 00 02 00 00 00 02 00 00 00 00 00 00 00 00 "+
+		"00 00 00 02 00 00 00 02 00 00 00 00 00 00 00 00 00 01 0b 01 01 ff 52 00 "+
+		"0c 00 00 00 01 00 00 02 02 00 01 ff 5c 00 04 80 60 ff 90 00 0a 00 00 00 "+
+		"00 00 18 00 01 ff 93 c7 f8 07 07 98 b2 74 90 6a 3f ff d9")
+
+	testcases := []struct {
+		name    string
+		encoded []byte
+		width   int64
+	}{
+		{"garbage", []byte("not a JPEG 2000 image"), 2},
+		{"size mismatch", encoded, 3},
+	}
+	for _, tc := range testcases {
+		stream := &PdfObjectStream{PdfObjectDictionary: MakeDict(), Stream: tc.encoded}
+		stream.Set("Filter", MakeName(StreamEncodingFilterNameJPX))
+		stream.Set("Width", MakeInteger(tc.width))
+		stream.Set("Height", MakeInteger(2))
+		stream.Set("BitsPerComponent", MakeInteger(8))
+		encoder, err := NewEncoderFromStream(stream)
+		if err != nil {
+			t.Fatalf("%s: failed to create encoder: %v", tc.name, err)
+		}
+		jpx, ok := encoder.(*JPXEncoder)
+		if !ok {
+			t.Fatalf("%s: unexpected encoder type %T", tc.name, encoder)
+		}
+		if jpx.Width != int(tc.width) || jpx.Height != 2 || jpx.BitsPerComponent != 8 {
+			t.Errorf("%s: unexpected encoder parameters: %+v", tc.name, jpx)
+		}
+		if _, err := jpx.DecodeStream(stream); err == nil {
+			t.Errorf("%s: decoding should fail", tc.name)
+		}
+	}
+}
+
+// Test JBIG2 encoding of a 1 bit image (10x4) and decoding it back.
+func TestJBIG2Encoding(t *testing.T) {
+	testcases := []struct {
+		chocolate bool
+		data      []byte
+	}{
+		// Vanilla: 0 is black and the padding bits of the decoded rows are set.
+		{false, []byte{0xff, 0xff, 0x81, 0x3f, 0x81, 0x3f, 0xaa, 0xbf}},
+		// Chocolate: 1 is black.
+		{true, []byte{0x00, 0x00, 0x7e, 0xc0, 0x7e, 0xc0, 0x55, 0x40}},
+	}
+
+	for _, tc := range testcases {
+		encoder := NewJBIG2Encoder()
+		params := MakeDict()
+		params.Set("Width", MakeInteger(10))
+		params.Set("Height", MakeInteger(4))
+		if tc.chocolate {
+			params.Set("Decode", MakeArray(MakeInteger(1), MakeInteger(0)))
+		}
+		encoder.UpdateParams(params)
+
+		encoded, err := encoder.EncodeBytes(tc.data)
+		if err != nil {
+			t.Fatalf("Failed to encode data: %v", err)
+		}
+
+		dict := encoder.MakeStreamDict()
+		if name, ok := GetName(dict.Get("Filter")); !ok || name.String() != StreamEncodingFilterNameJBIG2 {
+			t.Errorf("Unexpected filter: %v", dict.Get("Filter"))
+		}
+		if _, hasDecode := dict.Get("Decode").(*PdfObjectArray); hasDecode != tc.chocolate {
+			t.Errorf("Unexpected Decode: %v", dict.Get("Decode"))
+		}
+		dict.Set("Width", MakeInteger(10))
+		dict.Set("Height", MakeInteger(4))
+		stream := &PdfObjectStream{PdfObjectDictionary: dict, Stream: encoded}
+
+		decoded, err := DecodeStream(stream)
+		if err != nil {
+			t.Fatalf("Failed to decode data: %v", err)
+		}
+		if !compareSlices(decoded, tc.data) {
+			t.Errorf("Slices not matching (chocolate: %v)", tc.chocolate)
+			t.Errorf("Decoded  (%d): % x", len(decoded), decoded)
+			t.Errorf("Expected (%d): % x", len(tc.data), tc.data)
+		}
+	}
+
+	encoder := NewJBIG2Encoder()
+	if _, err := encoder.EncodeBytes([]byte{0x00}); err == nil {
+		t.Errorf("Encoding without the image dimensions should fail")
+	}
+}
+
+// Test the JBIG2 symbol encoding of several pages sharing the global symbol dictionary.
+func TestJBIG2EncodingGlobals(t *testing.T) {
+	// Vanilla pages with a repeated pattern and a unique one.
+	makePage := func(width, height, unique int) JBIG2Image {
+		stride := (width + 7) / 8
+		data := bytes.Repeat([]byte{0xff}, stride*height)
+		setBlack := func(x, y int) {
+			data[y*stride+x/8] &^= 0x80 >> uint(x%8)
+		}
+		for x := 1; x+4 < width; x += 6 {
+			for _, p := range [][2]int{{0, 0}, {1, 0}, {2, 0}, {1, 1}, {1, 2}, {0, 3}, {1, 3}, {2, 3}} {
+				setBlack(x+p[0], 2+p[1])
+			}
+		}
+		for i := 0; i < unique; i++ {
+			setBlack(2*i, height-1)
+		}
+		return JBIG2Image{Width: width, Height: height, Data: data}
+	}
+	pages := []JBIG2Image{makePage(40, 10, 3), makePage(27, 8, 5)}
+
+	encoder := NewJBIG2Encoder()
+	if err := encoder.EncodeGlobals(pages...); err != nil {
+		t.Fatalf("Failed to encode globals: %v", err)
+	}
+	if !encoder.SymbolMode {
+		t.Errorf("Symbol mode not enabled")
+	}
+
+	var globals *PdfObjectStream
+	for i, page := range pages {
+		encoder.Width = page.Width
+		encoder.Height = page.Height
+		encoded, err := encoder.EncodeBytes(page.Data)
+		if err != nil {
+			t.Fatalf("Failed to encode page %d: %v", i, err)
+		}
+
+		dict := encoder.MakeStreamDict()
+		decodeParams, ok := GetDict(dict.Get("DecodeParms"))
+		if !ok {
+			t.Fatalf("Missing DecodeParms: %v", dict)
+		}
+		stream, ok := GetStream(decodeParams.Get("JBIG2Globals"))
+		if !ok {
+			t.Fatalf("Missing JBIG2Globals: %v", decodeParams)
+		}
+		if globals != nil && globals != stream {
+			t.Errorf("Globals stream not shared")
+		}
+		globals = stream
+
+		// Decode with a new encoder created from the stream dictionary.
+		decoded, err := DecodeStream(&PdfObjectStream{PdfObjectDictionary: dict, Stream: encoded})
+		if err != nil {
+			t.Fatalf("Failed to decode page %d: %v", i, err)
+		}
+		if !compareSlices(decoded, page.Data) {
+			t.Errorf("Page %d slices not matching", i)
+			t.Errorf("Decoded  (%d): % x", len(decoded), decoded)
+			t.Errorf("Expected (%d): % x", len(page.Data), page.Data)
+		}
+	}
+}
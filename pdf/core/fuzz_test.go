@@ -99,9 +99,17 @@ endstream
 		0,
 	}
 
-	_, err := parser.ParseIndirectObject()
-	if err == nil {
-		t.Errorf("Should fail with an error")
+	// Object 12 does not exist, the stream length is determined by the endstream keyword.
+	obj, err := parser.ParseIndirectObject()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	stream, ok := obj.(*PdfObjectStream)
+	if !ok {
+		t.Fatalf("Not a stream (%T)", obj)
+	}
+	if string(stream.Stream) != "xxx" {
+		t.Errorf("Wrong stream data: %q", stream.Stream)
 	}
 }
 
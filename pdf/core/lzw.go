@@ -0,0 +1,114 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+)
+
+// LZW codes of the LZWDecode filter (7.4.4.2).
+const (
+	lzwClearCode = 256
+	lzwEODCode   = 257
+	lzwMaxCode   = 4095
+	lzwMinWidth  = 9
+)
+
+// lzwWriter compresses data with the LZW algorithm. The codes are written with the most
+// significant bit first and the code width grows from 9 to 12 bits.
+type lzwWriter struct {
+	buf bytes.Buffer
+
+	// earlyChange increases the code width one code early when set, as the EarlyChange 1.
+	earlyChange bool
+	width       uint
+	// hi is the last code of the table and overflow is the first code of the next code width.
+	hi       int
+	overflow int
+	table    map[uint32]int
+
+	// bits contains the nBits not written yet, aligned to the most significant bit.
+	bits  uint32
+	nBits uint
+}
+
+// lzwEncode compresses the 'data' with the LZW algorithm of the LZWDecode filter. If the
+// 'earlyChange' is 1, the code width is increased one code early as by the most PDF encoders,
+// otherwise the code width is postponed as long as possible.
+func lzwEncode(data []byte, earlyChange int) []byte {
+	w := &lzwWriter{earlyChange: earlyChange == 1, width: lzwMinWidth}
+
+	// The encoder begins with the clear table code.
+	w.clear()
+	if len(data) > 0 {
+		code := int(data[0])
+		for _, c := range data[1:] {
+			key := uint32(code)<<8 | uint32(c)
+			if next, ok := w.table[key]; ok {
+				code = next
+				continue
+			}
+			w.write(code)
+			if w.incHi() {
+				w.table[key] = w.hi
+			} else {
+				// The table is full, start over.
+				w.clear()
+			}
+			code = int(c)
+		}
+		w.write(code)
+		if !w.incHi() {
+			w.clear()
+		}
+	}
+	w.write(lzwEODCode)
+
+	// Write the final bits.
+	if w.nBits > 0 {
+		w.buf.WriteByte(byte(w.bits >> 24))
+	}
+	return w.buf.Bytes()
+}
+
+// clear writes the clear table code and resets the table.
+func (w *lzwWriter) clear() {
+	w.write(lzwClearCode)
+	w.width = lzwMinWidth
+	w.hi = lzwEODCode
+	w.overflow = 1 << lzwMinWidth
+	w.table = make(map[uint32]int)
+}
+
+// incHi moves to the next code of the table and increases the code width if needed.
+// Returns false if the table is full and has to be cleared.
+func (w *lzwWriter) incHi() bool {
+	w.hi++
+	if w.hi == lzwMaxCode {
+		return false
+	}
+
+	next := w.hi
+	if w.earlyChange {
+		next++
+	}
+	if next == w.overflow {
+		w.width++
+		w.overflow <<= 1
+	}
+	return true
+}
+
+// write writes the 'code' of the current code width.
+func (w *lzwWriter) write(code int) {
+	w.bits |= uint32(code) << (32 - w.width - w.nBits)
+	w.nBits += w.width
+	for w.nBits >= 8 {
+		w.buf.WriteByte(byte(w.bits >> 24))
+		w.bits <<= 8
+		w.nBits -= 8
+	}
+}
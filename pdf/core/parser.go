@@ -8,13 +8,14 @@ package core
 import (
 	"bufio"
 	"bytes"
+	"crypto"
+	"crypto/x509"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 
@@ -46,8 +47,11 @@ type PdfParser struct {
 	trailer          *PdfObjectDictionary
 	crypter          *PdfCrypt
 	repairsAttempted bool // Avoid multiple attempts for repair.
+	repairs          []Repair
 
 	ObjCache objectCache
+	// cache bounds the memory used by the cached objects, nil if not limited (see SetCacheLimit).
+	cache *boundedCache
 
 	// Tracker for reference lookups when looking up Length entry of stream objects.
 	// The Length entries of stream objects are a special case, as they can require recursive parsing, i.e. look up
@@ -715,10 +719,12 @@ func (parser *PdfParser) parsePdfVersion() (int, int, error) {
 		// Create a new offset reader that ignores the invalid data before
 		// the PDF version. Sets reader offset at the start of the PDF
 		// version string.
-		parser.rs, err = newOffsetReader(parser.rs, parser.GetFileOffset()-8)
+		versionOffset := parser.GetFileOffset() - 8
+		parser.rs, err = newOffsetReader(parser.rs, versionOffset)
 		if err != nil {
 			return 0, 0, err
 		}
+		parser.addRepair(RepairVersionOffset, 0, versionOffset, "%d bytes before the version header ignored", versionOffset)
 	} else {
 		if major, err = strconv.Atoi(match[1]); err != nil {
 			return 0, 0, err
@@ -1135,10 +1141,12 @@ func (parser *PdfParser) parseXref() (*PdfObjectDictionary, error) {
 	}
 
 	common.Log.Debug("Warning: Unable to find xref table or stream. Repair attempted: Looking for earliest xref from bottom.")
+	startOffset := parser.GetFileOffset()
 	if err := parser.repairSeekXrefMarker(); err != nil {
 		common.Log.Debug("Repair failed - %v", err)
 		return nil, err
 	}
+	parser.addRepair(RepairMissingXref, 0, startOffset, "xref table found at %d", parser.GetFileOffset())
 	return parser.parseXrefTable()
 }
 
@@ -1260,11 +1268,13 @@ func (parser *PdfParser) loadXrefs() (*PdfObjectDictionary, error) {
 	if offsetXref > fSize {
 		common.Log.Debug("ERROR: Xref offset outside of file")
 		common.Log.Debug("Attempting repair")
+		startxref := offsetXref
 		offsetXref, err = parser.repairLocateXref()
 		if err != nil {
-			common.Log.Debug("ERROR: Repair attempt failed (%s)")
+			common.Log.Debug("ERROR: Repair attempt failed (%s)", err)
 			return nil, err
 		}
+		parser.addRepair(RepairXrefOffset, 0, startxref, "startxref outside of file, xref found at %d", offsetXref)
 	}
 	// Read the xref.
 	parser.rs.Seek(int64(offsetXref), io.SeekStart)
@@ -1340,51 +1350,6 @@ func (parser *PdfParser) loadXrefs() (*PdfObjectDictionary, error) {
 	return trailerDict, nil
 }
 
-// Return the closest object following offset from the xrefs table.
-func (parser *PdfParser) xrefNextObjectOffset(offset int64) int64 {
-	nextOffset := int64(0)
-
-	if len(parser.xrefs.ObjectMap) == 0 {
-		return 0
-	}
-
-	if len(parser.xrefs.sortedObjects) == 0 {
-		count := 0
-		for _, xref := range parser.xrefs.ObjectMap {
-			if xref.Offset > 0 {
-				count++
-			}
-		}
-		if count == 0 {
-			// No objects with offset.
-			return 0
-		}
-		parser.xrefs.sortedObjects = make([]XrefObject, count)
-
-		i := 0
-		for _, xref := range parser.xrefs.ObjectMap {
-			if xref.Offset > 0 {
-				parser.xrefs.sortedObjects[i] = xref
-				i++
-			}
-		}
-
-		// Sort by offset, ascending.
-		sort.Slice(parser.xrefs.sortedObjects, func(i, j int) bool {
-			return parser.xrefs.sortedObjects[i].Offset < parser.xrefs.sortedObjects[j].Offset
-		})
-	}
-
-	i := sort.Search(len(parser.xrefs.sortedObjects), func(i int) bool {
-		return parser.xrefs.sortedObjects[i].Offset >= offset
-	})
-	if i < len(parser.xrefs.sortedObjects) {
-		nextOffset = parser.xrefs.sortedObjects[i].Offset
-	}
-
-	return nextOffset
-}
-
 // Get stream length, avoiding recursive loops.
 // The input is the PdfObject that is to be traced to a direct object.
 func (parser *PdfParser) traceStreamLength(lengthObj PdfObject) (PdfObject, error) {
@@ -1487,6 +1452,11 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 				return &indirect, err
 			}
 			common.Log.Trace("Parsed object ... finished.")
+		} else if indirect.PdfObject != nil && bb[0] != 'e' && bb[0] != 's' {
+			// The object is followed by another object or keyword rather than endobj.
+			parser.addRepair(RepairMissingEndobj, indirect.ObjectNumber, parser.GetFileOffset(),
+				"object terminated by %q", bb)
+			break
 		} else {
 			if bb[0] == 'e' {
 				lineStr, err := parser.readTextLine()
@@ -1531,52 +1501,65 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 						common.Log.Debug("Fail to trace stream length: %v", err)
 						return nil, err
 					}
-					common.Log.Trace("Stream length? %s", slo)
 
-					pstreamLength, ok := slo.(*PdfObjectInteger)
-					if !ok {
-						return nil, errors.New("stream length needs to be an integer")
-					}
-					streamLength := *pstreamLength
-					if streamLength < 0 {
-						return nil, errors.New("stream needs to be longer than 0")
+					// The length is unknown if missing or referring to a missing object, the data is
+					// then delimited by the endstream keyword.
+					streamLength := int64(-1)
+					if pstreamLength, ok := slo.(*PdfObjectInteger); ok {
+						streamLength = int64(*pstreamLength)
+					} else {
+						common.Log.Debug("Stream length not an integer (%T)", slo)
 					}
+					common.Log.Trace("Stream length? %d", streamLength)
 
-					// Validate the stream length based on the cross references.
-					// Find next object with closest offset to current object and calculate
-					// the expected stream length based on that.
+					// Validate the stream length by checking that the data is followed by
+					// the endstream keyword, otherwise search for the keyword.
 					streamStartOffset := parser.GetFileOffset()
-					nextObjectOffset := parser.xrefNextObjectOffset(streamStartOffset)
-					if streamStartOffset+int64(streamLength) > nextObjectOffset && nextObjectOffset > streamStartOffset {
-						common.Log.Debug("Expected ending at %d", streamStartOffset+int64(streamLength))
-						common.Log.Debug("Next object starting at %d", nextObjectOffset)
-						// endstream + "\n" endobj + "\n" (17)
-						newLength := nextObjectOffset - streamStartOffset - 17
-						if newLength < 0 {
-							return nil, errors.New("invalid stream length, going past boundaries")
+					if streamLength < 0 || !parser.isEndstreamAt(streamStartOffset+streamLength) {
+						if newLength, found := parser.repairStreamLength(streamStartOffset); found {
+							if streamLength < 0 {
+								parser.addRepair(RepairMissingStreamLength, indirect.ObjectNumber, streamStartOffset,
+									"Length %s, found %d", dict.Get("Length"), newLength)
+							} else {
+								parser.addRepair(RepairStreamLength, indirect.ObjectNumber, streamStartOffset,
+									"Length %d corrected to %d", streamLength, newLength)
+							}
+							streamLength = newLength
+							dict.Set("Length", MakeInteger(newLength))
+						} else if streamLength < 0 {
+							common.Log.Debug("ERROR: Stream length unknown and endstream not found")
+							return nil, errors.New("stream length needs to be an integer")
 						}
-
-						common.Log.Debug("Attempting a length correction to %d...", newLength)
-						streamLength = PdfObjectInteger(newLength)
-						dict.Set("Length", MakeInteger(newLength))
 					}
 
 					// Make sure is less than actual file size.
-					if int64(streamLength) > parser.fileSize {
+					if streamLength > parser.fileSize {
 						common.Log.Debug("ERROR: Stream length cannot be larger than file size")
 						return nil, errors.New("invalid stream length, larger than file size")
 					}
-
-					stream := make([]byte, streamLength)
-					_, err = parser.ReadAtLeast(stream, int(streamLength))
-					if err != nil {
-						common.Log.Debug("ERROR stream (%d): %X", len(stream), stream)
-						common.Log.Debug("ERROR: %v", err)
-						return nil, err
+					if remaining := parser.fileSize - streamStartOffset; streamLength > remaining && remaining >= 0 {
+						// Use the data available until the end of the file.
+						parser.addRepair(RepairTruncatedStream, indirect.ObjectNumber, streamStartOffset,
+							"Length %d, %d bytes available", streamLength, remaining)
+						streamLength = remaining
+						dict.Set("Length", MakeInteger(remaining))
 					}
 
 					streamobj := PdfObjectStream{}
-					streamobj.Stream = stream
+					if parser.cache != nil {
+						// The data is read on demand when the cache is limited, see SetCacheLimit.
+						streamobj.lazy = &lazyStreamData{parser: parser, offset: streamStartOffset, length: streamLength}
+						parser.SetFileOffset(streamStartOffset + streamLength)
+					} else {
+						stream := make([]byte, streamLength)
+						_, err = parser.ReadAtLeast(stream, int(streamLength))
+						if err != nil {
+							common.Log.Debug("ERROR stream (%d): %X", len(stream), stream)
+							common.Log.Debug("ERROR: %v", err)
+							return nil, err
+						}
+						streamobj.Stream = stream
+					}
 					streamobj.PdfObjectDictionary = indirect.PdfObject.(*PdfObjectDictionary)
 					streamobj.ObjectNumber = indirect.ObjectNumber
 					streamobj.GenerationNumber = indirect.GenerationNumber
@@ -1655,7 +1638,7 @@ func NewParser(rs io.ReadSeeker) (*PdfParser, error) {
 
 // Resolves a reference, returning the object and indicates whether or not it was cached.
 func (parser *PdfParser) resolveReference(ref *PdfObjectReference) (PdfObject, bool, error) {
-	cachedObj, isCached := parser.ObjCache[int(ref.ObjectNumber)]
+	cachedObj, isCached := parser.getCachedObject(int(ref.ObjectNumber))
 	if isCached {
 		return cachedObj, true, nil
 	}
@@ -1663,7 +1646,7 @@ func (parser *PdfParser) resolveReference(ref *PdfObjectReference) (PdfObject, b
 	if err != nil {
 		return nil, false, err
 	}
-	parser.ObjCache[int(ref.ObjectNumber)] = obj
+	parser.cacheObject(int(ref.ObjectNumber), obj)
 	return obj, false, nil
 }
 
@@ -1759,6 +1742,16 @@ func (parser *PdfParser) Decrypt(password []byte) (bool, error) {
 	return authenticated, err
 }
 
+// DecryptWithCertificate attempts to decrypt the PDF file encrypted by the public-key security
+// handler with the recipient's certificate and private key. Returns true if successful, false otherwise.
+// An error is returned when there is a problem with decrypting.
+func (parser *PdfParser) DecryptWithCertificate(cert *x509.Certificate, pkey crypto.PrivateKey) (bool, error) {
+	if parser.crypter == nil {
+		return false, errors.New("check encryption first")
+	}
+	return parser.crypter.authenticatePubKey(cert, pkey)
+}
+
 // CheckAccessRights checks access rights and permissions for a specified password. If either user/owner password is
 // specified, full rights are granted, otherwise the access rights are specified by the Permissions flag.
 //
@@ -11,7 +11,9 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -849,3 +851,92 @@ func TestPDFVersionParse(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, string(b), expected)
 }
+
+// Test the repair report of the damaged versions of a minimal pdf file.
+func TestRepairReport(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/minimal.pdf")
+	require.NoError(t, err)
+	minimal := string(data)
+	contents := "  BT\n    /F1 18 Tf\n    0 0 Td\n    (Hello World) Tj\n  ET"
+
+	testcases := []struct {
+		Name     string
+		Data     string
+		Expected []RepairAction
+	}{
+		{"intact", minimal, nil},
+		{"data before header", strings.Repeat("garbage\n", 4) + minimal, []RepairAction{RepairVersionOffset}},
+		{
+			"missing endobj",
+			strings.Replace(minimal, "  >>\nendobj\n\n2 0 obj", "  >>\n      \n\n2 0 obj", 1),
+			[]RepairAction{RepairMissingEndobj},
+		},
+		{
+			"truncated stream",
+			strings.Replace(strings.Replace(minimal, "/Length 55 >>", "/Length 400>>", 1), "endstream", "endstrXam", 1),
+			[]RepairAction{RepairTruncatedStream},
+		},
+		{
+			"wrong stream length",
+			strings.Replace(minimal, "/Length 55 >>", "/Length 400>>", 1),
+			[]RepairAction{RepairStreamLength},
+		},
+		{
+			"short stream length",
+			strings.Replace(minimal, "/Length 55 >>", "/Length 45 >>", 1),
+			[]RepairAction{RepairStreamLength},
+		},
+		{
+			"missing stream length",
+			strings.Replace(minimal, "/Length 55 >>", "           >>", 1),
+			[]RepairAction{RepairMissingStreamLength},
+		},
+		{
+			"missing stream length object",
+			strings.Replace(minimal, "  << /Length 55 >>", "<</Length 9 0 R>> ", 1),
+			[]RepairAction{RepairMissingStreamLength},
+		},
+		{
+			"bad startxref",
+			strings.Replace(minimal, "startxref\n565", "startxref\n999", 1),
+			[]RepairAction{RepairXrefOffset},
+		},
+		{
+			"bad xref object number",
+			strings.Replace(minimal, "0000000178 00000 n", "0000000077 00000 n", 1),
+			[]RepairAction{RepairXrefObjectNumber, RepairXrefRebuild},
+		},
+		{
+			"bad xref offset",
+			strings.Replace(minimal, "0000000178 00000 n", "0000000179 00000 n", 1),
+			[]RepairAction{RepairXrefRebuild},
+		},
+	}
+
+	for _, tcase := range testcases {
+		parser, err := NewParser(bytes.NewReader([]byte(tcase.Data)))
+		require.NoError(t, err, tcase.Name)
+		for _, objNum := range []int{1, 2, 3, 4} {
+			obj, err := parser.LookupByNumber(objNum)
+			require.NoError(t, err, tcase.Name)
+			num, _, err := getObjectNumber(obj)
+			require.NoError(t, err, tcase.Name)
+			require.Equal(t, int64(objNum), num, tcase.Name)
+		}
+
+		// Check the content stream data.
+		obj, err := parser.LookupByNumber(4)
+		require.NoError(t, err, tcase.Name)
+		stream, ok := obj.(*PdfObjectStream)
+		require.True(t, ok, tcase.Name)
+		require.True(t, strings.HasPrefix(string(stream.Stream), contents), tcase.Name)
+
+		report := parser.GetRepairReport()
+		require.Equal(t, len(tcase.Expected) > 0, report.IsRepaired(), tcase.Name)
+		var actions []RepairAction
+		for _, repair := range report.Repairs {
+			actions = append(actions, repair.Action)
+		}
+		require.Equal(t, tcase.Expected, actions, "%s: %s", tcase.Name, report)
+	}
+}
@@ -0,0 +1,296 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/unidoc/unidoc/common"
+)
+
+// Predictor values of the Flate and LZW filters (7.4.4.4 Table 8).
+const (
+	predictorNone       = 1  // No prediction.
+	predictorTIFF       = 2  // TIFF predictor 2.
+	predictorPNGNone    = 10 // PNG prediction, None on all rows.
+	predictorPNGSub     = 11 // PNG prediction, Sub on all rows.
+	predictorPNGUp      = 12 // PNG prediction, Up on all rows.
+	predictorPNGAvg     = 13 // PNG prediction, Average on all rows.
+	predictorPNGPaeth   = 14 // PNG prediction, Paeth on all rows.
+	predictorPNGOptimum = 15 // PNG prediction, optimum selected for each row.
+)
+
+// predictorParams are the parameters of the predictor functions.
+type predictorParams struct {
+	predictor int
+	bpc       int
+	columns   int
+	colors    int
+}
+
+// validate checks if the predictor parameters are supported.
+func (p predictorParams) validate() error {
+	switch {
+	case p.predictor <= predictorNone:
+		return nil
+	case p.predictor == predictorTIFF, p.predictor >= predictorPNGNone && p.predictor <= predictorPNGOptimum:
+	default:
+		common.Log.Debug("ERROR: Unsupported predictor (%d)", p.predictor)
+		return fmt.Errorf("unsupported predictor (%d)", p.predictor)
+	}
+	switch p.bpc {
+	case 1, 2, 4, 8, 16:
+	default:
+		return fmt.Errorf("invalid BitsPerComponent=%d", p.bpc)
+	}
+	if p.columns < 1 || p.colors < 1 {
+		return fmt.Errorf("invalid predictor Columns=%d Colors=%d", p.columns, p.colors)
+	}
+	return nil
+}
+
+// rowLength returns the number of bytes of each row of samples.
+func (p predictorParams) rowLength() int {
+	return (p.columns*p.colors*p.bpc + 7) / 8
+}
+
+// bytesPerPixel returns the distance of the bytes compared by the PNG filters, which is the
+// number of the bytes of a complete pixel rounded up to one.
+func (p predictorParams) bytesPerPixel() int {
+	return (p.colors*p.bpc + 7) / 8
+}
+
+// encode applies the predictor function to the 'data' before the compression.
+func (p predictorParams) encode(data []byte) ([]byte, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	if p.predictor <= predictorNone {
+		return data, nil
+	}
+
+	rowLength := p.rowLength()
+	if len(data)%rowLength != 0 {
+		common.Log.Debug("ERROR: Invalid row length (%d/%d)", len(data), rowLength)
+		return nil, errors.New("invalid row length")
+	}
+	rows := len(data) / rowLength
+
+	if p.predictor == predictorTIFF {
+		out := make([]byte, len(data))
+		copy(out, data)
+		for i := 0; i < rows; i++ {
+			p.tiffEncodeRow(out[rowLength*i:rowLength*(i+1)], data[rowLength*i:rowLength*(i+1)])
+		}
+		return out, nil
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)+rows))
+	bpp := p.bytesPerPixel()
+	prevRow := make([]byte, rowLength)
+	filtered := make([][]byte, pfPaeth+1)
+	for i := range filtered {
+		filtered[i] = make([]byte, rowLength)
+	}
+	for i := 0; i < rows; i++ {
+		row := data[rowLength*i : rowLength*(i+1)]
+
+		filter := p.predictor - predictorPNGNone
+		if p.predictor == predictorPNGOptimum {
+			// Select the filter with the minimal sum of the absolute differences, as recommended
+			// by the PNG specification (12.8 Filter selection).
+			best := -1
+			for f := pfNone; f <= pfPaeth; f++ {
+				pngFilterRow(f, filtered[f], row, prevRow, bpp)
+				if sum := absSum(filtered[f]); best < 0 || sum < best {
+					best, filter = sum, f
+				}
+			}
+		} else {
+			pngFilterRow(filter, filtered[filter], row, prevRow, bpp)
+		}
+
+		out.WriteByte(byte(filter))
+		out.Write(filtered[filter])
+		prevRow = row
+	}
+	return out.Bytes(), nil
+}
+
+// decode reverses the predictor function of the decompressed 'data'.
+func (p predictorParams) decode(data []byte) ([]byte, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	if p.predictor <= predictorNone {
+		return data, nil
+	}
+
+	rowLength := p.rowLength()
+	if p.predictor != predictorTIFF {
+		rowLength++ // 1 byte to specify predictor algorithms per row.
+	}
+	if len(data) < rowLength {
+		common.Log.Debug("Row length cannot be longer than data length (%d/%d)", rowLength, len(data))
+		return nil, errors.New("range check error")
+	}
+	if len(data)%rowLength != 0 {
+		common.Log.Debug("ERROR: Invalid row length (%d/%d)", len(data), rowLength)
+		return nil, fmt.Errorf("invalid row length (%d/%d)", len(data), rowLength)
+	}
+	rows := len(data) / rowLength
+	common.Log.Trace("Predictor %d: %d rows of %d bytes", p.predictor, rows, rowLength)
+
+	if p.predictor == predictorTIFF {
+		out := make([]byte, len(data))
+		copy(out, data)
+		for i := 0; i < rows; i++ {
+			p.tiffDecodeRow(out[rowLength*i : rowLength*(i+1)])
+		}
+		return out, nil
+	}
+
+	out := make([]byte, 0, len(data)-rows)
+	bpp := p.bytesPerPixel()
+	prevRow := make([]byte, rowLength-1)
+	row := make([]byte, rowLength-1)
+	for i := 0; i < rows; i++ {
+		copy(row, data[rowLength*i+1:rowLength*(i+1)])
+		fb := data[rowLength*i]
+		switch fb {
+		case pfNone:
+		case pfSub:
+			// Sub: Predicts the same as the sample to the left.
+			for j := bpp; j < len(row); j++ {
+				row[j] += row[j-bpp]
+			}
+		case pfUp:
+			// Up: Predicts the same as the sample above.
+			for j := range row {
+				row[j] += prevRow[j]
+			}
+		case pfAvg:
+			// Avg: Predicts the same as the average of the sample to the left and above.
+			for j := 0; j < bpp && j < len(row); j++ {
+				row[j] += prevRow[j] / 2
+			}
+			for j := bpp; j < len(row); j++ {
+				row[j] += byte((int(row[j-bpp]) + int(prevRow[j])) / 2)
+			}
+		case pfPaeth:
+			// Paeth: a nonlinear function of the sample to the left (a), sample above (b)
+			// and the upper left (c).
+			for j := range row {
+				var a, c byte
+				if j >= bpp {
+					a, c = row[j-bpp], prevRow[j-bpp]
+				}
+				row[j] += paeth(a, prevRow[j], c)
+			}
+		default:
+			common.Log.Debug("ERROR: Invalid filter byte (%d) @row %d", fb, i)
+			return nil, fmt.Errorf("invalid filter byte (%d)", fb)
+		}
+		out = append(out, row...)
+		prevRow, row = row, prevRow
+	}
+	return out, nil
+}
+
+// pngFilterRow writes the 'row' filtered by the PNG 'filter' to 'out'.
+func pngFilterRow(filter int, out, row, prevRow []byte, bpp int) {
+	switch filter {
+	case pfNone:
+		copy(out, row)
+	case pfSub:
+		for j := range row {
+			var a byte
+			if j >= bpp {
+				a = row[j-bpp]
+			}
+			out[j] = row[j] - a
+		}
+	case pfUp:
+		for j := range row {
+			out[j] = row[j] - prevRow[j]
+		}
+	case pfAvg:
+		for j := range row {
+			var a int
+			if j >= bpp {
+				a = int(row[j-bpp])
+			}
+			out[j] = row[j] - byte((a+int(prevRow[j]))/2)
+		}
+	case pfPaeth:
+		for j := range row {
+			var a, c byte
+			if j >= bpp {
+				a, c = row[j-bpp], prevRow[j-bpp]
+			}
+			out[j] = row[j] - paeth(a, prevRow[j], c)
+		}
+	}
+}
+
+// absSum returns the sum of the filtered bytes interpreted as the signed values.
+func absSum(filtered []byte) int {
+	var sum int
+	for _, b := range filtered {
+		sum += abs(int(int8(b)))
+	}
+	return sum
+}
+
+// tiffEncodeRow writes the differences of each sample of the 'row' from the preceding sample
+// of the same colour component to 'out'.
+func (p predictorParams) tiffEncodeRow(out, row []byte) {
+	max := uint(1)<<uint(p.bpc) - 1
+	for i := p.columns*p.colors - 1; i >= p.colors; i-- {
+		diff := getSample(row, i, p.bpc) - getSample(row, i-p.colors, p.bpc)
+		setSample(out, i, p.bpc, diff&max)
+	}
+}
+
+// tiffDecodeRow adds the preceding sample of the same colour component to each sample of the 'row'.
+func (p predictorParams) tiffDecodeRow(row []byte) {
+	max := uint(1)<<uint(p.bpc) - 1
+	for i := p.colors; i < p.columns*p.colors; i++ {
+		sum := getSample(row, i, p.bpc) + getSample(row, i-p.colors, p.bpc)
+		setSample(row, i, p.bpc, sum&max)
+	}
+}
+
+// getSample returns the i-th sample of 'bpc' bits of the 'row'.
+func getSample(row []byte, i, bpc int) uint {
+	switch bpc {
+	case 8:
+		return uint(row[i])
+	case 16:
+		return uint(row[2*i])<<8 | uint(row[2*i+1])
+	}
+	bit := i * bpc
+	shift := uint(8 - bpc - bit%8)
+	return uint(row[bit/8]>>shift) & (1<<uint(bpc) - 1)
+}
+
+// setSample sets the i-th sample of 'bpc' bits of the 'row' to 'v'.
+func setSample(row []byte, i, bpc int, v uint) {
+	switch bpc {
+	case 8:
+		row[i] = byte(v)
+		return
+	case 16:
+		row[2*i], row[2*i+1] = byte(v>>8), byte(v)
+		return
+	}
+	bit := i * bpc
+	shift := uint(8 - bpc - bit%8)
+	mask := byte(1<<uint(bpc)-1) << shift
+	row[bit/8] = row[bit/8]&^mask | byte(v)<<shift&mask
+}
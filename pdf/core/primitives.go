@@ -81,6 +81,10 @@ type PdfObjectStream struct {
 	PdfObjectReference
 	*PdfObjectDictionary
 	Stream []byte
+
+	// lazy locates the data in the file when the data is read on demand, nil if Stream contains
+	// the data (see PdfParser.SetCacheLimit).
+	lazy *lazyStreamData
 }
 
 // PdfObjectStreams represents the primitive PDF object streams.
@@ -8,6 +8,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
@@ -16,22 +17,224 @@ import (
 	"bufio"
 	"io"
 	"strconv"
+	"strings"
 
 	"github.com/unidoc/unidoc/common"
 )
 
 var repairReXrefTable = regexp.MustCompile(`[\r\n]\s*(xref)\s*[\r\n]`)
 
+var endstreamKeyword = []byte("endstream")
+
+// RepairAction is a type of repair made by the parser when reading a damaged file.
+type RepairAction int
+
+// Repair actions of the parser.
+const (
+	// RepairVersionOffset indicates data before the PDF version header, which is ignored.
+	RepairVersionOffset RepairAction = iota
+	// RepairXrefOffset indicates the startxref offset outside the file, the xref table was located by searching.
+	RepairXrefOffset
+	// RepairMissingXref indicates no xref table or stream at the startxref offset, the xref table
+	// was located by searching from the end of the file.
+	RepairMissingXref
+	// RepairXrefRebuild indicates an xref offset not pointing to an object, the xref table was rebuilt
+	// by scanning the file.
+	RepairXrefRebuild
+	// RepairXrefObjectNumber indicates an xref entry pointing to an object with another number,
+	// the object numbers of the xref table were corrected.
+	RepairXrefObjectNumber
+	// RepairStreamLength indicates a wrong stream Length, which was corrected.
+	RepairStreamLength
+	// RepairTruncatedStream indicates a stream truncated by the end of the file, the available data is used.
+	RepairTruncatedStream
+	// RepairMissingEndobj indicates an indirect object not terminated by the endobj keyword.
+	RepairMissingEndobj
+	// RepairMissingStreamLength indicates a stream Length missing or referring to a missing object,
+	// the length was determined by searching the endstream keyword.
+	RepairMissingStreamLength
+)
+
+// String returns a description of the repair action.
+func (a RepairAction) String() string {
+	switch a {
+	case RepairVersionOffset:
+		return "version header offset"
+	case RepairXrefOffset:
+		return "bad xref offset"
+	case RepairMissingXref:
+		return "missing xref"
+	case RepairXrefRebuild:
+		return "xref rebuild"
+	case RepairXrefObjectNumber:
+		return "bad xref object number"
+	case RepairStreamLength:
+		return "wrong stream length"
+	case RepairTruncatedStream:
+		return "truncated stream"
+	case RepairMissingEndobj:
+		return "missing endobj"
+	case RepairMissingStreamLength:
+		return "missing stream length"
+	}
+	return fmt.Sprintf("unknown repair (%d)", int(a))
+}
+
+// Repair describes a single repair made by the parser.
+type Repair struct {
+	Action RepairAction
+	// ObjectNumber is the number of the repaired object, or zero if not related to a single object.
+	ObjectNumber int64
+	// Offset is the file offset where the damage was detected.
+	Offset int64
+	// Message details the repair.
+	Message string
+}
+
+// String returns a string describing the repair.
+func (r Repair) String() string {
+	s := fmt.Sprintf("%s at offset %d", r.Action, r.Offset)
+	if r.ObjectNumber > 0 {
+		s += fmt.Sprintf(" (object %d)", r.ObjectNumber)
+	}
+	if r.Message != "" {
+		s += ": " + r.Message
+	}
+	return s
+}
+
+// RepairReport lists the repairs made by the parser when reading a damaged file.
+type RepairReport struct {
+	Repairs []Repair
+}
+
+// IsRepaired returns true if any repair was made, i.e. the file is damaged.
+func (r RepairReport) IsRepaired() bool {
+	return len(r.Repairs) > 0
+}
+
+// Has returns true if the report contains a repair of the specified action.
+func (r RepairReport) Has(action RepairAction) bool {
+	for _, repair := range r.Repairs {
+		if repair.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a string listing the repairs, one per line.
+func (r RepairReport) String() string {
+	var lines []string
+	for _, repair := range r.Repairs {
+		lines = append(lines, repair.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetRepairReport returns the report of the repairs made by the parser so far. The objects are
+// parsed when looked up, so the report of a lazily loaded document may grow while reading it.
+func (parser *PdfParser) GetRepairReport() RepairReport {
+	repairs := make([]Repair, len(parser.repairs))
+	copy(repairs, parser.repairs)
+	return RepairReport{Repairs: repairs}
+}
+
+// addRepair records a repair of the specified action made at the file 'offset'.
+func (parser *PdfParser) addRepair(action RepairAction, objNum, offset int64, format string, args ...interface{}) {
+	repair := Repair{
+		Action:       action,
+		ObjectNumber: objNum,
+		Offset:       offset,
+		Message:      fmt.Sprintf(format, args...),
+	}
+	common.Log.Debug("Repair: %s", repair)
+	parser.repairs = append(parser.repairs, repair)
+}
+
+// isEndstreamAt checks whether the stream data ending at 'offset' is followed by the endstream
+// keyword, optionally preceded by an EOL marker or white spaces.
+func (parser *PdfParser) isEndstreamAt(offset int64) bool {
+	size := int64(len(endstreamKeyword) + 16)
+	if remaining := parser.fileSize - offset; remaining < size {
+		size = remaining
+	}
+	if size < int64(len(endstreamKeyword)) {
+		return false
+	}
+	var bb []byte
+	var err error
+	if rel := offset - parser.GetFileOffset(); rel >= 0 && rel+size <= int64(parser.reader.Size()) {
+		// Avoid seeking when the data fits in the read buffer.
+		bb, err = parser.reader.Peek(int(rel + size))
+		if len(bb) > int(rel) {
+			bb, err = bb[rel:], nil
+		}
+	} else {
+		bb, err = parser.ReadBytesAt(offset, size)
+	}
+	if err != nil {
+		return false
+	}
+	return bytes.HasPrefix(bytes.TrimLeft(bb, "\x00\t\n\f\r "), endstreamKeyword)
+}
+
+// repairStreamLength determines the length of the stream data starting at 'offset' by searching
+// the endstream keyword. The EOL marker preceding the keyword is not part of the data.
+// Returns false if the keyword is not found.
+func (parser *PdfParser) repairStreamLength(offset int64) (int64, bool) {
+	curOffset := parser.GetFileOffset()
+	defer parser.SetFileOffset(curOffset)
+
+	if _, err := parser.rs.Seek(offset, io.SeekStart); err != nil {
+		return 0, false
+	}
+	reader := bufio.NewReader(parser.rs)
+	const chunkSize = 4096
+	// Keep enough bytes for the EOL marker preceding the keyword.
+	overlap := len(endstreamKeyword) + 1
+	var buf []byte
+	for pos := int64(0); ; {
+		chunk := make([]byte, chunkSize)
+		n, err := io.ReadFull(reader, chunk)
+		buf = append(buf, chunk[:n]...)
+		if i := bytes.Index(buf, endstreamKeyword); i >= 0 {
+			length := pos + int64(i)
+			data := buf[:i]
+			if bytes.HasSuffix(data, []byte("\r\n")) {
+				length -= 2
+			} else if bytes.HasSuffix(data, []byte("\n")) || bytes.HasSuffix(data, []byte("\r")) {
+				length--
+			}
+			return length, true
+		}
+		if err != nil {
+			return 0, false
+		}
+		// Keep the end of the buffer in case the keyword spans two chunks.
+		if len(buf) > overlap {
+			pos += int64(len(buf) - overlap)
+			buf = buf[len(buf)-overlap:]
+		}
+	}
+}
+
 // Locates a standard Xref table by looking for the "xref" entry.
 // Xref object stream not supported.
 func (parser *PdfParser) repairLocateXref() (int64, error) {
 	readBuf := int64(1000)
-	parser.rs.Seek(-readBuf, os.SEEK_CUR)
-
 	curOffset, err := parser.rs.Seek(0, os.SEEK_CUR)
 	if err != nil {
 		return 0, err
 	}
+	// Files shorter than the buffer are read from the start.
+	if curOffset < readBuf {
+		readBuf = curOffset
+	}
+	curOffset, err = parser.rs.Seek(-readBuf, os.SEEK_CUR)
+	if err != nil {
+		return 0, err
+	}
 	b2 := make([]byte, readBuf)
 	parser.rs.Read(b2)
 
@@ -62,6 +265,8 @@ func (parser *PdfParser) rebuildXrefTable() error {
 				common.Log.Debug("ERROR: Failed xref rebuild repair (%s)", err)
 				return err
 			}
+			parser.addRepair(RepairXrefRebuild, int64(objNum), xref.Offset,
+				"xref table completely broken, rebuilt %d xref entries", len(xrefTable.ObjectMap))
 			parser.xrefs = *xrefTable
 			common.Log.Debug("Repaired xref table built")
 			return nil
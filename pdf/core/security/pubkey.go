@@ -0,0 +1,187 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package security
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"sync"
+
+	"github.com/gunnsth/pkcs7"
+
+	"github.com/unidoc/unidoc/common"
+)
+
+var _ PubKeyHandler = pubKeyHandler{}
+
+// Sub filters of the public-key security handler.
+const (
+	// SubFilterPKCS7S4 is the public-key encryption with the recipients in the encryption dictionary.
+	SubFilterPKCS7S4 = "adbe.pkcs7.s4"
+	// SubFilterPKCS7S5 is the public-key encryption with the recipients in the crypt filters (V>=4).
+	SubFilterPKCS7S5 = "adbe.pkcs7.s5"
+)
+
+// seedLength is the length of the random seed of the enveloped data.
+const seedLength = 20
+
+// Recipient is a recipient of a document encrypted by the public-key security handler.
+type Recipient struct {
+	Certificate *x509.Certificate
+	Permissions Permissions
+}
+
+// PubKeyEncryptDict is a set of additional fields used by the public-key security handler, stored
+// in the encryption dictionary (adbe.pkcs7.s4) or in the crypt filter dictionary (adbe.pkcs7.s5).
+type PubKeyEncryptDict struct {
+	// Recipients are the PKCS#7 enveloped data objects, one for each group of the recipients
+	// with the same permissions.
+	Recipients      [][]byte
+	EncryptMetadata bool // Indicates whether the document-level metadata stream shall be encrypted.
+}
+
+// PubKeyHandler is an interface for public-key security handlers.
+type PubKeyHandler interface {
+	// GenerateParams creates the enveloped data for the recipients, sets the Recipients
+	// and generates an encryption key. It assumes that EncryptMetadata is already set.
+	GenerateParams(d *PubKeyEncryptDict, recipients []Recipient) ([]byte, error)
+
+	// Authenticate decrypts the enveloped data with the recipient's certificate and private key
+	// to calculate the document encryption key. It also returns permissions that should be granted
+	// to the recipient. In case of failed authentication, it returns empty key and zero permissions
+	// with no error.
+	Authenticate(d *PubKeyEncryptDict, cert *x509.Certificate, pkey crypto.PrivateKey) ([]byte, Permissions, error)
+}
+
+// NewPubKeyHandler creates a new public-key security handler for the encryption keys of 'length'
+// bytes. The 256 bit keys (AESV3) are computed with SHA-256, the shorter ones with SHA-1.
+func NewPubKeyHandler(length int) PubKeyHandler {
+	return pubKeyHandler{Length: length}
+}
+
+// pubKeyHandler is the public-key security handler (7.6.5 Public-Key Security Handlers).
+type pubKeyHandler struct {
+	Length int
+}
+
+// GenerateParams implements PubKeyHandler interface.
+func (sh pubKeyHandler) GenerateParams(d *PubKeyEncryptDict, recipients []Recipient) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	seed := make([]byte, seedLength)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+
+	// The recipients with the same permissions share the enveloped data.
+	var (
+		perms []Permissions
+		certs = make(map[Permissions][]*x509.Certificate)
+	)
+	for _, r := range recipients {
+		if r.Certificate == nil {
+			return nil, errors.New("recipient certificate missing")
+		}
+		if _, ok := certs[r.Permissions]; !ok {
+			perms = append(perms, r.Permissions)
+		}
+		certs[r.Permissions] = append(certs[r.Permissions], r.Certificate)
+	}
+
+	d.Recipients = nil
+	for _, p := range perms {
+		// The enveloped data contains the seed followed by the permissions, most significant byte first.
+		content := make([]byte, seedLength+4)
+		copy(content, seed)
+		binary.BigEndian.PutUint32(content[seedLength:], uint32(p))
+
+		envelope, err := encryptEnvelope(content, certs[p])
+		if err != nil {
+			return nil, err
+		}
+		d.Recipients = append(d.Recipients, envelope)
+	}
+	return sh.key(d, seed), nil
+}
+
+// pkcs7Mu guards the global state of the pkcs7 package: the content encryption algorithm and the
+// indentation counter of its BER parser.
+var pkcs7Mu sync.Mutex
+
+// encryptEnvelope creates the PKCS#7 enveloped data of `content` for `certs` with AES-256 content
+// encryption. The previous content encryption algorithm of the pkcs7 package is restored afterwards.
+func encryptEnvelope(content []byte, certs []*x509.Certificate) ([]byte, error) {
+	pkcs7Mu.Lock()
+	defer pkcs7Mu.Unlock()
+	prev := pkcs7.ContentEncryptionAlgorithm
+	pkcs7.ContentEncryptionAlgorithm = pkcs7.EncryptionAlgorithmAES256CBC
+	defer func() {
+		pkcs7.ContentEncryptionAlgorithm = prev
+	}()
+	return pkcs7.Encrypt(content, certs)
+}
+
+// parseEnvelope parses the PKCS#7 enveloped data `envelope`.
+func parseEnvelope(envelope []byte) (*pkcs7.PKCS7, error) {
+	pkcs7Mu.Lock()
+	defer pkcs7Mu.Unlock()
+	return pkcs7.Parse(envelope)
+}
+
+// Authenticate implements PubKeyHandler interface.
+func (sh pubKeyHandler) Authenticate(d *PubKeyEncryptDict, cert *x509.Certificate, pkey crypto.PrivateKey) ([]byte, Permissions, error) {
+	if cert == nil || pkey == nil {
+		return nil, 0, errors.New("certificate and private key required")
+	}
+	for i, envelope := range d.Recipients {
+		p7, err := parseEnvelope(envelope)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid enveloped data of recipients %d: %v", i, err)
+			continue
+		}
+		content, err := p7.Decrypt(cert, pkey)
+		if err != nil {
+			common.Log.Trace("Recipients %d not decrypted: %v", i, err)
+			continue
+		}
+		if len(content) < seedLength+4 {
+			common.Log.Debug("ERROR: Invalid enveloped content length (%d)", len(content))
+			continue
+		}
+		perm := Permissions(binary.BigEndian.Uint32(content[seedLength:]))
+		return sh.key(d, content[:seedLength]), perm, nil
+	}
+	return nil, 0, nil
+}
+
+// key computes the encryption key from the 'seed' and the recipients.
+func (sh pubKeyHandler) key(d *PubKeyEncryptDict, seed []byte) []byte {
+	var h hash.Hash
+	if sh.Length == 32 {
+		h = sha256.New()
+	} else {
+		h = sha1.New()
+	}
+	h.Write(seed)
+	for _, envelope := range d.Recipients {
+		h.Write(envelope)
+	}
+	if !d.EncryptMetadata {
+		h.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	}
+	key := h.Sum(nil)
+	if sh.Length < len(key) {
+		key = key[:sh.Length]
+	}
+	return key
+}
@@ -0,0 +1,143 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package security
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gunnsth/pkcs7"
+)
+
+// newTestCertificate creates a self-signed certificate with an RSA key.
+func newTestCertificate(t *testing.T, name string) (*x509.Certificate, *rsa.PrivateKey) {
+	pkey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDataEncipherment,
+	}
+	data, err := x509.CreateCertificate(rand.Reader, template, template, &pkey.PublicKey, pkey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert, pkey
+}
+
+func TestPubKeyHandler(t *testing.T) {
+	cert1, pkey1 := newTestCertificate(t, "recipient 1")
+	cert2, pkey2 := newTestCertificate(t, "recipient 2")
+	cert3, pkey3 := newTestCertificate(t, "recipient 3")
+	other, otherKey := newTestCertificate(t, "other")
+
+	const readOnly = PermPrinting | PermExtractGraphics
+	recipients := []Recipient{
+		{Certificate: cert1, Permissions: PermOwner},
+		{Certificate: cert2, Permissions: readOnly},
+		{Certificate: cert3, Permissions: readOnly},
+	}
+
+	for _, length := range []int{5, 16, 32} {
+		for _, encMeta := range []bool{true, false} {
+			h := NewPubKeyHandler(length)
+			d := &PubKeyEncryptDict{EncryptMetadata: encMeta}
+			key, err := h.GenerateParams(d, recipients)
+			if err != nil {
+				t.Fatalf("Failed to generate params: %v", err)
+			}
+			if len(key) != length {
+				t.Fatalf("Wrong key length: %d, expected %d", len(key), length)
+			}
+			if len(d.Recipients) != 2 {
+				t.Fatalf("Recipients with the same permissions should share the envelope, got %d envelopes",
+					len(d.Recipients))
+			}
+
+			for i, c := range []struct {
+				cert  *x509.Certificate
+				pkey  *rsa.PrivateKey
+				perms Permissions
+			}{
+				{cert1, pkey1, PermOwner},
+				{cert2, pkey2, readOnly},
+				{cert3, pkey3, readOnly},
+			} {
+				fkey, perm, err := h.Authenticate(d, c.cert, c.pkey)
+				if err != nil {
+					t.Fatalf("Recipient %d: %v", i, err)
+				}
+				if !bytes.Equal(fkey, key) {
+					t.Errorf("Recipient %d: wrong key: % x, expected % x", i, fkey, key)
+				}
+				if perm != c.perms {
+					t.Errorf("Recipient %d: wrong permissions: %#x, expected %#x", i, perm, c.perms)
+				}
+			}
+
+			fkey, perm, err := h.Authenticate(d, other, otherKey)
+			if err != nil {
+				t.Fatalf("Other certificate: %v", err)
+			}
+			if fkey != nil || perm != 0 {
+				t.Errorf("Not a recipient should not be authenticated")
+			}
+		}
+	}
+}
+
+// TestPubKeyHandlerConcurrent checks that concurrent encryption works and leaves the content
+// encryption algorithm of the pkcs7 package unchanged.
+func TestPubKeyHandlerConcurrent(t *testing.T) {
+	cert, pkey := newTestCertificate(t, "recipient")
+	recipients := []Recipient{{Certificate: cert, Permissions: PermOwner}}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h := NewPubKeyHandler(32)
+			d := &PubKeyEncryptDict{EncryptMetadata: true}
+			key, err := h.GenerateParams(d, recipients)
+			if err != nil {
+				errs <- err
+				return
+			}
+			fkey, _, err := h.Authenticate(d, cert, pkey)
+			if err == nil && !bytes.Equal(fkey, key) {
+				t.Errorf("Wrong key: % x, expected % x", fkey, key)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Failed to encrypt: %v", err)
+		}
+	}
+	if pkcs7.ContentEncryptionAlgorithm != pkcs7.EncryptionAlgorithmDESCBC {
+		t.Errorf("Content encryption algorithm of pkcs7 changed: %d", pkcs7.ContentEncryptionAlgorithm)
+	}
+}
@@ -72,7 +72,10 @@ func NewEncoderFromStream(streamObj *PdfObjectStream) (StreamEncoder, error) {
 	} else if *method == StreamEncodingFilterNameJBIG2 {
 		return newJBIG2EncoderFromStream(streamObj, nil)
 	} else if *method == StreamEncodingFilterNameJPX {
-		return NewJPXEncoder(), nil
+		return newJPXEncoderFromStream(streamObj, nil)
+	} else if *method == StreamEncodingFilterNameCrypt {
+		// The stream is decrypted by the security handler, the data is not encoded otherwise.
+		return NewRawEncoder(), nil
 	} else {
 		common.Log.Debug("ERROR: Unsupported encoding method!")
 		return nil, fmt.Errorf("unsupported encoding method (%s)", *method)
@@ -84,6 +87,19 @@ func NewEncoderFromStream(streamObj *PdfObjectStream) (StreamEncoder, error) {
 func DecodeStream(streamObj *PdfObjectStream) ([]byte, error) {
 	common.Log.Trace("Decode stream")
 
+	if streamObj.lazy != nil {
+		// Decode a copy with the data read from the file, so that the data is not kept in memory.
+		data, err := streamObj.lazy.read()
+		if err != nil {
+			common.Log.Debug("ERROR: Failed reading stream data: %v", err)
+			return nil, err
+		}
+		loaded := *streamObj
+		loaded.Stream = data
+		loaded.lazy = nil
+		streamObj = &loaded
+	}
+
 	encoder, err := NewEncoderFromStream(streamObj)
 	if err != nil {
 		common.Log.Debug("ERROR: Stream decoding failed: %v", err)
@@ -110,13 +126,6 @@ func EncodeStream(streamObj *PdfObjectStream) error {
 		return err
 	}
 
-	if lzwenc, is := encoder.(*LZWEncoder); is {
-		// If LZW:
-		// Make sure to use EarlyChange 0.. We do not have write support for 1 yet.
-		lzwenc.EarlyChange = 0
-		streamObj.PdfObjectDictionary.Set("EarlyChange", MakeInteger(0))
-	}
-
 	common.Log.Trace("Encoder: %+v\n", encoder)
 	encoded, err := encoder.EncodeBytes(streamObj.Stream)
 	if err != nil {
@@ -131,3 +140,63 @@ func EncodeStream(streamObj *PdfObjectStream) error {
 
 	return nil
 }
+
+// LoadStream reads the data of the stream into Stream if the data is read on demand, which is the
+// case for the streams parsed with a limited parser cache (see PdfParser.SetCacheLimit).
+// The data is then kept in memory as long as the stream object is referenced.
+func (stream *PdfObjectStream) LoadStream() error {
+	lazy := stream.lazy
+	if lazy == nil {
+		return nil
+	}
+	data, err := lazy.read()
+	if err != nil {
+		return err
+	}
+	stream.Stream = data
+	stream.lazy = nil
+	if lazy.filter != "" {
+		// Update the length based on the decrypted stream.
+		stream.PdfObjectDictionary.Set("Length", MakeInteger(int64(len(data))))
+	}
+
+	// The size of the cached object includes the data now.
+	num := int(stream.ObjectNumber)
+	if cached, ok := lazy.parser.ObjCache[num]; ok && cached == stream {
+		lazy.parser.cacheObject(num, stream)
+	}
+	return nil
+}
+
+// encodedData returns the encoded data of the stream. The data is read from the file if it is
+// not loaded, without keeping it in memory.
+func (stream *PdfObjectStream) encodedData() ([]byte, error) {
+	if stream.lazy == nil {
+		return stream.Stream, nil
+	}
+	return stream.lazy.read()
+}
+
+// lazyStreamData locates the data of a stream in the file for reading it on demand.
+type lazyStreamData struct {
+	parser *PdfParser
+	offset int64
+	length int64
+
+	// filter is the crypt filter and key the object key decrypting the data, filter is empty if
+	// the data is not encrypted.
+	filter string
+	key    []byte
+}
+
+// read reads the data from the file and decrypts it if needed.
+func (lazy *lazyStreamData) read() ([]byte, error) {
+	data, err := lazy.parser.ReadBytesAt(lazy.offset, lazy.length)
+	if err != nil {
+		return nil, err
+	}
+	if lazy.filter == "" {
+		return data, nil
+	}
+	return lazy.parser.crypter.decryptBytes(data, lazy.filter, lazy.key)
+}
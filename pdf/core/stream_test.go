@@ -70,7 +70,7 @@ func TestFlateTiffPredictor(t *testing.T) {
                 /Columns 2
              >>
 /Filter /FlateDecode
-/Length 24
+/Length ` + fmt.Sprintf("%d", len(encoded)) + `
 >>
 stream
 ` + string(encoded) + `endstream
@@ -175,3 +175,33 @@ endobj`
 	}
 
 }
+
+// Test re-encoding of LZW streams preserving the EarlyChange.
+func TestEncodeStreamLZW(t *testing.T) {
+	raw := []byte("this is a dummy text with some \x01\x02\x03 binary data, dummy text")
+
+	for _, earlyChange := range []int64{0, 1} {
+		decodeParams := MakeDict()
+		decodeParams.Set("EarlyChange", MakeInteger(earlyChange))
+		stream := &PdfObjectStream{PdfObjectDictionary: MakeDict(), Stream: raw}
+		stream.Set("Filter", MakeName(StreamEncodingFilterNameLZW))
+		stream.Set("DecodeParms", decodeParams)
+
+		if err := EncodeStream(stream); err != nil {
+			t.Fatalf("Failed to encode stream: %v", err)
+		}
+		if value, ok := GetIntVal(decodeParams.Get("EarlyChange")); !ok || value != int(earlyChange) {
+			t.Errorf("EarlyChange changed: %v", decodeParams.Get("EarlyChange"))
+		}
+
+		encoder := NewLZWEncoder()
+		encoder.EarlyChange = int(earlyChange)
+		decoded, err := encoder.DecodeBytes(stream.Stream)
+		if err != nil {
+			t.Fatalf("Failed to decode stream: %v", err)
+		}
+		if !compareSlices(decoded, raw) {
+			t.Errorf("Slices not matching (EarlyChange %d): %q", earlyChange, decoded)
+		}
+	}
+}
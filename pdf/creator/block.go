@@ -44,6 +44,9 @@ type Block struct {
 
 	// Block annotations.
 	annotations []*model.PdfAnnotation
+
+	// Structure elements of the marked-content sequences of the block by their BDC operation.
+	structElems map[*contentstream.ContentStreamOperation]*model.PdfStructElem
 }
 
 // NewBlock creates a new Block with specified width and height.
@@ -120,11 +123,23 @@ func (blk *Block) duplicate() *Block {
 	// Copy over.
 	*dup = *blk
 
+	// The BDC operations of the marked-content sequences are copied, as their MCIDs are set when
+	// each copy is drawn.
+	var structElems map[*contentstream.ContentStreamOperation]*model.PdfStructElem
+	if len(blk.structElems) > 0 {
+		structElems = map[*contentstream.ContentStreamOperation]*model.PdfStructElem{}
+	}
 	dupContents := contentstream.ContentStreamOperations{}
 	for _, op := range *blk.contents {
+		if elem, ok := blk.structElems[op]; ok {
+			bdc := *op
+			op = &bdc
+			structElems[op] = elem
+		}
 		dupContents = append(dupContents, op)
 	}
 	dup.contents = &dupContents
+	dup.structElems = structElems
 
 	return dup
 }
@@ -333,6 +348,7 @@ func (blk *Block) Draw(d Drawable) error {
 		if err != nil {
 			return err
 		}
+		blk.mergeStructElems(newBlock)
 	}
 
 	return nil
@@ -354,6 +370,7 @@ func (blk *Block) DrawWithContext(d Drawable, ctx DrawContext) error {
 		if err != nil {
 			return err
 		}
+		blk.mergeStructElems(newBlock)
 	}
 
 	return nil
@@ -370,6 +387,7 @@ func (blk *Block) mergeBlocks(toAdd *Block) error {
 	for _, annot := range toAdd.annotations {
 		blk.AddAnnotation(annot)
 	}
+	blk.mergeStructElems(toAdd)
 
 	return nil
 }
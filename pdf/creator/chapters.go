@@ -86,6 +86,13 @@ func newChapter(parent *Chapter, toc *TOC, outline *model.Outline, title string,
 	p.SetFont(style.Font)
 	p.SetFontSize(style.FontSize)
 
+	// The headings are tagged H1 to H6, the deeper levels as H6.
+	headingLevel := level
+	if headingLevel > 6 {
+		headingLevel = 6
+	}
+	p.structType = "H" + strconv.Itoa(int(headingLevel))
+
 	chapter.heading = p
 	return chapter
 }
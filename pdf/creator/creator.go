@@ -59,6 +59,27 @@ type Creator struct {
 
 	optimizer model.Optimizer
 
+	// Document information.
+	info *model.PdfInfo
+
+	// Page label ranges.
+	pageLabels []*model.PdfPageLabelRange
+
+	// Open action and viewer settings.
+	openAction  *model.PdfAction
+	openDest    *model.PdfDestination
+	pageMode    model.PdfPageMode
+	pageLayout  model.PdfPageLayout
+	viewerPrefs *model.PdfViewerPreferences
+
+	// Structure tree of tagged documents, nil if not tagged. The drawn components are added to
+	// the document element when their content is drawn on a page.
+	structTreeRoot *model.PdfStructTreeRoot
+	structDoc      *model.PdfStructElem
+	structLinked   map[*model.PdfStructElem]bool
+	// Next marked-content identifiers of the pages.
+	mcids map[*model.PdfPage]int
+
 	// Default fonts used by all components instantiated through the creator.
 	defaultFontRegular *model.PdfFont
 	defaultFontBold    *model.PdfFont
@@ -137,6 +158,72 @@ func New() *Creator {
 	return c
 }
 
+// SetDocInfo sets the document information dictionary of the output PDF: title, author, custom
+// keys, etc.
+func (c *Creator) SetDocInfo(info *model.PdfInfo) {
+	c.info = info
+}
+
+// SetPageLabels sets the page label ranges of the output PDF, e.g. roman numerals for the front
+// matter. The page indices include the generated front page and table of contents pages.
+func (c *Creator) SetPageLabels(ranges []*model.PdfPageLabelRange) {
+	c.pageLabels = ranges
+}
+
+// SetOpenAction sets the action performed when the output PDF is opened. It replaces the open
+// destination.
+func (c *Creator) SetOpenAction(action *model.PdfAction) {
+	c.openAction = action
+	c.openDest = nil
+}
+
+// SetOpenDestination sets the destination displayed when the output PDF is opened. The page
+// numbers include the generated front page and table of contents pages. It replaces the open
+// action.
+func (c *Creator) SetOpenDestination(dest *model.PdfDestination) {
+	c.openDest = dest
+	c.openAction = nil
+}
+
+// SetPageMode sets how the output PDF is displayed when opened, e.g. with the outlines visible.
+func (c *Creator) SetPageMode(mode model.PdfPageMode) {
+	c.pageMode = mode
+}
+
+// SetPageLayout sets the page layout used when the output PDF is opened.
+func (c *Creator) SetPageLayout(layout model.PdfPageLayout) {
+	c.pageLayout = layout
+}
+
+// SetViewerPreferences sets the viewer preferences of the output PDF.
+func (c *Creator) SetViewerPreferences(prefs *model.PdfViewerPreferences) {
+	c.viewerPrefs = prefs
+}
+
+// SetTagged sets whether the output PDF is tagged, with a structure tree describing its logical
+// structure for accessibility. The chapter headings are tagged as H1 to H6 depending on the
+// chapter level, the paragraphs as P, the tables as Table with TR, TH and TD, the lists as L with
+// LI, Lbl and LBody and the images as Figure. The headers, footers and table decorations are
+// marked as artifacts. Only the components drawn after enabling tagging are tagged.
+func (c *Creator) SetTagged(tagged bool) {
+	if !tagged {
+		c.structTreeRoot = nil
+		c.structDoc = nil
+		c.context.structParent = nil
+		return
+	}
+	if c.structTreeRoot != nil {
+		return
+	}
+
+	c.structTreeRoot = model.NewPdfStructTreeRoot()
+	c.structDoc = model.NewPdfStructElem("Document")
+	c.structTreeRoot.AddKid(c.structDoc)
+	c.structLinked = map[*model.PdfStructElem]bool{}
+	c.mcids = map[*model.PdfPage]int{}
+	c.context.structParent = c.structDoc
+}
+
 // SetOptimizer sets the optimizer to optimize PDF before writing.
 func (c *Creator) SetOptimizer(optimizer model.Optimizer) {
 	c.optimizer = optimizer
@@ -480,6 +567,9 @@ func (c *Creator) finalize() error {
 			}
 			c.drawHeaderFunc(headerBlock, args)
 			headerBlock.SetPos(0, 0)
+			if c.structTreeRoot != nil {
+				headerBlock.markArtifact()
+			}
 			err := c.Draw(headerBlock)
 			if err != nil {
 				common.Log.Debug("ERROR: drawing header: %v", err)
@@ -497,6 +587,9 @@ func (c *Creator) finalize() error {
 			}
 			c.drawFooterFunc(footerBlock, args)
 			footerBlock.SetPos(0, c.pageHeight-footerBlock.height)
+			if c.structTreeRoot != nil {
+				footerBlock.markArtifact()
+			}
 			err := c.Draw(footerBlock)
 			if err != nil {
 				common.Log.Debug("ERROR: drawing footer: %v", err)
@@ -505,6 +598,10 @@ func (c *Creator) finalize() error {
 		}
 	}
 
+	if c.structTreeRoot != nil {
+		c.sortStructElems()
+	}
+
 	c.finalized = true
 
 	return nil
@@ -555,6 +652,9 @@ func (c *Creator) Draw(d Drawable) error {
 		}
 
 		p := c.getActivePage()
+		if c.structTreeRoot != nil {
+			c.markContent(blk, p)
+		}
 		err := blk.drawToPage(p)
 		if err != nil {
 			return err
@@ -578,6 +678,34 @@ func (c *Creator) Write(ws io.Writer) error {
 	pdfWriter := model.NewPdfWriter()
 	pdfWriter.SetOptimizer(c.optimizer)
 
+	// Document information.
+	if c.info != nil {
+		pdfWriter.SetDocInfo(c.info)
+	}
+
+	// Open action and viewer settings.
+	if c.openAction != nil {
+		pdfWriter.SetOpenAction(c.openAction)
+	}
+	if c.openDest != nil {
+		pdfWriter.SetOpenDestination(c.openDest)
+	}
+	pdfWriter.SetPageMode(c.pageMode)
+	pdfWriter.SetPageLayout(c.pageLayout)
+	pdfWriter.SetViewerPreferences(c.viewerPrefs)
+
+	// Page labels.
+	if c.pageLabels != nil {
+		if err := pdfWriter.SetPageLabels(c.pageLabels); err != nil {
+			return err
+		}
+	}
+
+	// Structure tree.
+	if c.structTreeRoot != nil {
+		pdfWriter.SetStructTreeRoot(c.structTreeRoot)
+	}
+
 	// Form fields.
 	if c.acroForm != nil {
 		err := pdfWriter.SetForms(c.acroForm)
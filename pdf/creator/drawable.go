@@ -5,6 +5,8 @@
 
 package creator
 
+import "github.com/unidoc/unidoc/pdf/model"
+
 // Drawable is a widget that can be used to draw with the Creator.
 type Drawable interface {
 	// GeneratePageBlocks draw onto blocks representing Page contents. As the content can wrap over many pages, multiple
@@ -46,4 +48,8 @@ type DrawContext struct {
 
 	// Controls whether the components are stacked horizontally
 	Inline bool
+
+	// Structure element containing the drawn components in tagged documents, nil if the document
+	// is not tagged.
+	structParent *model.PdfStructElem
 }
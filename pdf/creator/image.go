@@ -53,6 +53,9 @@ type Image struct {
 
 	// Encoder
 	encoder core.StreamEncoder
+
+	// Alternate description of the image in tagged documents.
+	altText string
 }
 
 // newImage create a new image from a unidoc image (model.Image).
@@ -236,6 +239,12 @@ func (img *Image) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, er
 	return blocks, ctx, nil
 }
 
+// SetAltText sets the alternate description of the image, read by screen readers in tagged
+// documents.
+func (img *Image) SetAltText(text string) {
+	img.altText = text
+}
+
 // SetPos sets the absolute position. Changes object positioning to absolute.
 func (img *Image) SetPos(x, y float64) {
 	img.positioning = positionAbsolute
@@ -378,7 +387,11 @@ func drawImageOnBlock(blk *Block, img *Image, ctx DrawContext) (DrawContext, err
 	ops := contentCreator.Operations()
 	ops.WrapIfNeeded()
 
-	blk.addContents(ops)
+	figure := newStructElem(ctx.structParent, "Figure")
+	if figure != nil {
+		figure.Alt = img.altText
+	}
+	blk.addTaggedContents(ops, figure)
 
 	if img.positioning.isRelative() {
 		ctx.Y += rotatedHeight
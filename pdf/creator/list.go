@@ -199,6 +199,7 @@ func (l *List) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error
 
 	// Draw items.
 	table := newTable(2)
+	table.isList = true
 	table.SetColumnWidths(markerWidth, 1-markerWidth)
 	table.SetMargins(l.indent, 0, 0, 0)
 
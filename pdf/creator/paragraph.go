@@ -64,6 +64,9 @@ type Paragraph struct {
 
 	// Text lines after wrapping to available width.
 	textLines []string
+
+	// Structure type of the paragraph in tagged documents.
+	structType string
 }
 
 // newParagraph create a new text paragraph. Uses default parameters: Helvetica, WinAnsiEncoding and
@@ -84,6 +87,7 @@ func newParagraph(text string, style TextStyle) *Paragraph {
 		scaleX:      1,
 		scaleY:      1,
 		positioning: positionRelative,
+		structType:  "P",
 	}
 
 	p.SetColor(style.Color)
@@ -381,8 +385,11 @@ func (p *Paragraph) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 		ctx.Y = p.yPos
 	}
 
+	// The paragraph is a single structure element even if drawn on more than one block.
+	elem := newStructElem(ctx.structParent, p.structType)
+
 	// Place the Paragraph on the template at position (x,y) based on the ctx.
-	ctx, err := drawParagraphOnBlock(blk, p, ctx)
+	ctx, err := drawParagraphOnBlock(blk, p, ctx, elem)
 	if err != nil {
 		common.Log.Debug("ERROR: %v", err)
 		return nil, ctx, err
@@ -399,8 +406,8 @@ func (p *Paragraph) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 }
 
 // drawParagraphOnBlock draws Paragraph `p` on Block `blk` at the specified location on the page,
-// adding it to the content stream.
-func drawParagraphOnBlock(blk *Block, p *Paragraph, ctx DrawContext) (DrawContext, error) {
+// adding it to the content stream as content of the structure element `elem` if not nil.
+func drawParagraphOnBlock(blk *Block, p *Paragraph, ctx DrawContext, elem *model.PdfStructElem) (DrawContext, error) {
 	// Find a free name for the font.
 	num := 1
 	fontName := core.PdfObjectName("Font" + strconv.Itoa(num))
@@ -525,7 +532,7 @@ func drawParagraphOnBlock(blk *Block, p *Paragraph, ctx DrawContext) (DrawContex
 	ops := cc.Operations()
 	ops.WrapIfNeeded()
 
-	blk.addContents(ops)
+	blk.addTaggedContents(ops, elem)
 
 	if p.positioning.isRelative() {
 		pHeight := p.Height() + p.margins.bottom
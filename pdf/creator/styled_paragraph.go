@@ -67,6 +67,9 @@ type StyledParagraph struct {
 
 	// Before render callback.
 	beforeRender func(p *StyledParagraph, ctx DrawContext)
+
+	// Structure type of the paragraph in tagged documents.
+	structType string
 }
 
 // newStyledParagraph creates a new styled paragraph.
@@ -84,6 +87,7 @@ func newStyledParagraph(style TextStyle) *StyledParagraph {
 		scaleX:           1,
 		scaleY:           1,
 		positioning:      positionRelative,
+		structType:       "P",
 	}
 }
 
@@ -503,8 +507,11 @@ func (p *StyledParagraph) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawCon
 		p.beforeRender(p, ctx)
 	}
 
+	// The paragraph is a single structure element even if drawn on more than one block.
+	elem := newStructElem(ctx.structParent, p.structType)
+
 	// Place the Paragraph on the template at position (x,y) based on the ctx.
-	ctx, err := drawStyledParagraphOnBlock(blk, p, ctx)
+	ctx, err := drawStyledParagraphOnBlock(blk, p, ctx, elem)
 	if err != nil {
 		common.Log.Debug("ERROR: %v", err)
 		return nil, ctx, err
@@ -520,8 +527,9 @@ func (p *StyledParagraph) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawCon
 	return blocks, origContext, nil
 }
 
-// Draw block on specified location on Page, adding to the content stream.
-func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext) (DrawContext, error) {
+// Draw block on specified location on Page, adding to the content stream as content of the
+// structure element `elem` if not nil.
+func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext, elem *model.PdfStructElem) (DrawContext, error) {
 	// Find first free index for the font resources of the paragraph.
 	num := 1
 	fontName := core.PdfObjectName(fmt.Sprintf("Font%d", num))
@@ -802,7 +810,7 @@ func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext)
 	ops := cc.Operations()
 	ops.WrapIfNeeded()
 
-	blk.addContents(ops)
+	blk.addTaggedContents(ops, elem)
 
 	if p.positioning.isRelative() {
 		pHeight := p.Height() + p.margins.bottom
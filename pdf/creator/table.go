@@ -54,6 +54,9 @@ type Table struct {
 	// Header rows.
 	headerStartRow int
 	headerEndRow   int
+
+	// Specifies whether the table lays out a list, which is tagged as a list in tagged documents.
+	isList bool
 }
 
 // newTable create a new Table with a specified number of columns.
@@ -267,6 +270,14 @@ func (table *Table) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 	}
 	tableWidth := ctx.Width
 
+	// Structure elements of the table and its rows in tagged documents.
+	tableType, rowType := "Table", "TR"
+	if table.isList {
+		tableType, rowType = "L", "LI"
+	}
+	tableElem := newStructElem(ctx.structParent, tableType)
+	rowElems := map[int]*model.PdfStructElem{}
+
 	// Store table's upper left corner.
 	ulX := ctx.X
 	ulY := ctx.Y
@@ -510,7 +521,13 @@ func (table *Table) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 		border.SetWidthRight(cell.borderWidthRight)
 		border.SetWidthTop(cell.borderWidthTop)
 
-		err := block.Draw(border)
+		// The cell backgrounds and borders are not part of the table structure.
+		var err error
+		if tableElem != nil {
+			err = block.drawArtifact(border, ctx)
+		} else {
+			err = block.Draw(border)
+		}
 		if err != nil {
 			common.Log.Debug("ERROR: %v", err)
 		}
@@ -571,7 +588,21 @@ func (table *Table) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 				}
 			}
 
-			err := block.DrawWithContext(cell.content, ctx)
+			if tableElem != nil && drawingHeaders {
+				// The headers repeated on the next pages are not part of the table structure.
+				err = block.drawArtifact(cell.content, ctx)
+			} else {
+				cellCtx := ctx
+				if tableElem != nil {
+					row, ok := rowElems[cell.row]
+					if !ok {
+						row = newStructElem(tableElem, rowType)
+						rowElems[cell.row] = row
+					}
+					cellCtx.structParent = table.newCellStructElem(cell, row)
+				}
+				err = block.DrawWithContext(cell.content, cellCtx)
+			}
 			if err != nil {
 				common.Log.Debug("ERROR: %v", err)
 			}
@@ -607,6 +638,35 @@ func (table *Table) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 	return blocks, ctx, nil
 }
 
+// newCellStructElem returns the structure element of the cell 'cell' of the row 'row' in tagged
+// documents: TH for the header cells and TD for the other cells, or Lbl for the markers and LBody
+// for the items of lists.
+func (table *Table) newCellStructElem(cell *TableCell, row *model.PdfStructElem) *model.PdfStructElem {
+	if table.isList {
+		if cell.col == 1 {
+			return newStructElem(row, "Lbl")
+		}
+		return newStructElem(row, "LBody")
+	}
+
+	typ := "TD"
+	if table.hasHeader && cell.row >= table.headerStartRow && cell.row <= table.headerEndRow {
+		typ = "TH"
+	}
+	elem := newStructElem(row, typ)
+	if cell.rowspan > 1 || cell.colspan > 1 {
+		attrs := core.MakeDict()
+		if cell.rowspan > 1 {
+			attrs.Set("RowSpan", core.MakeInteger(int64(cell.rowspan)))
+		}
+		if cell.colspan > 1 {
+			attrs.Set("ColSpan", core.MakeInteger(int64(cell.colspan)))
+		}
+		elem.Attributes = []*model.PdfStructAttributes{{O: "Table", Attributes: attrs}}
+	}
+	return elem
+}
+
 // CellBorderStyle defines the table cell's border style.
 type CellBorderStyle int
 
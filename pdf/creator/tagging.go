@@ -0,0 +1,153 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package creator
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/unidoc/unidoc/pdf/contentstream"
+	"github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/model"
+)
+
+// newStructElem returns a new structure element of type 'typ' with the parent 'parent', nil if
+// the document is not tagged, i.e. 'parent' is nil. The element is only added to the kids of its
+// parent when its content is drawn on a page, so that the elements of discarded blocks are left
+// out.
+func newStructElem(parent *model.PdfStructElem, typ string) *model.PdfStructElem {
+	if parent == nil {
+		return nil
+	}
+	elem := model.NewPdfStructElem(typ)
+	elem.Parent = parent
+	return elem
+}
+
+// addTaggedContents adds contents to the block as a marked-content sequence of the structure
+// element 'elem'. The contents are added untagged if 'elem' is nil.
+func (blk *Block) addTaggedContents(operations *contentstream.ContentStreamOperations, elem *model.PdfStructElem) {
+	if elem == nil {
+		blk.addContents(operations)
+		return
+	}
+
+	// The MCID is set when the block is drawn on a page.
+	cc := contentstream.NewContentCreator().Add_BDC(core.PdfObjectName(elem.S), core.MakeDict())
+	bdc := (*cc.Operations())[0]
+	marked := append(*cc.Operations(), *operations.WrapIfNeeded()...)
+	marked = append(marked, &contentstream.ContentStreamOperation{Operand: "EMC"})
+
+	if blk.structElems == nil {
+		blk.structElems = map[*contentstream.ContentStreamOperation]*model.PdfStructElem{}
+	}
+	blk.structElems[bdc] = elem
+	blk.addContents(&marked)
+}
+
+// mergeStructElems merges the structure elements of the marked-content sequences of 'toAdd' into
+// the block.
+func (blk *Block) mergeStructElems(toAdd *Block) {
+	if len(toAdd.structElems) == 0 {
+		return
+	}
+	if blk.structElems == nil {
+		blk.structElems = map[*contentstream.ContentStreamOperation]*model.PdfStructElem{}
+	}
+	for op, elem := range toAdd.structElems {
+		blk.structElems[op] = elem
+	}
+}
+
+// markArtifact marks the contents of the block as an artifact, i.e. content which is not part of
+// the structure tree, such as headers, footers and other page decorations.
+func (blk *Block) markArtifact() {
+	if len(*blk.contents) == 0 {
+		return
+	}
+	marked := *contentstream.NewContentCreator().Add_BMC("Artifact").Operations()
+	marked = append(marked, *blk.contents.WrapIfNeeded()...)
+	marked = append(marked, &contentstream.ContentStreamOperation{Operand: "EMC"})
+	blk.contents = &marked
+	blk.structElems = nil
+}
+
+// drawArtifact draws the drawable d on the block in the context 'ctx' as an artifact.
+// Note that the drawable must not wrap, i.e. only return one block. Otherwise an error is returned.
+func (blk *Block) drawArtifact(d Drawable, ctx DrawContext) error {
+	ctx.structParent = nil
+	blocks, _, err := d.GeneratePageBlocks(ctx)
+	if err != nil {
+		return err
+	}
+	if len(blocks) != 1 {
+		return errors.New("too many output blocks")
+	}
+
+	blocks[0].markArtifact()
+	return mergeContents(blk.contents, blk.resources, blocks[0].contents, blocks[0].resources)
+}
+
+// markContent numbers the marked-content sequences of the block drawn on the page 'page' and
+// adds them to the kids of their structure elements.
+func (c *Creator) markContent(blk *Block, page *model.PdfPage) {
+	if len(blk.structElems) == 0 {
+		return
+	}
+
+	pageObj := page.GetPageAsIndirectObject()
+	for i, op := range *blk.contents {
+		elem, ok := blk.structElems[op]
+		if !ok {
+			continue
+		}
+		mcid := c.mcids[page]
+		c.mcids[page]++
+
+		// The BDC operation is replaced, as it can be shared by the copies of the block.
+		props := core.MakeDict()
+		props.Set("MCID", core.MakeInteger(int64(mcid)))
+		(*blk.contents)[i] = &contentstream.ContentStreamOperation{
+			Operand: op.Operand,
+			Params:  []core.PdfObject{core.MakeName(elem.S), props},
+		}
+
+		// Add the element and its ancestors to the tree the first time they have content.
+		for e := elem; e.Parent != nil && !c.structLinked[e]; e = e.Parent {
+			c.structLinked[e] = true
+			e.Parent.AddKid(e)
+		}
+		elem.AddMarkedContent(pageObj, mcid)
+	}
+}
+
+// sortStructElems sorts the top level elements of the document by the page of their first content
+// item, as the front page and the table of contents are drawn last.
+func (c *Creator) sortStructElems() {
+	pageIndices := map[*core.PdfIndirectObject]int{}
+	for i, page := range c.pages {
+		pageIndices[page.GetPageAsIndirectObject()] = i
+	}
+
+	var firstPage func(elem *model.PdfStructElem) int
+	firstPage = func(elem *model.PdfStructElem) int {
+		for _, kid := range elem.Kids {
+			if kid.Elem != nil {
+				if idx := firstPage(kid.Elem); idx >= 0 {
+					return idx
+				}
+			} else if idx, ok := pageIndices[kid.Page]; ok {
+				return idx
+			}
+		}
+		return -1
+	}
+
+	kids := c.structDoc.Kids
+	sort.SliceStable(kids, func(i, j int) bool {
+		return firstPage(kids[i].Elem) < firstPage(kids[j].Elem)
+	})
+}
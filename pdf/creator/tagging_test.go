@@ -0,0 +1,195 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package creator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/contentstream"
+	"github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/model"
+)
+
+func TestTaggedOutput(t *testing.T) {
+	c := New()
+	c.SetTagged(true)
+	c.AddTOC = true
+	c.DrawHeader(func(block *Block, args HeaderFunctionArgs) {
+		p := c.NewParagraph("Header")
+		p.SetPos(50, 20)
+		block.Draw(p)
+	})
+
+	ch := c.NewChapter("Introduction")
+	ch.Add(c.NewParagraph("First paragraph."))
+	sub := ch.NewSubchapter("Details")
+	sp := c.NewStyledParagraph()
+	sp.Append("Styled paragraph.")
+	sub.Add(sp)
+
+	table := c.NewTable(2)
+	require.NoError(t, table.SetHeaderRows(1, 1))
+	for _, text := range []string{"Name", "Value", "a", "1", "b", "2"} {
+		table.NewCell().SetContent(c.NewParagraph(text))
+	}
+	sub.Add(table)
+	require.NoError(t, c.Draw(ch))
+
+	list := c.NewList()
+	list.AddTextItem("One")
+	list.AddTextItem("Two")
+	require.NoError(t, c.Draw(list))
+
+	img, err := c.NewImageFromFile(testImageFile1)
+	require.NoError(t, err)
+	img.ScaleToWidth(100)
+	img.SetAltText("Logo")
+	require.NoError(t, c.Draw(img))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.True(t, reader.IsTagged())
+	tree, err := reader.GetStructTreeRoot()
+	require.NoError(t, err)
+	require.NotNil(t, tree)
+
+	var types []string
+	require.NoError(t, tree.Walk(func(elem *model.PdfStructElem, depth int) error {
+		types = append(types, strings.Repeat(" ", depth)+elem.S)
+		return nil
+	}))
+	require.Equal(t, []string{
+		"Document",
+		// The table of contents is drawn last but comes first.
+		" P", " P", " P",
+		" H1", " P", " H2", " P",
+		" Table",
+		"  TR", "   TH", "    P", "   TH", "    P",
+		"  TR", "   TD", "    P", "   TD", "    P",
+		"  TR", "   TD", "    P", "   TD", "    P",
+		" L",
+		"  LI", "   Lbl", "    P", "   LBody", "    P",
+		"  LI", "   Lbl", "    P", "   LBody", "    P",
+		" Figure",
+	}, types)
+
+	elems := tree.K[0].GetKidElems()
+	require.Equal(t, 1, elems[0].PageNumber)
+	require.Equal(t, 2, elems[3].PageNumber)
+	figure := elems[len(elems)-1]
+	require.Equal(t, "Logo", figure.Alt)
+
+	// Each marked-content sequence of the pages belongs to its element.
+	for i, elem := range []*model.PdfStructElem{elems[3], elems[4], figure} {
+		kid := elem.Kids[0]
+		require.True(t, kid.IsMarkedContent(), i)
+		page, err := reader.GetPage(kid.PageNumber)
+		require.NoError(t, err)
+		key, ok := core.GetIntVal(page.StructParents)
+		require.True(t, ok)
+		require.Equal(t, elem, tree.GetMarkedContentElem(key, kid.MCID))
+	}
+
+	// The headers are artifacts.
+	page, err := reader.GetPage(2)
+	require.NoError(t, err)
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.Contains(t, content, "/Artifact BMC")
+	require.Contains(t, content, "/H1 <<")
+}
+
+func TestUntaggedOutput(t *testing.T) {
+	c := New()
+	require.NoError(t, c.Draw(c.NewParagraph("Untagged")))
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.False(t, reader.IsTagged())
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.NotContains(t, content, "BDC")
+}
+
+func TestTaggedParagraphsOverPages(t *testing.T) {
+	c := New()
+	c.SetTagged(true)
+	const numParagraphs = 60
+	for i := 0; i < numParagraphs; i++ {
+		require.NoError(t, c.Draw(c.NewParagraph("Paragraph")))
+		sp := c.NewStyledParagraph()
+		sp.Append("Styled paragraph")
+		require.NoError(t, c.Draw(sp))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.True(t, numPages > 1)
+	tree, err := reader.GetStructTreeRoot()
+	require.NoError(t, err)
+
+	// Each paragraph is one element with one marked-content sequence.
+	elems := tree.K[0].GetKidElems()
+	require.Len(t, elems, 2*numParagraphs)
+	for _, elem := range elems {
+		require.Equal(t, "P", elem.S)
+		require.Len(t, elem.Kids, 1)
+	}
+}
+
+func TestTaggedBlockDrawnTwice(t *testing.T) {
+	c := New()
+	c.SetTagged(true)
+	c.NewPage()
+	elem := newStructElem(c.context.structParent, "P")
+	blk := NewBlock(100, 20)
+	cc := contentstream.NewContentCreator().Add_BT().Add_ET()
+	blk.addTaggedContents(cc.Operations(), elem)
+	parent := NewBlock(100, 40)
+	require.NoError(t, parent.Draw(blk))
+	require.NoError(t, parent.Draw(blk))
+	require.NoError(t, c.Draw(parent))
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.Contains(t, content, "/MCID 0")
+	require.Contains(t, content, "/MCID 1")
+
+	// Each drawn copy is a marked-content sequence of the element.
+	tree, err := reader.GetStructTreeRoot()
+	require.NoError(t, err)
+	elems := tree.K[0].GetKidElems()
+	require.Len(t, elems, 1)
+	require.Len(t, elems[0].Kids, 2)
+	key, ok := core.GetIntVal(page.StructParents)
+	require.True(t, ok)
+	for i, kid := range elems[0].Kids {
+		require.True(t, kid.IsMarkedContent())
+		require.Equal(t, i, kid.MCID)
+		require.Equal(t, elems[0], tree.GetMarkedContentElem(key, i))
+	}
+}
@@ -44,10 +44,7 @@ func newExternalLinkAnnotation(url string) *model.PdfAnnotation {
 	annotation.BS = bs.ToPdfObject()
 
 	// Set link destination.
-	action := core.MakeDict()
-	action.Set(core.PdfObjectName("S"), core.MakeName("URI"))
-	action.Set(core.PdfObjectName("URI"), core.MakeString(url))
-	annotation.A = action
+	annotation.SetAction(model.NewPdfActionURI(url).PdfAction)
 
 	return annotation.PdfAnnotation
 }
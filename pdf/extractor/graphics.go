@@ -0,0 +1,353 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+
+	"github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/contentstream"
+	"github.com/unidoc/unidoc/pdf/contentstream/draw"
+	"github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/internal/transform"
+	"github.com/unidoc/unidoc/pdf/model"
+)
+
+// ExtractPageGraphics returns the vector graphics of the page extractor: the paths that are
+// stroked or filled, including those in form XObjects. Paths that are only used for clipping are
+// returned as the clipping paths of the paths painted inside them.
+func (e *Extractor) ExtractPageGraphics() (*PageGraphics, error) {
+	ctx := &graphicsExtractContext{forms: map[core.PdfObject]bool{}}
+	err := ctx.extractContentStreamGraphics(e.contents, e.resources, nil, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &PageGraphics{Paths: ctx.paths}, nil
+}
+
+// PageGraphics represents the vector graphics on a PDF page.
+type PageGraphics struct {
+	Paths []PathMark
+}
+
+// PathMark represents a path painted on a page.
+// All coordinates and lengths are in device coordinates. The line width and dash pattern are
+// scaled from user space by the average scaling of the CTM.
+type PathMark struct {
+	Subpaths []Subpath
+	BBox     model.PdfRectangle // Bounding box of the path, not including the line width.
+
+	Stroked bool
+	Filled  bool
+	EvenOdd bool // Filled with the even-odd rule instead of the nonzero winding number rule.
+
+	StrokeColorspace model.PdfColorspace
+	StrokeColor      model.PdfColor
+	FillColorspace   model.PdfColorspace
+	FillColor        model.PdfColor
+
+	LineWidth float64
+	LineCap   int
+	LineJoin  int
+	DashArray []float64 // Empty for solid lines.
+	DashPhase float64
+
+	// Clip are the clipping paths in effect when the path is painted. The clipping region is the
+	// intersection of the regions of the paths. The path is not clipped if Clip is empty.
+	Clip []ClipPath
+}
+
+// ClipPath represents a path used for clipping.
+type ClipPath struct {
+	Subpaths []Subpath
+	BBox     model.PdfRectangle
+	EvenOdd  bool // The region is determined with the even-odd rule.
+}
+
+// Subpath represents a connected sequence of path segments.
+type Subpath struct {
+	Segments []PathSegment
+	Closed   bool // The subpath was closed, by a straight line to its start if needed.
+}
+
+// PathSegmentType represents the type of a path segment.
+type PathSegmentType int
+
+// Path segment types.
+const (
+	PathSegmentLine  PathSegmentType = iota // Straight line.
+	PathSegmentCurve                        // Cubic Bézier curve.
+)
+
+// PathSegment represents a straight line or cubic Bézier curve of a subpath.
+type PathSegment struct {
+	Type PathSegmentType
+
+	// Points are the start and end points of lines and the start point, the two control points and
+	// the end point of curves.
+	Points []draw.Point
+}
+
+// maxFormDepth is the maximal nesting depth of the form XObjects whose graphics are extracted.
+const maxFormDepth = 20
+
+// graphicsExtractContext provides the context for extracting the graphics of content streams.
+type graphicsExtractContext struct {
+	paths []PathMark
+	// forms are the streams of the form XObjects being processed, which must not be drawn again
+	// from their own content.
+	forms map[core.PdfObject]bool
+}
+
+// extractContentStreamGraphics adds the paths painted by content stream `contents` to `ctx`.
+// `initialState` is the graphics state at the start of the content stream, nil for the default
+// state. The coordinates are transformed to device coordinates by the CTM followed by the matrices
+// in `transforms`, i.e. the matrices of the enclosing forms and their CTMs. `clip` are the clipping
+// paths in effect at the start of the content stream. `level` is the nesting depth of the forms.
+func (ctx *graphicsExtractContext) extractContentStreamGraphics(contents string,
+	resources *model.PdfPageResources, initialState *contentstream.GraphicsState,
+	transforms []transform.Matrix, clip []ClipPath, level int) error {
+	cstreamParser := contentstream.NewContentStreamParser(contents)
+	operations, err := cstreamParser.Parse()
+	if err != nil {
+		return err
+	}
+
+	var subpaths []Subpath
+	var start, current draw.Point // Start of the current subpath and current point in user space.
+	var clipStack [][]ClipPath
+	clipOp := "" // "W" or "W*" if the current path is used for clipping.
+
+	toDevice := func(gs contentstream.GraphicsState, p draw.Point) draw.Point {
+		x, y := gs.Transform(p.X, p.Y)
+		for _, m := range transforms {
+			x, y = m.Transform(x, y)
+		}
+		return draw.NewPoint(x, y)
+	}
+	addSegment := func(gs contentstream.GraphicsState, typ PathSegmentType, points ...draw.Point) {
+		if len(subpaths) == 0 {
+			subpaths = append(subpaths, Subpath{})
+			start = current
+		}
+		segment := PathSegment{Type: typ, Points: []draw.Point{toDevice(gs, current)}}
+		for _, p := range points {
+			segment.Points = append(segment.Points, toDevice(gs, p))
+		}
+		sp := &subpaths[len(subpaths)-1]
+		sp.Segments = append(sp.Segments, segment)
+		current = points[len(points)-1]
+	}
+	closePath := func(gs contentstream.GraphicsState) {
+		if len(subpaths) == 0 || subpaths[len(subpaths)-1].Closed {
+			return
+		}
+		if current != start {
+			addSegment(gs, PathSegmentLine, start)
+		}
+		subpaths[len(subpaths)-1].Closed = true
+	}
+
+	processor := contentstream.NewContentStreamProcessor(*operations)
+	if initialState != nil {
+		processor.SetInitialGraphicsState(*initialState)
+	}
+	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
+			resources *model.PdfPageResources) error {
+			operand := op.Operand
+			switch operand {
+			case "q":
+				clipStack = append(clipStack, clip)
+			case "Q":
+				if len(clipStack) > 0 {
+					clip = clipStack[len(clipStack)-1]
+					clipStack = clipStack[:len(clipStack)-1]
+				}
+			case "m", "l", "c", "v", "y", "re":
+				numParams := map[string]int{"m": 2, "l": 2, "c": 6, "v": 4, "y": 4, "re": 4}[operand]
+				floats, err := core.GetNumbersAsFloat(op.Params)
+				if err != nil || len(floats) != numParams {
+					common.Log.Debug("ERROR: %s invalid params %v", operand, op.Params)
+					return errTypeCheck
+				}
+				var points []draw.Point
+				for i := 0; i+1 < len(floats); i += 2 {
+					points = append(points, draw.NewPoint(floats[i], floats[i+1]))
+				}
+				switch operand {
+				case "m": // Begin a new subpath.
+					subpaths = append(subpaths, Subpath{})
+					start, current = points[0], points[0]
+				case "l": // Straight line.
+					addSegment(gs, PathSegmentLine, points[0])
+				case "c": // Bézier curve.
+					addSegment(gs, PathSegmentCurve, points...)
+				case "v": // Bézier curve with the current point as the first control point.
+					addSegment(gs, PathSegmentCurve, current, points[0], points[1])
+				case "y": // Bézier curve with the end point as the second control point.
+					addSegment(gs, PathSegmentCurve, points[0], points[1], points[1])
+				case "re": // Rectangle as a complete closed subpath.
+					x, y, w, h := floats[0], floats[1], floats[2], floats[3]
+					subpaths = append(subpaths, Subpath{})
+					start, current = draw.NewPoint(x, y), draw.NewPoint(x, y)
+					addSegment(gs, PathSegmentLine, draw.NewPoint(x+w, y))
+					addSegment(gs, PathSegmentLine, draw.NewPoint(x+w, y+h))
+					addSegment(gs, PathSegmentLine, draw.NewPoint(x, y+h))
+					closePath(gs)
+				}
+			case "h": // Close the current subpath.
+				closePath(gs)
+			case "W", "W*": // Use the current path for clipping after it is painted.
+				clipOp = operand
+			case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n": // Paint or end the path.
+				if operand == "s" || operand == "b" || operand == "b*" {
+					closePath(gs)
+				}
+				var painted []Subpath
+				for _, sp := range subpaths {
+					if len(sp.Segments) > 0 {
+						painted = append(painted, sp)
+					}
+				}
+				if operand != "n" && len(painted) > 0 {
+					ctx.paths = append(ctx.paths, newPathMark(operand, painted, gs, transforms, clip))
+				}
+				if clipOp != "" && len(painted) > 0 {
+					// The clipping paths are replaced rather than modified as they are shared
+					// with the saved graphics states and the painted paths.
+					clip = append(append([]ClipPath(nil), clip...), ClipPath{
+						Subpaths: painted,
+						BBox:     subpathsBBox(painted),
+						EvenOdd:  clipOp == "W*",
+					})
+				}
+				subpaths, clipOp = nil, ""
+			case "Do": // Recurse into forms.
+				if len(op.Params) != 1 {
+					return errTypeCheck
+				}
+				name, ok := core.GetName(op.Params[0])
+				if !ok {
+					return errTypeCheck
+				}
+				if _, xtype := resources.GetXObjectByName(*name); xtype != model.XObjectTypeForm {
+					break
+				}
+				if level >= maxFormDepth {
+					common.Log.Debug("ERROR: Form XObjects nested too deeply, skipping %s", *name)
+					break
+				}
+				xform, err := resources.GetXObjectFormByName(*name)
+				if err != nil || xform == nil {
+					return err
+				}
+				formObj := xform.GetContainingPdfObject()
+				if ctx.forms[formObj] {
+					common.Log.Debug("ERROR: Form XObject %s draws itself, skipping", *name)
+					break
+				}
+				formContent, err := xform.GetContentStream()
+				if err != nil {
+					return err
+				}
+				formResources := xform.Resources
+				if formResources == nil {
+					formResources = resources
+				}
+				ctx.forms[formObj] = true
+				defer delete(ctx.forms, formObj)
+				// The form is drawn with the graphics state of the caller. Its CTM is applied by
+				// the transforms.
+				formState := gs
+				formState.CTM = transform.IdentityMatrix()
+				return ctx.extractContentStreamGraphics(string(formContent), formResources, &formState,
+					formTransforms(xform, gs.CTM, transforms), clip, level+1)
+			}
+			return nil
+		})
+
+	return processor.Process(resources)
+}
+
+// formTransforms returns the matrices mapping the content of form XObject `xform` drawn with CTM
+// `ctm` to device space: the form Matrix, `ctm` and `transforms`, the matrices of the enclosing
+// forms and their CTMs.
+func formTransforms(xform *model.XObjectForm, ctm transform.Matrix,
+	transforms []transform.Matrix) []transform.Matrix {
+	var matrices []transform.Matrix
+	if matrix, ok := core.GetArray(xform.Matrix); ok {
+		if f, err := matrix.ToFloat64Array(); err == nil && len(f) == 6 {
+			matrices = append(matrices, transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5]))
+		}
+	}
+	matrices = append(matrices, ctm)
+	return append(matrices, transforms...)
+}
+
+// newPathMark returns the PathMark of `subpaths` painted by operator `operand` with graphics state
+// `gs` and clipping paths `clip`. `transforms` are the matrices applied after the CTM.
+func newPathMark(operand string, subpaths []Subpath, gs contentstream.GraphicsState,
+	transforms []transform.Matrix, clip []ClipPath) PathMark {
+	scale := averageScale(gs.CTM)
+	for _, m := range transforms {
+		scale *= averageScale(m)
+	}
+	var dashes []float64
+	for _, d := range gs.DashArray {
+		dashes = append(dashes, d*scale)
+	}
+
+	path := PathMark{
+		Subpaths:         subpaths,
+		BBox:             subpathsBBox(subpaths),
+		Stroked:          operand == "S" || operand == "s" || operand[0] == 'B' || operand[0] == 'b',
+		Filled:           operand != "S" && operand != "s",
+		EvenOdd:          operand == "f*" || operand == "B*" || operand == "b*",
+		StrokeColorspace: gs.ColorspaceStroking,
+		StrokeColor:      gs.ColorStroking,
+		FillColorspace:   gs.ColorspaceNonStroking,
+		FillColor:        gs.ColorNonStroking,
+		LineWidth:        gs.LineWidth * scale,
+		LineCap:          gs.LineCap,
+		LineJoin:         gs.LineJoin,
+		DashArray:        dashes,
+		DashPhase:        gs.DashPhase * scale,
+		Clip:             clip,
+	}
+	return path
+}
+
+// averageScale returns the geometric mean of the scaling factors of `m` in x and y.
+func averageScale(m transform.Matrix) float64 {
+	return math.Sqrt(m.ScalingFactorX() * m.ScalingFactorY())
+}
+
+// subpathsBBox returns the bounding box of `subpaths`.
+func subpathsBBox(subpaths []Subpath) model.PdfRectangle {
+	bbox := model.PdfRectangle{
+		Llx: math.Inf(1), Lly: math.Inf(1),
+		Urx: math.Inf(-1), Ury: math.Inf(-1),
+	}
+	for _, sp := range subpaths {
+		for _, seg := range sp.Segments {
+			var segBBox model.PdfRectangle
+			if seg.Type == PathSegmentCurve {
+				p := seg.Points
+				segBBox = draw.NewCubicBezierCurve(p[0].X, p[0].Y, p[1].X, p[1].Y,
+					p[2].X, p[2].Y, p[3].X, p[3].Y).GetBounds()
+			} else {
+				p0, p1 := seg.Points[0], seg.Points[1]
+				segBBox = model.PdfRectangle{
+					Llx: minFloat(p0.X, p1.X), Lly: minFloat(p0.Y, p1.Y),
+					Urx: maxFloat(p0.X, p1.X), Ury: maxFloat(p0.Y, p1.Y),
+				}
+			}
+			bbox = rectUnion(bbox, segBBox)
+		}
+	}
+	return bbox
+}
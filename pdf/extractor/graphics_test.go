@@ -0,0 +1,161 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/contentstream/draw"
+	"github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/model"
+)
+
+func TestGraphicsExtraction(t *testing.T) {
+	resources := model.NewPdfPageResources()
+	gsDict := core.MakeDict()
+	gsDict.Set("LW", core.MakeFloat(4))
+	require.NoError(t, resources.AddExtGState("GS0", gsDict))
+
+	xform := model.NewXObjectForm()
+	xform.Matrix = core.MakeArrayFromFloats([]float64{1, 0, 0, 1, 10, 0})
+	xform.Resources = resources
+	require.NoError(t, xform.SetContentStream([]byte("0 0 m 10 0 l S"), nil))
+	require.NoError(t, resources.SetXObjectFormByName("Fm0", xform))
+	xform = model.NewXObjectForm()
+	require.NoError(t, xform.SetContentStream([]byte("0 0 m 10 0 l S"), nil))
+	require.NoError(t, resources.SetXObjectFormByName("Fm1", xform))
+
+	e := Extractor{resources: resources, contents: `
+		2 w
+		[3 2] 1 d
+		1 0 0 RG
+		100 700 m 200 700 l S
+		[] 0 d
+		q
+		0 0 1 rg
+		2 0 0 2 0 0 cm
+		50 300 100 50 re f
+		Q
+		100 100 m 100 150 150 150 150 100 c h B*
+		q
+		0 0 100 100 re W n
+		/GS0 gs
+		10 10 m 50 50 l S
+		Q
+		500 500 m 550 500 l S
+		q 1 0 0 1 0 100 cm /Fm0 Do Q
+		q 1 0 0 RG 5 w 1 J [2 1] 0 d 2 0 0 2 0 0 cm /Fm1 Do Q
+		`}
+	pageGraphics, err := e.ExtractPageGraphics()
+	require.NoError(t, err)
+	paths := pageGraphics.Paths
+	require.Len(t, paths, 7)
+
+	// Stroked dashed line.
+	line := paths[0]
+	require.True(t, line.Stroked)
+	require.False(t, line.Filled)
+	require.Equal(t, []Subpath{{Segments: []PathSegment{{
+		Type:   PathSegmentLine,
+		Points: []draw.Point{draw.NewPoint(100, 700), draw.NewPoint(200, 700)},
+	}}}}, line.Subpaths)
+	require.InDelta(t, 2, line.LineWidth, 1e-9)
+	require.Equal(t, []float64{3, 2}, line.DashArray)
+	require.InDelta(t, 1, line.DashPhase, 1e-9)
+	rgb, err := line.StrokeColorspace.ColorToRGB(line.StrokeColor)
+	require.NoError(t, err)
+	require.Equal(t, []float64{1, 0, 0}, rgbValues(t, rgb))
+	require.Empty(t, line.Clip)
+
+	// Filled rectangle scaled by the CTM.
+	rect := paths[1]
+	require.True(t, rect.Filled)
+	require.False(t, rect.Stroked)
+	require.Len(t, rect.Subpaths, 1)
+	require.True(t, rect.Subpaths[0].Closed)
+	require.Len(t, rect.Subpaths[0].Segments, 4)
+	require.Equal(t, model.PdfRectangle{Llx: 100, Lly: 600, Urx: 300, Ury: 700}, rect.BBox)
+	require.InDelta(t, 4, rect.LineWidth, 1e-9)
+	require.Empty(t, rect.DashArray)
+	rgb, err = rect.FillColorspace.ColorToRGB(rect.FillColor)
+	require.NoError(t, err)
+	require.Equal(t, []float64{0, 0, 1}, rgbValues(t, rgb))
+
+	// Closed Bézier curve, filled with the even-odd rule and stroked.
+	curve := paths[2]
+	require.True(t, curve.Stroked)
+	require.True(t, curve.Filled)
+	require.True(t, curve.EvenOdd)
+	require.Len(t, curve.Subpaths[0].Segments, 2)
+	require.Equal(t, PathSegmentCurve, curve.Subpaths[0].Segments[0].Type)
+	require.Len(t, curve.Subpaths[0].Segments[0].Points, 4)
+	require.Equal(t, PathSegmentLine, curve.Subpaths[0].Segments[1].Type)
+	require.InDelta(t, 100, curve.BBox.Lly, 1e-9)
+	require.InDelta(t, 137.5, curve.BBox.Ury, 1e-9)
+
+	// Clipped line with the line width from the ExtGState.
+	clipped := paths[3]
+	require.InDelta(t, 4, clipped.LineWidth, 1e-9)
+	require.Len(t, clipped.Clip, 1)
+	require.Equal(t, model.PdfRectangle{Llx: 0, Lly: 0, Urx: 100, Ury: 100}, clipped.Clip[0].BBox)
+	require.False(t, clipped.Clip[0].EvenOdd)
+
+	// The clipping path and line width are restored by Q.
+	require.Empty(t, paths[4].Clip)
+	require.InDelta(t, 2, paths[4].LineWidth, 1e-9)
+
+	// Form XObject line transformed by the form matrix and the CTM.
+	form := paths[5]
+	require.Equal(t, []draw.Point{draw.NewPoint(10, 100), draw.NewPoint(20, 100)},
+		form.Subpaths[0].Segments[0].Points)
+
+	// Form XObject line drawn with the graphics state of the caller.
+	form = paths[6]
+	require.Equal(t, []draw.Point{draw.NewPoint(0, 0), draw.NewPoint(20, 0)},
+		form.Subpaths[0].Segments[0].Points)
+	require.InDelta(t, 10, form.LineWidth, 1e-9)
+	require.Equal(t, 1, form.LineCap)
+	require.Equal(t, []float64{4, 2}, form.DashArray)
+	rgb, err = form.StrokeColorspace.ColorToRGB(form.StrokeColor)
+	require.NoError(t, err)
+	require.Equal(t, []float64{1, 0, 0}, rgbValues(t, rgb))
+}
+
+func TestGraphicsExtractionFormRecursion(t *testing.T) {
+	// A form that draws itself with the resources of the page.
+	resources := model.NewPdfPageResources()
+	xform := model.NewXObjectForm()
+	require.NoError(t, xform.SetContentStream([]byte("0 0 m 10 0 l S /Fm1 Do"), nil))
+	require.NoError(t, resources.SetXObjectFormByName("Fm1", xform))
+
+	e := Extractor{resources: resources, contents: "/Fm1 Do"}
+	pageGraphics, err := e.ExtractPageGraphics()
+	require.NoError(t, err)
+	require.Len(t, pageGraphics.Paths, 1)
+
+	// A chain of nested forms deeper than the limit.
+	resources = model.NewPdfPageResources()
+	for i := 0; i < maxFormDepth+5; i++ {
+		xform := model.NewXObjectForm()
+		content := fmt.Sprintf("0 0 m 10 0 l S /Fm%d Do", i+1)
+		require.NoError(t, xform.SetContentStream([]byte(content), nil))
+		require.NoError(t, resources.SetXObjectFormByName(core.PdfObjectName(fmt.Sprintf("Fm%d", i)), xform))
+	}
+	e = Extractor{resources: resources, contents: "/Fm0 Do"}
+	pageGraphics, err = e.ExtractPageGraphics()
+	require.NoError(t, err)
+	require.Len(t, pageGraphics.Paths, maxFormDepth)
+}
+
+// rgbValues returns the components of RGB color `c`.
+func rgbValues(t *testing.T, c model.PdfColor) []float64 {
+	rgb, ok := c.(*model.PdfColorDeviceRGB)
+	require.True(t, ok)
+	return []float64{rgb.R(), rgb.G(), rgb.B()}
+}
@@ -0,0 +1,701 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"sort"
+
+	"github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/contentstream"
+	"github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/internal/transform"
+	"github.com/unidoc/unidoc/pdf/model"
+)
+
+// TableExtractOptions contains options for controlling table extraction from PDF pages.
+type TableExtractOptions struct {
+	// Tolerance is the distance in device units within which ruling lines are considered to be
+	// aligned or touching. The default is 2.
+	Tolerance float64
+}
+
+// ExtractPageTables returns the tables on the page of the extractor.
+// Tables are located by their ruling lines, the stroked lines and thin filled rectangles drawn with
+// the path operators. The rows and columns of a table are given by its ruling lines. Where there
+// are no ruling lines between the rows or columns, as in tables that only have horizontal rules,
+// they are found from the alignment of the text inside the table.
+// The options parameter can be nil for the default options.
+func (e *Extractor) ExtractPageTables(options *TableExtractOptions) (*PageTables, error) {
+	if options == nil {
+		options = &TableExtractOptions{}
+	}
+	tol := options.Tolerance
+	if tol <= 0 {
+		tol = defaultTableTolerance
+	}
+
+	rulings, err := extractRulings(e.contents, e.resources)
+	if err != nil {
+		return nil, err
+	}
+	pageText, _, _, err := e.ExtractPageText()
+	if err != nil {
+		return nil, err
+	}
+	words := pageText.Words()
+
+	tables := &PageTables{}
+	for _, region := range findTableRegions(rulings, tol) {
+		if table := region.toTable(words, tol); table != nil {
+			tables.Tables = append(tables.Tables, *table)
+		}
+	}
+	return tables, nil
+}
+
+// PageTables represents the tables on a PDF page.
+type PageTables struct {
+	Tables []Table
+}
+
+// Table represents a table on a page. All coordinates are in device coordinates.
+type Table struct {
+	BBox model.PdfRectangle `json:"bbox"`
+	Rows int                `json:"rows"`
+	Cols int                `json:"cols"`
+
+	// RowEdges are the y coordinates of the row boundaries from top to bottom and ColEdges the x
+	// coordinates of the column boundaries from left to right.
+	RowEdges []float64 `json:"rowEdges"`
+	ColEdges []float64 `json:"colEdges"`
+
+	// Cells are the cells of the table ordered by row then column.
+	Cells []TableCell `json:"cells"`
+}
+
+// TableCell represents a cell of a table. A cell spans the rows from Row to Row+RowSpan-1 and the
+// columns from Col to Col+ColSpan-1.
+type TableCell struct {
+	Row     int                `json:"row"`
+	Col     int                `json:"col"`
+	RowSpan int                `json:"rowSpan"`
+	ColSpan int                `json:"colSpan"`
+	BBox    model.PdfRectangle `json:"bbox"`
+
+	// Text is the text of the cell. Words on the same line are separated by spaces and lines by
+	// newlines.
+	Text string `json:"text"`
+}
+
+// Grid returns the text of the cells of `t` as a slice of rows. The text of a cell spanning several
+// rows or columns is in its top left position. The other positions of the cell are empty.
+func (t Table) Grid() [][]string {
+	grid := make([][]string, t.Rows)
+	for i := range grid {
+		grid[i] = make([]string, t.Cols)
+	}
+	for _, cell := range t.Cells {
+		grid[cell.Row][cell.Col] = cell.Text
+	}
+	return grid
+}
+
+// CSV returns the text of the cells of `t` as a string in CSV format. See Grid.
+func (t Table) CSV() (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(t.Grid()); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// JSON returns `t` as a string in JSON format.
+func (t Table) JSON() (string, error) {
+	data, err := json.MarshalIndent(t, "", "    ")
+	return string(data), err
+}
+
+const (
+	// defaultTableTolerance is the default TableExtractOptions.Tolerance.
+	defaultTableTolerance = 2.0
+	// maxRulingWidth is the maximum width of a filled rectangle that is taken as a ruling line.
+	maxRulingWidth = 3.0
+	// minColumnGap is the minimum gap between the text of columns that are found from the text
+	// alignment, as a fraction of the average font size.
+	minColumnGap = 1.0
+)
+
+// ruling represents a horizontal or vertical ruling line. `pos` is the y coordinate of horizontal
+// lines and the x coordinate of vertical lines. The line extends from `start` to `end` in the
+// other coordinate.
+type ruling struct {
+	horizontal bool
+	pos        float64
+	start, end float64
+}
+
+// covers returns true if `r` extends over `x` within tolerance `tol`.
+func (r ruling) covers(x, tol float64) bool {
+	return r.start-tol <= x && x <= r.end+tol
+}
+
+// crosses returns true if rulings `r` and `s` have different directions and touch or cross each other.
+func (r ruling) crosses(s ruling, tol float64) bool {
+	return r.horizontal != s.horizontal && r.covers(s.pos, tol) && s.covers(r.pos, tol)
+}
+
+// extractRulings returns the ruling lines painted in content stream `contents`, including those
+// painted by the form XObjects it draws.
+func extractRulings(contents string, resources *model.PdfPageResources) ([]ruling, error) {
+	ctx := &rulingExtractContext{forms: map[core.PdfObject]bool{}}
+	if err := ctx.extractContentStreamRulings(contents, resources, nil, 0); err != nil {
+		return nil, err
+	}
+	return ctx.rulings, nil
+}
+
+// rulingExtractContext provides the context for extracting the ruling lines of content streams.
+type rulingExtractContext struct {
+	rulings []ruling
+	// forms are the streams of the form XObjects being processed, which must not be drawn again
+	// from their own content.
+	forms map[core.PdfObject]bool
+}
+
+// extractContentStreamRulings adds the ruling lines painted by content stream `contents` to `ctx`.
+// The points are mapped to device space by the CTM and then by `transforms`, the matrices of the
+// enclosing forms and their CTMs. `level` is the nesting depth of the forms.
+func (ctx *rulingExtractContext) extractContentStreamRulings(contents string,
+	resources *model.PdfPageResources, transforms []transform.Matrix, level int) error {
+	cstreamParser := contentstream.NewContentStreamParser(contents)
+	operations, err := cstreamParser.Parse()
+	if err != nil {
+		return err
+	}
+
+	var subpaths [][]transform.Point // The current path in device coordinates.
+	toDevice := func(gs contentstream.GraphicsState, x, y float64) transform.Point {
+		x, y = gs.Transform(x, y)
+		for _, m := range transforms {
+			x, y = m.Transform(x, y)
+		}
+		return transform.Point{X: x, Y: y}
+	}
+
+	processor := contentstream.NewContentStreamProcessor(*operations)
+	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
+			resources *model.PdfPageResources) error {
+			switch op.Operand {
+			case "m", "l": // Move to, line to.
+				floats, err := core.GetNumbersAsFloat(op.Params)
+				if err != nil || len(floats) != 2 {
+					common.Log.Debug("ERROR: %s invalid params %v", op.Operand, op.Params)
+					return errTypeCheck
+				}
+				p := toDevice(gs, floats[0], floats[1])
+				if op.Operand == "m" || len(subpaths) == 0 {
+					subpaths = append(subpaths, []transform.Point{p})
+				} else {
+					subpaths[len(subpaths)-1] = append(subpaths[len(subpaths)-1], p)
+				}
+			case "c", "v", "y": // Curves are not rulings. They end the line segments before them.
+				floats, err := core.GetNumbersAsFloat(op.Params)
+				if err != nil || len(floats) < 2 {
+					common.Log.Debug("ERROR: %s invalid params %v", op.Operand, op.Params)
+					return errTypeCheck
+				}
+				p := toDevice(gs, floats[len(floats)-2], floats[len(floats)-1])
+				subpaths = append(subpaths, []transform.Point{p})
+			case "h": // Close subpath.
+				if len(subpaths) > 0 {
+					sp := subpaths[len(subpaths)-1]
+					subpaths[len(subpaths)-1] = append(sp, sp[0])
+				}
+			case "re": // Rectangle.
+				floats, err := core.GetNumbersAsFloat(op.Params)
+				if err != nil || len(floats) != 4 {
+					common.Log.Debug("ERROR: re invalid params %v", op.Params)
+					return errTypeCheck
+				}
+				x, y, w, h := floats[0], floats[1], floats[2], floats[3]
+				var rect []transform.Point
+				for _, p := range [][2]float64{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}, {x, y}} {
+					rect = append(rect, toDevice(gs, p[0], p[1]))
+				}
+				subpaths = append(subpaths, rect)
+			case "S", "s", "B", "B*", "b", "b*": // Stroke.
+				if op.Operand == "s" || op.Operand == "b" || op.Operand == "b*" {
+					if len(subpaths) > 0 {
+						sp := subpaths[len(subpaths)-1]
+						subpaths[len(subpaths)-1] = append(sp, sp[0])
+					}
+				}
+				for _, sp := range subpaths {
+					ctx.rulings = append(ctx.rulings, pathRulings(sp)...)
+				}
+				subpaths = nil
+			case "f", "F", "f*": // Fill.
+				for _, sp := range subpaths {
+					ctx.rulings = append(ctx.rulings, rectRulings(sp)...)
+				}
+				subpaths = nil
+			case "n": // End path without painting. Clipping paths end here.
+				subpaths = nil
+			case "Do": // Recurse into forms.
+				if len(op.Params) != 1 {
+					return errTypeCheck
+				}
+				name, ok := core.GetName(op.Params[0])
+				if !ok {
+					return errTypeCheck
+				}
+				if _, xtype := resources.GetXObjectByName(*name); xtype != model.XObjectTypeForm {
+					break
+				}
+				if level >= maxFormDepth {
+					common.Log.Debug("ERROR: Form XObjects nested too deeply, skipping %s", *name)
+					break
+				}
+				xform, err := resources.GetXObjectFormByName(*name)
+				if err != nil || xform == nil {
+					return err
+				}
+				formObj := xform.GetContainingPdfObject()
+				if ctx.forms[formObj] {
+					common.Log.Debug("ERROR: Form XObject %s draws itself, skipping", *name)
+					break
+				}
+				formContent, err := xform.GetContentStream()
+				if err != nil {
+					return err
+				}
+				formResources := xform.Resources
+				if formResources == nil {
+					formResources = resources
+				}
+				ctx.forms[formObj] = true
+				defer delete(ctx.forms, formObj)
+				return ctx.extractContentStreamRulings(string(formContent), formResources,
+					formTransforms(xform, gs.CTM, transforms), level+1)
+			}
+			return nil
+		})
+
+	return processor.Process(resources)
+}
+
+// pathRulings returns the horizontal and vertical line segments of stroked subpath `points`.
+func pathRulings(points []transform.Point) []ruling {
+	var rulings []ruling
+	for i := 1; i < len(points); i++ {
+		p0, p1 := points[i-1], points[i]
+		dx, dy := math.Abs(p1.X-p0.X), math.Abs(p1.Y-p0.Y)
+		switch {
+		case dy < 0.1 && dx > 0:
+			rulings = append(rulings, ruling{true, p0.Y,
+				minFloat(p0.X, p1.X), maxFloat(p0.X, p1.X)})
+		case dx < 0.1 && dy > 0:
+			rulings = append(rulings, ruling{false, p0.X,
+				minFloat(p0.Y, p1.Y), maxFloat(p0.Y, p1.Y)})
+		}
+	}
+	return rulings
+}
+
+// rectRulings returns the rulings of filled subpath `points` if it is a rectangle. A thin rectangle
+// is a ruling line along its center and other rectangles, such as cell backgrounds, are bounded by
+// rulings on each edge. Other shapes, including rotated rectangles, have no rulings.
+func rectRulings(points []transform.Point) []ruling {
+	edges := pathRulings(points)
+	if len(edges) != 4 {
+		return nil
+	}
+	bbox := model.PdfRectangle{
+		Llx: math.Inf(1), Lly: math.Inf(1),
+		Urx: math.Inf(-1), Ury: math.Inf(-1),
+	}
+	for _, p := range points {
+		bbox.Llx, bbox.Lly = minFloat(bbox.Llx, p.X), minFloat(bbox.Lly, p.Y)
+		bbox.Urx, bbox.Ury = maxFloat(bbox.Urx, p.X), maxFloat(bbox.Ury, p.Y)
+	}
+	switch {
+	case bbox.Height() <= maxRulingWidth:
+		return []ruling{{true, (bbox.Lly + bbox.Ury) / 2, bbox.Llx, bbox.Urx}}
+	case bbox.Width() <= maxRulingWidth:
+		return []ruling{{false, (bbox.Llx + bbox.Urx) / 2, bbox.Lly, bbox.Ury}}
+	}
+	return edges
+}
+
+// tableRegion represents the rulings of a table.
+type tableRegion struct {
+	horizontal []ruling
+	vertical   []ruling
+	bbox       model.PdfRectangle
+}
+
+// findTableRegions returns the regions of `rulings` that may be tables: groups of horizontal and
+// vertical rulings that cross each other, and stacks of at least 2 horizontal rulings of the same
+// extent that don't cross any vertical rulings.
+func findTableRegions(rulings []ruling, tol float64) []*tableRegion {
+	rulings = mergeRulings(rulings, tol)
+
+	// Group the crossing rulings with a union-find.
+	parent := make([]int, len(rulings))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range rulings {
+		for j := i + 1; j < len(rulings); j++ {
+			if rulings[i].crosses(rulings[j], tol) {
+				parent[find(i)] = find(j)
+			}
+		}
+	}
+	groups := map[int][]ruling{}
+	var roots []int
+	for i, r := range rulings {
+		root := find(i)
+		if _, ok := groups[root]; !ok {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], r)
+	}
+
+	var regions []*tableRegion
+	var lone []ruling
+	for _, root := range roots {
+		group := groups[root]
+		if len(group) == 1 {
+			if group[0].horizontal {
+				lone = append(lone, group[0])
+			}
+			continue
+		}
+		regions = append(regions, newTableRegion(group))
+	}
+
+	// Stack the horizontal rules that have the same extent.
+	used := make([]bool, len(lone))
+	for i, r := range lone {
+		if used[i] {
+			continue
+		}
+		stack := []ruling{r}
+		for j := i + 1; j < len(lone); j++ {
+			s := lone[j]
+			if !used[j] && math.Abs(r.start-s.start) <= tol && math.Abs(r.end-s.end) <= tol {
+				stack = append(stack, s)
+				used[j] = true
+			}
+		}
+		if len(stack) >= 2 {
+			regions = append(regions, newTableRegion(stack))
+		}
+	}
+
+	sort.SliceStable(regions, func(i, j int) bool {
+		return regions[i].bbox.Ury > regions[j].bbox.Ury
+	})
+	return regions
+}
+
+// mergeRulings returns `rulings` with the rulings that lie on the same line and overlap or touch
+// within `tol` merged.
+func mergeRulings(rulings []ruling, tol float64) []ruling {
+	sorted := append([]ruling(nil), rulings...)
+	sort.Slice(sorted, func(i, j int) bool {
+		ri, rj := sorted[i], sorted[j]
+		if ri.horizontal != rj.horizontal {
+			return ri.horizontal
+		}
+		if ri.pos != rj.pos {
+			return ri.pos < rj.pos
+		}
+		return ri.start < rj.start
+	})
+
+	var merged []ruling
+	for _, r := range sorted {
+		joined := false
+		for i := range merged {
+			m := &merged[i]
+			if m.horizontal == r.horizontal && math.Abs(m.pos-r.pos) <= tol &&
+				r.start <= m.end+tol && m.start <= r.end+tol {
+				m.start, m.end = minFloat(m.start, r.start), maxFloat(m.end, r.end)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			merged = append(merged, r)
+		}
+	}
+	return merged
+}
+
+// newTableRegion returns the tableRegion of `rulings`.
+func newTableRegion(rulings []ruling) *tableRegion {
+	region := &tableRegion{bbox: model.PdfRectangle{
+		Llx: math.Inf(1), Lly: math.Inf(1),
+		Urx: math.Inf(-1), Ury: math.Inf(-1),
+	}}
+	for _, r := range rulings {
+		var llx, lly, urx, ury float64
+		if r.horizontal {
+			region.horizontal = append(region.horizontal, r)
+			llx, lly, urx, ury = r.start, r.pos, r.end, r.pos
+		} else {
+			region.vertical = append(region.vertical, r)
+			llx, lly, urx, ury = r.pos, r.start, r.pos, r.end
+		}
+		region.bbox.Llx, region.bbox.Lly = minFloat(region.bbox.Llx, llx), minFloat(region.bbox.Lly, lly)
+		region.bbox.Urx, region.bbox.Ury = maxFloat(region.bbox.Urx, urx), maxFloat(region.bbox.Ury, ury)
+	}
+	return region
+}
+
+// toTable returns the table in `region` with the text of `words`, or nil if the region is not a
+// table, i.e. it has less than 2 cells or the text in it is not in columns.
+func (region *tableRegion) toTable(words []TextWord, tol float64) *Table {
+	bbox := region.bbox
+	var inside []TextWord
+	for _, w := range words {
+		x, y := (w.BBox.Llx+w.BBox.Urx)/2, (w.BBox.Lly+w.BBox.Ury)/2
+		if bbox.Llx < x && x < bbox.Urx && bbox.Lly < y && y < bbox.Ury {
+			inside = append(inside, w)
+		}
+	}
+
+	// The columns are given by the vertical rulings if there is more than one column between them.
+	// Otherwise they are found from the gaps between the text. The rows are given by the horizontal
+	// rulings and are split at the text lines if either the rows or columns are not ruled.
+	colEdges := rulingPositions(region.vertical, tol)
+	colsRuled := len(colEdges) >= 3
+	if !colsRuled {
+		colEdges = textColumnEdges(inside, bbox)
+	}
+	rowEdges := rulingPositions(region.horizontal, tol)
+	for i, j := 0, len(rowEdges)-1; i < j; i, j = i+1, j-1 {
+		rowEdges[i], rowEdges[j] = rowEdges[j], rowEdges[i]
+	}
+	rowsRuled := len(rowEdges) >= 3
+	textRows := map[int]bool{}
+	if !colsRuled || !rowsRuled {
+		rowEdges = addTextRowEdges(rowEdges, inside, bbox, tol, textRows)
+	}
+	numRows, numCols := len(rowEdges)-1, len(colEdges)-1
+	if numRows < 1 || numCols < 1 || numRows*numCols < 2 || !colsRuled && numCols < 2 {
+		return nil
+	}
+
+	// Cells are separated by rulings or by the text rows and columns.
+	hBoundary := func(row, col int) bool {
+		if textRows[row] {
+			return true
+		}
+		x := (colEdges[col] + colEdges[col+1]) / 2
+		for _, r := range region.horizontal {
+			if math.Abs(r.pos-rowEdges[row]) <= tol && r.covers(x, 0) {
+				return true
+			}
+		}
+		return false
+	}
+	vBoundary := func(row, col int) bool {
+		if !colsRuled {
+			return true
+		}
+		y := (rowEdges[row] + rowEdges[row+1]) / 2
+		for _, r := range region.vertical {
+			if math.Abs(r.pos-colEdges[col]) <= tol && r.covers(y, 0) {
+				return true
+			}
+		}
+		return false
+	}
+
+	table := &Table{
+		BBox:     bbox,
+		Rows:     numRows,
+		Cols:     numCols,
+		RowEdges: rowEdges,
+		ColEdges: colEdges,
+	}
+	owner := make([][]int, numRows)
+	for i := range owner {
+		owner[i] = make([]int, numCols)
+		for j := range owner[i] {
+			owner[i][j] = -1
+		}
+	}
+	for row := 0; row < numRows; row++ {
+		for col := 0; col < numCols; col++ {
+			if owner[row][col] >= 0 {
+				continue
+			}
+			colSpan := 1
+			for col+colSpan < numCols && owner[row][col+colSpan] < 0 && !vBoundary(row, col+colSpan) {
+				colSpan++
+			}
+			rowSpan := 1
+			for row+rowSpan < numRows {
+				open := true
+				for c := col; c < col+colSpan; c++ {
+					if owner[row+rowSpan][c] >= 0 || hBoundary(row+rowSpan, c) {
+						open = false
+						break
+					}
+				}
+				if !open {
+					break
+				}
+				rowSpan++
+			}
+
+			for r := row; r < row+rowSpan; r++ {
+				for c := col; c < col+colSpan; c++ {
+					owner[r][c] = len(table.Cells)
+				}
+			}
+			table.Cells = append(table.Cells, TableCell{
+				Row:     row,
+				Col:     col,
+				RowSpan: rowSpan,
+				ColSpan: colSpan,
+				BBox: model.PdfRectangle{
+					Llx: colEdges[col],
+					Lly: rowEdges[row+rowSpan],
+					Urx: colEdges[col+colSpan],
+					Ury: rowEdges[row],
+				},
+			})
+		}
+	}
+
+	// Add the words to the cells they are centered in. The words are in line order.
+	lastWord := make([]TextWord, len(table.Cells))
+	for _, w := range inside {
+		x, y := (w.BBox.Llx+w.BBox.Urx)/2, (w.BBox.Lly+w.BBox.Ury)/2
+		row := sort.Search(numRows, func(i int) bool { return y >= rowEdges[i+1] })
+		col := sort.Search(numCols, func(i int) bool { return x <= colEdges[i+1] })
+		if row >= numRows || col >= numCols {
+			continue
+		}
+		idx := owner[row][col]
+		cell := &table.Cells[idx]
+		if cell.Text != "" {
+			last := lastWord[idx].BBox
+			if w.BBox.Ury < (last.Lly+last.Ury)/2 {
+				cell.Text += "\n"
+			} else {
+				cell.Text += " "
+			}
+		}
+		cell.Text += w.Text
+		lastWord[idx] = w
+	}
+	return table
+}
+
+// rulingPositions returns the sorted positions of `rulings` with the positions within `tol` of
+// each other merged.
+func rulingPositions(rulings []ruling, tol float64) []float64 {
+	var positions []float64
+	for _, r := range rulings {
+		positions = append(positions, r.pos)
+	}
+	sort.Float64s(positions)
+	var merged []float64
+	for _, p := range positions {
+		if len(merged) > 0 && p-merged[len(merged)-1] <= tol {
+			continue
+		}
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// textColumnEdges returns the column boundaries in `bbox` of the text in `words`. The columns are
+// separated by vertical gaps between the words that are at least minColumnGap times the average
+// font size wide.
+func textColumnEdges(words []TextWord, bbox model.PdfRectangle) []float64 {
+	edges := []float64{bbox.Llx}
+	if len(words) == 0 {
+		return append(edges, bbox.Urx)
+	}
+
+	sorted := append([]TextWord(nil), words...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].BBox.Llx < sorted[j].BBox.Llx })
+	fontSize := 0.0
+	for _, w := range sorted {
+		fontSize += w.Marks[0].FontSize
+	}
+	minGap := minColumnGap * fontSize / float64(len(sorted))
+
+	right := sorted[0].BBox.Urx
+	for _, w := range sorted[1:] {
+		if w.BBox.Llx-right >= minGap {
+			edges = append(edges, (right+w.BBox.Llx)/2)
+		}
+		right = maxFloat(right, w.BBox.Urx)
+	}
+	return append(edges, bbox.Urx)
+}
+
+// addTextRowEdges returns the row boundaries `rowEdges` (from top to bottom) of `bbox` with
+// boundaries added between the lines of text in `words`. The indexes of the added boundaries are
+// set in `textRows`.
+func addTextRowEdges(rowEdges []float64, words []TextWord, bbox model.PdfRectangle, tol float64,
+	textRows map[int]bool) []float64 {
+	if len(rowEdges) < 2 {
+		rowEdges = []float64{bbox.Ury, bbox.Lly}
+	}
+
+	// Group the words into lines with overlapping vertical extents.
+	sorted := append([]TextWord(nil), words...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].BBox.Ury > sorted[j].BBox.Ury })
+	var separators []float64
+	for i := 0; i < len(sorted); {
+		mid := (sorted[i].BBox.Lly + sorted[i].BBox.Ury) / 2
+		bottom := sorted[i].BBox.Lly
+		j := i + 1
+		for ; j < len(sorted) && sorted[j].BBox.Ury > mid; j++ {
+			bottom = minFloat(bottom, sorted[j].BBox.Lly)
+		}
+		if j < len(sorted) {
+			separators = append(separators, (bottom+sorted[j].BBox.Ury)/2)
+		}
+		i = j
+	}
+
+	var edges []float64
+	for i, y := range rowEdges {
+		if i > 0 {
+			for _, s := range separators {
+				if s < rowEdges[i-1]-tol && s > y+tol {
+					textRows[len(edges)] = true
+					edges = append(edges, s)
+				}
+			}
+		}
+		edges = append(edges, y)
+	}
+	return edges
+}
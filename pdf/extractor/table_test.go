@@ -0,0 +1,139 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/model"
+)
+
+func TestTableExtraction(t *testing.T) {
+	resources := model.NewPdfPageResources()
+	courier := model.NewStandard14FontMustCompile(model.CourierName)
+	resources.SetFontByName("UniDocCourier", courier.ToPdfObject())
+
+	e := Extractor{resources: resources, contents: `
+		0.5 w
+		100 700 m 400 700 l S
+		100 680 m 400 680 l S
+		100 660 m 400 660 l S
+		100 640 m 400 640 l S
+		100 640 m 100 700 l S
+		200 640 m 200 700 l S
+		300 640 m 300 680 l S
+		400 640 m 400 700 l S
+		BT
+		/UniDocCourier 10 Tf
+		105 686 Td (Name) Tj
+		100 0 Td (Amount) Tj
+		-100 -20 Td (Rent) Tj
+		100 0 Td (100) Tj
+		100 0 Td (EUR) Tj
+		-200 -20 Td (Food) Tj
+		100 0 Td (20) Tj
+		100 0 Td (USD) Tj
+		ET
+
+		100 600 200 0.5 re f
+		100 580 200 0.5 re f
+		100 540 200 0.5 re f
+		BT
+		/UniDocCourier 10 Tf
+		105 586 Td (Item) Tj
+		100 0 Td (Qty) Tj
+		-100 -20 Td (Apples) Tj
+		100 0 Td (3) Tj
+		-100 -20 Td (Pears, ripe) Tj
+		100 0 Td (12) Tj
+		ET
+
+		100 400 200 50 re S
+		BT
+		/UniDocCourier 10 Tf
+		105 420 Td (Just a note) Tj
+		ET
+		`}
+	pageTables, err := e.ExtractPageTables(nil)
+	require.NoError(t, err)
+	require.Len(t, pageTables.Tables, 2)
+
+	// Ruled table with a header cell spanning two columns.
+	table := pageTables.Tables[0]
+	require.Equal(t, 3, table.Rows)
+	require.Equal(t, 3, table.Cols)
+	require.Equal(t, []float64{700, 680, 660, 640}, table.RowEdges)
+	require.Equal(t, []float64{100, 200, 300, 400}, table.ColEdges)
+	require.Len(t, table.Cells, 8)
+	amount := table.Cells[1]
+	require.Equal(t, TableCell{
+		Row: 0, Col: 1, RowSpan: 1, ColSpan: 2,
+		BBox: model.PdfRectangle{Llx: 200, Lly: 680, Urx: 400, Ury: 700},
+		Text: "Amount",
+	}, amount)
+	require.Equal(t, [][]string{
+		{"Name", "Amount", ""},
+		{"Rent", "100", "EUR"},
+		{"Food", "20", "USD"},
+	}, table.Grid())
+
+	// Table with horizontal rules only. The columns and rows are found from the text.
+	table = pageTables.Tables[1]
+	require.Equal(t, [][]string{
+		{"Item", "Qty"},
+		{"Apples", "3"},
+		{"Pears, ripe", "12"},
+	}, table.Grid())
+	csv, err := table.CSV()
+	require.NoError(t, err)
+	require.Equal(t, "Item,Qty\nApples,3\n\"Pears, ripe\",12\n", csv)
+	json, err := table.JSON()
+	require.NoError(t, err)
+	require.Contains(t, json, `"rowSpan": 1`)
+	require.Contains(t, json, `"text": "Apples"`)
+}
+
+func TestTableExtractionFormRulings(t *testing.T) {
+	resources := model.NewPdfPageResources()
+	courier := model.NewStandard14FontMustCompile(model.CourierName)
+	resources.SetFontByName("UniDocCourier", courier.ToPdfObject())
+
+	// Ruling lines drawn by a form XObject, transformed by the form matrix and the CTM.
+	xform := model.NewXObjectForm()
+	xform.Matrix = core.MakeArrayFromFloats([]float64{1, 0, 0, 1, 0, 40})
+	require.NoError(t, xform.SetContentStream([]byte(`
+		0 0 m 200 0 l S
+		0 20 m 200 20 l S
+		0 40 m 200 40 l S
+		0 0 m 0 40 l S
+		100 0 m 100 40 l S
+		200 0 m 200 40 l S`), nil))
+	require.NoError(t, resources.SetXObjectFormByName("Fm0", xform))
+
+	e := Extractor{resources: resources, contents: `
+		q 1 0 0 1 100 620 cm /Fm0 Do Q
+		BT
+		/UniDocCourier 10 Tf
+		105 686 Td (Name) Tj
+		100 0 Td (Amount) Tj
+		-100 -20 Td (Rent) Tj
+		100 0 Td (100) Tj
+		ET
+		`}
+	pageTables, err := e.ExtractPageTables(nil)
+	require.NoError(t, err)
+	require.Len(t, pageTables.Tables, 1)
+	table := pageTables.Tables[0]
+	require.Equal(t, []float64{700, 680, 660}, table.RowEdges)
+	require.Equal(t, []float64{100, 200, 300}, table.ColEdges)
+	require.Equal(t, [][]string{
+		{"Name", "Amount"},
+		{"Rent", "100"},
+	}, table.Grid())
+}
@@ -40,21 +40,78 @@ func (e *Extractor) ExtractTextWithStats() (extracted string, numChars int, numM
 	return pageText.ToText(), numChars, numMisses, nil
 }
 
+// TextExtractOptions contains options for controlling text extraction from PDF pages.
+type TextExtractOptions struct {
+	// Layout is the order of the text returned by PageText.ToText and PageText.Lines.
+	// The default is TextLayoutLines.
+	Layout TextLayout
+
+	// SkipArtifacts skips the text in /Artifact marked-content sequences, such as running headers
+	// and footers.
+	SkipArtifacts bool
+
+	// ExpandAbbreviations replaces the text in marked-content sequences with an /E property by the
+	// expansion. The text in sequences with an /ActualText property is always replaced.
+	ExpandAbbreviations bool
+}
+
+// TextLayout specifies the order in which the text of a page is returned.
+type TextLayout int
+
+// Text layout modes.
+const (
+	// TextLayoutLines orders the text by lines from the top to the bottom of the page. Lines at the
+	// same height in different columns are joined.
+	TextLayoutLines TextLayout = iota
+
+	// TextLayoutBlocks splits the page into blocks of text, such as columns and paragraphs, and
+	// orders the blocks in reading order. See PageText.Blocks.
+	TextLayoutBlocks
+)
+
 // ExtractPageText returns the text contents of `e` (an Extractor for a page) as a PageText.
 func (e *Extractor) ExtractPageText() (*PageText, int, int, error) {
-	return e.extractPageText(e.contents, e.resources, 0)
+	return e.ExtractPageTextWithOptions(nil)
+}
+
+// ExtractPageTextWithOptions returns the text contents of `e` (an Extractor for a page) as a
+// PageText. A set of options to control the text extraction can be passed in. The options
+// parameter can be nil for the default options.
+func (e *Extractor) ExtractPageTextWithOptions(options *TextExtractOptions) (*PageText, int, int, error) {
+	if options == nil {
+		options = &TextExtractOptions{}
+	}
+	// The text of the forms depends on the options.
+	e.formResults = map[string]textResult{}
+	pageText, numChars, numMisses, err := e.extractPageText(e.contents, e.resources, options, 0)
+	if err != nil {
+		return pageText, numChars, numMisses, err
+	}
+	pageText.layout = options.Layout
+	return pageText, numChars, numMisses, nil
 }
 
 // extractPageText returns the text contents of content stream `e` and resouces `resources` as a
 // PageText.
 // This can be called on a page or a form XObject.
-func (e *Extractor) extractPageText(contents string, resources *model.PdfPageResources, level int) (*PageText, int, int, error) {
+func (e *Extractor) extractPageText(contents string, resources *model.PdfPageResources,
+	options *TextExtractOptions, level int) (*PageText, int, int, error) {
 
 	common.Log.Trace("extractPageText: level=%d", level)
 	pageText := &PageText{}
 	state := newTextState()
 	fontStack := fontStacker{}
 	var to *textObject
+	var mcStack []markedContent
+
+	// flushMarks moves the marks of the current text object to `pageText` so that the marks of
+	// marked-content sequences can be processed.
+	flushMarks := func() {
+		if to != nil {
+			pageText.marks = append(pageText.marks, to.marks...)
+			to.marks = nil
+		}
+	}
 
 	cstreamParser := contentstream.NewContentStreamParser(contents)
 	operations, err := cstreamParser.Parse()
@@ -70,6 +127,10 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 			resources *model.PdfPageResources) error {
 
 			operand := op.Operand
+			if to != nil {
+				// The colors may be changed inside a text object.
+				to.gs = gs
+			}
 
 			switch operand {
 			case "q":
@@ -298,7 +359,7 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 						formResources = resources
 					}
 					tList, numChars, numMisses, err := e.extractPageText(string(formContent),
-						formResources, level+1)
+						formResources, options, level+1)
 					if err != nil {
 						common.Log.Debug("ERROR: %v", err)
 						return err
@@ -310,6 +371,21 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				pageText.marks = append(pageText.marks, formResult.pageText.marks...)
 				state.numChars += formResult.numChars
 				state.numMisses += formResult.numMisses
+			case "BMC", "BDC": // Begin marked-content sequence.
+				flushMarks()
+				mc := newMarkedContent(op, resources)
+				mc.start = len(pageText.marks)
+				mcStack = append(mcStack, mc)
+			case "EMC": // End marked-content sequence.
+				if len(mcStack) == 0 {
+					common.Log.Debug("ERROR: EMC without marked-content sequence")
+					break
+				}
+				flushMarks()
+				mc := mcStack[len(mcStack)-1]
+				mcStack = mcStack[:len(mcStack)-1]
+				marks := mc.apply(pageText.marks[mc.start:], options)
+				pageText.marks = append(pageText.marks[:mc.start], marks...)
 			}
 			return nil
 		})
@@ -327,6 +403,89 @@ type textResult struct {
 	numMisses int
 }
 
+// markedContent represents a marked-content sequence (14.6 Marked Content) that is
+// being processed.
+type markedContent struct {
+	tag        string  // Tag of the sequence, e.g. Artifact or P.
+	mcid       int     // Marked-content identifier. -1 if the sequence has none.
+	actualText *string // Replacement text. nil if the sequence has no /ActualText.
+	expansion  *string // Expansion of an abbreviation. nil if the sequence has no /E.
+	alt        string  // Alternate description.
+	start      int     // Index of the first mark of the sequence in PageText.marks.
+}
+
+// newMarkedContent returns the markedContent of the "BMC" or "BDC" operation `op`. The property
+// list of "BDC" is either inline or a named resource in the /Properties of `resources`.
+func newMarkedContent(op *contentstream.ContentStreamOperation,
+	resources *model.PdfPageResources) markedContent {
+	mc := markedContent{mcid: -1}
+	if len(op.Params) > 0 {
+		mc.tag, _ = core.GetNameVal(op.Params[0])
+	}
+	if op.Operand != "BDC" || len(op.Params) < 2 {
+		return mc
+	}
+
+	props, ok := core.GetDict(op.Params[1])
+	if name, isName := core.GetName(op.Params[1]); isName && resources != nil {
+		if properties, hasProps := core.GetDict(resources.Properties); hasProps {
+			props, ok = core.GetDict(properties.Get(*name))
+		}
+	}
+	if !ok {
+		common.Log.Debug("ERROR: BDC has no property list. op=%s", op)
+		return mc
+	}
+
+	if mcid, ok := core.GetIntVal(props.Get("MCID")); ok {
+		mc.mcid = mcid
+	}
+	if str, ok := core.GetString(props.Get("ActualText")); ok {
+		text := str.Decoded()
+		mc.actualText = &text
+	}
+	if str, ok := core.GetString(props.Get("E")); ok {
+		text := str.Decoded()
+		mc.expansion = &text
+	}
+	if str, ok := core.GetString(props.Get("Alt")); ok {
+		mc.alt = str.Decoded()
+	}
+	return mc
+}
+
+// apply returns the text marks `marks` rendered in `mc` with the properties of `mc` applied.
+// The marks are dropped if `mc` is an artifact and artifacts are skipped. They are replaced by a
+// single mark if `mc` has replacement text. The MCID and alternate description of `mc` are set on
+// the marks that are not in an inner sequence with their own.
+func (mc markedContent) apply(marks []textMark, options *TextExtractOptions) []textMark {
+	if mc.tag == "Artifact" && options.SkipArtifacts {
+		return nil
+	}
+
+	replacement := mc.actualText
+	if replacement == nil && options.ExpandAbbreviations {
+		replacement = mc.expansion
+	}
+	if replacement != nil {
+		// A replacement without any text has no position, e.g. /ActualText for an image.
+		if len(marks) == 0 || *replacement == "" {
+			return nil
+		}
+		marks = []textMark{mergeMarks(marks, *replacement)}
+	}
+
+	for i := range marks {
+		if marks[i].mcid < 0 {
+			marks[i].mcid = mc.mcid
+		}
+		if marks[i].alt == "" {
+			marks[i].alt = mc.alt
+		}
+	}
+	return marks
+}
+
 //
 // Text operators
 //
@@ -449,12 +608,28 @@ func (to *textObject) setFont(name string, size float64) error {
 	return nil
 }
 
+// renderModes maps the "Tr" operands to text rendering modes.
+var renderModes = []RenderMode{
+	RenderModeFill,
+	RenderModeStroke,
+	RenderModeFill | RenderModeStroke,
+	0, // Invisible.
+	RenderModeFill | RenderModeClip,
+	RenderModeStroke | RenderModeClip,
+	RenderModeFill | RenderModeStroke | RenderModeClip,
+	RenderModeClip,
+}
+
 // setTextRenderMode "Tr". Set text rendering mode.
 func (to *textObject) setTextRenderMode(mode int) {
 	if to == nil {
 		return
 	}
-	to.state.tmode = RenderMode(mode)
+	if mode < 0 || mode >= len(renderModes) {
+		common.Log.Debug("ERROR: Invalid text rendering mode %d", mode)
+		return
+	}
+	to.state.tmode = renderModes[mode]
 }
 
 // setTextRise "Ts". Set text rise.
@@ -680,6 +855,7 @@ func (to *textObject) renderText(data []byte) error {
 	}
 	spaceWidth := spaceMetrics.Wx * glyphTextRatio
 	common.Log.Trace("spaceWidth=%.2f text=%q font=%s fontSize=%.1f", spaceWidth, runes, font, tfs)
+	descent, ascent := fontVerticalExtent(font)
 
 	stateMatrix := transform.NewMatrix(
 		tfs*th, 0,
@@ -735,7 +911,8 @@ func (to *textObject) renderText(data []byte) error {
 			string(r),
 			trm,
 			translation(to.gs.CTM.Mult(to.tm).Mult(td0)),
-			spaceWidth*trm.ScalingFactorX())
+			spaceWidth*trm.ScalingFactorX(),
+			glyphBBox(trm, c.X, descent, ascent))
 		common.Log.Trace("i=%d code=%d mark=%s trm=%s", i, code, mark, trm)
 		to.marks = append(to.marks, mark)
 
@@ -750,6 +927,45 @@ func (to *textObject) renderText(data []byte) error {
 // glyphTextRatio converts Glyph metrics units to unscaled text space units.
 const glyphTextRatio = 1.0 / 1000.0
 
+// fontVerticalExtent returns the descent and ascent of `font` in unscaled text space units. The
+// glyphs are assumed to extend from the baseline to the font size if `font` has no font descriptor
+// or the descriptor has no Descent or Ascent.
+func fontVerticalExtent(font *model.PdfFont) (descent, ascent float64) {
+	descent, ascent = 0.0, 1.0
+	desc, err := font.GetFontDescriptor()
+	if err != nil || desc == nil {
+		return descent, ascent
+	}
+	if d, err := desc.GetDescent(); err == nil {
+		descent = d * glyphTextRatio
+	}
+	if a, err := desc.GetAscent(); err == nil && a > 0 {
+		ascent = a * glyphTextRatio
+	}
+	return descent, ascent
+}
+
+// glyphBBox returns the bounding box in device coordinates of a glyph of width `width` that
+// extends from `descent` to `ascent` and is rendered with text rendering matrix `trm`.
+// `width`, `descent` and `ascent` are in unscaled text space units.
+func glyphBBox(trm transform.Matrix, width, descent, ascent float64) model.PdfRectangle {
+	bbox := model.PdfRectangle{
+		Llx: math.MaxFloat64,
+		Lly: math.MaxFloat64,
+		Urx: -math.MaxFloat64,
+		Ury: -math.MaxFloat64,
+	}
+	for _, p := range []transform.Point{{X: 0, Y: descent}, {X: width, Y: descent},
+		{X: 0, Y: ascent}, {X: width, Y: ascent}} {
+		x, y := trm.Transform(p.X, p.Y)
+		bbox.Llx = minFloat(bbox.Llx, x)
+		bbox.Lly = minFloat(bbox.Lly, y)
+		bbox.Urx = maxFloat(bbox.Urx, x)
+		bbox.Ury = maxFloat(bbox.Ury, y)
+	}
+	return bbox
+}
+
 // translation returns the translation part of `m`.
 func translation(m transform.Matrix) transform.Point {
 	tx, ty := m.Translation()
@@ -780,12 +996,22 @@ type textMark struct {
 	height        float64         // Text height.
 	spaceWidth    float64         // Best guess at the width of a space in the font the text was rendered with.
 	count         int64           // To help with reading debug logs.
+
+	bbox           model.PdfRectangle  // Bounding box of the glyph.
+	font           *model.PdfFont      // Font the text was rendered with.
+	fontSize       float64             // Font size. This is the font size scaled by the TRM.
+	fillColorspace model.PdfColorspace // Nonstroking colorspace.
+	fillColor      model.PdfColor      // Nonstroking color.
+	renderMode     RenderMode          // Text rendering mode.
+	mcid           int                 // MCID of the marked-content sequence. -1 if there is none.
+	alt            string              // Alternate description of the marked-content sequence.
 }
 
 // newTextMark returns an textMark for text `text` rendered with text rendering matrix (TRM) `trm` and end
 // of character device coordinates `end`. `spaceWidth` is our best guess at the width of a space in
-// the font the text is rendered in device coordinates.
-func (to *textObject) newTextMark(text string, trm transform.Matrix, end transform.Point, spaceWidth float64) textMark {
+// the font the text is rendered in device coordinates. `bbox` is the bounding box of the text.
+func (to *textObject) newTextMark(text string, trm transform.Matrix, end transform.Point,
+	spaceWidth float64, bbox model.PdfRectangle) textMark {
 	to.e.textCount++
 	theta := trm.Angle()
 	orient := nearestMultiple(theta, 10)
@@ -797,14 +1023,34 @@ func (to *textObject) newTextMark(text string, trm transform.Matrix, end transfo
 	}
 
 	return textMark{
-		text:          text,
-		orient:        orient,
-		orientedStart: translation(trm).Rotate(theta),
-		orientedEnd:   end.Rotate(theta),
-		height:        height,
-		spaceWidth:    spaceWidth,
-		count:         to.e.textCount,
+		text:           text,
+		orient:         orient,
+		orientedStart:  translation(trm).Rotate(theta),
+		orientedEnd:    end.Rotate(theta),
+		height:         height,
+		spaceWidth:     spaceWidth,
+		count:          to.e.textCount,
+		bbox:           bbox,
+		font:           to.getCurrentFont(),
+		fontSize:       height,
+		fillColorspace: to.gs.ColorspaceNonStroking,
+		fillColor:      to.gs.ColorNonStroking,
+		renderMode:     to.state.tmode,
+		mcid:           -1,
+	}
+}
+
+// mergeMarks returns a textMark with text `text` that covers the text marks `marks`. The other
+// properties are those of the first mark.
+func mergeMarks(marks []textMark, text string) textMark {
+	t := marks[0]
+	t.text = text
+	t.orientedEnd = marks[len(marks)-1].orientedEnd
+	for _, m := range marks[1:] {
+		t.bbox = rectUnion(t.bbox, m.bbox)
+		t.height = maxFloat(t.height, m.height)
 	}
+	return t
 }
 
 // nearestMultiple return the integer multiple of `m` that is closest to `x`.
@@ -831,7 +1077,8 @@ func (t textMark) Width() float64 {
 // It's implementation is opaque to allow for future optimizations.
 type PageText struct {
 	// PageText is currently implemented as a list of texts and their positions on a PDF page.
-	marks []textMark
+	marks  []textMark
+	layout TextLayout // Order of the text returned by ToText and Lines.
 }
 
 // String returns a string describing `pt`.
@@ -859,8 +1106,36 @@ func (pt PageText) height() float64 {
 	return fontHeight
 }
 
-// ToText returns the contents of `pt` as a single string.
+// ToText returns the contents of `pt` as a single string. The lines are separated by newlines.
+// With TextLayoutBlocks the blocks are separated by empty lines.
 func (pt PageText) ToText() string {
+	if pt.layout == TextLayoutBlocks {
+		var texts []string
+		for _, block := range pt.blocks() {
+			texts = append(texts, PageText{marks: block}.ToText())
+		}
+		return strings.Join(texts, "\n\n")
+	}
+
+	lines := pt.lines()
+	texts := make([]string, 0, len(lines))
+	for _, l := range lines {
+		texts = append(texts, l.text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// lines returns the text in `pt` as lines in the order of `pt.layout`. `pt.marks` are left in the
+// order they were rendered.
+func (pt PageText) lines() []textLine {
+	if pt.layout == TextLayoutBlocks {
+		var lines []textLine
+		for _, block := range pt.blocks() {
+			lines = append(lines, PageText{marks: block}.lines()...)
+		}
+		return lines
+	}
+
 	fontHeight := pt.height()
 	// We sort with a y tolerance to allow for subscripts, diacritics etc.
 	tol := minFloat(fontHeight*0.2, 5.0)
@@ -868,15 +1143,11 @@ func (pt PageText) ToText() string {
 
 	// Uncomment the 2 following Trace statements to see the effects of sorting/
 	// common.Log.Trace("ToText: Before sorting %s", pt)
+	pt.marks = append([]textMark(nil), pt.marks...)
 	pt.sortPosition(tol)
 	// common.Log.Trace("ToText: After sorting %s", pt)
 
-	lines := pt.toLines(tol)
-	texts := make([]string, 0, len(lines))
-	for _, l := range lines {
-		texts = append(texts, l.text)
-	}
-	return strings.Join(texts, "\n")
+	return pt.toLines(tol)
 }
 
 // sortPosition sorts a text list by its elements' position on a page.
@@ -897,10 +1168,11 @@ func (pt *PageText) sortPosition(tol float64) {
 
 // textLine represents a line of text on a page.
 type textLine struct {
-	y      float64   // y position of line.
-	dxList []float64 // x distance between successive words in line.
-	text   string    // text in the line.
-	words  []string  // words in the line.
+	y      float64    // y position of line.
+	dxList []float64  // x distance between successive words in line.
+	text   string     // text in the line.
+	words  []string   // words in the line.
+	marks  []textMark // marks of the words in the line. Detected spaces have empty bounding boxes.
 }
 
 // toLines returns the text and positions in `pt.marks` as a slice of textLine.
@@ -915,7 +1187,7 @@ func (pt PageText) toLines(tol float64) []textLine {
 	}
 	var lines []textLine
 	for _, o := range orientKeys(tlOrient) {
-		lines = append(lines, PageText{tlOrient[o]}.toLinesOrient(tol)...)
+		lines = append(lines, PageText{marks: tlOrient[o]}.toLinesOrient(tol)...)
 	}
 	return lines
 }
@@ -931,6 +1203,7 @@ func (pt PageText) toLinesOrient(tol float64) []textLine {
 	}
 	var lines []textLine
 	var words []string
+	var marks []textMark
 	var x []float64
 	y := pt.marks[0].orientedStart.Y
 
@@ -943,7 +1216,7 @@ func (pt PageText) toLinesOrient(tol float64) []textLine {
 	for _, t := range pt.marks {
 		if t.orientedStart.Y+tol < y {
 			if len(words) > 0 {
-				line := newLine(y, x, words)
+				line := newLine(y, x, words, marks)
 				if averageCharWidth.running {
 					// FIXME(peterwilliams97): Fix and reinstate combineDiacritics.
 					// line = combineDiacritics(line, averageCharWidth.ave)
@@ -952,6 +1225,7 @@ func (pt PageText) toLinesOrient(tol float64) []textLine {
 				lines = append(lines, line)
 			}
 			words = []string{}
+			marks = []textMark{}
 			x = []float64{}
 			y = t.orientedStart.Y
 			scanning = false
@@ -986,18 +1260,25 @@ func (pt PageText) toLinesOrient(tol float64) []textLine {
 
 		if isSpace {
 			words = append(words, " ")
+			marks = append(marks, textMark{
+				text:          " ",
+				orient:        t.orient,
+				orientedStart: transform.Point{X: lastEndX, Y: t.orientedStart.Y},
+				orientedEnd:   transform.Point{X: t.orientedStart.X, Y: t.orientedStart.Y},
+			})
 			x = append(x, (lastEndX+t.orientedStart.X)*0.5)
 		}
 
 		// Add the text to the line.
 		lastEndX = t.orientedEnd.X
 		words = append(words, t.text)
+		marks = append(marks, t)
 		x = append(x, t.orientedStart.X)
 		scanning = true
 		common.Log.Trace("lastEndX=%.2f", lastEndX)
 	}
 	if len(words) > 0 {
-		line := newLine(y, x, words)
+		line := newLine(y, x, words, marks)
 		if averageCharWidth.running {
 			line = removeDuplicates(line, averageCharWidth.ave)
 		}
@@ -1036,14 +1317,14 @@ func (exp *exponAve) update(x float64) float64 {
 	return exp.ave
 }
 
-// newLine returns the textLine representation of strings `words` with y coordinate `y` and x
-// coordinates `x`.
-func newLine(y float64, x []float64, words []string) textLine {
+// newLine returns the textLine representation of strings `words` with y coordinate `y`, x
+// coordinates `x` and text marks `marks`.
+func newLine(y float64, x []float64, words []string, marks []textMark) textLine {
 	dxList := make([]float64, 0, len(x))
 	for i := 1; i < len(x); i++ {
 		dxList = append(dxList, x[i]-x[i-1])
 	}
-	return textLine{y: y, dxList: dxList, text: strings.Join(words, ""), words: words}
+	return textLine{y: y, dxList: dxList, text: strings.Join(words, ""), words: words, marks: marks}
 }
 
 // removeDuplicates returns `line` with duplicate characters removed. `charWidth` is the average
@@ -1056,6 +1337,7 @@ func removeDuplicates(line textLine, charWidth float64) textLine {
 	// NOTE(peterwilliams97) 0.3 is a guess. It may be possible to tune this to a better value.
 	tol := charWidth * 0.3
 	words := []string{line.words[0]}
+	marks := []textMark{line.marks[0]}
 	var dxList []float64
 
 	w0 := line.words[0]
@@ -1063,11 +1345,12 @@ func removeDuplicates(line textLine, charWidth float64) textLine {
 		w := line.words[i+1]
 		if w != w0 || dx > tol {
 			words = append(words, w)
+			marks = append(marks, line.marks[i+1])
 			dxList = append(dxList, dx)
 		}
 		w0 = w
 	}
-	return textLine{y: line.y, dxList: dxList, text: strings.Join(words, ""), words: words}
+	return textLine{y: line.y, dxList: dxList, text: strings.Join(words, ""), words: words, marks: marks}
 }
 
 // combineDiacritics returns `line` with diacritics close to characters combined with the characters.
@@ -0,0 +1,154 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"sort"
+
+	"github.com/unidoc/unidoc/pdf/model"
+)
+
+const (
+	// columnGap is the minimum width of the gap between columns of text as a fraction of the
+	// average text height.
+	columnGap = 1.0
+	// blockGap is the minimum height of the gap between blocks of text, such as paragraphs, as a
+	// fraction of the average text height. Lines of text are normally separated by gaps of about
+	// 0.2 times the text height.
+	blockGap = 0.5
+)
+
+// TextBlock represents a block of text on a page, such as a paragraph or a column of text.
+type TextBlock struct {
+	Text  string             // Text of the block. The lines are separated by newlines.
+	BBox  model.PdfRectangle // Union of the bounding boxes of the lines.
+	Lines []TextLine
+}
+
+// Blocks returns the text of `pt` split into blocks in reading order.
+// The blocks are found by recursive XY-cuts: The text is split at the widest vertical gap between
+// columns of text if there is one and otherwise at the highest horizontal gap between lines of
+// text, then the parts are split in the same way until there are no gaps left. Columns are
+// therefore ordered left to right and blocks within a column top to bottom.
+func (pt PageText) Blocks() []TextBlock {
+	var blocks []TextBlock
+	for _, marks := range pt.blocks() {
+		block := PageText{marks: marks}
+		lines := block.Lines()
+		if len(lines) == 0 {
+			continue
+		}
+		bbox := lines[0].BBox
+		for _, l := range lines[1:] {
+			bbox = rectUnion(bbox, l.BBox)
+		}
+		blocks = append(blocks, TextBlock{Text: block.ToText(), BBox: bbox, Lines: lines})
+	}
+	return blocks
+}
+
+// blocks returns the marks of `pt` split into blocks in reading order. The marks of each
+// orientation are split separately.
+func (pt PageText) blocks() [][]textMark {
+	tlOrient := make(map[int][]textMark, len(pt.marks))
+	for _, t := range pt.marks {
+		tlOrient[t.orient] = append(tlOrient[t.orient], t)
+	}
+	var blocks [][]textMark
+	for _, o := range orientKeys(tlOrient) {
+		blocks = append(blocks, xyCut(tlOrient[o])...)
+	}
+	return blocks
+}
+
+// xyCut returns `marks` split into blocks in reading order by recursive XY-cuts. All the marks must
+// have the same orientation.
+func xyCut(marks []textMark) [][]textMark {
+	height := 0.0
+	for _, t := range marks {
+		height += t.height
+	}
+	if len(marks) == 0 || height <= 0 {
+		return [][]textMark{marks}
+	}
+	height /= float64(len(marks))
+
+	parts := splitColumns(marks, columnGap*height)
+	if parts == nil {
+		parts = splitRows(marks, blockGap*height)
+	}
+	if parts == nil {
+		return [][]textMark{marks}
+	}
+	var blocks [][]textMark
+	for _, part := range parts {
+		blocks = append(blocks, xyCut(part)...)
+	}
+	return blocks
+}
+
+// splitColumns splits `marks` at the widest vertical gap between them that is at least `minGap`
+// wide. The left part is returned first. nil is returned if there is no such gap.
+func splitColumns(marks []textMark, minGap float64) [][]textMark {
+	sorted := append([]textMark(nil), marks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return markLeft(sorted[i]) < markLeft(sorted[j])
+	})
+
+	cut, widest := -1, minGap
+	right := markRight(sorted[0])
+	for i, t := range sorted[1:] {
+		if gap := markLeft(t) - right; gap >= widest {
+			cut, widest = i+1, gap
+		}
+		right = maxFloat(right, markRight(t))
+	}
+	if cut < 0 {
+		return nil
+	}
+	return [][]textMark{sorted[:cut], sorted[cut:]}
+}
+
+// splitRows splits `marks` at the highest horizontal gap between them that is at least `minGap`
+// high. The upper part is returned first. nil is returned if there is no such gap.
+func splitRows(marks []textMark, minGap float64) [][]textMark {
+	sorted := append([]textMark(nil), marks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return markTop(sorted[i]) > markTop(sorted[j])
+	})
+
+	cut, highest := -1, minGap
+	bottom := markBottom(sorted[0])
+	for i, t := range sorted[1:] {
+		if gap := bottom - markTop(t); gap >= highest {
+			cut, highest = i+1, gap
+		}
+		bottom = minFloat(bottom, markBottom(t))
+	}
+	if cut < 0 {
+		return nil
+	}
+	return [][]textMark{sorted[:cut], sorted[cut:]}
+}
+
+// markLeft, markRight, markTop and markBottom return the edges of the box of `t` in the orientation
+// where the text is horizontal. The box extends from 0.2 times the text height below the baseline
+// to 0.8 times the height above it.
+func markLeft(t textMark) float64 {
+	return minFloat(t.orientedStart.X, t.orientedEnd.X)
+}
+
+func markRight(t textMark) float64 {
+	return maxFloat(t.orientedStart.X, t.orientedEnd.X)
+}
+
+func markTop(t textMark) float64 {
+	return t.orientedStart.Y + 0.8*t.height
+}
+
+func markBottom(t textMark) float64 {
+	return t.orientedStart.Y - 0.2*t.height
+}
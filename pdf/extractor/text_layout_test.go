@@ -0,0 +1,68 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/model"
+)
+
+func TestTextLayoutBlocks(t *testing.T) {
+	resources := model.NewPdfPageResources()
+	courier := model.NewStandard14FontMustCompile(model.CourierName)
+	resources.SetFontByName("UniDocCourier", courier.ToPdfObject())
+
+	// A heading above two columns. The lines of the columns are at the same heights and the left
+	// column has 2 paragraphs.
+	e := Extractor{resources: resources, contents: `
+		BT
+		/UniDocCourier 10 Tf
+		12 TL
+		100 750 Td
+		(Two Columns) Tj
+		0 -50 Td
+		(Left one) Tj
+		(Left two) '
+		(Left three) '
+		0 -26 Td
+		(Left para) Tj
+		ET
+		BT
+		/UniDocCourier 10 Tf
+		12 TL
+		300 700 Td
+		(Right one) Tj
+		(Right two) '
+		(Right three) '
+		ET
+		`}
+
+	pageText, _, _, err := e.ExtractPageText()
+	require.NoError(t, err)
+	require.Equal(t, "Two Columns\nLeft one Right one\nLeft two Right two\nLeft three Right three\nLeft para",
+		pageText.ToText())
+
+	pageText, _, _, err = e.ExtractPageTextWithOptions(&TextExtractOptions{Layout: TextLayoutBlocks})
+	require.NoError(t, err)
+	require.Equal(t, "Two Columns\n\nLeft one\nLeft two\nLeft three\n\nLeft para\n\nRight one\nRight two\nRight three",
+		pageText.ToText())
+	lines := pageText.Lines()
+	require.Len(t, lines, 8)
+	require.Equal(t, "Left one", lines[1].Text)
+	require.Equal(t, "Right one", lines[5].Text)
+
+	blocks := pageText.Blocks()
+	require.Len(t, blocks, 4)
+	require.Equal(t, "Left one\nLeft two\nLeft three", blocks[1].Text)
+	require.Len(t, blocks[1].Lines, 3)
+	require.InDelta(t, 100, blocks[1].BBox.Llx, 1e-9)
+	require.InDelta(t, 160, blocks[1].BBox.Urx, 1e-9)
+	require.Equal(t, "Right one\nRight two\nRight three", blocks[3].Text)
+	require.InDelta(t, 300, blocks[3].BBox.Llx, 1e-9)
+}
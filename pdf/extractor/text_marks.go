@@ -0,0 +1,167 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/unidoc/unidoc/pdf/model"
+)
+
+// TextMark represents a character of text drawn on a page.
+// All coordinates are in device coordinates, i.e. the default coordinate system of the page.
+type TextMark struct {
+	Text string
+
+	// BBox is the bounding box of the text. It extends from the descent to the ascent of the font.
+	BBox model.PdfRectangle
+
+	// Orientation is the angle of the text in degrees rounded to a multiple of 10°.
+	Orientation int
+
+	// Font is the font the text is rendered with and FontName its base font name.
+	Font     *model.PdfFont
+	FontName string
+
+	// FontSize is the size of the font scaled to device coordinates.
+	FontSize float64
+
+	// FillColor is the nonstroking color in the colorspace FillColorspace. It may be nil for
+	// pattern colorspaces.
+	FillColorspace model.PdfColorspace
+	FillColor      model.PdfColor
+
+	// RenderMode is the text rendering mode. It is 0 for invisible text.
+	RenderMode RenderMode
+
+	// MCID is the marked-content identifier of the innermost marked-content sequence with one that
+	// contains the text, or -1 if there is none. It links the text to the structure tree.
+	MCID int
+
+	// Alt is the alternate description of the innermost marked-content sequence with one that
+	// contains the text.
+	Alt string
+}
+
+// FillColorRGB returns the fill color of `m` as red, green and blue components in the range [0,1].
+func (m TextMark) FillColorRGB() (r, g, b float64, err error) {
+	if m.FillColorspace == nil || m.FillColor == nil {
+		return 0, 0, 0, errors.New("no fill color")
+	}
+	color, err := m.FillColorspace.ColorToRGB(m.FillColor)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	rgb, ok := color.(*model.PdfColorDeviceRGB)
+	if !ok {
+		return 0, 0, 0, errors.New("type check error")
+	}
+	return rgb.R(), rgb.G(), rgb.B(), nil
+}
+
+// TextWord represents a word of text drawn on a page. Words are separated by spaces or by gaps
+// between the marks that are wide enough to be taken as spaces.
+type TextWord struct {
+	Text  string
+	BBox  model.PdfRectangle // Union of the bounding boxes of the marks.
+	Marks []TextMark
+}
+
+// TextLine represents a line of text drawn on a page.
+type TextLine struct {
+	Text  string             // Text of the line, including the spaces between the words.
+	BBox  model.PdfRectangle // Union of the bounding boxes of the words.
+	Words []TextWord
+}
+
+// Marks returns the text marks of `pt` in the order they were rendered.
+func (pt PageText) Marks() []TextMark {
+	marks := make([]TextMark, 0, len(pt.marks))
+	for _, t := range pt.marks {
+		marks = append(marks, t.toTextMark())
+	}
+	return marks
+}
+
+// Lines returns the text of `pt` grouped into lines of words. The lines are in the same order as
+// the lines returned by ToText. Lines that only contain spaces are omitted.
+func (pt PageText) Lines() []TextLine {
+	var lines []TextLine
+	for _, l := range pt.lines() {
+		line := TextLine{Text: l.text}
+		var word *TextWord
+		for _, t := range l.marks {
+			if strings.TrimSpace(t.text) == "" {
+				word = nil
+				continue
+			}
+			if word == nil {
+				line.Words = append(line.Words, TextWord{})
+				word = &line.Words[len(line.Words)-1]
+			}
+			word.Text += t.text
+			word.Marks = append(word.Marks, t.toTextMark())
+		}
+		if len(line.Words) == 0 {
+			continue
+		}
+
+		for i := range line.Words {
+			word := &line.Words[i]
+			word.BBox = word.Marks[0].BBox
+			for _, m := range word.Marks[1:] {
+				word.BBox = rectUnion(word.BBox, m.BBox)
+			}
+			if i == 0 {
+				line.BBox = word.BBox
+			} else {
+				line.BBox = rectUnion(line.BBox, word.BBox)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Words returns the words of `pt` in the order of Lines.
+func (pt PageText) Words() []TextWord {
+	var words []TextWord
+	for _, l := range pt.Lines() {
+		words = append(words, l.Words...)
+	}
+	return words
+}
+
+// toTextMark returns the TextMark representation of `t`.
+func (t textMark) toTextMark() TextMark {
+	m := TextMark{
+		Text:           t.text,
+		BBox:           t.bbox,
+		Orientation:    t.orient,
+		Font:           t.font,
+		FontSize:       t.fontSize,
+		FillColorspace: t.fillColorspace,
+		FillColor:      t.fillColor,
+		RenderMode:     t.renderMode,
+		MCID:           t.mcid,
+		Alt:            t.alt,
+	}
+	if t.font != nil {
+		m.FontName = t.font.BaseFont()
+	}
+	return m
+}
+
+// rectUnion returns the smallest rectangle containing `a` and `b`.
+func rectUnion(a, b model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: minFloat(a.Llx, b.Llx),
+		Lly: minFloat(a.Lly, b.Lly),
+		Urx: maxFloat(a.Urx, b.Urx),
+		Ury: maxFloat(a.Ury, b.Ury),
+	}
+}
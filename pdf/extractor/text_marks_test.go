@@ -0,0 +1,80 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/model"
+)
+
+func TestTextMarks(t *testing.T) {
+	resources := model.NewPdfPageResources()
+	courier := model.NewStandard14FontMustCompile(model.CourierName)
+	resources.SetFontByName("UniDocCourier", courier.ToPdfObject())
+
+	e := Extractor{resources: resources, contents: `
+		BT
+		/UniDocCourier 24 Tf
+		1 0 0 rg
+		100 700 Td
+		(Hello World)Tj
+		0 0 1 rg
+		1 Tr
+		0 -30 Td
+		(Bye)Tj
+		ET
+		`}
+	pageText, _, _, err := e.ExtractPageText()
+	require.NoError(t, err)
+
+	marks := pageText.Marks()
+	require.Len(t, marks, 14)
+	// Courier glyphs are 600 units wide, the ascent is 629 units and the descent -157 units.
+	h := marks[0]
+	require.Equal(t, "H", h.Text)
+	require.Equal(t, "Courier", h.FontName)
+	require.InDelta(t, 24, h.FontSize, 1e-9)
+	require.Equal(t, 0, h.Orientation)
+	require.Equal(t, RenderModeFill, h.RenderMode)
+	require.InDelta(t, 100, h.BBox.Llx, 1e-9)
+	require.InDelta(t, 696.232, h.BBox.Lly, 1e-9)
+	require.InDelta(t, 114.4, h.BBox.Urx, 1e-9)
+	require.InDelta(t, 715.096, h.BBox.Ury, 1e-9)
+	r, g, b, err := h.FillColorRGB()
+	require.NoError(t, err)
+	require.Equal(t, []float64{1, 0, 0}, []float64{r, g, b})
+
+	bye := marks[11]
+	require.Equal(t, "B", bye.Text)
+	require.Equal(t, RenderModeStroke, bye.RenderMode)
+	r, g, b, err = bye.FillColorRGB()
+	require.NoError(t, err)
+	require.Equal(t, []float64{0, 0, 1}, []float64{r, g, b})
+
+	lines := pageText.Lines()
+	require.Len(t, lines, 2)
+	require.Equal(t, "Hello World", lines[0].Text)
+	require.InDelta(t, 100, lines[0].BBox.Llx, 1e-9)
+	require.InDelta(t, 258.4, lines[0].BBox.Urx, 1e-9)
+
+	words := pageText.Words()
+	var texts []string
+	for _, w := range words {
+		texts = append(texts, w.Text)
+	}
+	require.Equal(t, []string{"Hello", "World", "Bye"}, texts)
+	require.Len(t, words[1].Marks, 5)
+	require.InDelta(t, 186.4, words[1].BBox.Llx, 1e-9)
+	require.InDelta(t, 258.4, words[1].BBox.Urx, 1e-9)
+	require.InDelta(t, 666.232, words[2].BBox.Lly, 1e-9)
+
+	// The marks are still in rendering order after extracting the text.
+	require.Equal(t, "Hello World\nBye", pageText.ToText())
+	require.Equal(t, "H", pageText.Marks()[0].Text)
+}
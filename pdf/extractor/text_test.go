@@ -14,10 +14,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/unicode/norm"
+
 	"github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/core"
 	"github.com/unidoc/unidoc/pdf/model"
-
-	"golang.org/x/text/unicode/norm"
 )
 
 // NOTE: We do a best effort at finding the PDF file because we don't keep PDF test files in this repo so you
@@ -121,6 +123,66 @@ func TestTextExtractionFragments(t *testing.T) {
 	}
 }
 
+// TestTextExtractionMarkedContent tests the handling of marked-content sequences.
+func TestTextExtractionMarkedContent(t *testing.T) {
+	resources := model.NewPdfPageResources()
+	courier := model.NewStandard14FontMustCompile(model.CourierName)
+	resources.SetFontByName("UniDocCourier", courier.ToPdfObject())
+	abbr := core.MakeDict()
+	abbr.Set("E", core.MakeString("Doctor"))
+	abbr.Set("MCID", core.MakeInteger(1))
+	properties := core.MakeDict()
+	properties.Set("Abbr", abbr)
+	resources.Properties = properties
+
+	e := Extractor{resources: resources, contents: `
+		/Artifact <</Type /Pagination>> BDC
+		BT /UniDocCourier 10 Tf 100 800 Td (Page 1) Tj ET
+		EMC
+		/P <</MCID 0>> BDC
+		BT
+		/UniDocCourier 10 Tf
+		100 700 Td
+		(Hello ) Tj
+		/Span <</ActualText (fi)>> BDC (X) Tj EMC
+		(ne) Tj
+		/Span <</ActualText ()>> BDC (-) Tj EMC
+		ET
+		EMC
+		/Span /Abbr BDC
+		BT /UniDocCourier 10 Tf 100 680 Td (Dr.) Tj ET
+		EMC
+		/Figure <</Alt (A chart) /MCID 2>> BDC
+		BT /UniDocCourier 10 Tf 100 660 Td (Chart) Tj ET
+		EMC
+		`}
+
+	pageText, _, _, err := e.ExtractPageText()
+	require.NoError(t, err)
+	require.Equal(t, "Page 1\nHello fine\nDr.\nChart", pageText.ToText())
+	marks := pageText.Marks()
+	var texts []string
+	var mcids []int
+	for _, m := range marks {
+		texts = append(texts, m.Text)
+		mcids = append(mcids, m.MCID)
+	}
+	require.Equal(t, []string{"P", "a", "g", "e", " ", "1", "H", "e", "l", "l", "o", " ", "fi", "n", "e",
+		"D", "r", ".", "C", "h", "a", "r", "t"}, texts)
+	require.Equal(t, []int{-1, -1, -1, -1, -1, -1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 2, 2, 2, 2, 2}, mcids)
+	require.Equal(t, "A chart", marks[18].Alt)
+	// The replacement text covers the replaced text.
+	require.InDelta(t, 136, marks[12].BBox.Llx, 1e-9)
+	require.InDelta(t, 142, marks[12].BBox.Urx, 1e-9)
+
+	pageText, _, _, err = e.ExtractPageTextWithOptions(&TextExtractOptions{
+		SkipArtifacts:       true,
+		ExpandAbbreviations: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Hello fine\nDoctor\nChart", pageText.ToText())
+}
+
 // TestTextExtractionFiles tests text extraction on a set of PDF files.
 // It checks for the existence of specified strings of words on specified pages.
 // We currently only check within lines as our line order is still improving.
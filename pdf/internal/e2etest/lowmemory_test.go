@@ -0,0 +1,111 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package e2etest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/model"
+)
+
+// Low memory tests read the content streams of all pages with the lazy reader and with the reader
+// limiting the parser cache, check that the contents match and that the peak heap size of the
+// reader limiting the cache is lower.
+// Set environment variables:
+//		UNIDOC_E2E_FORCE_TESTS to "1" to force the tests to execute.
+//		UNIDOC_LOWMEM_TESTDATA to the path of the corpus folder.
+var (
+	lowMemoryCorpusFolder = os.Getenv("UNIDOC_LOWMEM_TESTDATA")
+)
+
+// lowMemoryCacheLimit is the parser cache limit of the low memory reader.
+const lowMemoryCacheLimit = 4 * 1024 * 1024
+
+func TestLowMemoryReading(t *testing.T) {
+	if len(lowMemoryCorpusFolder) == 0 {
+		if forceTest {
+			t.Fatalf("UNIDOC_LOWMEM_TESTDATA not set")
+		}
+	}
+
+	files, err := ioutil.ReadDir(lowMemoryCorpusFolder)
+	if err != nil {
+		if forceTest {
+			t.Fatalf("Error opening %s: %v", lowMemoryCorpusFolder, err)
+		}
+		t.Skipf("Skipping low memory bench - unable to open UNIDOC_LOWMEM_TESTDATA (%s)", lowMemoryCorpusFolder)
+		return
+	}
+
+	for _, file := range files {
+		t.Logf("%s", file.Name())
+		fpath := filepath.Join(lowMemoryCorpusFolder, file.Name())
+
+		debug.FreeOSMemory()
+		lazy, lazyPeak := readPagesContents(t, fpath, &model.ReaderOpts{LazyLoad: true})
+		debug.FreeOSMemory()
+		bounded, boundedPeak := readPagesContents(t, fpath, &model.ReaderOpts{CacheLimit: lowMemoryCacheLimit})
+		require.Equal(t, lazy, bounded)
+
+		// The limit only makes a difference when the objects read exceed it.
+		if lazyPeak > 2*lowMemoryCacheLimit {
+			require.Truef(t, boundedPeak < lazyPeak, "%s: peak heap %d with cache limit, %d without",
+				file.Name(), boundedPeak, lazyPeak)
+		}
+	}
+	t.Logf("Low memory benchmark complete for %d files in %s", len(files), lowMemoryCorpusFolder)
+}
+
+// readPagesContents reads the content streams of all pages of the file 'fpath' with the reader
+// options 'opts' and returns the content lengths and the peak heap size grown while reading.
+func readPagesContents(t *testing.T, fpath string, opts *model.ReaderOpts) ([]int, uint64) {
+	measure := startMemoryMeasurement()
+
+	file, err := os.Open(fpath)
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader, err := model.NewPdfReaderWithOpts(file, opts)
+	require.NoError(t, err)
+
+	isEncrypted, err := reader.IsEncrypted()
+	require.NoError(t, err)
+	if isEncrypted {
+		auth, err := reader.Decrypt([]byte(""))
+		require.NoError(t, err)
+		require.True(t, auth)
+	}
+
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+
+	lengths := make([]int, numPages)
+	for i := range lengths {
+		page, err := reader.GetPage(i + 1)
+		require.NoError(t, err)
+		contents, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+		lengths[i] = len(contents)
+		measure.SampleHeap()
+	}
+
+	measure.Stop()
+	runtime.KeepAlive(reader)
+	t.Logf("%s - cache limit %d - summary %s", fpath, opts.CacheLimit, measure.Summary())
+
+	var peak uint64
+	if measure.PeakHeap() > measure.start.HeapAlloc {
+		peak = measure.PeakHeap() - measure.start.HeapAlloc
+	}
+	return lengths, peak
+}
@@ -17,6 +17,8 @@ type memoryMeasure struct {
 	startTime time.Time
 	end       runtime.MemStats
 	endTime   time.Time
+	// peakHeap is the largest live heap size sampled, in bytes.
+	peakHeap uint64
 }
 
 func startMemoryMeasurement() memoryMeasure {
@@ -27,6 +29,21 @@ func startMemoryMeasurement() memoryMeasure {
 	return m
 }
 
+// SampleHeap collects the garbage and records the live heap size for the peak heap size.
+func (m *memoryMeasure) SampleHeap() {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	if ms.HeapAlloc > m.peakHeap {
+		m.peakHeap = ms.HeapAlloc
+	}
+}
+
+// PeakHeap returns the largest live heap size sampled, in bytes.
+func (m memoryMeasure) PeakHeap() uint64 {
+	return m.peakHeap
+}
+
 // Stops finishes the measurement.
 func (m *memoryMeasure) Stop() {
 	runtime.ReadMemStats(&m.end)
@@ -45,5 +62,8 @@ func (m memoryMeasure) Summary() string {
 	b.WriteString(fmt.Sprintf("Alloc: %.2f MB\n", alloc/1024.0/1024.0))
 	b.WriteString(fmt.Sprintf("Mallocs: %d\n", mallocs))
 	b.WriteString(fmt.Sprintf("Frees: %d\n", frees))
+	if m.peakHeap > 0 {
+		b.WriteString(fmt.Sprintf("Peak heap: %.2f MB\n", float64(m.peakHeap)/1024.0/1024.0))
+	}
 	return b.String()
 }
@@ -0,0 +1,140 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package arithmetic
+
+// qe contains the probability estimation table of ITU-T T.88 Table E.1.
+// Each row holds: Qe value, NMPS, NLPS and the SWITCH flag.
+var qe = [][4]uint32{
+	{0x5601, 1, 1, 1}, {0x3401, 2, 6, 0}, {0x1801, 3, 9, 0}, {0x0AC1, 4, 12, 0},
+	{0x0521, 5, 29, 0}, {0x0221, 38, 33, 0}, {0x5601, 7, 6, 1}, {0x5401, 8, 14, 0},
+	{0x4801, 9, 14, 0}, {0x3801, 10, 14, 0}, {0x3001, 11, 17, 0}, {0x2401, 12, 18, 0},
+	{0x1C01, 13, 20, 0}, {0x1601, 29, 21, 0}, {0x5601, 15, 14, 1}, {0x5401, 16, 14, 0},
+	{0x5101, 17, 15, 0}, {0x4801, 18, 16, 0}, {0x3801, 19, 17, 0}, {0x3401, 20, 18, 0},
+	{0x3001, 21, 19, 0}, {0x2801, 22, 19, 0}, {0x2401, 23, 20, 0}, {0x2201, 24, 21, 0},
+	{0x1C01, 25, 22, 0}, {0x1801, 26, 23, 0}, {0x1601, 27, 24, 0}, {0x1401, 28, 25, 0},
+	{0x1201, 29, 26, 0}, {0x1101, 30, 27, 0}, {0x0AC1, 31, 28, 0}, {0x09C1, 32, 29, 0},
+	{0x08A1, 33, 30, 0}, {0x0521, 34, 31, 0}, {0x0441, 35, 32, 0}, {0x02A1, 36, 33, 0},
+	{0x0221, 37, 34, 0}, {0x0141, 38, 35, 0}, {0x0111, 39, 36, 0}, {0x0085, 40, 37, 0},
+	{0x0049, 41, 38, 0}, {0x0025, 42, 39, 0}, {0x0015, 43, 40, 0}, {0x0009, 44, 41, 0},
+	{0x0005, 45, 42, 0}, {0x0001, 45, 43, 0}, {0x5601, 46, 46, 0},
+}
+
+// Encoder is the arithmetic (MQ) encoder as defined in ITU-T T.88 Annex E.2.
+// The coded data is terminated with the 0xFF 0xAC marker as required for the JBIG2 segments.
+type Encoder struct {
+	a, c uint32
+	ct   int
+
+	// data holds a placeholder byte followed by the coded data.
+	// The last byte of the slice is the 'B' register of the specification.
+	data []byte
+}
+
+// New creates and initializes new arithmetic Encoder.
+func New() *Encoder {
+	e := &Encoder{}
+	e.Reset()
+	return e
+}
+
+// Reset initializes the encoder (INITENC) so it might be used for a new coded segment.
+func (e *Encoder) Reset() {
+	e.a = 0x8000
+	e.c = 0
+	e.ct = 12
+	e.data = []byte{0}
+}
+
+// EncodeBit encodes the 'bit' value within the context at current 'stats' index.
+func (e *Encoder) EncodeBit(stats *EncoderStats, bit int) {
+	cx := stats.index
+	entry := qe[stats.state[cx]]
+	q := entry[0]
+	mps := int(stats.mps[cx])
+
+	e.a -= q
+	if bit == mps {
+		// CODEMPS
+		if e.a&0x8000 != 0 {
+			e.c += q
+			return
+		}
+		if e.a < q {
+			e.a = q
+		} else {
+			e.c += q
+		}
+		stats.state[cx] = byte(entry[1])
+	} else {
+		// CODELPS
+		if e.a < q {
+			e.c += q
+		} else {
+			e.a = q
+		}
+		if entry[3] == 1 {
+			stats.mps[cx] ^= 1
+		}
+		stats.state[cx] = byte(entry[2])
+	}
+	e.renormalize()
+}
+
+// Flush terminates the coded data (FLUSH) and returns it. The encoder should be
+// reset before it is used again.
+func (e *Encoder) Flush() []byte {
+	// SETBITS
+	temp := e.c + e.a
+	e.c |= 0xFFFF
+	if e.c >= temp {
+		e.c -= 0x8000
+	}
+
+	e.c <<= uint(e.ct)
+	e.byteOut()
+	e.c <<= uint(e.ct)
+	e.byteOut()
+
+	if e.data[len(e.data)-1] != 0xFF {
+		e.data = append(e.data, 0xFF)
+	}
+	e.data = append(e.data, 0xAC)
+	return e.data[1:]
+}
+
+// renormalize is the RENORME procedure.
+func (e *Encoder) renormalize() {
+	for {
+		e.a <<= 1
+		e.c <<= 1
+		e.ct--
+		if e.ct == 0 {
+			e.byteOut()
+		}
+		if e.a&0x8000 != 0 {
+			return
+		}
+	}
+}
+
+// byteOut is the BYTEOUT procedure with the bit stuffing after 0xFF bytes.
+func (e *Encoder) byteOut() {
+	b := &e.data[len(e.data)-1]
+	if *b != 0xFF && e.c >= 0x8000000 {
+		*b++
+		e.c &= 0x7FFFFFF
+	}
+
+	if *b == 0xFF {
+		e.data = append(e.data, byte(e.c>>20))
+		e.c &= 0xFFFFF
+		e.ct = 7
+		return
+	}
+	e.data = append(e.data, byte(e.c>>19))
+	e.c &= 0x7FFFF
+	e.ct = 8
+}
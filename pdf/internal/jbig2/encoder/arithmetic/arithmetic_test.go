@@ -0,0 +1,98 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package arithmetic
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/decoder/arithmetic"
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/reader"
+)
+
+// TestEncoder checks the encoder against the test sequence of ITU-T T.88 Annex H.2.
+func TestEncoder(t *testing.T) {
+	data := []byte{
+		0x00, 0x02, 0x00, 0x51, 0x00, 0x00, 0x00, 0xC0, 0x03, 0x52, 0x87, 0x2A, 0xAA, 0xAA, 0xAA, 0xAA,
+		0x82, 0xC0, 0x20, 0x00, 0xFC, 0xD7, 0x9E, 0xF6, 0xBF, 0x7F, 0xED, 0x90, 0x4F, 0x46, 0xA3, 0xBF,
+	}
+	expected := []byte{
+		0x84, 0xC7, 0x3B, 0xFC, 0xE1, 0xA1, 0x43, 0x04, 0x02, 0x20, 0x00, 0x00, 0x41, 0x0D, 0xBB, 0x86,
+		0xF4, 0x31, 0x7F, 0xFF, 0x88, 0xFF, 0x37, 0x47, 0x1A, 0xDB, 0x6A, 0xDF, 0xFF, 0xAC,
+	}
+
+	e := New()
+	stats := NewStats(1)
+	for _, b := range data {
+		for i := 7; i >= 0; i-- {
+			e.EncodeBit(stats, int(b>>uint(i))&1)
+		}
+	}
+	assert.Equal(t, expected, e.Flush())
+}
+
+// TestRoundTrip checks that the bits encoded within multiple contexts are decoded back.
+func TestRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	bits := make([]int, 10000)
+	for i := range bits {
+		if rnd.Intn(10) < 2 {
+			bits[i] = 1
+		}
+	}
+
+	e := New()
+	stats := NewStats(4)
+	for i, b := range bits {
+		stats.SetIndex(i % 4)
+		e.EncodeBit(stats, b)
+	}
+	encoded := e.Flush()
+
+	d, err := arithmetic.New(reader.New(encoded))
+	require.NoError(t, err)
+	dstats := arithmetic.NewStats(4, 0)
+	for i, b := range bits {
+		dstats.SetIndex(i % 4)
+		bit, err := d.DecodeBit(dstats)
+		require.NoError(t, err)
+		require.Equal(t, b, bit, "bit %d", i)
+	}
+}
+
+// TestIntegerRoundTrip checks the integer and the symbol ID encoding with the decoder procedures.
+func TestIntegerRoundTrip(t *testing.T) {
+	values := []int{0, 1, -1, 3, 4, -4, 19, 20, 83, 84, -200, 339, 340, 4435, 4436, -4436, 100000, 7, 0}
+	const codeLen = 5
+
+	e := New()
+	stats, iaid := NewStats(512), NewStats(1<<codeLen)
+	for i, v := range values {
+		e.EncodeInteger(stats, v)
+		e.EncodeIAID(iaid, codeLen, i)
+	}
+	e.EncodeOOB(stats)
+	encoded := e.Flush()
+
+	d, err := arithmetic.New(reader.New(encoded))
+	require.NoError(t, err)
+	dstats, diaid := arithmetic.NewStats(512, 1), arithmetic.NewStats(1<<codeLen, 1)
+	for i, v := range values {
+		decoded, err := d.DecodeInt(dstats)
+		require.NoError(t, err)
+		assert.Equal(t, v, decoded)
+
+		id, err := d.DecodeIAID(codeLen, diaid)
+		require.NoError(t, err)
+		assert.Equal(t, int64(i), id)
+	}
+	oob, err := d.DecodeInt(dstats)
+	require.NoError(t, err)
+	assert.Equal(t, math.MaxInt64, oob)
+}
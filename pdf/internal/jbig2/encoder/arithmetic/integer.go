@@ -0,0 +1,78 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package arithmetic
+
+// intRanges are the value ranges of the integer encoding procedure (Table A.1).
+// The prefix bits are written starting with the most significant one.
+var intRanges = []struct {
+	prefix, prefixLen uint
+	bits              uint
+	offset            int
+}{
+	{0x0, 1, 2, 0},
+	{0x2, 2, 4, 4},
+	{0x6, 3, 6, 20},
+	{0xE, 4, 8, 84},
+	{0x1E, 5, 12, 340},
+	{0x1F, 5, 32, 4436},
+}
+
+// EncodeInteger encodes the integer 'v' with the IAx procedure (Annex A.2) within the 'stats' contexts.
+// The stats should have 512 contexts.
+func (e *Encoder) EncodeInteger(stats *EncoderStats, v int) {
+	if v < 0 {
+		e.encodeInteger(stats, 1, -v)
+		return
+	}
+	e.encodeInteger(stats, 0, v)
+}
+
+// EncodeOOB encodes the out of band value with the IAx procedure.
+func (e *Encoder) EncodeOOB(stats *EncoderStats) {
+	e.encodeInteger(stats, 1, 0)
+}
+
+// EncodeIAID encodes the symbol ID 'v' of 'codeLen' bits with the IAID procedure (Annex A.3).
+// The stats should have 1 << codeLen contexts.
+func (e *Encoder) EncodeIAID(stats *EncoderStats, codeLen uint, v int) {
+	prev := 1
+	for i := int(codeLen) - 1; i >= 0; i-- {
+		bit := (v >> uint(i)) & 1
+		stats.SetIndex(prev)
+		e.EncodeBit(stats, bit)
+		prev = prev<<1 | bit
+	}
+}
+
+func (e *Encoder) encodeInteger(stats *EncoderStats, sign, magnitude int) {
+	r := intRanges[len(intRanges)-1]
+	for _, ir := range intRanges[:len(intRanges)-1] {
+		if magnitude < ir.offset+1<<ir.bits {
+			r = ir
+			break
+		}
+	}
+
+	prev := 1
+	put := func(bit int) {
+		stats.SetIndex(prev)
+		e.EncodeBit(stats, bit)
+		if prev < 256 {
+			prev = prev<<1 | bit
+		} else {
+			prev = (prev<<1|bit)&511 | 256
+		}
+	}
+
+	put(sign)
+	for i := int(r.prefixLen) - 1; i >= 0; i-- {
+		put(int(r.prefix>>uint(i)) & 1)
+	}
+	value := magnitude - r.offset
+	for i := int(r.bits) - 1; i >= 0; i-- {
+		put((value >> uint(i)) & 1)
+	}
+}
@@ -0,0 +1,34 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package arithmetic
+
+// EncoderStats contains the adaptive probability states of the coding contexts.
+type EncoderStats struct {
+	index int
+	state []byte
+	mps   []byte
+}
+
+// NewStats creates new EncoderStats with 'contextSize' coding contexts.
+func NewStats(contextSize int) *EncoderStats {
+	return &EncoderStats{
+		state: make([]byte, contextSize),
+		mps:   make([]byte, contextSize),
+	}
+}
+
+// SetIndex sets the context used by the next encoded bit.
+func (s *EncoderStats) SetIndex(index int) {
+	s.index = index
+}
+
+// Reset resets all the contexts to their initial state.
+func (s *EncoderStats) Reset() {
+	for i := range s.state {
+		s.state[i] = 0
+		s.mps[i] = 0
+	}
+}
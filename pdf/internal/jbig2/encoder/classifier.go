@@ -0,0 +1,99 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package encoder
+
+import (
+	"bytes"
+	"math/bits"
+	"sort"
+
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/bitmap"
+)
+
+// symbolClass is a class of the components encoded with the same symbol bitmap.
+type symbolClass struct {
+	bm *bitmap.Bitmap
+	// count is the number of the classified components.
+	count int
+}
+
+// classKey is the key of the classes that might be matched with each other.
+type classKey struct {
+	width, height int
+}
+
+// classifier assigns the components to the symbol classes. The components are of the same
+// class if they are equal or, when the threshold is not zero, if they are of the same size
+// and the fraction of their differing pixels is not greater than the threshold.
+type classifier struct {
+	threshold float64
+	classes   []*symbolClass
+	index     map[classKey][]int
+}
+
+func newClassifier(threshold float64) *classifier {
+	return &classifier{threshold: threshold, index: map[classKey][]int{}}
+}
+
+// find returns the index of the class matching the bitmap 'bm' or -1 if there is none.
+func (c *classifier) find(bm *bitmap.Bitmap) int {
+	for _, i := range c.index[classKey{bm.Width, bm.Height}] {
+		if c.matches(c.classes[i].bm, bm) {
+			return i
+		}
+	}
+	return -1
+}
+
+// classify returns the index of the class of the bitmap 'bm'. A new class is created if none matches.
+func (c *classifier) classify(bm *bitmap.Bitmap) int {
+	i := c.find(bm)
+	if i < 0 {
+		i = c.add(bm)
+	}
+	c.classes[i].count++
+	return i
+}
+
+// add creates new class represented by the bitmap 'bm' and returns its index.
+func (c *classifier) add(bm *bitmap.Bitmap) int {
+	key := classKey{bm.Width, bm.Height}
+	c.classes = append(c.classes, &symbolClass{bm: bm})
+	c.index[key] = append(c.index[key], len(c.classes)-1)
+	return len(c.classes) - 1
+}
+
+func (c *classifier) matches(a, b *bitmap.Bitmap) bool {
+	if c.threshold <= 0 {
+		return bytes.Equal(a.Data, b.Data)
+	}
+	maxDiff := int(c.threshold * float64(a.Width*a.Height))
+	var diff int
+	for i := range a.Data {
+		diff += bits.OnesCount8(a.Data[i] ^ b.Data[i])
+		if diff > maxDiff {
+			return false
+		}
+	}
+	return true
+}
+
+// symbolOrder returns the indexes of the 'classes' in the order of the symbol dictionary,
+// that is sorted by the height and the width of the symbols.
+func symbolOrder(classes []*symbolClass) []int {
+	order := make([]int, len(classes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := classes[order[i]].bm, classes[order[j]].bm
+		if a.Height != b.Height {
+			return a.Height < b.Height
+		}
+		return a.Width < b.Width
+	})
+	return order
+}
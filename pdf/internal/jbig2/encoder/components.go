@@ -0,0 +1,117 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package encoder
+
+import (
+	"sort"
+
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/bitmap"
+)
+
+// component is a connected component of the black pixels of a page.
+type component struct {
+	// x and y is the location of the top left corner of the component's bounding box.
+	x, y int
+	bm   *bitmap.Bitmap
+}
+
+// run is a horizontal run of the black pixels [x0, x1) in the row y.
+type run struct {
+	y, x0, x1 int
+	parent    int
+}
+
+// connectedComponents returns the 8-connected components of the bitmap 'bm' ordered
+// by the position of their top left pixel. The components are found by joining the
+// overlapping runs of the neighbouring rows.
+func connectedComponents(bm *bitmap.Bitmap) []component {
+	var runs []run
+	find := func(i int) int {
+		for runs[i].parent != i {
+			runs[i].parent = runs[runs[i].parent].parent
+			i = runs[i].parent
+		}
+		return i
+	}
+
+	prevStart, prevEnd := 0, 0
+	for y := 0; y < bm.Height; y++ {
+		row := bitmapRow(bm, y)
+		start := len(runs)
+		for x := 0; x < bm.Width; {
+			if pixel(row, x, bm.Width) == 0 {
+				x++
+				continue
+			}
+			x0 := x
+			for x < bm.Width && pixel(row, x, bm.Width) == 1 {
+				x++
+			}
+			i := len(runs)
+			runs = append(runs, run{y: y, x0: x0, x1: x, parent: i})
+
+			// Join the runs of the previous row touching this one, including diagonally.
+			for j := prevStart; j < prevEnd; j++ {
+				if runs[j].x0 > x || runs[j].x1 < x0 {
+					continue
+				}
+				if ri, rj := find(i), find(j); ri != rj {
+					if ri < rj {
+						runs[rj].parent = ri
+					} else {
+						runs[ri].parent = rj
+					}
+				}
+			}
+		}
+		prevStart, prevEnd = start, len(runs)
+	}
+
+	// Group the runs by their roots. The root is always the first run of the component.
+	groups := map[int][]int{}
+	var roots []int
+	for i := range runs {
+		r := find(i)
+		if _, ok := groups[r]; !ok {
+			roots = append(roots, r)
+		}
+		groups[r] = append(groups[r], i)
+	}
+
+	components := make([]component, 0, len(roots))
+	for _, r := range roots {
+		members := groups[r]
+		minX, maxX := runs[r].x0, runs[r].x1
+		minY, maxY := runs[r].y, runs[r].y
+		for _, i := range members {
+			if runs[i].x0 < minX {
+				minX = runs[i].x0
+			}
+			if runs[i].x1 > maxX {
+				maxX = runs[i].x1
+			}
+			if runs[i].y > maxY {
+				maxY = runs[i].y
+			}
+		}
+
+		c := component{x: minX, y: minY, bm: bitmap.New(maxX-minX, maxY-minY+1)}
+		for _, i := range members {
+			for x := runs[i].x0; x < runs[i].x1; x++ {
+				c.bm.SetPixel(x-minX, runs[i].y-minY, 1)
+			}
+		}
+		components = append(components, c)
+	}
+
+	sort.SliceStable(components, func(i, j int) bool {
+		if components[i].y != components[j].y {
+			return components[i].y < components[j].y
+		}
+		return components[i].x < components[j].x
+	})
+	return components
+}
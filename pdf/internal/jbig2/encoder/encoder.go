@@ -5,5 +5,169 @@
 
 package encoder
 
-type Encoder interface {
+import (
+	"bytes"
+	"errors"
+
+	"github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/bitmap"
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/segments"
+)
+
+// ErrInvalidBitmap is returned when the provided bitmap cannot be encoded.
+var ErrInvalidBitmap = errors.New("invalid bitmap for the JBIG2 encoding")
+
+// Segment numbers used by the encoder. The global segments precede the segments of the page.
+const (
+	globalDictionarySegment = 0
+	pageInformationSegment  = 1
+	pageDictionarySegment   = 2
+	regionSegment           = 3
+)
+
+// Encoder encodes the bitmaps into the JBIG2 embedded stream format (ISO/IEC 14492 Annex D.3)
+// as used by the PDF JBIG2Decode filter. The stream contains neither the file header
+// nor the end of page and the end of file segments.
+type Encoder struct {
+	// DuplicateLineRemoval enables the typical prediction for generic direct coding (TPGDON),
+	// where the rows that are the same as the row above are coded with a single bit.
+	DuplicateLineRemoval bool
+
+	// ResolutionX and ResolutionY are the page resolution in pixels per metre. Zero if unknown.
+	ResolutionX, ResolutionY int
+
+	// ClassThreshold is the maximal fraction of the differing pixels of the same sized connected
+	// components that are coded with the same symbol. Zero keeps the symbol coding lossless.
+	ClassThreshold float64
+
+	// globals contains the classes of the global symbol dictionary, indexed by the symbol ID.
+	globals *classifier
+}
+
+// EncodeGeneric encodes the bitmap 'bm' as a single page with one immediate generic region
+// coded losslessly with the arithmetic coding. The bitmap pixels with value 1 are black.
+func (e *Encoder) EncodeGeneric(bm *bitmap.Bitmap) ([]byte, error) {
+	if !isValid(bm) {
+		return nil, ErrInvalidBitmap
+	}
+	common.Log.Trace("JBIG2 generic region encoding of the bitmap %dx%d", bm.Width, bm.Height)
+
+	var w bytes.Buffer
+	writeSegment(&w, pageInformationSegment, segments.TPageInformation, 1, nil,
+		pageInformation(bm.Width, bm.Height, e.ResolutionX, e.ResolutionY, true))
+	writeSegment(&w, regionSegment, segments.TImmediateGenericRegion, 1, nil,
+		genericRegion(bm, 0, 0, e.DuplicateLineRemoval))
+	return w.Bytes(), nil
+}
+
+// EncodeGlobals creates the symbol dictionary from the connected components of the 'pages'
+// and returns the data of the global segments, stored in the PDF JBIG2Globals stream.
+// Only the symbols occurring more than once are stored in the global dictionary.
+// The pages encoded afterwards with EncodeText refer to this dictionary. If none of the symbols
+// is repeated, no dictionary is created and nil data is returned.
+func (e *Encoder) EncodeGlobals(pages ...*bitmap.Bitmap) ([]byte, error) {
+	c := newClassifier(e.ClassThreshold)
+	for _, bm := range pages {
+		if !isValid(bm) {
+			return nil, ErrInvalidBitmap
+		}
+		for _, comp := range connectedComponents(bm) {
+			c.classify(comp.bm)
+		}
+	}
+
+	var shared []*symbolClass
+	for _, class := range c.classes {
+		if class.count > 1 {
+			shared = append(shared, class)
+		}
+	}
+	e.globals = nil
+	if len(shared) == 0 {
+		return nil, nil
+	}
+
+	e.globals = newClassifier(e.ClassThreshold)
+	symbols := make([]*bitmap.Bitmap, len(shared))
+	for i, j := range symbolOrder(shared) {
+		symbols[i] = shared[j].bm
+		e.globals.add(shared[j].bm)
+	}
+	common.Log.Trace("JBIG2 global symbol dictionary with %d symbols", len(symbols))
+
+	var w bytes.Buffer
+	writeSegment(&w, globalDictionarySegment, segments.TSymbolDictionary, 0, nil, symbolDictionary(symbols))
+	return w.Bytes(), nil
+}
+
+// EncodeText encodes the bitmap 'bm' as a single page with one text region. The connected
+// components of the bitmap are coded as the symbols of the global dictionary created with
+// EncodeGlobals. The symbols not found there are stored in the symbol dictionary of the page.
+// The blank bitmaps are encoded as the generic region.
+func (e *Encoder) EncodeText(bm *bitmap.Bitmap) ([]byte, error) {
+	if !isValid(bm) {
+		return nil, ErrInvalidBitmap
+	}
+	components := connectedComponents(bm)
+	if len(components) == 0 {
+		return e.EncodeGeneric(bm)
+	}
+
+	var numGlobals int
+	if e.globals != nil {
+		numGlobals = len(e.globals.classes)
+	}
+
+	// Classify the components, the local classes are numbered after the global ones.
+	local := newClassifier(e.ClassThreshold)
+	instances := make([]symbolInstance, len(components))
+	isLocal := make([]bool, len(components))
+	for i, comp := range components {
+		instances[i] = symbolInstance{x: comp.x, y: comp.y, width: comp.bm.Width, height: comp.bm.Height}
+		if e.globals != nil {
+			if id := e.globals.find(comp.bm); id >= 0 {
+				instances[i].id = id
+				continue
+			}
+		}
+		instances[i].id = local.classify(comp.bm)
+		isLocal[i] = true
+	}
+
+	order := symbolOrder(local.classes)
+	ids := make([]int, len(order))
+	symbols := make([]*bitmap.Bitmap, len(order))
+	for i, j := range order {
+		ids[j] = numGlobals + i
+		symbols[i] = local.classes[j].bm
+	}
+	for i := range instances {
+		if isLocal[i] {
+			instances[i].id = ids[instances[i].id]
+		}
+	}
+	common.Log.Trace("JBIG2 text region with %d symbol instances, %d global and %d page symbols",
+		len(instances), numGlobals, len(symbols))
+
+	var (
+		w        bytes.Buffer
+		referred []uint32
+	)
+	// The symbol matching is lossy with a class threshold.
+	writeSegment(&w, pageInformationSegment, segments.TPageInformation, 1, nil,
+		pageInformation(bm.Width, bm.Height, e.ResolutionX, e.ResolutionY, e.ClassThreshold <= 0))
+	if numGlobals > 0 {
+		referred = append(referred, globalDictionarySegment)
+	}
+	if len(symbols) > 0 {
+		writeSegment(&w, pageDictionarySegment, segments.TSymbolDictionary, 1, nil, symbolDictionary(symbols))
+		referred = append(referred, pageDictionarySegment)
+	}
+	writeSegment(&w, regionSegment, segments.TImmediateTextRegion, 1, referred,
+		textRegion(bm.Width, bm.Height, instances, numGlobals+len(symbols)))
+	return w.Bytes(), nil
+}
+
+func isValid(bm *bitmap.Bitmap) bool {
+	return bm != nil && bm.Width > 0 && bm.Height > 0 && len(bm.Data) >= bm.Height*bm.RowStride
 }
@@ -0,0 +1,289 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package encoder
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unidoc/unidoc/pdf/internal/jbig2"
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/bitmap"
+)
+
+// testBitmap creates a bitmap with some text like blocks, repeated rows and noise.
+func testBitmap(width, height int, seed int64) *bitmap.Bitmap {
+	rnd := rand.New(rand.NewSource(seed))
+	bm := bitmap.New(width, height)
+	for y := 0; y < height; y++ {
+		if y%7 == 6 && y > 0 {
+			copy(bm.Data[y*bm.RowStride:], bm.Data[(y-1)*bm.RowStride:y*bm.RowStride])
+			continue
+		}
+		for x := 0; x < width; x++ {
+			black := (x/5+y/9)%3 == 0
+			if rnd.Intn(20) == 0 {
+				black = !black
+			}
+			if black {
+				bm.SetPixel(x, y, 1)
+			}
+		}
+	}
+	return bm
+}
+
+func decodePage(t *testing.T, data []byte) *bitmap.Bitmap {
+	doc, err := jbig2.NewDocument(data)
+	require.NoError(t, err)
+	page, err := doc.GetPage(1)
+	require.NoError(t, err)
+	bm, err := page.GetBitmap()
+	require.NoError(t, err)
+	return bm
+}
+
+// isLosslessPage returns true if the page information segment at the start of the page 'data'
+// flags the page as eventually lossless. The segment data follows the 11 byte header.
+func isLosslessPage(data []byte) bool {
+	return data[11+16]&0x01 != 0
+}
+
+func TestEncodeGeneric(t *testing.T) {
+	testcases := []struct {
+		name          string
+		width, height int
+		tpgdon        bool
+	}{
+		{"small", 5, 3, false},
+		{"byte aligned", 64, 40, false},
+		{"unaligned", 77, 51, false},
+		{"unaligned tpgdon", 77, 51, true},
+		{"single row", 130, 1, true},
+		{"single column", 1, 33, true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			bm := testBitmap(tc.width, tc.height, int64(tc.width))
+			e := &Encoder{DuplicateLineRemoval: tc.tpgdon}
+			data, err := e.EncodeGeneric(bm)
+			require.NoError(t, err)
+
+			decoded := decodePage(t, data)
+			require.Equal(t, tc.width, decoded.Width)
+			require.Equal(t, tc.height, decoded.Height)
+			assert.True(t, bm.Equals(decoded), "expected:%s\ndecoded:%s", bm, decoded)
+		})
+	}
+
+	t.Run("blank", func(t *testing.T) {
+		bm := bitmap.New(100, 100)
+		e := &Encoder{DuplicateLineRemoval: true}
+		data, err := e.EncodeGeneric(bm)
+		require.NoError(t, err)
+		assert.True(t, len(data) < 80, "blank page takes %d bytes", len(data))
+		assert.True(t, bm.Equals(decodePage(t, data)))
+	})
+
+	t.Run("padding", func(t *testing.T) {
+		// The padding bits of the rows must not influence the coding.
+		bm := testBitmap(13, 9, 2)
+		padded := bitmap.New(13, 9)
+		for i, b := range bm.Data {
+			padded.Data[i] = b
+			if i%padded.RowStride == padded.RowStride-1 {
+				padded.Data[i] |= 0x07
+			}
+		}
+		e := &Encoder{DuplicateLineRemoval: true}
+		expected, err := e.EncodeGeneric(bm)
+		require.NoError(t, err)
+		data, err := e.EncodeGeneric(padded)
+		require.NoError(t, err)
+		assert.Equal(t, expected, data)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		e := &Encoder{}
+		_, err := e.EncodeGeneric(nil)
+		assert.Equal(t, ErrInvalidBitmap, err)
+		_, err = e.EncodeGeneric(&bitmap.Bitmap{})
+		assert.Equal(t, ErrInvalidBitmap, err)
+	})
+}
+
+// glyphs are the patterns drawn on the text test pages.
+var glyphs = [][]string{
+	{
+		".###.",
+		"#...#",
+		"#####",
+		"#...#",
+		"#...#",
+	},
+	{
+		"####.",
+		"#...#",
+		"####.",
+		"#...#",
+		"####.",
+	},
+	{
+		"#....",
+		"#....",
+		"#....",
+		"#..#.",
+		"#####",
+	},
+	{
+		"..#",
+		"...",
+		"..#",
+		"..#",
+		"..#",
+		"#.#",
+		".#.",
+	},
+}
+
+func drawGlyph(bm *bitmap.Bitmap, glyph []string, x, y int) {
+	for dy, row := range glyph {
+		for dx, c := range row {
+			if c == '#' {
+				bm.SetPixel(x+dx, y+dy, 1)
+			}
+		}
+	}
+}
+
+// textBitmap creates a page with lines of glyphs and some noise. The glyph 'L' encloses a dot
+// within its bounding box, so the bounding boxes of the components overlap.
+func textBitmap(width, height int, seed int64) *bitmap.Bitmap {
+	rnd := rand.New(rand.NewSource(seed))
+	bm := bitmap.New(width, height)
+	for y := 2; y+12 < height; y += 12 {
+		for x := 1 + rnd.Intn(3); x+7 < width; x += 7 + rnd.Intn(3) {
+			g := rnd.Intn(len(glyphs))
+			drawGlyph(bm, glyphs[g], x, y)
+			if g == 2 {
+				bm.SetPixel(x+2, y+1, 1)
+			}
+		}
+	}
+	for i := 0; i < 10; i++ {
+		bm.SetPixel(rnd.Intn(width), rnd.Intn(height), 1)
+	}
+	return bm
+}
+
+func TestConnectedComponents(t *testing.T) {
+	bm := bitmap.New(10, 6)
+	drawGlyph(bm, []string{
+		"##..#.....",
+		".#...#.##.",
+		"..#..#..#.",
+		"......###.",
+		"#.........",
+		"#........#",
+	}, 0, 0)
+
+	// The second component is joined diagonally at the pixel (6, 3).
+	components := connectedComponents(bm)
+	require.Len(t, components, 4)
+	expected := []struct{ x, y, w, h int }{{0, 0, 3, 3}, {4, 0, 5, 4}, {0, 4, 1, 2}, {9, 5, 1, 1}}
+	for i, c := range components {
+		assert.Equal(t, expected[i].x, c.x, "component %d", i)
+		assert.Equal(t, expected[i].y, c.y, "component %d", i)
+		assert.Equal(t, expected[i].w, c.bm.Width, "component %d", i)
+		assert.Equal(t, expected[i].h, c.bm.Height, "component %d", i)
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	t.Run("page symbols", func(t *testing.T) {
+		bm := textBitmap(90, 50, 1)
+		e := &Encoder{}
+		data, err := e.EncodeText(bm)
+		require.NoError(t, err)
+
+		decoded := decodePage(t, data)
+		assert.True(t, bm.Equals(decoded), "expected:%s\ndecoded:%s", bm, decoded)
+		assert.True(t, isLosslessPage(data))
+	})
+
+	t.Run("blank", func(t *testing.T) {
+		bm := bitmap.New(20, 10)
+		e := &Encoder{}
+		data, err := e.EncodeText(bm)
+		require.NoError(t, err)
+		assert.True(t, bm.Equals(decodePage(t, data)))
+	})
+
+	t.Run("shared globals", func(t *testing.T) {
+		pages := []*bitmap.Bitmap{textBitmap(120, 60, 2), textBitmap(100, 75, 3), textBitmap(64, 30, 4)}
+		e := &Encoder{}
+		globals, err := e.EncodeGlobals(pages...)
+		require.NoError(t, err)
+		require.NotNil(t, globals)
+
+		globalsDoc, err := jbig2.NewDocument(globals)
+		require.NoError(t, err)
+
+		var sizes []int
+		for i, bm := range pages {
+			data, err := e.EncodeText(bm)
+			require.NoError(t, err)
+			sizes = append(sizes, len(data))
+
+			doc, err := jbig2.NewDocumentWithGlobals(data, globalsDoc.GlobalSegments)
+			require.NoError(t, err)
+			page, err := doc.GetPage(1)
+			require.NoError(t, err)
+			decoded, err := page.GetBitmap()
+			require.NoError(t, err)
+			assert.True(t, bm.Equals(decoded), "page %d expected:%s\ndecoded:%s", i, bm, decoded)
+		}
+
+		// The pages using the shared dictionary should be smaller than the self contained ones.
+		for i, bm := range pages {
+			data, err := (&Encoder{}).EncodeText(bm)
+			require.NoError(t, err)
+			assert.True(t, sizes[i] < len(data), "page %d: %d >= %d", i, sizes[i], len(data))
+		}
+	})
+
+	t.Run("no shared symbols", func(t *testing.T) {
+		bm := bitmap.New(10, 10)
+		bm.SetPixel(1, 1, 1)
+		e := &Encoder{}
+		globals, err := e.EncodeGlobals(bm)
+		require.NoError(t, err)
+		assert.Nil(t, globals)
+	})
+
+	t.Run("lossy", func(t *testing.T) {
+		bm := bitmap.New(40, 10)
+		drawGlyph(bm, glyphs[0], 1, 1)
+		drawGlyph(bm, glyphs[0], 10, 1)
+		drawGlyph(bm, glyphs[0], 20, 1)
+		// The third glyph differs in a single pixel from the other ones.
+		bm.SetPixel(21, 2, 1)
+
+		e := &Encoder{ClassThreshold: 0.1}
+		data, err := e.EncodeText(bm)
+		require.NoError(t, err)
+		decoded := decodePage(t, data)
+
+		expected := bitmap.New(40, 10)
+		drawGlyph(expected, glyphs[0], 1, 1)
+		drawGlyph(expected, glyphs[0], 10, 1)
+		drawGlyph(expected, glyphs[0], 20, 1)
+		assert.True(t, expected.Equals(decoded), "expected:%s\ndecoded:%s", expected, decoded)
+		assert.False(t, isLosslessPage(data))
+	})
+}
@@ -0,0 +1,115 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package encoder
+
+import (
+	"bytes"
+
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/bitmap"
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/encoder/arithmetic"
+)
+
+// genericATPixels are the nominal adaptive template pixels of the generic template 0 (6.2.5.4),
+// stored as the pairs of x and y offsets.
+var genericATPixels = []int8{3, -1, -3, -1, 2, -2, -2, -2}
+
+// sltpContext is the context used to encode the SLTP bit of the template 0 (Figure 8).
+const sltpContext = 0x9B25
+
+// genericRegion returns the data of the generic region segment (7.4.6) for the bitmap 'bm'
+// placed at the 'x', 'y' location on the page.
+func genericRegion(bm *bitmap.Bitmap, x, y int, tpgdon bool) []byte {
+	var w bytes.Buffer
+	writeRegionInfo(&w, bm.Width, bm.Height, x, y)
+
+	// 7.4.6.2 Generic region segment flags: arithmetic coding with the template 0.
+	var flags byte
+	if tpgdon {
+		flags |= 0x08
+	}
+	w.WriteByte(flags)
+
+	// 7.4.6.3 Generic region segment AT flags.
+	for _, at := range genericATPixels {
+		w.WriteByte(byte(at))
+	}
+
+	e := arithmetic.New()
+	encodeGeneric(e, arithmetic.NewStats(1<<16), bm, tpgdon)
+	w.Write(e.Flush())
+	return w.Bytes()
+}
+
+// encodeGeneric encodes the bitmap using the generic region encoding procedure with the
+// template 0 and the nominal AT pixels, that is the reverse of the 6.2.5.7 decoding procedure.
+func encodeGeneric(e *arithmetic.Encoder, stats *arithmetic.EncoderStats, bm *bitmap.Bitmap, tpgdon bool) {
+	var ltp bool
+	w := bm.Width
+	for y := 0; y < bm.Height; y++ {
+		row := bitmapRow(bm, y)
+		line1, line2 := bitmapRow(bm, y-1), bitmapRow(bm, y-2)
+
+		if tpgdon {
+			// The row above the first one consists of zero pixels.
+			typical := rowsEqual(row, line1, bm.Width)
+			stats.SetIndex(sltpContext)
+			if typical != ltp {
+				e.EncodeBit(stats, 1)
+			} else {
+				e.EncodeBit(stats, 0)
+			}
+			ltp = typical
+			if ltp {
+				continue
+			}
+		}
+
+		// The context bits are ordered as in the decoder: bits 0-3 are the pixels preceding
+		// the current one, bits 4-10 come from the row above, bits 11-15 from the row above that.
+		context := pixel(line1, 3, w)<<4 | pixel(line1, 2, w)<<5 | pixel(line1, 1, w)<<6 | pixel(line1, 0, w)<<7 |
+			pixel(line2, 2, w)<<11 | pixel(line2, 1, w)<<12 | pixel(line2, 0, w)<<13
+		for x := 0; x < w; x++ {
+			bit := pixel(row, x, w)
+			stats.SetIndex(context)
+			e.EncodeBit(stats, bit)
+			context = (context&0x7bf7)<<1 | bit | pixel(line1, x+4, w)<<4 | pixel(line2, x+3, w)<<11
+		}
+	}
+}
+
+// bitmapRow returns the data of the row 'y' of the bitmap or nil if the row is outside of it.
+func bitmapRow(bm *bitmap.Bitmap, y int) []byte {
+	if y < 0 || y >= bm.Height {
+		return nil
+	}
+	return bm.Data[y*bm.RowStride : (y+1)*bm.RowStride]
+}
+
+// pixel returns the value of the pixel 'x' in the 'row' of 'width' pixels. Pixels outside of the row are 0.
+func pixel(row []byte, x, width int) int {
+	if x < 0 || x >= width || row == nil {
+		return 0
+	}
+	return int(row[x>>3]>>uint(7-x&7)) & 1
+}
+
+// rowsEqual checks if the first 'width' pixels of the rows are the same. A nil row is treated as white.
+func rowsEqual(row, other []byte, width int) bool {
+	for i := 0; i < len(row); i++ {
+		mask := byte(0xff)
+		if rest := width - i*8; rest < 8 {
+			mask <<= uint(8 - rest)
+		}
+		var o byte
+		if other != nil {
+			o = other[i]
+		}
+		if (row[i]^o)&mask != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package encoder
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/segments"
+)
+
+// writeSegment writes the segment header (7.2) followed by the segment 'data'.
+// The 'referred' contains the numbers of the referred-to segments.
+func writeSegment(w *bytes.Buffer, number uint32, kind segments.Type, page int, referred []uint32, data []byte) {
+	writeUint32(w, number)
+
+	// 7.2.3 Segment header flags.
+	flags := byte(kind) & 0x3f
+	if page > 0xff {
+		flags |= 0x40
+	}
+	w.WriteByte(flags)
+
+	// 7.2.4 Referred-to segment count and retention flags.
+	if len(referred) <= 4 {
+		w.WriteByte(byte(len(referred)) << 5)
+	} else {
+		writeUint32(w, 0xE0000000|uint32(len(referred)))
+		w.Write(make([]byte, (len(referred)+8)>>3))
+	}
+
+	// 7.2.5 Referred-to segment numbers, the size depends on this segment's number.
+	for _, rt := range referred {
+		switch {
+		case number <= 256:
+			w.WriteByte(byte(rt))
+		case number <= 65536:
+			writeUint16(w, uint16(rt))
+		default:
+			writeUint32(w, rt)
+		}
+	}
+
+	// 7.2.6 Segment page association.
+	if page > 0xff {
+		writeUint32(w, uint32(page))
+	} else {
+		w.WriteByte(byte(page))
+	}
+
+	// 7.2.7 Segment data length.
+	writeUint32(w, uint32(len(data)))
+	w.Write(data)
+}
+
+// pageInformation returns the data of the page information segment (7.4.8)
+// for the page of size 'width' x 'height' and the default pixel value 0. The page is flagged
+// as eventually lossless if 'lossless' is true.
+func pageInformation(width, height, resolutionX, resolutionY int, lossless bool) []byte {
+	var w bytes.Buffer
+	writeUint32(&w, uint32(width))
+	writeUint32(&w, uint32(height))
+	writeUint32(&w, uint32(resolutionX))
+	writeUint32(&w, uint32(resolutionY))
+	// Bit 0: page is eventually lossless, combination operator OR.
+	var flags byte
+	if lossless {
+		flags |= 0x01
+	}
+	w.WriteByte(flags)
+	// No striping.
+	writeUint16(&w, 0)
+	return w.Bytes()
+}
+
+// writeRegionInfo writes the region segment information field (7.4.1) with the OR combination operator.
+func writeRegionInfo(w *bytes.Buffer, width, height, x, y int) {
+	writeUint32(w, uint32(width))
+	writeUint32(w, uint32(height))
+	writeUint32(w, uint32(x))
+	writeUint32(w, uint32(y))
+	w.WriteByte(0)
+}
+
+func writeUint32(w *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	w.Write(b[:])
+}
+
+func writeUint16(w *bytes.Buffer, v uint16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	w.Write(b[:])
+}
@@ -0,0 +1,62 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package encoder
+
+import (
+	"bytes"
+
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/bitmap"
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/encoder/arithmetic"
+)
+
+// symbolDictionary returns the data of the symbol dictionary segment (7.4.2) with the 'symbols'
+// coded directly with the generic region template 0. The symbols should be sorted by their height.
+// All the symbols are exported in the provided order.
+func symbolDictionary(symbols []*bitmap.Bitmap) []byte {
+	var w bytes.Buffer
+
+	// 7.4.2.1.1 Symbol dictionary flags: arithmetic coding, no refinement, SDTEMPLATE 0.
+	writeUint16(&w, 0)
+
+	// 7.4.2.1.2 Symbol dictionary AT flags.
+	for _, at := range genericATPixels {
+		w.WriteByte(byte(at))
+	}
+
+	// 7.4.2.1.4 and 7.4.2.1.5 - the number of exported and new symbols.
+	writeUint32(&w, uint32(len(symbols)))
+	writeUint32(&w, uint32(len(symbols)))
+
+	e := arithmetic.New()
+	var (
+		iadh = arithmetic.NewStats(512)
+		iadw = arithmetic.NewStats(512)
+		iaex = arithmetic.NewStats(512)
+		cx   = arithmetic.NewStats(1 << 16)
+	)
+
+	// 6.5.5 - the symbols are coded by the height classes.
+	var height int
+	for i := 0; i < len(symbols); {
+		e.EncodeInteger(iadh, symbols[i].Height-height)
+		height = symbols[i].Height
+
+		var width int
+		for ; i < len(symbols) && symbols[i].Height == height; i++ {
+			e.EncodeInteger(iadw, symbols[i].Width-width)
+			width = symbols[i].Width
+			encodeGeneric(e, cx, symbols[i], false)
+		}
+		e.EncodeOOB(iadw)
+	}
+
+	// 6.5.10 - the export flags: no symbols are imported, all the new ones are exported.
+	e.EncodeInteger(iaex, 0)
+	e.EncodeInteger(iaex, len(symbols))
+
+	w.Write(e.Flush())
+	return w.Bytes()
+}
@@ -0,0 +1,92 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package encoder
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/unidoc/unidoc/pdf/internal/jbig2/encoder/arithmetic"
+)
+
+// symbolInstance is a symbol placed within a text region.
+type symbolInstance struct {
+	// x and y is the location of the top left corner of the symbol.
+	x, y          int
+	width, height int
+	id            int
+}
+
+// symbolCodeLength returns the length of the symbol IDs for the 'n' symbols (SBSYMCODELEN).
+func symbolCodeLength(n int) uint {
+	var length uint
+	for 1<<length < n {
+		length++
+	}
+	return length
+}
+
+// textRegion returns the data of the text region segment (7.4.3) of the size 'width' x 'height'
+// located at the page origin. The 'numSymbols' is the number of the symbols available to the region.
+// The instances are referred by the bottom left corner and grouped into the strips of the same height.
+func textRegion(width, height int, instances []symbolInstance, numSymbols int) []byte {
+	var w bytes.Buffer
+	writeRegionInfo(&w, width, height, 0, 0)
+
+	// 7.4.3.1.1 Text region segment flags: arithmetic coding, no refinement, SBSTRIPS 1,
+	// REFCORNER bottom left, not transposed, OR combination operator and SBDSOFFSET 0.
+	writeUint16(&w, 0)
+
+	// 7.4.3.1.4 Number of symbol instances.
+	writeUint32(&w, uint32(len(instances)))
+
+	// With the bottom left reference corner the T coordinate is the bottom row of the symbol.
+	sorted := make([]symbolInstance, len(instances))
+	copy(sorted, instances)
+	bottom := func(s symbolInstance) int { return s.y + s.height - 1 }
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if bi, bj := bottom(sorted[i]), bottom(sorted[j]); bi != bj {
+			return bi < bj
+		}
+		return sorted[i].x < sorted[j].x
+	})
+
+	e := arithmetic.New()
+	codeLen := symbolCodeLength(numSymbols)
+	var (
+		iadt = arithmetic.NewStats(512)
+		iafs = arithmetic.NewStats(512)
+		iads = arithmetic.NewStats(512)
+		iaid = arithmetic.NewStats(1 << codeLen)
+	)
+
+	// 6.4.5 - the initial STRIPT.
+	e.EncodeInteger(iadt, 0)
+	var stripT, firstS int
+	for i := 0; i < len(sorted); {
+		t := bottom(sorted[i])
+		e.EncodeInteger(iadt, t-stripT)
+		stripT = t
+
+		var curS int
+		for first := true; i < len(sorted) && bottom(sorted[i]) == t; i++ {
+			s := sorted[i]
+			if first {
+				e.EncodeInteger(iafs, s.x-firstS)
+				firstS = s.x
+				first = false
+			} else {
+				e.EncodeInteger(iads, s.x-curS)
+			}
+			e.EncodeIAID(iaid, codeLen, s.id)
+			curS = s.x + s.width - 1
+		}
+		e.EncodeOOB(iads)
+	}
+
+	w.Write(e.Flush())
+	return w.Bytes()
+}
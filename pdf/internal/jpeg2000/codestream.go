@@ -0,0 +1,639 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpeg2000
+
+import (
+	"encoding/binary"
+
+	"github.com/unidoc/unidoc/common"
+)
+
+// Codestream markers (ITU-T T.800 Annex A).
+const (
+	markerSOC = 0xFF4F
+	markerSIZ = 0xFF51
+	markerCOD = 0xFF52
+	markerCOC = 0xFF53
+	markerTLM = 0xFF55
+	markerPLM = 0xFF57
+	markerPLT = 0xFF58
+	markerQCD = 0xFF5C
+	markerQCC = 0xFF5D
+	markerRGN = 0xFF5E
+	markerPOC = 0xFF5F
+	markerPPM = 0xFF60
+	markerPPT = 0xFF61
+	markerCRG = 0xFF63
+	markerCOM = 0xFF64
+	markerSOT = 0xFF90
+	markerSOP = 0xFF91
+	markerEPH = 0xFF92
+	markerSOD = 0xFF93
+	markerEOC = 0xFFD9
+)
+
+// Progression orders.
+const (
+	progressionLRCP = iota
+	progressionRLCP
+	progressionRPCL
+	progressionPCRL
+	progressionCPRL
+)
+
+// Code-block style flags (Table A.19).
+const (
+	cbBypass        = 0x01
+	cbReset         = 0x02
+	cbTermAll       = 0x04
+	cbCausal        = 0x08
+	cbPredictable   = 0x10
+	cbSegmentSymbol = 0x20
+)
+
+// Quantization styles (Table A.28).
+const (
+	quantNone    = 0
+	quantDerived = 1
+	quantExpound = 2
+)
+
+// imageSize holds the content of the SIZ marker segment.
+type imageSize struct {
+	width, height  int // Xsiz, Ysiz
+	x0, y0         int // XOsiz, YOsiz
+	tileW, tileH   int // XTsiz, YTsiz
+	tileX0, tileY0 int // XTOsiz, YTOsiz
+	components     []componentSize
+	numTilesX      int
+	numTilesY      int
+}
+
+// componentSize holds the per component values of the SIZ marker segment.
+type componentSize struct {
+	precision int
+	signed    bool
+	dx, dy    int
+}
+
+// codingStyle holds the tile wide coding parameters of the COD marker segment.
+type codingStyle struct {
+	progression int
+	layers      int
+	mct         bool
+	sop         bool
+	eph         bool
+}
+
+// componentStyle holds the component specific coding parameters of the COD and COC marker segments.
+type componentStyle struct {
+	levels     int
+	cbw, cbh   int // Code-block size exponents.
+	cbStyle    int
+	reversible bool
+	// precincts holds the precinct size exponents for each resolution level, nil for the maximal size.
+	precincts [][2]int
+}
+
+// quantization holds the content of the QCD and QCC marker segments.
+type quantization struct {
+	style int
+	guard int
+	// steps holds the exponent and mantissa of each subband quantization step.
+	steps [][2]int
+}
+
+// progressionChange is one entry of the POC marker segment.
+type progressionChange struct {
+	resStart, compStart int
+	layerEnd            int
+	resEnd, compEnd     int
+	order               int
+}
+
+// tileParams groups the parameters that can be set in the main header and overridden in tile headers.
+type tileParams struct {
+	coding codingStyle
+	comps  []componentStyle
+	quants []quantization
+	rois   []int
+	pocs   []progressionChange
+
+	// cocSet and qccSet mark the components with component specific parameters in the current header,
+	// which take precedence over the COD and QCD marker segments of the same header.
+	cocSet []bool
+	qccSet []bool
+}
+
+// newTileParams returns the parameters for an image with `numComps` components.
+func newTileParams(numComps int) *tileParams {
+	return &tileParams{
+		coding: codingStyle{layers: 1},
+		comps:  make([]componentStyle, numComps),
+		quants: make([]quantization, numComps),
+		rois:   make([]int, numComps),
+		cocSet: make([]bool, numComps),
+		qccSet: make([]bool, numComps),
+	}
+}
+
+// copy returns a copy of the main header parameters to be changed by tile-part headers.
+func (p *tileParams) copy() *tileParams {
+	c := newTileParams(len(p.comps))
+	c.coding = p.coding
+	copy(c.comps, p.comps)
+	copy(c.quants, p.quants)
+	copy(c.rois, p.rois)
+	c.pocs = append([]progressionChange(nil), p.pocs...)
+	return c
+}
+
+// codestream is the parsed representation of a JPEG 2000 codestream.
+type codestream struct {
+	siz   imageSize
+	main  *tileParams
+	tiles []*tileData
+	// partOrder holds the tile of each tile-part in codestream order.
+	partOrder []*tileData
+}
+
+// tileData collects the tile-part headers and bodies of a tile.
+type tileData struct {
+	index  int
+	params *tileParams
+	// data is the concatenation of the bodies of all the tile-parts.
+	data []byte
+	// headers holds the packed packet headers from PPT or PPM marker segments.
+	headers []byte
+	packed  bool
+}
+
+// byteReader is a simple big endian reader over a byte slice.
+type byteReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *byteReader) u8() int {
+	if r.pos+1 > len(r.data) {
+		r.err = errUnexpectedEOF
+		return 0
+	}
+	v := r.data[r.pos]
+	r.pos++
+	return int(v)
+}
+
+func (r *byteReader) u16() int {
+	if r.pos+2 > len(r.data) {
+		r.err = errUnexpectedEOF
+		return 0
+	}
+	v := binary.BigEndian.Uint16(r.data[r.pos:])
+	r.pos += 2
+	return int(v)
+}
+
+func (r *byteReader) u32() int {
+	if r.pos+4 > len(r.data) {
+		r.err = errUnexpectedEOF
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return int(v)
+}
+
+// parseCodestream parses the main header and collects the tile-parts of the codestream.
+// When `headerOnly` is true, parsing stops after the SIZ marker segment.
+func parseCodestream(data []byte, headerOnly bool) (*codestream, error) {
+	r := &byteReader{data: data}
+	if r.u16() != markerSOC {
+		return nil, errNoCodestream
+	}
+	cs := &codestream{}
+	var ppm []byte
+	for {
+		if r.pos+2 > len(data) {
+			// Missing EOC: decode what is available.
+			common.Log.Debug("JPX codestream truncated, EOC missing")
+			break
+		}
+		marker := r.u16()
+		if marker == markerEOC {
+			break
+		}
+		if marker < 0xFF30 {
+			common.Log.Debug("JPX invalid marker 0x%04x at %d", marker, r.pos-2)
+			return nil, errInvalidMarker
+		}
+		if marker == markerSOT {
+			if cs.tiles == nil {
+				return nil, errInvalidMarker
+			}
+			end, err := cs.parseTilePart(r)
+			if err != nil {
+				return nil, err
+			}
+			r.pos = end
+			continue
+		}
+		length := r.u16()
+		if r.err != nil {
+			return nil, r.err
+		}
+		if length < 2 || r.pos+length-2 > len(data) {
+			return nil, errInvalidMarker
+		}
+		seg := data[r.pos : r.pos+length-2]
+		r.pos += length - 2
+
+		if cs.main == nil && marker != markerSIZ {
+			return nil, errInvalidSIZ
+		}
+		var err error
+		switch marker {
+		case markerSIZ:
+			err = cs.parseSIZ(seg)
+			if err == nil {
+				if headerOnly {
+					return cs, nil
+				}
+				cs.tiles = make([]*tileData, cs.siz.numTilesX*cs.siz.numTilesY)
+				cs.main = newTileParams(len(cs.siz.components))
+			}
+		case markerCOD:
+			err = cs.main.parseCOD(seg)
+		case markerCOC:
+			err = cs.main.parseCOC(seg, len(cs.siz.components))
+		case markerQCD:
+			err = cs.main.parseQCD(seg)
+		case markerQCC:
+			err = cs.main.parseQCC(seg, len(cs.siz.components))
+		case markerRGN:
+			err = cs.main.parseRGN(seg, len(cs.siz.components))
+		case markerPOC:
+			err = cs.main.parsePOC(seg, len(cs.siz.components))
+		case markerPPM:
+			if len(seg) > 0 {
+				ppm = append(ppm, seg[1:]...)
+			}
+		case markerTLM, markerPLM, markerCRG, markerCOM:
+			// Informational only.
+		default:
+			common.Log.Debug("JPX skipping unknown marker 0x%04x", marker)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cs.tiles == nil {
+		return nil, errInvalidSIZ
+	}
+	if ppm != nil {
+		cs.assignPPM(ppm)
+	}
+	return cs, nil
+}
+
+// assignPPM distributes the packed packet headers of the main header to the tiles. The PPM data holds
+// the headers of each tile-part in the order the tile-parts appear in the codestream.
+func (cs *codestream) assignPPM(ppm []byte) {
+	pos := 0
+	for _, t := range cs.partOrder {
+		if pos+4 > len(ppm) {
+			break
+		}
+		n := int(binary.BigEndian.Uint32(ppm[pos:]))
+		pos += 4
+		if pos+n > len(ppm) {
+			n = len(ppm) - pos
+		}
+		t.headers = append(t.headers, ppm[pos:pos+n]...)
+		t.packed = true
+		pos += n
+	}
+}
+
+// parseSIZ reads the image and tile size marker segment.
+func (cs *codestream) parseSIZ(seg []byte) error {
+	r := &byteReader{data: seg}
+	r.u16() // Rsiz: capabilities.
+	siz := &cs.siz
+	siz.width = r.u32()
+	siz.height = r.u32()
+	siz.x0 = r.u32()
+	siz.y0 = r.u32()
+	siz.tileW = r.u32()
+	siz.tileH = r.u32()
+	siz.tileX0 = r.u32()
+	siz.tileY0 = r.u32()
+	numComps := r.u16()
+	for i := 0; i < numComps; i++ {
+		ssiz := r.u8()
+		comp := componentSize{
+			precision: ssiz&0x7F + 1,
+			signed:    ssiz&0x80 != 0,
+			dx:        r.u8(),
+			dy:        r.u8(),
+		}
+		siz.components = append(siz.components, comp)
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if siz.width <= siz.x0 || siz.height <= siz.y0 || siz.tileW <= 0 || siz.tileH <= 0 || numComps == 0 ||
+		siz.tileX0 > siz.x0 || siz.tileY0 > siz.y0 ||
+		siz.tileX0+siz.tileW <= siz.x0 || siz.tileY0+siz.tileH <= siz.y0 {
+		common.Log.Debug("JPX invalid SIZ: %+v", *siz)
+		return errInvalidSIZ
+	}
+	for _, comp := range siz.components {
+		if comp.dx == 0 || comp.dy == 0 || comp.precision > 38 {
+			return errInvalidSIZ
+		}
+		// Sub-sampling factors larger than the image leave components without samples.
+		if siz.componentRect(comp).empty() {
+			common.Log.Debug("JPX component without samples: %+v", comp)
+			return errInvalidSIZ
+		}
+	}
+	siz.numTilesX = ceilDiv(siz.width-siz.tileX0, siz.tileW)
+	siz.numTilesY = ceilDiv(siz.height-siz.tileY0, siz.tileH)
+	if siz.numTilesX*siz.numTilesY > 65535 {
+		return errInvalidSIZ
+	}
+	return nil
+}
+
+// componentRect returns the area of component `comp` on its own sampling grid (B-12).
+func (siz *imageSize) componentRect(comp componentSize) rect {
+	return rect{
+		ceilDiv(siz.x0, comp.dx), ceilDiv(siz.y0, comp.dy),
+		ceilDiv(siz.width, comp.dx), ceilDiv(siz.height, comp.dy),
+	}
+}
+
+// parseComponentStyle reads the SPcod or SPcoc parameters.
+func parseComponentStyle(r *byteReader, usePrecincts bool) (componentStyle, error) {
+	cs := componentStyle{
+		levels:  r.u8(),
+		cbw:     r.u8() + 2,
+		cbh:     r.u8() + 2,
+		cbStyle: r.u8(),
+	}
+	cs.reversible = r.u8() == 1
+	if cs.levels > 32 || cs.cbw > 10 || cs.cbh > 10 || cs.cbw+cs.cbh > 12 {
+		return cs, errInvalidMarker
+	}
+	if usePrecincts {
+		for i := 0; i <= cs.levels; i++ {
+			b := r.u8()
+			cs.precincts = append(cs.precincts, [2]int{b & 0x0F, b >> 4})
+		}
+	}
+	return cs, r.err
+}
+
+// parseCOD reads the coding style default marker segment.
+func (p *tileParams) parseCOD(seg []byte) error {
+	r := &byteReader{data: seg}
+	scod := r.u8()
+	p.coding = codingStyle{
+		progression: r.u8(),
+		layers:      r.u16(),
+		mct:         r.u8() != 0,
+		sop:         scod&0x02 != 0,
+		eph:         scod&0x04 != 0,
+	}
+	if p.coding.progression > progressionCPRL || p.coding.layers == 0 {
+		return errInvalidMarker
+	}
+	style, err := parseComponentStyle(r, scod&0x01 != 0)
+	if err != nil {
+		return err
+	}
+	for i := range p.comps {
+		if !p.cocSet[i] {
+			p.comps[i] = style
+		}
+	}
+	return nil
+}
+
+// componentIndex reads a component index, which takes two bytes in images with more than 256 components.
+func componentIndex(r *byteReader, numComps int) int {
+	if numComps < 257 {
+		return r.u8()
+	}
+	return r.u16()
+}
+
+// parseCOC reads the coding style component marker segment.
+func (p *tileParams) parseCOC(seg []byte, numComps int) error {
+	r := &byteReader{data: seg}
+	c := componentIndex(r, numComps)
+	scoc := r.u8()
+	style, err := parseComponentStyle(r, scoc&0x01 != 0)
+	if err != nil {
+		return err
+	}
+	if c >= numComps {
+		return errInvalidMarker
+	}
+	p.comps[c] = style
+	p.cocSet[c] = true
+	return nil
+}
+
+// parseQuantization reads the Sqcd/Sqcc and SPqcd/SPqcc parameters.
+func parseQuantization(r *byteReader) (quantization, error) {
+	sq := r.u8()
+	q := quantization{style: sq & 0x1F, guard: sq >> 5}
+	for r.err == nil && r.pos < len(r.data) {
+		switch q.style {
+		case quantNone:
+			q.steps = append(q.steps, [2]int{r.u8() >> 3, 0})
+		case quantDerived, quantExpound:
+			v := r.u16()
+			q.steps = append(q.steps, [2]int{v >> 11, v & 0x7FF})
+		default:
+			return q, errInvalidMarker
+		}
+	}
+	if len(q.steps) == 0 {
+		return q, errInvalidMarker
+	}
+	return q, r.err
+}
+
+// parseQCD reads the quantization default marker segment.
+func (p *tileParams) parseQCD(seg []byte) error {
+	q, err := parseQuantization(&byteReader{data: seg})
+	if err != nil {
+		return err
+	}
+	for i := range p.quants {
+		if !p.qccSet[i] {
+			p.quants[i] = q
+		}
+	}
+	return nil
+}
+
+// parseQCC reads the quantization component marker segment.
+func (p *tileParams) parseQCC(seg []byte, numComps int) error {
+	r := &byteReader{data: seg}
+	c := componentIndex(r, numComps)
+	q, err := parseQuantization(r)
+	if err != nil {
+		return err
+	}
+	if c >= numComps {
+		return errInvalidMarker
+	}
+	p.quants[c] = q
+	p.qccSet[c] = true
+	return nil
+}
+
+// parseRGN reads the region of interest marker segment. Only the implicit (max shift) method exists.
+func (p *tileParams) parseRGN(seg []byte, numComps int) error {
+	r := &byteReader{data: seg}
+	c := componentIndex(r, numComps)
+	r.u8() // Srgn: always 0.
+	shift := r.u8()
+	if r.err != nil || c >= numComps {
+		return errInvalidMarker
+	}
+	p.rois[c] = shift
+	return nil
+}
+
+// parsePOC reads the progression order change marker segment.
+func (p *tileParams) parsePOC(seg []byte, numComps int) error {
+	r := &byteReader{data: seg}
+	p.pocs = nil
+	for r.pos < len(seg) {
+		var pc progressionChange
+		pc.resStart = r.u8()
+		pc.compStart = componentIndex(r, numComps)
+		pc.layerEnd = r.u16()
+		pc.resEnd = r.u8()
+		pc.compEnd = componentIndex(r, numComps)
+		if pc.compEnd == 0 {
+			pc.compEnd = 256
+		}
+		pc.order = r.u8()
+		if r.err != nil {
+			return r.err
+		}
+		if pc.order > progressionCPRL {
+			return errInvalidMarker
+		}
+		p.pocs = append(p.pocs, pc)
+	}
+	return nil
+}
+
+// parseTilePart reads a tile-part starting after the SOT marker. It returns the position of the
+// end of the tile-part.
+func (cs *codestream) parseTilePart(r *byteReader) (int, error) {
+	start := r.pos - 2
+	r.u16() // Lsot.
+	index := r.u16()
+	length := r.u32()
+	r.u8() // TPsot.
+	r.u8() // TNsot.
+	if r.err != nil {
+		return 0, r.err
+	}
+	if index >= len(cs.tiles) {
+		common.Log.Debug("JPX invalid tile index %d", index)
+		return 0, errInvalidMarker
+	}
+	end := start + length
+	if length == 0 || end > len(r.data) {
+		// The last tile-part lasts until the EOC marker.
+		end = len(r.data)
+		if end >= 2 && r.data[end-2] == 0xFF && r.data[end-1] == 0xD9 {
+			end -= 2
+		}
+	}
+
+	t := cs.tiles[index]
+	first := t == nil
+	if first {
+		t = &tileData{index: index, params: cs.main.copy()}
+		cs.tiles[index] = t
+	}
+	cs.partOrder = append(cs.partOrder, t)
+	numComps := len(cs.siz.components)
+	for {
+		marker := r.u16()
+		if r.err != nil {
+			return 0, r.err
+		}
+		if marker == markerSOD {
+			break
+		}
+		length := r.u16()
+		if r.err != nil || length < 2 || r.pos+length-2 > end {
+			return 0, errInvalidMarker
+		}
+		seg := r.data[r.pos : r.pos+length-2]
+		r.pos += length - 2
+
+		var err error
+		switch marker {
+		case markerCOD:
+			if first {
+				err = t.params.parseCOD(seg)
+			}
+		case markerCOC:
+			if first {
+				err = t.params.parseCOC(seg, numComps)
+			}
+		case markerQCD:
+			if first {
+				err = t.params.parseQCD(seg)
+			}
+		case markerQCC:
+			if first {
+				err = t.params.parseQCC(seg, numComps)
+			}
+		case markerRGN:
+			if first {
+				err = t.params.parseRGN(seg, numComps)
+			}
+		case markerPOC:
+			err = t.params.parsePOC(seg, numComps)
+		case markerPPT:
+			if len(seg) > 0 {
+				t.headers = append(t.headers, seg[1:]...)
+				t.packed = true
+			}
+		case markerPLT, markerCOM:
+			// Informational only.
+		default:
+			common.Log.Debug("JPX skipping unknown tile-part marker 0x%04x", marker)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if r.pos > end {
+		return 0, errInvalidMarker
+	}
+	t.data = append(t.data, r.data[r.pos:end]...)
+	return end, nil
+}
+
+// ceilDiv returns ceil(a/b) for a non negative `a` and positive `b`.
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
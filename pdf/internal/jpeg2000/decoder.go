@@ -0,0 +1,559 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpeg2000
+
+import (
+	"math"
+	"sort"
+
+	"github.com/unidoc/unidoc/common"
+)
+
+// maxPrecision is the maximal bit depth of the decoded channels. Samples with higher precision are scaled down.
+const maxPrecision = 16
+
+// DefaultMaxPixels is the default limit of the number of pixels of decoded images.
+const DefaultMaxPixels = 1 << 26
+
+// maxSamplesPerPixel limits the total number of samples of the component planes relative to the
+// pixel limit. It allows for full size CMYK and opacity components.
+const maxSamplesPerPixel = 5
+
+// Image is a decoded JPEG 2000 image.
+type Image struct {
+	Width  int
+	Height int
+	// ColorSpace is the colour space specified in the JP2 header, or derived from the number of
+	// colour channels of a raw codestream.
+	ColorSpace ColorSpace
+	// Channels holds the colour channels, ordered by their colour association.
+	Channels []*Channel
+	// Alpha holds the opacity channel or nil if the image has none.
+	Alpha *Channel
+	// PremultipliedAlpha indicates that the colour channels are premultiplied with the opacity.
+	PremultipliedAlpha bool
+}
+
+// Channel holds the samples of an image channel.
+type Channel struct {
+	// Precision is the number of bits of each sample.
+	Precision int
+	// Data holds Width*Height unsigned samples in row-major order.
+	Data []uint16
+}
+
+// Config describes an image without decoding it.
+type Config struct {
+	Width       int
+	Height      int
+	ColorSpace  ColorSpace
+	NumChannels int
+	// Precision is the highest bit depth of the colour channels.
+	Precision int
+	HasAlpha  bool
+}
+
+// DecodeOptions control the conversion of the decoded components to the image channels.
+type DecodeOptions struct {
+	// IgnorePalette returns the palette indices instead of applying the JP2 palette. It is used when
+	// the colour space is specified outside of the JPEG 2000 data, e.g. as an Indexed PDF colour space.
+	IgnorePalette bool
+	// IgnoreColorSpace disables the colour conversions implied by the JP2 colour specification (sYCC).
+	IgnoreColorSpace bool
+	// Width and Height are the expected image size, e.g. from the PDF image dictionary. When nonzero,
+	// images of a different size are rejected before any sample memory is allocated.
+	Width, Height int
+	// MaxPixels limits the number of pixels of the image. DefaultMaxPixels is used when it is zero.
+	MaxPixels int
+}
+
+// channelSpec describes how an output channel is obtained from the codestream components.
+type channelSpec struct {
+	component int
+	// column is the palette column applied to the component or -1.
+	column      int
+	precision   int
+	typ         int
+	association int
+}
+
+// DecodeConfig returns the dimensions and channel layout of the JPEG 2000 image `data`.
+func DecodeConfig(data []byte, opts *DecodeOptions) (Config, error) {
+	if opts == nil {
+		opts = &DecodeOptions{}
+	}
+	hdr, codestreamData, err := parseJP2(data)
+	if err != nil {
+		return Config{}, err
+	}
+	cs, err := parseCodestream(codestreamData, true)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := checkImageSize(&cs.siz, opts); err != nil {
+		return Config{}, err
+	}
+	specs, err := channelLayout(hdr, &cs.siz, opts)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg := Config{
+		Width:  cs.siz.width - cs.siz.x0,
+		Height: cs.siz.height - cs.siz.y0,
+	}
+	for _, spec := range specs {
+		if spec.typ == channelColor {
+			cfg.NumChannels++
+			cfg.Precision = maxInt(cfg.Precision, minInt(spec.precision, maxPrecision))
+		} else {
+			cfg.HasAlpha = true
+		}
+	}
+	cfg.ColorSpace = colorSpace(hdr, cfg.NumChannels, opts)
+	return cfg, nil
+}
+
+// Decode decodes the JPEG 2000 codestream or JP2/JPX file `data`.
+func Decode(data []byte, opts *DecodeOptions) (*Image, error) {
+	if opts == nil {
+		opts = &DecodeOptions{}
+	}
+	hdr, codestreamData, err := parseJP2(data)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := parseCodestream(codestreamData, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkImageSize(&cs.siz, opts); err != nil {
+		return nil, err
+	}
+	specs, err := channelLayout(hdr, &cs.siz, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	d := newDecoder(cs)
+	for _, t := range cs.tiles {
+		if t == nil {
+			continue
+		}
+		if err := d.decodeTile(t); err != nil {
+			return nil, err
+		}
+	}
+
+	img := &Image{Width: cs.siz.width - cs.siz.x0, Height: cs.siz.height - cs.siz.y0}
+	var pal *palette
+	if hdr != nil {
+		pal = hdr.palette
+	}
+	for _, spec := range specs {
+		ch := d.channel(spec, pal, img.Width, img.Height)
+		switch spec.typ {
+		case channelColor:
+			img.Channels = append(img.Channels, ch)
+		case channelOpacity, channelPremultiplied:
+			if img.Alpha == nil {
+				img.Alpha = ch
+				img.PremultipliedAlpha = spec.typ == channelPremultiplied
+			}
+		}
+	}
+	img.ColorSpace = colorSpace(hdr, len(img.Channels), opts)
+	if hdr != nil && hdr.colorSpace == ColorSpaceYCC && img.ColorSpace == ColorSpaceRGB {
+		yccToRGB(img.Channels)
+	}
+	return img, nil
+}
+
+// checkImageSize checks the image size `siz` against the expected size and the pixel limit of `opts`.
+func checkImageSize(siz *imageSize, opts *DecodeOptions) error {
+	width, height := siz.width-siz.x0, siz.height-siz.y0
+	if opts.Width > 0 && opts.Height > 0 && (width != opts.Width || height != opts.Height) {
+		common.Log.Debug("JPX image size %dx%d does not match the expected size %dx%d",
+			width, height, opts.Width, opts.Height)
+		return errSizeMismatch
+	}
+	maxPixels := opts.MaxPixels
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+	// The sizes are checked separately to avoid overflows of the product.
+	if width > maxPixels || height > maxPixels || int64(width)*int64(height) > int64(maxPixels) {
+		common.Log.Debug("JPX image size %dx%d exceeds the limit of %d pixels", width, height, maxPixels)
+		return errImageTooLarge
+	}
+	samples := int64(0)
+	for _, comp := range siz.components {
+		r := siz.componentRect(comp)
+		samples += int64(r.width()) * int64(r.height())
+	}
+	if samples > maxSamplesPerPixel*int64(maxPixels) {
+		common.Log.Debug("JPX image with %d components exceeds the sample limit", len(siz.components))
+		return errImageTooLarge
+	}
+	return nil
+}
+
+// colorChannels holds the number of colour channels of the colour spaces with a fixed number of channels.
+var colorChannels = map[ColorSpace]int{ColorSpaceGray: 1, ColorSpaceRGB: 3, ColorSpaceYCC: 3, ColorSpaceCMYK: 4}
+
+// colorSpace determines the colour space of the decoded image with `n` colour channels.
+func colorSpace(hdr *jp2Header, n int, opts *DecodeOptions) ColorSpace {
+	if hdr != nil && hdr.colorSpace != ColorSpaceUnknown {
+		expected, ok := colorChannels[hdr.colorSpace]
+		switch {
+		case ok && expected != n:
+			// E.g. palette indices returned as such.
+		case hdr.colorSpace == ColorSpaceYCC && !opts.IgnoreColorSpace:
+			return ColorSpaceRGB
+		default:
+			return hdr.colorSpace
+		}
+	}
+	switch n {
+	case 1:
+		return ColorSpaceGray
+	case 3:
+		return ColorSpaceRGB
+	case 4:
+		return ColorSpaceCMYK
+	}
+	return ColorSpaceUnknown
+}
+
+// channelLayout determines the output channels from the JP2 header boxes (I.5.3).
+func channelLayout(hdr *jp2Header, siz *imageSize, opts *DecodeOptions) ([]channelSpec, error) {
+	var specs []channelSpec
+	// index maps the JP2 channels to their specs, -1 for channels which are not output.
+	var index []int
+	if hdr != nil && hdr.palette != nil && len(hdr.mapping) > 0 {
+		indexed := map[int]bool{}
+		for _, m := range hdr.mapping {
+			if m.component >= len(siz.components) {
+				return nil, errInvalidMarker
+			}
+			spec := channelSpec{component: m.component, column: -1, precision: siz.components[m.component].precision}
+			if m.usePalette {
+				if m.column >= len(hdr.palette.values) {
+					return nil, errInvalidMarker
+				}
+				if opts.IgnorePalette {
+					// The channels obtained from the same palette indices are replaced by the indices.
+					if indexed[m.component] {
+						index = append(index, -1)
+						continue
+					}
+					indexed[m.component] = true
+				} else {
+					spec.column = m.column
+					spec.precision = hdr.palette.precision[m.column]
+				}
+			}
+			index = append(index, len(specs))
+			specs = append(specs, spec)
+		}
+	} else {
+		for c, comp := range siz.components {
+			index = append(index, c)
+			specs = append(specs, channelSpec{component: c, column: -1, precision: comp.precision})
+		}
+	}
+	for i := range specs {
+		specs[i].association = i + 1
+	}
+
+	if hdr != nil && len(hdr.channels) > 0 {
+		for _, def := range hdr.channels {
+			if def.channel >= len(index) || index[def.channel] < 0 {
+				continue
+			}
+			spec := &specs[index[def.channel]]
+			spec.typ = def.typ
+			spec.association = def.association
+			if def.typ > channelPremultiplied {
+				spec.typ = -1 // Unspecified channels are not used.
+			}
+		}
+		// Order the colour channels by their association.
+		sort.SliceStable(specs, func(i, j int) bool {
+			a, b := specs[i], specs[j]
+			if (a.typ == channelColor) != (b.typ == channelColor) {
+				return a.typ == channelColor
+			}
+			return a.typ == channelColor && a.association < b.association
+		})
+	} else if hdr != nil {
+		// Without channel definitions, an extra channel after the expected colour channels is opacity.
+		if n, ok := colorChannels[hdr.colorSpace]; ok && len(specs) == n+1 {
+			specs[n].typ = channelOpacity
+		}
+	}
+	return specs, nil
+}
+
+// decoder holds the decoded components of an image.
+type decoder struct {
+	cs *codestream
+	// planes holds the samples of each component as unsigned values.
+	planes []componentPlane
+	t1     t1Decoder
+}
+
+// componentPlane is a component of the image at its own sampling.
+type componentPlane struct {
+	rect
+	data []int32
+}
+
+// newDecoder allocates the component planes of the codestream `cs`.
+func newDecoder(cs *codestream) *decoder {
+	d := &decoder{cs: cs}
+	siz := &cs.siz
+	for _, comp := range siz.components {
+		p := componentPlane{rect: siz.componentRect(comp)}
+		p.data = make([]int32, p.width()*p.height())
+		d.planes = append(d.planes, p)
+	}
+	return d
+}
+
+// tileRect returns the area of tile `index` on the reference grid (B-7).
+func (siz *imageSize) tileRect(index int) rect {
+	p, q := index%siz.numTilesX, index/siz.numTilesX
+	return rect{
+		maxInt(siz.tileX0+p*siz.tileW, siz.x0),
+		maxInt(siz.tileY0+q*siz.tileH, siz.y0),
+		minInt(siz.tileX0+(p+1)*siz.tileW, siz.width),
+		minInt(siz.tileY0+(q+1)*siz.tileH, siz.height),
+	}
+}
+
+// decodeTile decodes tile `t` into the component planes.
+func (d *decoder) decodeTile(t *tileData) error {
+	siz := &d.cs.siz
+	params := t.params
+	tr := siz.tileRect(t.index)
+	comps := make([]*tileComponent, len(siz.components))
+	for c := range comps {
+		style := &params.comps[c]
+		if style.cbw == 0 {
+			common.Log.Debug("JPX missing COD marker segment")
+			return errInvalidMarker
+		}
+		tc, err := newTileComponent(tr, siz.components[c], style, &params.quants[c], params.rois[c])
+		if err != nil {
+			return err
+		}
+		comps[c] = tc
+	}
+
+	// Tier-2: read the packets.
+	ps := &packetSource{
+		body:   t.data,
+		hdr:    &bitReader{},
+		packed: t.packed,
+		sop:    params.coding.sop,
+		eph:    params.coding.eph,
+	}
+	if t.packed {
+		ps.hdr.data = t.headers
+	}
+	po := &packetOrder{
+		comps:  comps,
+		layers: params.coding.layers,
+		visit: func(c, r int, p *precinct, layer int) error {
+			return ps.readPacket(comps[c].resolutions[r], p, layer, comps[c].style.cbStyle)
+		},
+	}
+	progressions := params.pocs
+	if len(progressions) == 0 {
+		progressions = []progressionChange{{
+			order:    params.coding.progression,
+			layerEnd: params.coding.layers,
+			resEnd:   math.MaxInt32,
+			compEnd:  len(comps),
+		}}
+	}
+	for _, pc := range progressions {
+		if err := po.run(pc, tr, siz); err != nil {
+			if err != errUnexpectedEOF {
+				return err
+			}
+			common.Log.Debug("JPX tile %d truncated, decoding available data", t.index)
+			break
+		}
+	}
+
+	// Tier-1, dequantization and inverse wavelet transformation.
+	samples := make([][]float32, len(comps))
+	for c, tc := range comps {
+		d.decodeCodeBlocks(tc)
+		ws := &waveletSynthesizer{reversible: tc.style.reversible}
+		samples[c] = ws.reconstruct(tc)
+	}
+	if params.coding.mct && len(comps) >= 3 {
+		if comps[0].rect == comps[1].rect && comps[0].rect == comps[2].rect {
+			inverseMCT(samples[0], samples[1], samples[2], comps[0].style.reversible)
+		} else {
+			common.Log.Debug("JPX MCT on components of different sizes ignored")
+		}
+	}
+	for c, tc := range comps {
+		d.storeTileComponent(c, tc, samples[c])
+	}
+	return nil
+}
+
+// decodeCodeBlocks decodes the code-blocks of tile-component `tc` into the subband coefficients.
+func (d *decoder) decodeCodeBlocks(tc *tileComponent) {
+	for _, res := range tc.resolutions {
+		for _, p := range res.precincts {
+			for bi, pb := range p.bands {
+				band := res.bands[bi]
+				for _, cb := range pb.blocks {
+					if len(cb.segments) == 0 {
+						continue
+					}
+					numBps := band.numBps - cb.zeroPlanes
+					if numBps <= 0 {
+						continue
+					}
+					w, h := cb.width(), cb.height()
+					d.t1.reset(w, h, band.orientation, tc.style.cbStyle, numBps)
+					d.t1.decode(cb, numBps)
+					if tc.roiShift > 0 {
+						d.t1.applyROI(tc.roiShift)
+					}
+					bw := band.width()
+					for y := 0; y < h; y++ {
+						row := band.coefs[(cb.y0-band.y0+y)*bw+cb.x0-band.x0:]
+						for x := 0; x < w; x++ {
+							row[x] = d.t1.value(x, y, tc.style.reversible) * band.delta
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// inverseMCT applies the inverse multiple component transformation (G.2 and G.3).
+func inverseMCT(c0, c1, c2 []float32, reversible bool) {
+	if reversible {
+		for i := range c0 {
+			y0, y1, y2 := c0[i], c1[i], c2[i]
+			g := y0 - float32(math.Floor(float64(y2+y1)/4))
+			c0[i] = y2 + g
+			c1[i] = g
+			c2[i] = y1 + g
+		}
+		return
+	}
+	for i := range c0 {
+		y, cb, cr := c0[i], c1[i], c2[i]
+		c0[i] = y + 1.402*cr
+		c1[i] = y - 0.34413*cb - 0.71414*cr
+		c2[i] = y + 1.772*cb
+	}
+}
+
+// storeTileComponent level shifts and clips the samples of tile-component `tc` into the plane of component `c`.
+func (d *decoder) storeTileComponent(c int, tc *tileComponent, samples []float32) {
+	plane := &d.planes[c]
+	comp := d.cs.siz.components[c]
+	shift := float32(int64(1) << uint(comp.precision-1))
+	max := int32(int64(1)<<uint(comp.precision) - 1)
+	if comp.precision > 31 {
+		max = math.MaxInt32
+	}
+	w := tc.width()
+	pw := plane.width()
+	for y := tc.y0; y < tc.y1; y++ {
+		src := samples[(y-tc.y0)*w:]
+		dst := plane.data[(y-plane.y0)*pw+tc.x0-plane.x0:]
+		for x := 0; x < w; x++ {
+			v := int32(math.Floor(float64(src[x]+shift) + 0.5))
+			if v < 0 {
+				v = 0
+			} else if v > max {
+				v = max
+			}
+			dst[x] = v
+		}
+	}
+}
+
+// channel builds the output channel described by `spec` at the full image size.
+func (d *decoder) channel(spec channelSpec, pal *palette, width, height int) *Channel {
+	plane := &d.planes[spec.component]
+	comp := d.cs.siz.components[spec.component]
+	siz := &d.cs.siz
+	ch := &Channel{Precision: minInt(spec.precision, maxPrecision), Data: make([]uint16, width*height)}
+	downshift := uint(0)
+	if spec.precision > maxPrecision {
+		downshift = uint(spec.precision - maxPrecision)
+	}
+	var lut []int32
+	if spec.column >= 0 {
+		lut = pal.values[spec.column]
+	}
+	pw := plane.width()
+	for y := 0; y < height; y++ {
+		py := minInt((siz.y0+y)/comp.dy-plane.y0, plane.height()-1)
+		if py < 0 {
+			py = 0
+		}
+		row := plane.data[py*pw:]
+		out := ch.Data[y*width:]
+		for x := 0; x < width; x++ {
+			px := minInt((siz.x0+x)/comp.dx-plane.x0, pw-1)
+			if px < 0 {
+				px = 0
+			}
+			v := row[px]
+			if lut != nil {
+				if comp.signed {
+					v -= int32(1) << uint(comp.precision-1)
+				}
+				if v < 0 {
+					v = 0
+				} else if int(v) >= len(lut) {
+					v = int32(len(lut) - 1)
+				}
+				v = lut[v]
+			}
+			out[x] = uint16(uint32(v) >> downshift)
+		}
+	}
+	return ch
+}
+
+// yccToRGB converts sYCC channels to RGB in place.
+func yccToRGB(channels []*Channel) {
+	if len(channels) != 3 {
+		return
+	}
+	maxVal := float64(int(1)<<uint(channels[0].Precision) - 1)
+	mid := float64(int(1) << uint(channels[0].Precision-1))
+	clip := func(v float64) uint16 {
+		if v < 0 {
+			return 0
+		} else if v > maxVal {
+			return uint16(maxVal)
+		}
+		return uint16(v + 0.5)
+	}
+	y, cb, cr := channels[0].Data, channels[1].Data, channels[2].Data
+	for i := range y {
+		fy, fcb, fcr := float64(y[i]), float64(cb[i])-mid, float64(cr[i])-mid
+		y[i] = clip(fy + 1.402*fcr)
+		cb[i] = clip(fy - 0.344136*fcb - 0.714136*fcr)
+		cr[i] = clip(fy + 1.772*fcb)
+	}
+}
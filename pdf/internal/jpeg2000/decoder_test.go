@@ -0,0 +1,328 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpeg2000
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMQDecoder checks the arithmetic decoder against the test sequence of ITU-T T.88 Annex H.2.
+func TestMQDecoder(t *testing.T) {
+	encoded := []byte{
+		0x84, 0xC7, 0x3B, 0xFC, 0xE1, 0xA1, 0x43, 0x04, 0x02, 0x20, 0x00, 0x00, 0x41, 0x0D, 0xBB, 0x86,
+		0xF4, 0x31, 0x7F, 0xFF, 0x88, 0xFF, 0x37, 0x47, 0x1A, 0xDB, 0x6A, 0xDF, 0xFF, 0xAC,
+	}
+	expected := []byte{
+		0x00, 0x02, 0x00, 0x51, 0x00, 0x00, 0x00, 0xC0, 0x03, 0x52, 0x87, 0x2A, 0xAA, 0xAA, 0xAA, 0xAA,
+		0x82, 0xC0, 0x20, 0x00, 0xFC, 0xD7, 0x9E, 0xF6, 0xBF, 0x7F, 0xED, 0x90, 0x4F, 0x46, 0xA3, 0xBF,
+	}
+	var d mqDecoder
+	d.init(encoded)
+	var cx uint8
+	decoded := make([]byte, len(expected))
+	for i := range decoded {
+		for b := 7; b >= 0; b-- {
+			decoded[i] |= byte(d.decode(&cx) << uint(b))
+		}
+	}
+	assert.Equal(t, expected, decoded)
+}
+
+// TestMQRoundTrip checks that decisions coded with several contexts are decoded back.
+func TestMQRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	bits := make([]int, 5000)
+	for i := range bits {
+		// Skewed distribution to exercise both the MPS and LPS paths.
+		if rnd.Intn(10) < 2 {
+			bits[i] = 1
+		}
+	}
+	var e mqEncoder
+	e.init()
+	var ectx [3]uint8
+	for i, b := range bits {
+		e.encode(b, &ectx[i%3])
+	}
+	data := e.flush()
+
+	var d mqDecoder
+	d.init(data)
+	var dctx [3]uint8
+	for i, b := range bits {
+		require.Equal(t, b, d.decode(&dctx[i%3]), "decision %d", i)
+	}
+}
+
+func randomComponent(rnd *rand.Rand, n, precision int) []int32 {
+	data := make([]int32, n)
+	for i := range data {
+		data[i] = int32(rnd.Intn(1 << uint(precision)))
+	}
+	return data
+}
+
+// smoothComponent returns a gradient with some noise, which gives a more natural coefficient distribution.
+func smoothComponent(rnd *rand.Rand, w, h, precision int) []int32 {
+	max := 1<<uint(precision) - 1
+	data := make([]int32, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := (x*max)/w/2 + (y*max)/h/2 + rnd.Intn(5) - 2
+			data[y*w+x] = int32(minInt(maxInt(v, 0), max))
+		}
+	}
+	return data
+}
+
+func TestDecodeLossless(t *testing.T) {
+	testcases := []struct {
+		name          string
+		width, height int
+		precision     int
+		numComps      int
+		levels        int
+		xcb, ycb      int
+		cbStyle       int
+		mct           bool
+	}{
+		{"gray no transform", 8, 8, 8, 1, 0, 6, 6, 0, false},
+		{"gray one level", 16, 16, 8, 1, 1, 6, 6, 0, false},
+		{"gray odd size", 13, 7, 8, 1, 2, 2, 2, 0, false},
+		{"gray small blocks", 40, 33, 8, 1, 3, 2, 3, 0, false},
+		{"gray 12 bit", 20, 20, 12, 1, 2, 4, 4, 0, false},
+		{"gray 1 bit", 9, 11, 1, 1, 1, 2, 2, 0, false},
+		{"gray single row", 17, 1, 8, 1, 2, 3, 3, 0, false},
+		{"rgb mct", 24, 18, 8, 3, 3, 3, 3, 0, true},
+		{"rgb no mct", 11, 9, 8, 3, 1, 6, 6, 0, false},
+		{"termall", 19, 15, 8, 1, 2, 3, 2, cbTermAll, false},
+		{"reset", 19, 15, 8, 1, 2, 3, 2, cbReset, false},
+		{"causal", 19, 15, 8, 1, 2, 3, 3, cbCausal, false},
+		{"segmentation symbols", 19, 15, 8, 1, 2, 3, 3, cbSegmentSymbol, false},
+		{"all styles", 21, 14, 8, 3, 2, 3, 2, cbTermAll | cbReset | cbCausal | cbSegmentSymbol, true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(int64(tc.width * tc.height)))
+			for _, smooth := range []bool{false, true} {
+				img := &testImage{
+					width: tc.width, height: tc.height, precision: tc.precision,
+					levels: tc.levels, xcb: tc.xcb, ycb: tc.ycb, cbStyle: tc.cbStyle, mct: tc.mct,
+				}
+				var expected [][]uint16
+				for c := 0; c < tc.numComps; c++ {
+					var data []int32
+					if smooth {
+						data = smoothComponent(rnd, tc.width, tc.height, tc.precision)
+					} else {
+						data = randomComponent(rnd, tc.width*tc.height, tc.precision)
+					}
+					img.comps = append(img.comps, data)
+					exp := make([]uint16, len(data))
+					for i, v := range data {
+						exp[i] = uint16(v)
+					}
+					expected = append(expected, exp)
+				}
+				data := encodeTestImage(img)
+
+				cfg, err := DecodeConfig(data, nil)
+				require.NoError(t, err)
+				assert.Equal(t, tc.width, cfg.Width)
+				assert.Equal(t, tc.height, cfg.Height)
+				assert.Equal(t, tc.numComps, cfg.NumChannels)
+				assert.Equal(t, tc.precision, cfg.Precision)
+				assert.False(t, cfg.HasAlpha)
+
+				decoded, err := Decode(data, nil)
+				require.NoError(t, err)
+				require.Len(t, decoded.Channels, tc.numComps)
+				for c, ch := range decoded.Channels {
+					assert.Equal(t, tc.precision, ch.Precision)
+					require.Equal(t, expected[c], ch.Data, "component %d", c)
+				}
+			}
+		})
+	}
+}
+
+// TestDecodeTruncated checks that the available data of a truncated codestream is decoded.
+func TestDecodeTruncated(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	img := &testImage{width: 32, height: 32, precision: 8, levels: 2, xcb: 4, ycb: 4}
+	img.comps = [][]int32{smoothComponent(rnd, 32, 32, 8)}
+	data := encodeTestImage(img)
+
+	decoded, err := Decode(data[:len(data)*2/3], nil)
+	require.NoError(t, err)
+	require.Len(t, decoded.Channels, 1)
+	assert.Len(t, decoded.Channels[0].Data, 32*32)
+}
+
+func TestDecodeJP2(t *testing.T) {
+	rnd := rand.New(rand.NewSource(5))
+	const w, h = 10, 6
+	img := &testImage{width: w, height: h, precision: 8, levels: 1, xcb: 4, ycb: 4}
+	// Palette indices and an opacity channel.
+	indices := make([]int32, w*h)
+	for i := range indices {
+		indices[i] = int32(rnd.Intn(4))
+	}
+	alpha := randomComponent(rnd, w*h, 8)
+	img.comps = [][]int32{indices, alpha}
+	codestream := encodeTestImage(img)
+
+	lut := [][3]byte{{0, 0, 0}, {255, 0, 0}, {0, 255, 0}, {10, 20, 30}}
+	pclr := []byte{0, byte(len(lut)), 3, 7, 7, 7}
+	for _, e := range lut {
+		pclr = append(pclr, e[:]...)
+	}
+	cmap := []byte{0, 0, 1, 0, 0, 0, 1, 1, 0, 0, 1, 2, 0, 1, 0, 0}
+	cdef := []byte{0, 4, 0, 0, 0, 0, 0, 1, 0, 1, 0, 0, 0, 2, 0, 2, 0, 0, 0, 3, 0, 3, 0, 1, 0, 0}
+	var jp2h []byte
+	jp2h = appendBox(jp2h, boxImageHeader, make([]byte, 14))
+	jp2h = appendBox(jp2h, boxColourSpec, []byte{1, 0, 0, 0, 0, 0, 16})
+	jp2h = appendBox(jp2h, boxPalette, pclr)
+	jp2h = appendBox(jp2h, boxComponentMap, cmap)
+	jp2h = appendBox(jp2h, boxChannelDef, cdef)
+	var data []byte
+	data = appendBox(data, boxSignature, []byte{0x0D, 0x0A, 0x87, 0x0A})
+	data = appendBox(data, boxFileType, []byte{'j', 'p', '2', ' ', 0, 0, 0, 0, 'j', 'p', '2', ' '})
+	data = appendBox(data, boxHeader, jp2h)
+	data = appendBox(data, boxCodestream, codestream)
+
+	t.Run("palette", func(t *testing.T) {
+		cfg, err := DecodeConfig(data, nil)
+		require.NoError(t, err)
+		assert.Equal(t, Config{Width: w, Height: h, ColorSpace: ColorSpaceRGB, NumChannels: 3, Precision: 8, HasAlpha: true}, cfg)
+
+		decoded, err := Decode(data, nil)
+		require.NoError(t, err)
+		assert.Equal(t, ColorSpaceRGB, decoded.ColorSpace)
+		require.Len(t, decoded.Channels, 3)
+		require.NotNil(t, decoded.Alpha)
+		assert.False(t, decoded.PremultipliedAlpha)
+		for i, idx := range indices {
+			for c := 0; c < 3; c++ {
+				require.Equal(t, uint16(lut[idx][c]), decoded.Channels[c].Data[i])
+			}
+			require.Equal(t, uint16(alpha[i]), decoded.Alpha.Data[i])
+		}
+	})
+
+	t.Run("ignore palette", func(t *testing.T) {
+		decoded, err := Decode(data, &DecodeOptions{IgnorePalette: true})
+		require.NoError(t, err)
+		require.Len(t, decoded.Channels, 1)
+		require.NotNil(t, decoded.Alpha)
+		for i, idx := range indices {
+			require.Equal(t, uint16(idx), decoded.Channels[0].Data[i])
+		}
+	})
+}
+
+// TestDecodeExternal decodes an image written by another encoder (see testdata/README.md), a photo
+// of a stream between mossy rocks with the white word "relax" at the bottom.
+func TestDecodeExternal(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/kakadu-rgb.jp2")
+	require.NoError(t, err)
+	_, codestream, err := parseJP2(data)
+	require.NoError(t, err)
+
+	cfg, err := DecodeConfig(data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, Config{Width: 400, Height: 300, ColorSpace: ColorSpaceICC, NumChannels: 3, Precision: 8}, cfg)
+
+	jp2, err := Decode(data, nil)
+	require.NoError(t, err)
+	raw, err := Decode(codestream, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ColorSpaceICC, jp2.ColorSpace)
+	assert.Equal(t, ColorSpaceRGB, raw.ColorSpace)
+
+	for _, decoded := range []*Image{jp2, raw} {
+		require.Equal(t, 400, decoded.Width)
+		require.Equal(t, 300, decoded.Height)
+		require.Len(t, decoded.Channels, 3)
+		assert.Nil(t, decoded.Alpha)
+
+		// Average of the samples of the channel 'c' in the rectangle (x0, y0)-(x1, y1).
+		mean := func(c, x0, y0, x1, y1 int) float64 {
+			sum := 0
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					sum += int(decoded.Channels[c].Data[y*decoded.Width+x])
+				}
+			}
+			return float64(sum) / float64((x1-x0)*(y1-y0))
+		}
+
+		// Stem of the white letter l.
+		for c := 0; c < 3; c++ {
+			assert.InDelta(t, 250, mean(c, 105, 224, 110, 286), 12, "channel %d", c)
+		}
+		// Dark green moss at the bottom right.
+		r, g, b := mean(0, 300, 220, 380, 280), mean(1, 300, 220, 380, 280), mean(2, 300, 220, 380, 280)
+		assert.InDelta(t, 80, g, 25)
+		assert.True(t, g > r+20 && g > b+20, "moss %.0f %.0f %.0f", r, g, b)
+		// Bright, slightly blue water.
+		r, g, b = mean(0, 60, 160, 100, 190), mean(1, 60, 160, 100, 190), mean(2, 60, 160, 100, 190)
+		assert.True(t, r > 180 && g > 180 && b > r, "water %.0f %.0f %.0f", r, g, b)
+	}
+
+	// The JP2 file and its codestream have the same samples.
+	for c := range jp2.Channels {
+		assert.Equal(t, raw.Channels[c].Data, jp2.Channels[c].Data, "channel %d", c)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	_, err := Decode([]byte("not an image"), nil)
+	assert.Error(t, err)
+	_, err = Decode([]byte{0xFF, 0x4F, 0xFF, 0x51, 0x00}, nil)
+	assert.Error(t, err)
+
+	rnd := rand.New(rand.NewSource(7))
+	img := &testImage{width: 13, height: 7, precision: 8, levels: 1, xcb: 2, ycb: 2}
+	img.comps = [][]int32{smoothComponent(rnd, 13, 7, 8)}
+	data := encodeTestImage(img)
+	// The SIZ marker segment starts at offset 2: Xsiz is at 8, Ysiz at 12, XOsiz at 16, YTsiz at 28
+	// and the sub-sampling factors of the first component at 43 and 44.
+	modified := func(pos int, b ...byte) []byte {
+		m := append([]byte(nil), data...)
+		copy(m[pos:], b)
+		return m
+	}
+
+	// The image origin and sub-sampling leave the component without samples.
+	empty := modified(16, 0, 0, 0, 12)
+	empty[43] = 200
+	_, err = DecodeConfig(empty, nil)
+	assert.Equal(t, errInvalidSIZ, err)
+	_, err = Decode(empty, nil)
+	assert.Equal(t, errInvalidSIZ, err)
+
+	// Too large images are rejected before decoding.
+	large := modified(12, 0x00, 0xEF, 0x00, 0x00)
+	copy(large[28:], large[12:16])
+	_, err = DecodeConfig(large, nil)
+	assert.Equal(t, errImageTooLarge, err)
+	_, err = Decode(large, nil)
+	assert.Equal(t, errImageTooLarge, err)
+	_, err = Decode(data, &DecodeOptions{MaxPixels: 13*7 - 1})
+	assert.Equal(t, errImageTooLarge, err)
+
+	// The size must match the expected size.
+	_, err = Decode(data, &DecodeOptions{Width: 13, Height: 8})
+	assert.Equal(t, errSizeMismatch, err)
+	_, err = Decode(data, &DecodeOptions{Width: 13, Height: 7})
+	assert.NoError(t, err)
+}
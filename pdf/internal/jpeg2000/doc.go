@@ -0,0 +1,9 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package jpeg2000 implements a decoder for JPEG 2000 images as defined in ITU-T T.800 | ISO/IEC 15444-1.
+// Both raw codestreams and JP2/JPX file format wrappers are supported. The decoder returns the
+// reconstructed sample values of each channel, which is what the PDF JPXDecode filter requires.
+package jpeg2000
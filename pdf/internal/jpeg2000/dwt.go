@@ -0,0 +1,172 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpeg2000
+
+import (
+	"math"
+)
+
+// Lifting parameters of the irreversible 9-7 filter (Table F.4).
+const (
+	liftAlpha = -1.586134342059924
+	liftBeta  = -0.052980118572961
+	liftGamma = 0.882911075530934
+	liftDelta = 0.443506852043971
+	liftK     = 1.230174104914001
+)
+
+// extensionPad is the number of samples added on both sides of a signal by the periodic symmetric
+// extension. It is even so that the parity of the samples is preserved.
+const extensionPad = 4
+
+// waveletSynthesizer performs the inverse discrete wavelet transformation (Annex F).
+type waveletSynthesizer struct {
+	reversible bool
+	buf        []float32
+	col        []float32
+}
+
+// reconstruct computes the samples of tile-component `tc` from its subband coefficients (2D_SR).
+func (ws *waveletSynthesizer) reconstruct(tc *tileComponent) []float32 {
+	ll := tc.resolutions[0].bands[0].coefs
+	for r := 1; r < len(tc.resolutions); r++ {
+		res := tc.resolutions[r]
+		w, h := res.width(), res.height()
+		out := make([]float32, w*h)
+		if w == 0 || h == 0 {
+			ll = out
+			continue
+		}
+		// 2D_INTERLEAVE.
+		lower := tc.resolutions[r-1].rect
+		ws.interleave(out, res.rect, ll, lower, 0, 0)
+		for _, band := range res.bands {
+			xo, yo := band.orientation&1, band.orientation>>1
+			ws.interleave(out, res.rect, band.coefs, band.rect, xo, yo)
+		}
+		// HOR_SR.
+		for y := 0; y < h; y++ {
+			ws.synthesize(out[y*w:(y+1)*w], res.x0)
+		}
+		// VER_SR.
+		if cap(ws.col) < h {
+			ws.col = make([]float32, h)
+		}
+		col := ws.col[:h]
+		for x := 0; x < w; x++ {
+			for y := 0; y < h; y++ {
+				col[y] = out[y*w+x]
+			}
+			ws.synthesize(col, res.y0)
+			for y := 0; y < h; y++ {
+				out[y*w+x] = col[y]
+			}
+		}
+		ll = out
+	}
+	return ll
+}
+
+// interleave places the coefficients of a subband covering `br` into the resolution samples `out`
+// covering `rr`. The subband samples go to positions with parity (`xo`, `yo`).
+func (ws *waveletSynthesizer) interleave(out []float32, rr rect, coefs []float32, br rect, xo, yo int) {
+	if br.empty() || coefs == nil {
+		return
+	}
+	w := rr.width()
+	bw := br.width()
+	for j := 0; j < br.height(); j++ {
+		y := 2*(br.y0+j) + yo - rr.y0
+		if y < 0 || y >= rr.height() {
+			continue
+		}
+		row := out[y*w:]
+		for i := 0; i < bw; i++ {
+			x := 2*(br.x0+i) + xo - rr.x0
+			if x < 0 || x >= w {
+				continue
+			}
+			row[x] = coefs[j*bw+i]
+		}
+	}
+}
+
+// synthesize performs the one dimensional synthesis (1D_SR) of the interleaved signal `line`,
+// whose first sample has index `i0`.
+func (ws *waveletSynthesizer) synthesize(line []float32, i0 int) {
+	n := len(line)
+	if n == 1 {
+		if i0&1 == 1 {
+			line[0] /= 2
+		}
+		return
+	}
+	size := n + 2*extensionPad
+	if cap(ws.buf) < size {
+		ws.buf = make([]float32, size)
+	}
+	x := ws.buf[:size]
+	copy(x[extensionPad:], line)
+	// 1D_EXTR: periodic symmetric extension.
+	period := 2 * (n - 1)
+	for k := 1; k <= extensionPad; k++ {
+		x[extensionPad-k] = line[reflect(-k, period, n)]
+		x[extensionPad+n-1+k] = line[reflect(n-1+k, period, n)]
+	}
+	// Buffer index j holds signal index i0-extensionPad+j, which has the parity of i0+j.
+	even := i0 & 1 // First buffer index holding an even signal index.
+	odd := 1 - even
+	if ws.reversible {
+		// F.3.8.1: 1D_FILTR_5-3R.
+		for j := firstIndex(1, even); j < size-1; j += 2 {
+			x[j] -= float32(math.Floor(float64(x[j-1]+x[j+1]+2) / 4))
+		}
+		for j := firstIndex(2, odd); j < size-2; j += 2 {
+			x[j] += float32(math.Floor(float64(x[j-1]+x[j+1]) / 2))
+		}
+	} else {
+		// F.3.8.2: 1D_FILTR_9-7I.
+		for j := even; j < size; j += 2 {
+			x[j] *= liftK
+		}
+		for j := odd; j < size; j += 2 {
+			x[j] *= 1 / liftK
+		}
+		ws.lift(x, firstIndex(1, even), 1, liftDelta)
+		ws.lift(x, firstIndex(2, odd), 2, liftGamma)
+		ws.lift(x, firstIndex(3, even), 3, liftBeta)
+		ws.lift(x, firstIndex(4, odd), 4, liftAlpha)
+	}
+	copy(line, x[extensionPad:extensionPad+n])
+}
+
+// firstIndex returns the first buffer index >= `min` with the parity of `parity`.
+func firstIndex(min, parity int) int {
+	if min&1 != parity {
+		return min + 1
+	}
+	return min
+}
+
+// lift performs a lifting step x[j] -= c * (x[j-1] + x[j+1]) from buffer index `start` in steps of two,
+// excluding the `margin` last samples.
+func (ws *waveletSynthesizer) lift(x []float32, start, margin int, c float32) {
+	for j := start; j < len(x)-margin; j += 2 {
+		x[j] -= c * (x[j-1] + x[j+1])
+	}
+}
+
+// reflect maps the index `i` into [0, n) by periodic symmetric extension with the period `period`.
+func reflect(i, period, n int) int {
+	i %= period
+	if i < 0 {
+		i += period
+	}
+	if i >= n {
+		i = period - i
+	}
+	return i
+}
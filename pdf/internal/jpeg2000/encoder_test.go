@@ -0,0 +1,671 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpeg2000
+
+import (
+	"math/bits"
+)
+
+// This file contains a minimal lossless JPEG 2000 encoder used to produce test data for the decoder:
+// a single tile, one quality layer, LRCP progression, default precincts and the reversible 5-3 filter.
+
+// testImage describes an image to be encoded by encodeTestImage.
+type testImage struct {
+	width, height int
+	precision     int
+	// comps holds the unsigned samples of each component.
+	comps    [][]int32
+	levels   int
+	xcb, ycb int
+	cbStyle  int
+	mct      bool
+}
+
+// testGuardBits is large enough for the dynamic range expansion of the transformations.
+const testGuardBits = 4
+
+// mqEncoder is the MQ arithmetic encoder (C.2).
+type mqEncoder struct {
+	a, c uint32
+	ct   int
+	// out holds a placeholder byte followed by the coded data.
+	out []byte
+}
+
+func (e *mqEncoder) init() {
+	e.a = 0x8000
+	e.c = 0
+	e.ct = 12
+	e.out = []byte{0}
+}
+
+func (e *mqEncoder) encode(bit int, cx *uint8) {
+	index := *cx >> 1
+	mps := int(*cx & 1)
+	en := &qeTable[index]
+	qe := en.qe
+	e.a -= qe
+	if bit == mps {
+		if e.a&0x8000 != 0 {
+			e.c += qe
+			return
+		}
+		if e.a < qe {
+			e.a = qe
+		} else {
+			e.c += qe
+		}
+		index = en.nmps
+	} else {
+		if e.a < qe {
+			e.c += qe
+		} else {
+			e.a = qe
+		}
+		if en.switchMPS {
+			mps = 1 - mps
+		}
+		index = en.nlps
+	}
+	*cx = index<<1 | uint8(mps)
+	for {
+		e.a <<= 1
+		e.c <<= 1
+		e.ct--
+		if e.ct == 0 {
+			e.byteOut()
+		}
+		if e.a&0x8000 != 0 {
+			break
+		}
+	}
+}
+
+func (e *mqEncoder) byteOut() {
+	b := &e.out[len(e.out)-1]
+	if *b != 0xFF && e.c >= 0x8000000 {
+		*b++
+		e.c &= 0x7FFFFFF
+	}
+	if *b == 0xFF {
+		e.out = append(e.out, byte(e.c>>20))
+		e.c &= 0xFFFFF
+		e.ct = 7
+		return
+	}
+	e.out = append(e.out, byte(e.c>>19))
+	e.c &= 0x7FFFF
+	e.ct = 8
+}
+
+// flush terminates the codeword and returns the coded data.
+func (e *mqEncoder) flush() []byte {
+	tempc := e.c + e.a
+	e.c |= 0xFFFF
+	if e.c >= tempc {
+		e.c -= 0x8000
+	}
+	e.c <<= uint(e.ct)
+	e.byteOut()
+	e.c <<= uint(e.ct)
+	e.byteOut()
+	data := e.out[1:]
+	if n := len(data); n > 0 && data[n-1] == 0xFF {
+		data = data[:n-1]
+	}
+	return data
+}
+
+// t1Encoder codes the samples of a code-block. The context modelling state is shared with the decoder.
+type t1Encoder struct {
+	t1Decoder
+	coefs    []int32
+	enc      mqEncoder
+	segments [][]byte
+}
+
+func (t *t1Encoder) put(bit, ctx int) {
+	t.enc.encode(bit, &t.contexts[ctx])
+}
+
+func (t *t1Encoder) magnitudeBit(x, y, bp int) int {
+	v := t.coefs[y*t.w+x]
+	if v < 0 {
+		v = -v
+	}
+	return int(v>>uint(bp)) & 1
+}
+
+func (t *t1Encoder) encodeSign(x, y int) {
+	ctx, xor := t.signContext(x, y)
+	i := (y+1)*t.stride + x + 1
+	sign := 0
+	if t.coefs[y*t.w+x] < 0 {
+		sign = 1
+		t.flags[i] |= flagNegative
+	}
+	t.flags[i] |= flagSignificant
+	t.put(sign^xor, ctx)
+}
+
+func (t *t1Encoder) significancePass(bp int) {
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			for y := y0; y < y0+4 && y < t.h; y++ {
+				i := (y+1)*t.stride + x + 1
+				if t.flags[i]&flagSignificant != 0 {
+					continue
+				}
+				ctx := t.zeroCodingContext(x, y)
+				if ctx == 0 {
+					continue
+				}
+				t.flags[i] |= flagVisited
+				bit := t.magnitudeBit(x, y, bp)
+				t.put(bit, ctx)
+				if bit != 0 {
+					t.encodeSign(x, y)
+				}
+			}
+		}
+	}
+}
+
+func (t *t1Encoder) refinementPass(bp int) {
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			for y := y0; y < y0+4 && y < t.h; y++ {
+				i := (y+1)*t.stride + x + 1
+				f := t.flags[i]
+				if f&flagSignificant == 0 || f&flagVisited != 0 {
+					continue
+				}
+				ctx := ctxRefinement + 2
+				if f&flagRefined == 0 {
+					ctx = ctxRefinement
+					if h, v, d := t.neighbours(x, y); h+v+d > 0 {
+						ctx = ctxRefinement + 1
+					}
+				}
+				t.put(t.magnitudeBit(x, y, bp), ctx)
+				t.flags[i] |= flagRefined
+			}
+		}
+	}
+}
+
+func (t *t1Encoder) cleanupPass(bp int) {
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			y := y0
+			if y0+4 <= t.h && t.runLengthCandidate(x, y0) {
+				k := 0
+				for k < 4 && t.magnitudeBit(x, y0+k, bp) == 0 {
+					k++
+				}
+				if k == 4 {
+					t.put(0, ctxRunLength)
+					continue
+				}
+				t.put(1, ctxRunLength)
+				t.put(k>>1, ctxUniform)
+				t.put(k&1, ctxUniform)
+				y = y0 + k
+				t.encodeSign(x, y)
+				y++
+			}
+			for ; y < y0+4 && y < t.h; y++ {
+				i := (y+1)*t.stride + x + 1
+				if t.flags[i]&(flagSignificant|flagVisited) != 0 {
+					continue
+				}
+				bit := t.magnitudeBit(x, y, bp)
+				t.put(bit, t.zeroCodingContext(x, y))
+				if bit != 0 {
+					t.encodeSign(x, y)
+				}
+			}
+		}
+	}
+	if t.cbStyle&cbSegmentSymbol != 0 {
+		for _, bit := range []int{1, 0, 1, 0} {
+			t.put(bit, ctxUniform)
+		}
+	}
+}
+
+// encode codes the `w`x`h` coefficients `coefs`. It returns the number of magnitude bit-planes and passes.
+func (t *t1Encoder) encode(coefs []int32, w, h, band, cbStyle int) (numBps, passes int) {
+	var maxMag int32
+	for _, v := range coefs {
+		if v < 0 {
+			v = -v
+		}
+		if v > maxMag {
+			maxMag = v
+		}
+	}
+	numBps = bits.Len32(uint32(maxMag))
+	t.reset(w, h, band, cbStyle, numBps)
+	t.coefs = coefs
+	t.segments = nil
+	t.enc.init()
+	passType := passCleanup
+	for bp := numBps - 1; bp >= 0; {
+		switch passType {
+		case passSignificance:
+			t.significancePass(bp)
+		case passRefinement:
+			t.refinementPass(bp)
+		case passCleanup:
+			t.cleanupPass(bp)
+		}
+		passes++
+		if cbStyle&cbReset != 0 {
+			t.resetContexts()
+		}
+		if cbStyle&cbTermAll != 0 {
+			t.segments = append(t.segments, t.enc.flush())
+			t.enc.init()
+		}
+		if passType == passCleanup {
+			t.clearVisited()
+			bp--
+			passType = passSignificance
+		} else {
+			passType++
+		}
+	}
+	if cbStyle&cbTermAll == 0 && passes > 0 {
+		t.segments = append(t.segments, t.enc.flush())
+	}
+	return numBps, passes
+}
+
+// bitWriter writes packet header bits with bit stuffing.
+type bitWriter struct {
+	out []byte
+	cur byte
+	n   uint
+	max uint
+}
+
+func (bw *bitWriter) writeBit(bit int) {
+	if bw.max == 0 {
+		bw.max = 8
+	}
+	bw.cur = bw.cur<<1 | byte(bit)
+	bw.n++
+	if bw.n == bw.max {
+		bw.out = append(bw.out, bw.cur)
+		bw.max = 8
+		if bw.cur == 0xFF {
+			bw.max = 7
+		}
+		bw.cur, bw.n = 0, 0
+	}
+}
+
+func (bw *bitWriter) writeBits(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		bw.writeBit((v >> uint(i)) & 1)
+	}
+}
+
+func (bw *bitWriter) flush() []byte {
+	if bw.n > 0 {
+		bw.out = append(bw.out, bw.cur<<(bw.max-bw.n))
+	}
+	if n := len(bw.out); n > 0 && bw.out[n-1] == 0xFF {
+		bw.out = append(bw.out, 0)
+	}
+	return bw.out
+}
+
+func (bw *bitWriter) writeNumPasses(n int) {
+	switch {
+	case n == 1:
+		bw.writeBit(0)
+	case n == 2:
+		bw.writeBits(2, 2)
+	case n <= 5:
+		bw.writeBits(3, 2)
+		bw.writeBits(n-3, 2)
+	case n <= 36:
+		bw.writeBits(15, 4)
+		bw.writeBits(n-6, 5)
+	default:
+		bw.writeBits(511, 9)
+		bw.writeBits(n-37, 7)
+	}
+}
+
+// tagTreeEncoder encodes the values of a tag tree.
+type tagTreeEncoder struct {
+	levels []tagTreeEncoderLevel
+}
+
+type tagTreeEncoderLevel struct {
+	w     int
+	value []int
+	low   []int
+	known []bool
+}
+
+func newTagTreeEncoder(w, h int, values []int) *tagTreeEncoder {
+	t := &tagTreeEncoder{}
+	for {
+		lvl := tagTreeEncoderLevel{w: w, value: values, low: make([]int, w*h), known: make([]bool, w*h)}
+		t.levels = append(t.levels, lvl)
+		if w <= 1 && h <= 1 {
+			break
+		}
+		pw, ph := (w+1)/2, (h+1)/2
+		parent := make([]int, pw*ph)
+		for i := range parent {
+			parent[i] = tagTreeInf
+		}
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				i := (y/2)*pw + x/2
+				parent[i] = minInt(parent[i], values[y*w+x])
+			}
+		}
+		w, h, values = pw, ph, parent
+	}
+	return t
+}
+
+func (t *tagTreeEncoder) encode(bw *bitWriter, x, y, threshold int) {
+	low := 0
+	for l := len(t.levels) - 1; l >= 0; l-- {
+		lvl := &t.levels[l]
+		i := (y>>uint(l))*lvl.w + (x >> uint(l))
+		if low > lvl.low[i] {
+			lvl.low[i] = low
+		} else {
+			low = lvl.low[i]
+		}
+		for low < threshold {
+			if low >= lvl.value[i] {
+				if !lvl.known[i] {
+					bw.writeBit(1)
+					lvl.known[i] = true
+				}
+				break
+			}
+			bw.writeBit(0)
+			low++
+		}
+		lvl.low[i] = low
+	}
+}
+
+// testBlock is an encoded code-block.
+type testBlock struct {
+	zeroPlanes int
+	passes     int
+	segments   [][]byte
+}
+
+// forwardLift53 performs the one dimensional forward 5-3 transformation of a signal starting at index 0.
+func forwardLift53(x []int32) {
+	n := len(x)
+	if n == 1 {
+		return
+	}
+	period := 2 * (n - 1)
+	at := func(i int) int32 { return x[reflect(i, period, n)] }
+	for i := 1; i < n; i += 2 {
+		x[i] -= floorDiv(at(i-1)+at(i+1), 2)
+	}
+	for i := 0; i < n; i += 2 {
+		x[i] += floorDiv(at(i-1)+at(i+1)+2, 4)
+	}
+}
+
+func floorDiv(a, b int32) int32 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// testBand is a subband of the forward transformation.
+type testBand struct {
+	w, h  int
+	coefs []int32
+}
+
+// forwardDWT decomposes the `w`x`h` samples `data`. It returns the subbands in codestream order:
+// LL, then HL, LH and HH of each resolution from the lowest.
+func forwardDWT(data []int32, w, h, levels int) []testBand {
+	var highs [][]testBand
+	for l := 0; l < levels; l++ {
+		col := make([]int32, h)
+		for x := 0; x < w; x++ {
+			for y := 0; y < h; y++ {
+				col[y] = data[y*w+x]
+			}
+			forwardLift53(col)
+			for y := 0; y < h; y++ {
+				data[y*w+x] = col[y]
+			}
+		}
+		for y := 0; y < h; y++ {
+			forwardLift53(data[y*w : (y+1)*w])
+		}
+		lw, lh := (w+1)/2, (h+1)/2
+		bands := make([]testBand, 4)
+		for o := range bands {
+			xo, yo := o&1, o>>1
+			bw, bh := lw, lh
+			if xo == 1 {
+				bw = w / 2
+			}
+			if yo == 1 {
+				bh = h / 2
+			}
+			b := testBand{w: bw, h: bh, coefs: make([]int32, bw*bh)}
+			for j := 0; j < bh; j++ {
+				for i := 0; i < bw; i++ {
+					b.coefs[j*bw+i] = data[(2*j+yo)*w+2*i+xo]
+				}
+			}
+			bands[o] = b
+		}
+		highs = append([][]testBand{bands[1:]}, highs...)
+		data, w, h = bands[0].coefs, lw, lh
+	}
+	out := []testBand{{w: w, h: h, coefs: data}}
+	for _, hb := range highs {
+		out = append(out, hb...)
+	}
+	return out
+}
+
+// encodeTestImage encodes `img` as a JPEG 2000 codestream.
+func encodeTestImage(img *testImage) []byte {
+	w, h := img.width, img.height
+	numComps := len(img.comps)
+	planes := make([][]int32, numComps)
+	for c, comp := range img.comps {
+		planes[c] = make([]int32, len(comp))
+		for i, v := range comp {
+			planes[c][i] = v - 1<<uint(img.precision-1)
+		}
+	}
+	if img.mct {
+		r, g, b := planes[0], planes[1], planes[2]
+		for i := range r {
+			y := floorDiv(r[i]+2*g[i]+b[i], 4)
+			u := b[i] - g[i]
+			v := r[i] - g[i]
+			r[i], g[i], b[i] = y, u, v
+		}
+	}
+
+	// Exponents of the subbands in codestream order.
+	exps := []int{img.precision}
+	for r := 1; r <= img.levels; r++ {
+		exps = append(exps, img.precision+1, img.precision+1, img.precision+2)
+	}
+
+	// Code-blocks of each component and subband.
+	blocks := make([][][]testBlock, numComps)
+	dims := make([][]testBand, numComps)
+	t1 := &t1Encoder{}
+	for c := range planes {
+		bands := forwardDWT(planes[c], w, h, img.levels)
+		dims[c] = bands
+		blocks[c] = make([][]testBlock, len(bands))
+		for bi, band := range bands {
+			orientation := bandLL
+			if bi > 0 {
+				orientation = (bi-1)%3 + 1
+			}
+			numBps := testGuardBits + exps[bi] - 1
+			cw, ch := 1<<uint(img.xcb), 1<<uint(img.ycb)
+			for y0 := 0; y0 < band.h; y0 += ch {
+				for x0 := 0; x0 < band.w; x0 += cw {
+					bw, bh := minInt(cw, band.w-x0), minInt(ch, band.h-y0)
+					coefs := make([]int32, bw*bh)
+					for y := 0; y < bh; y++ {
+						copy(coefs[y*bw:(y+1)*bw], band.coefs[(y0+y)*band.w+x0:])
+					}
+					n, passes := t1.encode(coefs, bw, bh, orientation, img.cbStyle)
+					if n > numBps {
+						panic("too few bit-planes")
+					}
+					blocks[c][bi] = append(blocks[c][bi], testBlock{
+						zeroPlanes: numBps - n,
+						passes:     passes,
+						segments:   t1.segments,
+					})
+				}
+			}
+		}
+	}
+
+	// Packets in LRCP order.
+	var body []byte
+	for r := 0; r <= img.levels; r++ {
+		for c := 0; c < numComps; c++ {
+			bandIdx := []int{0}
+			if r > 0 {
+				bandIdx = []int{3*r - 2, 3*r - 1, 3 * r}
+			}
+			bw := &bitWriter{}
+			var data []byte
+			bw.writeBit(1)
+			for _, bi := range bandIdx {
+				band := dims[c][bi]
+				if band.w == 0 || band.h == 0 {
+					continue
+				}
+				numX := (band.w + 1<<uint(img.xcb) - 1) >> uint(img.xcb)
+				numY := (band.h + 1<<uint(img.ycb) - 1) >> uint(img.ycb)
+				bl := blocks[c][bi]
+				incl := make([]int, len(bl))
+				zero := make([]int, len(bl))
+				for i, b := range bl {
+					if b.passes == 0 {
+						incl[i] = 1
+					}
+					zero[i] = b.zeroPlanes
+				}
+				inclTree := newTagTreeEncoder(numX, numY, incl)
+				zeroTree := newTagTreeEncoder(numX, numY, zero)
+				for i, b := range bl {
+					x, y := i%numX, i/numX
+					inclTree.encode(bw, x, y, 1)
+					if b.passes == 0 {
+						continue
+					}
+					zeroTree.encode(bw, x, y, b.zeroPlanes+1)
+					bw.writeNumPasses(b.passes)
+					perSegment := b.passes
+					if img.cbStyle&cbTermAll != 0 {
+						perSegment = 1
+					}
+					lblock := 3
+					for _, seg := range b.segments {
+						for lblock+bits.Len(uint(perSegment))-1 < bits.Len(uint(len(seg))) {
+							lblock++
+						}
+					}
+					for i := 3; i < lblock; i++ {
+						bw.writeBit(1)
+					}
+					bw.writeBit(0)
+					for _, seg := range b.segments {
+						bw.writeBits(len(seg), lblock+bits.Len(uint(perSegment))-1)
+						data = append(data, seg...)
+					}
+				}
+			}
+			body = append(body, bw.flush()...)
+			body = append(body, data...)
+		}
+	}
+
+	// Main header.
+	out := []byte{0xFF, 0x4F}
+	siz := []byte{0, 0}
+	for _, v := range []int{w, h, 0, 0, w, h, 0, 0} {
+		siz = appendU32(siz, uint32(v))
+	}
+	siz = appendU16(siz, uint16(numComps))
+	for range img.comps {
+		siz = append(siz, byte(img.precision-1), 1, 1)
+	}
+	out = appendMarker(out, markerSIZ, siz)
+	mct := byte(0)
+	if img.mct {
+		mct = 1
+	}
+	out = appendMarker(out, markerCOD, []byte{
+		0, progressionLRCP, 0, 1, mct,
+		byte(img.levels), byte(img.xcb - 2), byte(img.ycb - 2), byte(img.cbStyle), 1,
+	})
+	qcd := []byte{testGuardBits << 5}
+	for _, e := range exps {
+		qcd = append(qcd, byte(e<<3))
+	}
+	out = appendMarker(out, markerQCD, qcd)
+
+	// Tile-part.
+	sot := appendU16(nil, 0)
+	sot = appendU32(sot, uint32(12+2+len(body)))
+	sot = append(sot, 0, 1)
+	out = appendMarker(out, markerSOT, sot)
+	out = append(out, 0xFF, 0x93)
+	out = append(out, body...)
+	return append(out, 0xFF, 0xD9)
+}
+
+// appendMarker appends the marker segment `marker` with the parameters `params`.
+func appendMarker(out []byte, marker int, params []byte) []byte {
+	out = appendU16(out, uint16(marker))
+	out = appendU16(out, uint16(len(params)+2))
+	return append(out, params...)
+}
+
+// appendBox appends a JP2 box of type `typ`.
+func appendBox(out []byte, typ uint32, content []byte) []byte {
+	out = appendU32(out, uint32(len(content)+8))
+	out = appendU32(out, typ)
+	return append(out, content...)
+}
+
+func appendU16(out []byte, v uint16) []byte {
+	return append(out, byte(v>>8), byte(v))
+}
+
+func appendU32(out []byte, v uint32) []byte {
+	return append(out, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
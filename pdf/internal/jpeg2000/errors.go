@@ -0,0 +1,27 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpeg2000
+
+import (
+	"errors"
+)
+
+var (
+	// errUnexpectedEOF is returned when the data ends before a structure is read completely.
+	errUnexpectedEOF = errors.New("jpeg2000: unexpected end of data")
+	// errNoCodestream is returned when neither a codestream nor a JP2 file signature is found.
+	errNoCodestream = errors.New("jpeg2000: no codestream found")
+	// errInvalidMarker is returned when an unexpected or malformed marker segment is met.
+	errInvalidMarker = errors.New("jpeg2000: invalid marker segment")
+	// errInvalidSIZ is returned when the image and tile size marker holds invalid values.
+	errInvalidSIZ = errors.New("jpeg2000: invalid image size parameters")
+	// errImageTooLarge is returned when the image exceeds the size limit of the decoder.
+	errImageTooLarge = errors.New("jpeg2000: image too large")
+	// errSizeMismatch is returned when the image size differs from the expected size.
+	errSizeMismatch = errors.New("jpeg2000: image size does not match the expected size")
+	// errUnsupported is returned for valid but not supported codestream features.
+	errUnsupported = errors.New("jpeg2000: unsupported feature")
+)
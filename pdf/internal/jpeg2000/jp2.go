@@ -0,0 +1,259 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpeg2000
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/unidoc/unidoc/common"
+)
+
+// JP2 box types (ISO/IEC 15444-1 Annex I).
+const (
+	boxSignature     = 0x6A502020 // 'jP  '
+	boxFileType      = 0x66747970 // 'ftyp'
+	boxHeader        = 0x6A703268 // 'jp2h'
+	boxImageHeader   = 0x69686472 // 'ihdr'
+	boxColourSpec    = 0x636F6C72 // 'colr'
+	boxPalette       = 0x70636C72 // 'pclr'
+	boxComponentMap  = 0x636D6170 // 'cmap'
+	boxChannelDef    = 0x63646566 // 'cdef'
+	boxCodestream    = 0x6A703263 // 'jp2c'
+	boxFragmentTable = 0x66746274 // 'ftbl'
+)
+
+// ColorSpace is the colour space of an image as given by the JP2 colour specification box.
+type ColorSpace int
+
+// Colour spaces recognised in the enumerated colour specification method.
+const (
+	ColorSpaceUnknown ColorSpace = iota
+	ColorSpaceGray
+	ColorSpaceRGB
+	ColorSpaceYCC
+	ColorSpaceCMYK
+	ColorSpaceICC
+)
+
+// Channel types as defined by the channel definition box.
+const (
+	channelColor         = 0
+	channelOpacity       = 1
+	channelPremultiplied = 2
+)
+
+// jp2Header contains the information of the JP2 header box relevant for decoding.
+type jp2Header struct {
+	colorSpace ColorSpace
+	palette    *palette
+	mapping    []componentMapping
+	channels   []channelDefinition
+}
+
+// palette is the content of the JP2 palette box.
+type palette struct {
+	entries   int
+	precision []int
+	signed    []bool
+	// values holds the palette values column by column.
+	values [][]int32
+}
+
+// componentMapping maps a codestream component to an output channel, optionally through a palette column.
+type componentMapping struct {
+	component  int
+	usePalette bool
+	column     int
+}
+
+// channelDefinition defines the type and colour association of a channel.
+type channelDefinition struct {
+	channel     int
+	typ         int
+	association int
+}
+
+// isCodestream checks whether the data starts with the SOC and SIZ markers.
+func isCodestream(data []byte) bool {
+	return len(data) >= 4 && data[0] == 0xFF && data[1] == 0x4F && data[2] == 0xFF && data[3] == 0x51
+}
+
+// readBoxHeader reads the box header at `pos`. It returns the box type and the bounds of the box content.
+func readBoxHeader(data []byte, pos int) (typ uint32, start, end int, err error) {
+	if pos+8 > len(data) {
+		return 0, 0, 0, errUnexpectedEOF
+	}
+	length := uint64(binary.BigEndian.Uint32(data[pos:]))
+	typ = binary.BigEndian.Uint32(data[pos+4:])
+	start = pos + 8
+	switch length {
+	case 0:
+		// The box lasts until the end of the data.
+		return typ, start, len(data), nil
+	case 1:
+		if pos+16 > len(data) {
+			return 0, 0, 0, errUnexpectedEOF
+		}
+		length = binary.BigEndian.Uint64(data[pos+8:])
+		start = pos + 16
+	}
+	if length < uint64(start-pos) || uint64(pos)+length > uint64(len(data)) {
+		common.Log.Debug("JPX box %08x length %d exceeds data (%d)", typ, length, len(data)-pos)
+		return typ, start, len(data), nil
+	}
+	return typ, start, pos + int(length), nil
+}
+
+// parseJP2 walks the top level boxes of a JP2/JPX file and returns the header and the codestream.
+func parseJP2(data []byte) (*jp2Header, []byte, error) {
+	if isCodestream(data) {
+		return nil, data, nil
+	}
+	hdr := &jp2Header{}
+	var codestream []byte
+	for pos := 0; pos < len(data); {
+		typ, start, end, err := readBoxHeader(data, pos)
+		if err != nil {
+			if codestream != nil {
+				break
+			}
+			return nil, nil, err
+		}
+		switch typ {
+		case boxHeader:
+			if err := hdr.parse(data[start:end]); err != nil {
+				return nil, nil, err
+			}
+		case boxCodestream:
+			if codestream == nil {
+				codestream = data[start:end]
+			}
+		case boxFragmentTable:
+			common.Log.Debug("JPX fragment tables are not supported")
+		}
+		pos = end
+	}
+	if codestream == nil {
+		// Some writers embed a codestream without a valid box structure.
+		if i := bytes.Index(data, []byte{0xFF, 0x4F, 0xFF, 0x51}); i >= 0 {
+			return hdr, data[i:], nil
+		}
+		return nil, nil, errNoCodestream
+	}
+	return hdr, codestream, nil
+}
+
+// parse reads the sub-boxes of the JP2 header box.
+func (hdr *jp2Header) parse(data []byte) error {
+	for pos := 0; pos < len(data); {
+		typ, start, end, err := readBoxHeader(data, pos)
+		if err != nil {
+			return err
+		}
+		box := data[start:end]
+		switch typ {
+		case boxColourSpec:
+			// Only the first colour specification box is used.
+			if hdr.colorSpace == ColorSpaceUnknown {
+				hdr.colorSpace = parseColourSpec(box)
+			}
+		case boxPalette:
+			p, err := parsePalette(box)
+			if err != nil {
+				return err
+			}
+			hdr.palette = p
+		case boxComponentMap:
+			for i := 0; i+4 <= len(box); i += 4 {
+				hdr.mapping = append(hdr.mapping, componentMapping{
+					component:  int(binary.BigEndian.Uint16(box[i:])),
+					usePalette: box[i+2] == 1,
+					column:     int(box[i+3]),
+				})
+			}
+		case boxChannelDef:
+			if len(box) < 2 {
+				return errUnexpectedEOF
+			}
+			n := int(binary.BigEndian.Uint16(box))
+			for i := 0; i < n && 2+6*i+6 <= len(box); i++ {
+				b := box[2+6*i:]
+				hdr.channels = append(hdr.channels, channelDefinition{
+					channel:     int(binary.BigEndian.Uint16(b)),
+					typ:         int(binary.BigEndian.Uint16(b[2:])),
+					association: int(binary.BigEndian.Uint16(b[4:])),
+				})
+			}
+		}
+		pos = end
+	}
+	return nil
+}
+
+// parseColourSpec reads the colour specification box.
+func parseColourSpec(box []byte) ColorSpace {
+	if len(box) < 3 {
+		return ColorSpaceUnknown
+	}
+	switch box[0] {
+	case 1:
+		if len(box) < 7 {
+			return ColorSpaceUnknown
+		}
+		switch binary.BigEndian.Uint32(box[3:]) {
+		case 16, 20, 21:
+			return ColorSpaceRGB
+		case 17:
+			return ColorSpaceGray
+		case 18:
+			return ColorSpaceYCC
+		case 12:
+			return ColorSpaceCMYK
+		}
+		common.Log.Debug("JPX unsupported enumerated colour space %d", binary.BigEndian.Uint32(box[3:]))
+	case 2, 3:
+		return ColorSpaceICC
+	}
+	return ColorSpaceUnknown
+}
+
+// parsePalette reads the palette box.
+func parsePalette(box []byte) (*palette, error) {
+	if len(box) < 3 {
+		return nil, errUnexpectedEOF
+	}
+	p := &palette{entries: int(binary.BigEndian.Uint16(box))}
+	columns := int(box[2])
+	pos := 3
+	if pos+columns > len(box) {
+		return nil, errUnexpectedEOF
+	}
+	for i := 0; i < columns; i++ {
+		p.precision = append(p.precision, int(box[pos+i]&0x7F)+1)
+		p.signed = append(p.signed, box[pos+i]&0x80 != 0)
+	}
+	pos += columns
+	p.values = make([][]int32, columns)
+	for j := range p.values {
+		p.values[j] = make([]int32, p.entries)
+	}
+	for i := 0; i < p.entries; i++ {
+		for j := 0; j < columns; j++ {
+			size := (p.precision[j] + 7) / 8
+			if pos+size > len(box) {
+				return nil, errUnexpectedEOF
+			}
+			var v uint32
+			for k := 0; k < size; k++ {
+				v = v<<8 | uint32(box[pos+k])
+			}
+			pos += size
+			p.values[j][i] = int32(v)
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,176 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpeg2000
+
+// qeEntry is a row of the probability estimation table (Table C.2).
+type qeEntry struct {
+	qe        uint32
+	nmps      uint8
+	nlps      uint8
+	switchMPS bool
+}
+
+var qeTable = [47]qeEntry{
+	{0x5601, 1, 1, true}, {0x3401, 2, 6, false}, {0x1801, 3, 9, false}, {0x0AC1, 4, 12, false},
+	{0x0521, 5, 29, false}, {0x0221, 38, 33, false}, {0x5601, 7, 6, true}, {0x5401, 8, 14, false},
+	{0x4801, 9, 14, false}, {0x3801, 10, 14, false}, {0x3001, 11, 17, false}, {0x2401, 12, 18, false},
+	{0x1C01, 13, 20, false}, {0x1601, 29, 21, false}, {0x5601, 15, 14, true}, {0x5401, 16, 14, false},
+	{0x5101, 17, 15, false}, {0x4801, 18, 16, false}, {0x3801, 19, 17, false}, {0x3401, 20, 18, false},
+	{0x3001, 21, 19, false}, {0x2801, 22, 19, false}, {0x2401, 23, 20, false}, {0x2201, 24, 21, false},
+	{0x1C01, 25, 22, false}, {0x1801, 26, 23, false}, {0x1601, 27, 24, false}, {0x1401, 28, 25, false},
+	{0x1201, 29, 26, false}, {0x1101, 30, 27, false}, {0x0AC1, 31, 28, false}, {0x09C1, 32, 29, false},
+	{0x08A1, 33, 30, false}, {0x0521, 34, 31, false}, {0x0441, 35, 32, false}, {0x02A1, 36, 33, false},
+	{0x0221, 37, 34, false}, {0x0141, 38, 35, false}, {0x0111, 39, 36, false}, {0x0085, 40, 37, false},
+	{0x0049, 41, 38, false}, {0x0025, 42, 39, false}, {0x0015, 43, 40, false}, {0x0009, 44, 41, false},
+	{0x0005, 45, 42, false}, {0x0001, 45, 43, false}, {0x5601, 46, 46, false},
+}
+
+// mqDecoder is the MQ arithmetic decoder (Annex C) using the software conventions of C.3.
+// The context states are kept by the caller, each as (index << 1 | mps).
+type mqDecoder struct {
+	data  []byte
+	pos   int
+	chigh uint32
+	clow  uint32
+	a     uint32
+	ct    int
+}
+
+// init initializes the decoder on `data` (INITDEC).
+func (d *mqDecoder) init(data []byte) {
+	d.data = data
+	d.pos = 0
+	d.chigh = uint32(d.byteAt(0))
+	d.clow = 0
+	d.byteIn()
+	d.chigh = ((d.chigh << 7) & 0xFFFF) | ((d.clow >> 9) & 0x7F)
+	d.clow = (d.clow << 7) & 0xFFFF
+	d.ct -= 7
+	d.a = 0x8000
+}
+
+// byteAt returns the byte at `i`, or 0xFF past the end of the data.
+func (d *mqDecoder) byteAt(i int) byte {
+	if i < len(d.data) {
+		return d.data[i]
+	}
+	return 0xFF
+}
+
+// byteIn reads the next byte into the code register (BYTEIN).
+func (d *mqDecoder) byteIn() {
+	if d.byteAt(d.pos) == 0xFF {
+		if d.byteAt(d.pos+1) > 0x8F {
+			d.clow += 0xFF00
+			d.ct = 8
+		} else {
+			d.pos++
+			d.clow += uint32(d.byteAt(d.pos)) << 9
+			d.ct = 7
+		}
+	} else {
+		d.pos++
+		d.clow += uint32(d.byteAt(d.pos)) << 8
+		d.ct = 8
+	}
+	if d.clow > 0xFFFF {
+		d.chigh += d.clow >> 16
+		d.clow &= 0xFFFF
+	}
+}
+
+// decode decodes a decision using the context state `cx` (DECODE).
+func (d *mqDecoder) decode(cx *uint8) int {
+	index := *cx >> 1
+	mps := int(*cx & 1)
+	e := &qeTable[index]
+	qe := e.qe
+	a := d.a - qe
+	var bit int
+	if d.chigh < qe {
+		// LPS exchange.
+		if a < qe {
+			a = qe
+			bit = mps
+			index = e.nmps
+		} else {
+			a = qe
+			bit = 1 ^ mps
+			if e.switchMPS {
+				mps = bit
+			}
+			index = e.nlps
+		}
+	} else {
+		d.chigh -= qe
+		if a&0x8000 != 0 {
+			d.a = a
+			return mps
+		}
+		// MPS exchange.
+		if a < qe {
+			bit = 1 ^ mps
+			if e.switchMPS {
+				mps = bit
+			}
+			index = e.nlps
+		} else {
+			bit = mps
+			index = e.nmps
+		}
+	}
+	// Renormalization.
+	for {
+		if d.ct == 0 {
+			d.byteIn()
+		}
+		a <<= 1
+		d.chigh = ((d.chigh << 1) & 0xFFFF) | ((d.clow >> 15) & 1)
+		d.clow = (d.clow << 1) & 0xFFFF
+		d.ct--
+		if a&0x8000 != 0 {
+			break
+		}
+	}
+	d.a = a
+	*cx = index<<1 | uint8(mps)
+	return bit
+}
+
+// rawDecoder reads the uncompressed bits of the selective arithmetic coding bypass mode (D.6).
+type rawDecoder struct {
+	data []byte
+	pos  int
+	c    byte
+	ct   uint
+}
+
+// init initializes the raw decoder on `data`.
+func (d *rawDecoder) init(data []byte) {
+	d.data = data
+	d.pos = 0
+	d.c = 0
+	d.ct = 0
+}
+
+// decode returns the next raw bit. A zero bit is stuffed after each 0xFF byte.
+func (d *rawDecoder) decode() int {
+	if d.ct == 0 {
+		prev := d.c
+		d.c = 0xFF
+		if d.pos < len(d.data) {
+			d.c = d.data[d.pos]
+			d.pos++
+		}
+		if prev == 0xFF {
+			d.ct = 7
+		} else {
+			d.ct = 8
+		}
+	}
+	d.ct--
+	return int(d.c>>d.ct) & 1
+}
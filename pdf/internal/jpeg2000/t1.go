@@ -0,0 +1,439 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpeg2000
+
+// Subband orientations.
+const (
+	bandLL = iota
+	bandHL
+	bandLH
+	bandHH
+)
+
+// Coding contexts (Annex D): 0-8 significance, 9-13 sign, 14-16 refinement, run-length and uniform.
+const (
+	ctxSign       = 9
+	ctxRefinement = 14
+	ctxRunLength  = 17
+	ctxUniform    = 18
+	numContexts   = 19
+)
+
+// Coding pass types.
+const (
+	passSignificance = iota
+	passRefinement
+	passCleanup
+)
+
+// Sample state flags.
+const (
+	flagSignificant = 1 << iota
+	flagNegative
+	flagVisited
+	flagRefined
+)
+
+// zeroCodingLabels holds the significance coding contexts (Table D.1) indexed by band orientation,
+// the number of significant horizontal, vertical and diagonal neighbours.
+var zeroCodingLabels [4][3][3][5]uint8
+
+func init() {
+	for band := range zeroCodingLabels {
+		for h := 0; h < 3; h++ {
+			for v := 0; v < 3; v++ {
+				for d := 0; d < 5; d++ {
+					zeroCodingLabels[band][h][v][d] = zeroCodingLabel(band, h, v, d)
+				}
+			}
+		}
+	}
+}
+
+// zeroCodingLabel computes the significance coding context of Table D.1.
+func zeroCodingLabel(band, h, v, d int) uint8 {
+	if band == bandHH {
+		hv := h + v
+		switch {
+		case d >= 3:
+			return 8
+		case d == 2:
+			if hv >= 1 {
+				return 7
+			}
+			return 6
+		case d == 1:
+			if hv >= 2 {
+				return 5
+			} else if hv == 1 {
+				return 4
+			}
+			return 3
+		}
+		if hv >= 2 {
+			return 2
+		}
+		return uint8(hv)
+	}
+	if band == bandHL {
+		h, v = v, h
+	}
+	switch {
+	case h == 2:
+		return 8
+	case h == 1:
+		if v >= 1 {
+			return 7
+		} else if d >= 1 {
+			return 6
+		}
+		return 5
+	case v == 2:
+		return 4
+	case v == 1:
+		return 3
+	case d >= 2:
+		return 2
+	}
+	return uint8(d)
+}
+
+// t1Decoder decodes the coding passes of a code-block (tier-1 decoding, Annex D).
+type t1Decoder struct {
+	w, h    int
+	band    int
+	cbStyle int
+	// stride is the row length of the flags array, which has a border of one sample.
+	stride int
+	flags  []uint8
+	// magnitude holds the decoded magnitude bits of each sample at their bit-plane positions.
+	magnitude []uint32
+	// lowest holds the lowest bit-plane coded for each sample.
+	lowest   []uint8
+	contexts [numContexts]uint8
+	mq       mqDecoder
+	raw      rawDecoder
+	useRaw   bool
+}
+
+// reset prepares the decoder for a code-block of `w`x`h` samples.
+func (t *t1Decoder) reset(w, h, band, cbStyle, numBps int) {
+	t.w, t.h = w, h
+	t.band = band
+	t.cbStyle = cbStyle
+	t.stride = w + 2
+	n := (w + 2) * (h + 2)
+	if cap(t.flags) < n {
+		t.flags = make([]uint8, n)
+	}
+	t.flags = t.flags[:n]
+	for i := range t.flags {
+		t.flags[i] = 0
+	}
+	if cap(t.magnitude) < w*h {
+		t.magnitude = make([]uint32, w*h)
+		t.lowest = make([]uint8, w*h)
+	}
+	t.magnitude = t.magnitude[:w*h]
+	t.lowest = t.lowest[:w*h]
+	for i := range t.magnitude {
+		t.magnitude[i] = 0
+		t.lowest[i] = uint8(numBps)
+	}
+	t.resetContexts()
+}
+
+// resetContexts sets the initial context states (Table D.7).
+func (t *t1Decoder) resetContexts() {
+	for i := range t.contexts {
+		t.contexts[i] = 0
+	}
+	t.contexts[0] = 4 << 1
+	t.contexts[ctxRunLength] = 3 << 1
+	t.contexts[ctxUniform] = 46 << 1
+}
+
+// bit decodes a bit either in the arithmetic or raw mode.
+func (t *t1Decoder) bit(ctx int) int {
+	if t.useRaw {
+		return t.raw.decode()
+	}
+	return t.mq.decode(&t.contexts[ctx])
+}
+
+// isSignificant returns 1 if the sample at flags index `i` is significant.
+func (t *t1Decoder) isSignificant(i int) int {
+	return int(t.flags[i] & flagSignificant)
+}
+
+// neighbours counts the significant horizontal, vertical and diagonal neighbours of the sample at
+// (x, y). In vertically causal mode, samples of the next stripe are considered insignificant.
+func (t *t1Decoder) neighbours(x, y int) (h, v, d int) {
+	i := (y+1)*t.stride + x + 1
+	up := i - t.stride
+	down := i + t.stride
+	h = t.isSignificant(i-1) + t.isSignificant(i+1)
+	v = t.isSignificant(up)
+	d = t.isSignificant(up-1) + t.isSignificant(up+1)
+	if t.cbStyle&cbCausal == 0 || y%4 != 3 {
+		v += t.isSignificant(down)
+		d += t.isSignificant(down-1) + t.isSignificant(down+1)
+	}
+	return h, v, d
+}
+
+// zeroCodingContext returns the significance coding context of the sample at (x, y).
+func (t *t1Decoder) zeroCodingContext(x, y int) int {
+	h, v, d := t.neighbours(x, y)
+	return int(zeroCodingLabels[t.band][h][v][d])
+}
+
+// signContribution returns the sign contribution of the sample at flags index `i`.
+func (t *t1Decoder) signContribution(i int) int {
+	f := t.flags[i]
+	if f&flagSignificant == 0 {
+		return 0
+	}
+	if f&flagNegative != 0 {
+		return -1
+	}
+	return 1
+}
+
+// signContext returns the sign coding context of the sample at (x, y) and the bit to XOR with the
+// decoded symbol (Table D.3).
+func (t *t1Decoder) signContext(x, y int) (ctx, xor int) {
+	i := (y+1)*t.stride + x + 1
+	hc := t.signContribution(i-1) + t.signContribution(i+1)
+	vc := t.signContribution(i - t.stride)
+	if t.cbStyle&cbCausal == 0 || y%4 != 3 {
+		vc += t.signContribution(i + t.stride)
+	}
+	hc = clampUnit(hc)
+	vc = clampUnit(vc)
+	if hc < 0 || (hc == 0 && vc < 0) {
+		hc, vc, xor = -hc, -vc, 1
+	}
+	if hc == 1 {
+		return ctxSign + 3 + vc, xor
+	}
+	return ctxSign + vc, xor
+}
+
+// decodeSign decodes the sign of the sample at (x, y) (D.3.2) and marks it significant.
+func (t *t1Decoder) decodeSign(x, y, bp int) {
+	ctx, xor := t.signContext(x, y)
+	i := (y+1)*t.stride + x + 1
+	t.flags[i] |= flagSignificant
+	if t.bit(ctx)^xor != 0 {
+		t.flags[i] |= flagNegative
+	}
+	t.magnitude[y*t.w+x] = 1 << uint(bp)
+}
+
+// clampUnit clamps `v` to [-1, 1].
+func clampUnit(v int) int {
+	if v > 1 {
+		return 1
+	} else if v < -1 {
+		return -1
+	}
+	return v
+}
+
+// significancePass performs the significance propagation pass (D.3.1) on bit-plane `bp`.
+func (t *t1Decoder) significancePass(bp int) {
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			for y := y0; y < y0+4 && y < t.h; y++ {
+				i := (y+1)*t.stride + x + 1
+				if t.flags[i]&flagSignificant != 0 {
+					continue
+				}
+				ctx := t.zeroCodingContext(x, y)
+				if ctx == 0 {
+					continue
+				}
+				t.flags[i] |= flagVisited
+				t.lowest[y*t.w+x] = uint8(bp)
+				if t.bit(ctx) != 0 {
+					t.decodeSign(x, y, bp)
+				}
+			}
+		}
+	}
+}
+
+// refinementPass performs the magnitude refinement pass (D.3.3) on bit-plane `bp`.
+func (t *t1Decoder) refinementPass(bp int) {
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			for y := y0; y < y0+4 && y < t.h; y++ {
+				i := (y+1)*t.stride + x + 1
+				f := t.flags[i]
+				if f&flagSignificant == 0 || f&flagVisited != 0 {
+					continue
+				}
+				ctx := ctxRefinement + 2
+				if f&flagRefined == 0 {
+					ctx = ctxRefinement
+					if h, v, d := t.neighbours(x, y); h+v+d > 0 {
+						ctx = ctxRefinement + 1
+					}
+				}
+				if t.bit(ctx) != 0 {
+					t.magnitude[y*t.w+x] |= 1 << uint(bp)
+				}
+				t.flags[i] |= flagRefined
+				t.lowest[y*t.w+x] = uint8(bp)
+			}
+		}
+	}
+}
+
+// cleanupPass performs the cleanup pass (D.3.4) on bit-plane `bp`.
+func (t *t1Decoder) cleanupPass(bp int) {
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			y := y0
+			if y0+4 <= t.h && t.runLengthCandidate(x, y0) {
+				for k := 0; k < 4; k++ {
+					t.lowest[(y0+k)*t.w+x] = uint8(bp)
+				}
+				if t.mq.decode(&t.contexts[ctxRunLength]) == 0 {
+					continue
+				}
+				r := t.mq.decode(&t.contexts[ctxUniform]) << 1
+				r |= t.mq.decode(&t.contexts[ctxUniform])
+				y = y0 + r
+				t.decodeSign(x, y, bp)
+				y++
+			}
+			for ; y < y0+4 && y < t.h; y++ {
+				i := (y+1)*t.stride + x + 1
+				if t.flags[i]&(flagSignificant|flagVisited) != 0 {
+					continue
+				}
+				t.lowest[y*t.w+x] = uint8(bp)
+				if t.mq.decode(&t.contexts[t.zeroCodingContext(x, y)]) != 0 {
+					t.decodeSign(x, y, bp)
+				}
+			}
+		}
+	}
+	if t.cbStyle&cbSegmentSymbol != 0 {
+		// The segmentation symbol 1010 is decoded and ignored.
+		for k := 0; k < 4; k++ {
+			t.mq.decode(&t.contexts[ctxUniform])
+		}
+	}
+}
+
+// runLengthCandidate checks whether the column of the stripe at (x, y0) can be coded in run-length
+// mode: all four samples are insignificant, not visited and have no significant neighbours.
+func (t *t1Decoder) runLengthCandidate(x, y0 int) bool {
+	for k := 0; k < 4; k++ {
+		i := (y0+k+1)*t.stride + x + 1
+		if t.flags[i]&(flagSignificant|flagVisited) != 0 {
+			return false
+		}
+		if h, v, d := t.neighbours(x, y0+k); h+v+d != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// clearVisited clears the visited flags at the end of a bit-plane.
+func (t *t1Decoder) clearVisited() {
+	for i := range t.flags {
+		t.flags[i] &^= flagVisited
+	}
+}
+
+// isRawPass checks whether pass number `pass` is coded without arithmetic coding in bypass mode.
+func isRawPass(cbStyle, pass int) bool {
+	return cbStyle&cbBypass != 0 && pass >= 10 && (pass-10)%3 != 2
+}
+
+// decode runs the coding passes of code-block `cb`, which has `numBps` magnitude bit-planes.
+func (t *t1Decoder) decode(cb *codeBlock, numBps int) {
+	bp := numBps - 1
+	passType := passCleanup
+	pass := 0
+	for _, seg := range cb.segments {
+		t.useRaw = isRawPass(t.cbStyle, pass)
+		if t.useRaw {
+			t.raw.init(seg.data)
+		} else {
+			t.mq.init(seg.data)
+		}
+		for i := 0; i < seg.passes; i++ {
+			if bp < 0 {
+				return
+			}
+			switch passType {
+			case passSignificance:
+				t.significancePass(bp)
+			case passRefinement:
+				t.refinementPass(bp)
+			case passCleanup:
+				t.cleanupPass(bp)
+			}
+			if t.cbStyle&cbReset != 0 {
+				t.resetContexts()
+			}
+			if passType == passCleanup {
+				t.clearVisited()
+				bp--
+				passType = passSignificance
+			} else {
+				passType++
+			}
+			pass++
+		}
+	}
+}
+
+// value returns the reconstructed quantization index of the sample at (x, y). Samples are
+// reconstructed at the middle of their uncertainty interval, except for fully decoded samples of
+// reversibly transformed code-blocks, which are exact.
+func (t *t1Decoder) value(x, y int, reversible bool) float32 {
+	i := y*t.w + x
+	m := t.magnitude[i]
+	if m == 0 {
+		return 0
+	}
+	v := float32(m)
+	if low := t.lowest[i]; !reversible {
+		v += float32(uint64(1)<<low) / 2
+	} else if low > 0 {
+		v += float32(uint32(1) << (low - 1))
+	}
+	if t.flags[(y+1)*t.stride+x+1]&flagNegative != 0 {
+		return -v
+	}
+	return v
+}
+
+// applyROI undoes the region of interest scaling with the maximum shift method (Annex H): the
+// magnitudes of samples in the region of interest are at least 2^`shift` and are shifted down.
+func (t *t1Decoder) applyROI(shift int) {
+	if shift <= 0 {
+		return
+	}
+	for i, m := range t.magnitude {
+		if m < 1<<uint(shift) {
+			continue
+		}
+		t.magnitude[i] = m >> uint(shift)
+		low := int(t.lowest[i]) - shift
+		if low < 0 {
+			low = 0
+		}
+		t.lowest[i] = uint8(low)
+	}
+}
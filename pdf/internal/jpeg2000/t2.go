@@ -0,0 +1,404 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpeg2000
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// tagTreeInf is the initial value of tag tree nodes which are not yet known.
+const tagTreeInf = 1 << 30
+
+// tagTree is a tag tree (B.10.2) used for the inclusion and zero bit-plane information.
+type tagTree struct {
+	// levels holds the node values from the leaves to the root.
+	levels []tagTreeLevel
+}
+
+type tagTreeLevel struct {
+	w, h  int
+	value []int
+	low   []int
+}
+
+// newTagTree creates a tag tree with `w`x`h` leaves.
+func newTagTree(w, h int) *tagTree {
+	t := &tagTree{}
+	for {
+		lvl := tagTreeLevel{w: w, h: h, value: make([]int, w*h), low: make([]int, w*h)}
+		for i := range lvl.value {
+			lvl.value[i] = tagTreeInf
+		}
+		t.levels = append(t.levels, lvl)
+		if w <= 1 && h <= 1 {
+			break
+		}
+		w = (w + 1) / 2
+		h = (h + 1) / 2
+	}
+	return t
+}
+
+// decode decodes the leaf at (x, y) up to `threshold`. It returns true if the value of the leaf is
+// known to be lower than the threshold.
+func (t *tagTree) decode(br *bitReader, x, y, threshold int) (bool, error) {
+	low := 0
+	for l := len(t.levels) - 1; l >= 0; l-- {
+		lvl := &t.levels[l]
+		i := (y>>uint(l))*lvl.w + (x >> uint(l))
+		if low > lvl.low[i] {
+			lvl.low[i] = low
+		} else {
+			low = lvl.low[i]
+		}
+		for low < threshold && low < lvl.value[i] {
+			bit, err := br.readBit()
+			if err != nil {
+				return false, err
+			}
+			if bit == 1 {
+				lvl.value[i] = low
+			} else {
+				low++
+			}
+		}
+		lvl.low[i] = low
+	}
+	return t.levels[0].value[y*t.levels[0].w+x] < threshold, nil
+}
+
+// value returns the decoded value of leaf (x, y).
+func (t *tagTree) value(x, y int) int {
+	return t.levels[0].value[y*t.levels[0].w+x]
+}
+
+// bitReader reads the bits of packet headers, skipping the zero bit stuffed after each 0xFF byte.
+type bitReader struct {
+	data   []byte
+	pos    int
+	cur    byte
+	n      uint
+	lastFF bool
+}
+
+// readBit reads a single bit.
+func (br *bitReader) readBit() (int, error) {
+	if br.n == 0 {
+		if br.pos >= len(br.data) {
+			return 0, errUnexpectedEOF
+		}
+		br.cur = br.data[br.pos]
+		br.pos++
+		br.n = 8
+		if br.lastFF {
+			br.n = 7
+		}
+		br.lastFF = br.cur == 0xFF
+	}
+	br.n--
+	return int(br.cur>>br.n) & 1, nil
+}
+
+// readBits reads `n` bits as an unsigned integer.
+func (br *bitReader) readBits(n int) (int, error) {
+	v := 0
+	for i := 0; i < n; i++ {
+		bit, err := br.readBit()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<1 | bit
+	}
+	return v, nil
+}
+
+// align skips to the end of the packet header. If the last byte was 0xFF, the following byte holding
+// the stuffed bit belongs to the header as well.
+func (br *bitReader) align() {
+	br.n = 0
+	if br.lastFF {
+		br.pos++
+		br.lastFF = false
+	}
+}
+
+// readNumPasses reads the number of new coding passes (Table B.4).
+func (br *bitReader) readNumPasses() (int, error) {
+	if bit, err := br.readBit(); err != nil || bit == 0 {
+		return 1, err
+	}
+	if bit, err := br.readBit(); err != nil || bit == 0 {
+		return 2, err
+	}
+	v, err := br.readBits(2)
+	if err != nil || v != 3 {
+		return 3 + v, err
+	}
+	v, err = br.readBits(5)
+	if err != nil || v != 31 {
+		return 6 + v, err
+	}
+	v, err = br.readBits(7)
+	return 37 + v, err
+}
+
+// packetSource provides the packet headers and bodies of a tile.
+type packetSource struct {
+	body []byte
+	pos  int
+	// hdr reads the headers; it shares the body data unless packed packet headers are used.
+	hdr    *bitReader
+	packed bool
+	sop    bool
+	eph    bool
+}
+
+// skipMarker skips the marker `marker` (and its segment of `length` bytes) at `pos` in `data` if present.
+func skipMarker(data []byte, pos, marker, length int) int {
+	if pos+2 <= len(data) && int(data[pos])<<8|int(data[pos+1]) == marker {
+		return pos + length
+	}
+	return pos
+}
+
+// contribution is the data of a codeword segment contributed to a code-block by a packet.
+type contribution struct {
+	seg    *segment
+	length int
+}
+
+// readPacket decodes the packet of `layer` for precinct `p` of resolution `res` (B.10).
+func (ps *packetSource) readPacket(res *resolution, p *precinct, layer int, cbStyle int) error {
+	if ps.sop {
+		ps.pos = skipMarker(ps.body, ps.pos, markerSOP, 6)
+	}
+	br := ps.hdr
+	if !ps.packed {
+		br.data = ps.body
+		br.pos = ps.pos
+	}
+	br.n = 0
+	br.lastFF = false
+
+	var contributions []contribution
+	present, err := br.readBit()
+	if err != nil {
+		return err
+	}
+	if present == 1 {
+		for _, pb := range p.bands {
+			for i, cb := range pb.blocks {
+				x, y := i%pb.numX, i/pb.numX
+				var included bool
+				if cb.included {
+					bit, err := br.readBit()
+					if err != nil {
+						return err
+					}
+					included = bit == 1
+				} else {
+					included, err = pb.inclusion.decode(br, x, y, layer+1)
+					if err != nil {
+						return err
+					}
+					if included {
+						for threshold := 1; ; threshold++ {
+							known, err := pb.zeroPlanes.decode(br, x, y, threshold)
+							if err != nil {
+								return err
+							}
+							if known {
+								break
+							}
+						}
+						cb.zeroPlanes = pb.zeroPlanes.value(x, y)
+						cb.included = true
+					}
+				}
+				if !included {
+					continue
+				}
+				passes, err := br.readNumPasses()
+				if err != nil {
+					return err
+				}
+				for {
+					bit, err := br.readBit()
+					if err != nil {
+						return err
+					}
+					if bit == 0 {
+						break
+					}
+					cb.lblock++
+				}
+				for passes > 0 {
+					var seg *segment
+					if n := len(cb.segments); n > 0 && cb.segments[n-1].passes < cb.segments[n-1].maxPasses {
+						seg = cb.segments[n-1]
+					} else {
+						seg = &segment{maxPasses: segmentPasses(cbStyle, cb.passes)}
+						cb.segments = append(cb.segments, seg)
+					}
+					k := minInt(passes, seg.maxPasses-seg.passes)
+					length, err := br.readBits(cb.lblock + bits.Len(uint(k)) - 1)
+					if err != nil {
+						return err
+					}
+					seg.passes += k
+					cb.passes += k
+					passes -= k
+					contributions = append(contributions, contribution{seg: seg, length: length})
+				}
+			}
+		}
+	}
+	br.align()
+	if ps.eph {
+		br.pos = skipMarker(br.data, br.pos, markerEPH, 2)
+	}
+	if !ps.packed {
+		ps.pos = br.pos
+	}
+	for _, c := range contributions {
+		end := ps.pos + c.length
+		if end > len(ps.body) {
+			// Truncated data: use what is available.
+			end = len(ps.body)
+		}
+		c.seg.data = append(c.seg.data, ps.body[ps.pos:end]...)
+		ps.pos = end
+	}
+	return nil
+}
+
+// packetOrder lists the packets of a tile in the order they appear in the codestream.
+type packetOrder struct {
+	comps  []*tileComponent
+	layers int
+	visit  func(c, r int, p *precinct, layer int) error
+}
+
+// precinctRef identifies a precinct with its position on the reference grid, used by the position
+// driven progression orders.
+type precinctRef struct {
+	c, r int
+	p    *precinct
+	x, y int
+}
+
+// run visits the packets of the progression `pc` (B.12).
+func (po *packetOrder) run(pc progressionChange, tr rect, siz *imageSize) error {
+	layerEnd := minInt(pc.layerEnd, po.layers)
+	compEnd := minInt(pc.compEnd, len(po.comps))
+	maxRes := 0
+	for _, tc := range po.comps {
+		maxRes = maxInt(maxRes, len(tc.resolutions))
+	}
+	resEnd := minInt(pc.resEnd, maxRes)
+
+	visit := func(c, r, layer int, p *precinct) error {
+		if p.nextLayer != layer {
+			return nil
+		}
+		p.nextLayer++
+		return po.visit(c, r, p, layer)
+	}
+
+	switch pc.order {
+	case progressionLRCP, progressionRLCP:
+		outer, inner := layerEnd, resEnd
+		if pc.order == progressionRLCP {
+			outer, inner = resEnd, layerEnd
+		}
+		for a := 0; a < outer; a++ {
+			for b := 0; b < inner; b++ {
+				layer, r := a, b
+				if pc.order == progressionRLCP {
+					layer, r = b, a
+				}
+				if r < pc.resStart {
+					continue
+				}
+				for c := pc.compStart; c < compEnd; c++ {
+					if r >= len(po.comps[c].resolutions) {
+						continue
+					}
+					for _, p := range po.comps[c].resolutions[r].precincts {
+						if err := visit(c, r, layer, p); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+		return nil
+	}
+
+	// Position driven progressions: order the precincts by their position on the reference grid.
+	var refs []precinctRef
+	for c := pc.compStart; c < compEnd; c++ {
+		tc := po.comps[c]
+		comp := siz.components[c]
+		nl := len(tc.resolutions) - 1
+		for r := pc.resStart; r < resEnd && r <= nl; r++ {
+			res := tc.resolutions[r]
+			for _, p := range res.precincts {
+				px := res.precX0 + p.index%res.numPrecX
+				py := res.precY0 + p.index/res.numPrecX
+				x := maxInt(tr.x0, px*comp.dx<<uint(res.ppx+nl-r))
+				y := maxInt(tr.y0, py*comp.dy<<uint(res.ppy+nl-r))
+				refs = append(refs, precinctRef{c: c, r: r, p: p, x: x, y: y})
+			}
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		switch pc.order {
+		case progressionRPCL:
+			if a.r != b.r {
+				return a.r < b.r
+			}
+			if a.y != b.y {
+				return a.y < b.y
+			}
+			if a.x != b.x {
+				return a.x < b.x
+			}
+			return a.c < b.c
+		case progressionPCRL:
+			if a.y != b.y {
+				return a.y < b.y
+			}
+			if a.x != b.x {
+				return a.x < b.x
+			}
+			if a.c != b.c {
+				return a.c < b.c
+			}
+			return a.r < b.r
+		}
+		if a.c != b.c {
+			return a.c < b.c
+		}
+		if a.y != b.y {
+			return a.y < b.y
+		}
+		if a.x != b.x {
+			return a.x < b.x
+		}
+		return a.r < b.r
+	})
+	for _, ref := range refs {
+		for layer := ref.p.nextLayer; layer < layerEnd; layer++ {
+			if err := visit(ref.c, ref.r, layer, ref.p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
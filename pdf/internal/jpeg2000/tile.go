@@ -0,0 +1,275 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpeg2000
+
+import (
+	"math"
+
+	"github.com/unidoc/unidoc/common"
+)
+
+// maxPassesPerSegment is used for the codeword segments that are not terminated before the last pass.
+const maxPassesPerSegment = 164
+
+// rect is a rectangle on a sample grid, including x0, y0 and excluding x1, y1.
+type rect struct {
+	x0, y0, x1, y1 int
+}
+
+func (r rect) width() int  { return r.x1 - r.x0 }
+func (r rect) height() int { return r.y1 - r.y0 }
+func (r rect) empty() bool { return r.x1 <= r.x0 || r.y1 <= r.y0 }
+
+// intersect returns the intersection of the rectangles.
+func (r rect) intersect(o rect) rect {
+	return rect{maxInt(r.x0, o.x0), maxInt(r.y0, o.y0), minInt(r.x1, o.x1), minInt(r.y1, o.y1)}
+}
+
+// scaleDown returns the rectangle with coordinates ceil(c/2^n) (B-14).
+func (r rect) scaleDown(n uint) rect {
+	d := 1 << n
+	return rect{ceilDiv(r.x0, d), ceilDiv(r.y0, d), ceilDiv(r.x1, d), ceilDiv(r.y1, d)}
+}
+
+// tileComponent holds the decoding state of a component within a tile.
+type tileComponent struct {
+	rect
+	style       *componentStyle
+	quant       *quantization
+	roiShift    int
+	precision   int
+	resolutions []*resolution
+}
+
+// resolution is a resolution level of a tile-component.
+type resolution struct {
+	rect
+	level    int
+	ppx, ppy int
+	// precinct grid: origin index and number of precincts.
+	precX0, precY0 int
+	numPrecX       int
+	numPrecY       int
+	bands          []*subband
+	precincts      []*precinct
+}
+
+// subband holds the coefficients of a subband of a tile-component.
+type subband struct {
+	rect
+	orientation int
+	cbw, cbh    int
+	numBps      int
+	delta       float32
+	coefs       []float32
+}
+
+// precinct holds the packet decoding state of a precinct.
+type precinct struct {
+	index     int
+	nextLayer int
+	bands     []*precinctBand
+}
+
+// precinctBand holds the code-blocks of a subband that belong to a precinct.
+type precinctBand struct {
+	numX, numY int
+	blocks     []*codeBlock
+	inclusion  *tagTree
+	zeroPlanes *tagTree
+}
+
+// codeBlock holds the coded data of a code-block.
+type codeBlock struct {
+	rect
+	included   bool
+	lblock     int
+	zeroPlanes int
+	passes     int
+	segments   []*segment
+}
+
+// segment is a codeword segment: the data of a number of coding passes terminated together.
+type segment struct {
+	data      []byte
+	passes    int
+	maxPasses int
+}
+
+// segmentPasses returns the maximal number of passes in a codeword segment starting with pass number `pass`.
+func segmentPasses(cbStyle, pass int) int {
+	switch {
+	case cbStyle&cbTermAll != 0:
+		return 1
+	case cbStyle&cbBypass != 0:
+		if pass < 10 {
+			return 10 - pass
+		}
+		if (pass-10)%3 == 2 {
+			return 1
+		}
+		return 2
+	}
+	return maxPassesPerSegment
+}
+
+// newTileComponent sets up the geometry of component `c` of the tile covering `tr` on the reference grid.
+func newTileComponent(tr rect, comp componentSize, style *componentStyle, quant *quantization, roiShift int) (*tileComponent, error) {
+	tc := &tileComponent{
+		rect: rect{
+			ceilDiv(tr.x0, comp.dx), ceilDiv(tr.y0, comp.dy),
+			ceilDiv(tr.x1, comp.dx), ceilDiv(tr.y1, comp.dy),
+		},
+		style:     style,
+		quant:     quant,
+		roiShift:  roiShift,
+		precision: comp.precision,
+	}
+	nl := style.levels
+	for r := 0; r <= nl; r++ {
+		res := &resolution{rect: tc.rect.scaleDown(uint(nl - r)), level: r, ppx: 15, ppy: 15}
+		if style.precincts != nil {
+			res.ppx = style.precincts[r][0]
+			res.ppy = style.precincts[r][1]
+			if r > 0 && (res.ppx == 0 || res.ppy == 0) {
+				return nil, errInvalidMarker
+			}
+		}
+		if !res.empty() {
+			res.precX0 = res.x0 >> uint(res.ppx)
+			res.precY0 = res.y0 >> uint(res.ppy)
+			res.numPrecX = ceilDiv(res.x1, 1<<uint(res.ppx)) - res.precX0
+			res.numPrecY = ceilDiv(res.y1, 1<<uint(res.ppy)) - res.precY0
+		}
+
+		// Subbands of the resolution (B.5).
+		if r == 0 {
+			res.bands = []*subband{{rect: res.rect, orientation: bandLL}}
+		} else {
+			nb := uint(nl - r + 1)
+			for _, o := range []int{bandHL, bandLH, bandHH} {
+				xo, yo := o&1, o>>1
+				band := &subband{orientation: o}
+				band.x0 = ceilDiv(tc.x0-(xo<<(nb-1)), 1<<nb)
+				band.y0 = ceilDiv(tc.y0-(yo<<(nb-1)), 1<<nb)
+				band.x1 = ceilDiv(tc.x1-(xo<<(nb-1)), 1<<nb)
+				band.y1 = ceilDiv(tc.y1-(yo<<(nb-1)), 1<<nb)
+				res.bands = append(res.bands, band)
+			}
+		}
+		for i, band := range res.bands {
+			if err := tc.setupBand(band, res, i); err != nil {
+				return nil, err
+			}
+		}
+		res.precincts = make([]*precinct, res.numPrecX*res.numPrecY)
+		for i := range res.precincts {
+			res.precincts[i] = newPrecinct(res, i)
+		}
+		tc.resolutions = append(tc.resolutions, res)
+	}
+	return tc, nil
+}
+
+// setupBand computes the code-block size, the number of bit-planes and the quantization step of `band`,
+// the `i`th subband of resolution `res`.
+func (tc *tileComponent) setupBand(band *subband, res *resolution, i int) error {
+	ppx, ppy := res.ppx, res.ppy
+	if res.level > 0 {
+		ppx--
+		ppy--
+	}
+	band.cbw = minInt(tc.style.cbw, ppx)
+	band.cbh = minInt(tc.style.cbh, ppy)
+	if !band.empty() {
+		band.coefs = make([]float32, band.width()*band.height())
+	}
+
+	// Quantization (E.1).
+	q := tc.quant
+	if len(q.steps) == 0 {
+		return errInvalidMarker
+	}
+	idx := 0
+	if res.level > 0 {
+		idx = 1 + 3*(res.level-1) + i
+	}
+	var exp, mant int
+	if q.style == quantDerived {
+		nb := tc.style.levels
+		if res.level > 0 {
+			nb = tc.style.levels - res.level + 1
+		}
+		exp = q.steps[0][0] - tc.style.levels + nb
+		mant = q.steps[0][1]
+	} else {
+		if idx >= len(q.steps) {
+			common.Log.Debug("JPX missing quantization step for subband %d", idx)
+			return errInvalidMarker
+		}
+		exp, mant = q.steps[idx][0], q.steps[idx][1]
+	}
+	band.numBps = q.guard + exp - 1 + tc.roiShift
+	if band.numBps > 31 {
+		return errUnsupported
+	}
+	band.delta = 1
+	if !tc.style.reversible {
+		gain := [4]int{0, 1, 1, 2}[band.orientation]
+		band.delta = float32(math.Ldexp(1+float64(mant)/2048, tc.precision+gain-exp))
+	}
+	return nil
+}
+
+// newPrecinct creates the precinct with index `index` of resolution `res` and its code-blocks.
+func newPrecinct(res *resolution, index int) *precinct {
+	p := &precinct{index: index}
+	px := res.precX0 + index%res.numPrecX
+	py := res.precY0 + index/res.numPrecX
+	for _, band := range res.bands {
+		ppx, ppy := res.ppx, res.ppy
+		if res.level > 0 {
+			ppx--
+			ppy--
+		}
+		region := rect{px << uint(ppx), py << uint(ppy), (px + 1) << uint(ppx), (py + 1) << uint(ppy)}
+		region = region.intersect(band.rect)
+		pb := &precinctBand{}
+		if !region.empty() {
+			cbx0 := region.x0 >> uint(band.cbw)
+			cby0 := region.y0 >> uint(band.cbh)
+			pb.numX = ceilDiv(region.x1, 1<<uint(band.cbw)) - cbx0
+			pb.numY = ceilDiv(region.y1, 1<<uint(band.cbh)) - cby0
+			for j := 0; j < pb.numY; j++ {
+				for i := 0; i < pb.numX; i++ {
+					x := (cbx0 + i) << uint(band.cbw)
+					y := (cby0 + j) << uint(band.cbh)
+					cb := &codeBlock{rect: rect{x, y, x + 1<<uint(band.cbw), y + 1<<uint(band.cbh)}, lblock: 3}
+					cb.rect = cb.rect.intersect(region)
+					pb.blocks = append(pb.blocks, cb)
+				}
+			}
+			pb.inclusion = newTagTree(pb.numX, pb.numY)
+			pb.zeroPlanes = newTagTree(pb.numX, pb.numY)
+		}
+		p.bands = append(p.bands, pb)
+	}
+	return p
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,550 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+// PdfActionType is the type of an action (Table 198 - Action types).
+type PdfActionType string
+
+// Action types.
+const (
+	ActionTypeGoTo       PdfActionType = "GoTo"
+	ActionTypeGoToR      PdfActionType = "GoToR"
+	ActionTypeLaunch     PdfActionType = "Launch"
+	ActionTypeURI        PdfActionType = "URI"
+	ActionTypeNamed      PdfActionType = "Named"
+	ActionTypeSubmitForm PdfActionType = "SubmitForm"
+	ActionTypeResetForm  PdfActionType = "ResetForm"
+	ActionTypeJavaScript PdfActionType = "JavaScript"
+)
+
+// Flags of the submit-form actions (Table 237 - Flags for submit-form actions).
+const (
+	SubmitFormFlagExclude              = 1 << 0
+	SubmitFormFlagIncludeNoValueFields = 1 << 1
+	SubmitFormFlagExportFormat         = 1 << 2
+	SubmitFormFlagGetMethod            = 1 << 3
+	SubmitFormFlagSubmitCoordinates    = 1 << 4
+	SubmitFormFlagXFDF                 = 1 << 5
+	SubmitFormFlagIncludeAppendSaves   = 1 << 6
+	SubmitFormFlagIncludeAnnotations   = 1 << 7
+	SubmitFormFlagSubmitPDF            = 1 << 8
+	SubmitFormFlagCanonicalFormat      = 1 << 9
+	SubmitFormFlagExclNonUserAnnots    = 1 << 10
+	SubmitFormFlagExclFKey             = 1 << 11
+	SubmitFormFlagEmbedForm            = 1 << 13
+)
+
+// ResetFormFlagExclude resets all the fields except the fields of the reset-form action
+// (Table 239 - Flag for reset-form actions).
+const ResetFormFlagExclude = 1 << 0
+
+// maxActions limits the number of actions of an action chain (Next entries).
+const maxActions = 1000
+
+// PdfAction represents an action (section 12.6 Actions). The fields specific to the action type
+// are in the context, such as PdfActionURI, which is nil for the unsupported types. The entries
+// of the action dictionary which are not represented are kept.
+type PdfAction struct {
+	// context contains the specific action fields.
+	context PdfModel
+
+	// S is the type of the action.
+	S PdfActionType
+	// Next contains the actions performed after the action, in order.
+	Next []*PdfAction
+
+	container *core.PdfObjectDictionary
+	// indirect is the indirect object of the action dictionary read from a document, nil if the
+	// dictionary is a direct object.
+	indirect *core.PdfIndirectObject
+}
+
+// PdfActionGoTo represents a go-to action, which changes the view to a destination.
+// (Section 12.6.4.2).
+type PdfActionGoTo struct {
+	*PdfAction
+	// D is the destination: a destination array, a name or a string for named destinations.
+	D core.PdfObject
+}
+
+// PdfActionURI represents a URI action, which resolves a uniform resource identifier.
+// (Section 12.6.4.7).
+type PdfActionURI struct {
+	*PdfAction
+	URI string
+	// IsMap specifies whether to track the mouse position when the URI is resolved.
+	IsMap bool
+}
+
+// PdfActionLaunch represents a launch action, which launches an application or opens a document.
+// (Section 12.6.4.5).
+type PdfActionLaunch struct {
+	*PdfAction
+	// F is the application or document to launch.
+	F *PdfFileSpec
+	// NewWindow specifies whether to open the document in a new window, nil for the viewer
+	// preference.
+	NewWindow *bool
+}
+
+// PdfActionJavaScript represents a JavaScript action. (Section 12.6.4.16).
+type PdfActionJavaScript struct {
+	*PdfAction
+	// JS is the script.
+	JS string
+}
+
+// PdfActionNamed represents a named action, such as NextPage, PrevPage, FirstPage or LastPage.
+// (Section 12.6.4.11).
+type PdfActionNamed struct {
+	*PdfAction
+	// N is the name of the action.
+	N string
+}
+
+// PdfActionSubmitForm represents a submit-form action. (Section 12.7.5.2).
+type PdfActionSubmitForm struct {
+	*PdfAction
+	// URL is the URL of the script processing the submission.
+	URL string
+	// Fields are the fields to include (or exclude), as field dictionaries or fully qualified
+	// field names. All the fields are submitted if empty.
+	Fields []core.PdfObject
+	// Flags are a combination of the SubmitFormFlag values.
+	Flags int
+}
+
+// PdfActionResetForm represents a reset-form action. (Section 12.7.5.3).
+type PdfActionResetForm struct {
+	*PdfAction
+	// Fields are the fields to reset (or not to reset), as field dictionaries or fully qualified
+	// field names. All the fields are reset if empty.
+	Fields []core.PdfObject
+	// Flags are a combination of the ResetFormFlag values.
+	Flags int
+}
+
+// newPdfAction returns a new action of the type 'typ' with the context 'ctx'.
+func newPdfAction(typ PdfActionType, ctx PdfModel) *PdfAction {
+	return &PdfAction{
+		context:   ctx,
+		S:         typ,
+		container: core.MakeDict(),
+	}
+}
+
+// NewPdfActionGoTo returns a new go-to action to the destination 'dest', such as the object of a
+// PdfDestination or the name of a named destination. The page numbers of the destinations refer to
+// the pages of the PdfWriter.
+func NewPdfActionGoTo(dest core.PdfObject) *PdfActionGoTo {
+	action := &PdfActionGoTo{D: dest}
+	action.PdfAction = newPdfAction(ActionTypeGoTo, action)
+	return action
+}
+
+// NewPdfActionURI returns a new URI action to the URI 'uri'.
+func NewPdfActionURI(uri string) *PdfActionURI {
+	action := &PdfActionURI{URI: uri}
+	action.PdfAction = newPdfAction(ActionTypeURI, action)
+	return action
+}
+
+// NewPdfActionLaunch returns a new launch action of the file 'fs'.
+func NewPdfActionLaunch(fs *PdfFileSpec) *PdfActionLaunch {
+	action := &PdfActionLaunch{F: fs}
+	action.PdfAction = newPdfAction(ActionTypeLaunch, action)
+	return action
+}
+
+// NewPdfActionJavaScript returns a new JavaScript action running the script 'js'.
+func NewPdfActionJavaScript(js string) *PdfActionJavaScript {
+	action := &PdfActionJavaScript{JS: js}
+	action.PdfAction = newPdfAction(ActionTypeJavaScript, action)
+	return action
+}
+
+// NewPdfActionNamed returns a new named action 'name', such as NextPage.
+func NewPdfActionNamed(name string) *PdfActionNamed {
+	action := &PdfActionNamed{N: name}
+	action.PdfAction = newPdfAction(ActionTypeNamed, action)
+	return action
+}
+
+// NewPdfActionSubmitForm returns a new submit-form action submitting the fields to the URL 'url'.
+func NewPdfActionSubmitForm(url string) *PdfActionSubmitForm {
+	action := &PdfActionSubmitForm{URL: url}
+	action.PdfAction = newPdfAction(ActionTypeSubmitForm, action)
+	return action
+}
+
+// NewPdfActionResetForm returns a new reset-form action.
+func NewPdfActionResetForm() *PdfActionResetForm {
+	action := &PdfActionResetForm{}
+	action.PdfAction = newPdfAction(ActionTypeResetForm, action)
+	return action
+}
+
+// NewPdfActionFromObject loads the action dictionary 'obj' and the actions of its Next entry.
+func NewPdfActionFromObject(obj core.PdfObject) (*PdfAction, error) {
+	visited := map[*core.PdfObjectDictionary]struct{}{}
+	return newPdfActionFromObject(obj, visited)
+}
+
+func newPdfActionFromObject(obj core.PdfObject, visited map[*core.PdfObjectDictionary]struct{}) (*PdfAction, error) {
+	d, ok := core.GetDict(obj)
+	if !ok {
+		return nil, fmt.Errorf("invalid action type: %T", obj)
+	}
+	if len(visited) >= maxActions {
+		return nil, errors.New("too many actions")
+	}
+	visited[d] = struct{}{}
+
+	typ, ok := core.GetNameVal(d.Get("S"))
+	if !ok {
+		return nil, errors.New("missing action type")
+	}
+	action := &PdfAction{S: PdfActionType(typ), container: d}
+	action.indirect, _ = core.GetIndirect(obj)
+
+	switch action.S {
+	case ActionTypeGoTo:
+		ctx := &PdfActionGoTo{PdfAction: action, D: d.Get("D")}
+		action.context = ctx
+	case ActionTypeURI:
+		ctx := &PdfActionURI{PdfAction: action}
+		if str, ok := core.GetString(d.Get("URI")); ok {
+			ctx.URI = str.Str()
+		}
+		if isMap, ok := core.TraceToDirectObject(d.Get("IsMap")).(*core.PdfObjectBool); ok {
+			ctx.IsMap = bool(*isMap)
+		}
+		action.context = ctx
+	case ActionTypeLaunch:
+		ctx := &PdfActionLaunch{PdfAction: action}
+		if obj := d.Get("F"); obj != nil {
+			fs, err := NewPdfFileSpecFromObject(obj)
+			if err != nil {
+				return nil, err
+			}
+			ctx.F = fs
+		}
+		if newWindow, ok := core.TraceToDirectObject(d.Get("NewWindow")).(*core.PdfObjectBool); ok {
+			val := bool(*newWindow)
+			ctx.NewWindow = &val
+		}
+		action.context = ctx
+	case ActionTypeJavaScript:
+		ctx := &PdfActionJavaScript{PdfAction: action}
+		switch js := core.TraceToDirectObject(d.Get("JS")).(type) {
+		case *core.PdfObjectString:
+			ctx.JS = js.Decoded()
+		case *core.PdfObjectStream:
+			data, err := core.DecodeStream(js)
+			if err != nil {
+				return nil, err
+			}
+			ctx.JS = string(data)
+		}
+		action.context = ctx
+	case ActionTypeNamed:
+		ctx := &PdfActionNamed{PdfAction: action}
+		ctx.N, _ = core.GetNameVal(d.Get("N"))
+		action.context = ctx
+	case ActionTypeSubmitForm:
+		ctx := &PdfActionSubmitForm{PdfAction: action}
+		switch f := core.TraceToDirectObject(d.Get("F")).(type) {
+		case *core.PdfObjectString:
+			ctx.URL = f.Decoded()
+		case *core.PdfObjectDictionary:
+			// URL specification.
+			if str, ok := core.GetString(f.Get("F")); ok {
+				ctx.URL = str.Decoded()
+			}
+		}
+		if fields, ok := core.GetArray(d.Get("Fields")); ok {
+			ctx.Fields = fields.Elements()
+		}
+		ctx.Flags, _ = core.GetIntVal(d.Get("Flags"))
+		action.context = ctx
+	case ActionTypeResetForm:
+		ctx := &PdfActionResetForm{PdfAction: action}
+		if fields, ok := core.GetArray(d.Get("Fields")); ok {
+			ctx.Fields = fields.Elements()
+		}
+		ctx.Flags, _ = core.GetIntVal(d.Get("Flags"))
+		action.context = ctx
+	default:
+		common.Log.Trace("Unsupported action type: %s", typ)
+	}
+
+	// The Next entry is an action dictionary or an array of action dictionaries.
+	var next []core.PdfObject
+	switch t := core.TraceToDirectObject(d.Get("Next")).(type) {
+	case *core.PdfObjectDictionary:
+		next = []core.PdfObject{d.Get("Next")}
+	case *core.PdfObjectArray:
+		next = t.Elements()
+	}
+	for _, obj := range next {
+		if nd, ok := core.GetDict(obj); ok {
+			if _, ok := visited[nd]; ok {
+				common.Log.Debug("ERROR: Action chain loop - skipping")
+				continue
+			}
+		}
+		nextAction, err := newPdfActionFromObject(obj, visited)
+		if err != nil {
+			return nil, err
+		}
+		action.Next = append(action.Next, nextAction)
+	}
+	return action, nil
+}
+
+// GetContext returns the action context which contains the fields specific to the action type,
+// nil for the unsupported types.
+func (a *PdfAction) GetContext() PdfModel {
+	if a == nil {
+		return nil
+	}
+	return a.context
+}
+
+// GetContainingPdfObject implements interface PdfModel. It returns the dictionary the action was
+// loaded from, which is left unchanged by ToPdfObject.
+func (a *PdfAction) GetContainingPdfObject() core.PdfObject {
+	return a.container
+}
+
+// ToPdfObject implements interface PdfModel. It returns a new action dictionary with the fields of
+// the context and the actions of the chain.
+func (a *PdfAction) ToPdfObject() core.PdfObject {
+	if a.context != nil {
+		return a.context.ToPdfObject()
+	}
+	return a.toPdfObject()
+}
+
+// toPdfObject returns a copy of the container, which keeps the entries not represented, with the
+// entries common to all the actions.
+func (a *PdfAction) toPdfObject() *core.PdfObjectDictionary {
+	d := core.MakeDict()
+	for _, key := range a.container.Keys() {
+		d.Set(key, a.container.Get(key))
+	}
+	d.Set("Type", core.MakeName("Action"))
+	d.Set("S", core.MakeName(string(a.S)))
+	switch len(a.Next) {
+	case 0:
+		d.Remove("Next")
+	case 1:
+		d.Set("Next", a.Next[0].nextObject())
+	default:
+		next := core.MakeArray()
+		for _, action := range a.Next {
+			next.Append(action.nextObject())
+		}
+		d.Set("Next", next)
+	}
+	return d
+}
+
+// nextObject returns the object of the action in the Next entry of the previous action of the
+// chain, an indirect object if the action was read from an indirect object.
+func (a *PdfAction) nextObject() core.PdfObject {
+	obj := a.ToPdfObject()
+	if a.indirect == nil {
+		return obj
+	}
+	return core.MakeIndirectObject(obj)
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionGoTo) ToPdfObject() core.PdfObject {
+	d := a.toPdfObject()
+	d.SetIfNotNil("D", a.D)
+	return d
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionURI) ToPdfObject() core.PdfObject {
+	d := a.toPdfObject()
+	d.Set("URI", core.MakeString(a.URI))
+	if a.IsMap {
+		d.Set("IsMap", core.MakeBool(true))
+	} else {
+		d.Remove("IsMap")
+	}
+	return d
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionLaunch) ToPdfObject() core.PdfObject {
+	d := a.toPdfObject()
+	if a.F != nil {
+		d.Set("F", a.F.ToPdfObject())
+	} else {
+		d.Remove("F")
+	}
+	if a.NewWindow != nil {
+		d.Set("NewWindow", core.MakeBool(*a.NewWindow))
+	} else {
+		d.Remove("NewWindow")
+	}
+	return d
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionJavaScript) ToPdfObject() core.PdfObject {
+	d := a.toPdfObject()
+	d.Set("JS", makeTextString(a.JS))
+	return d
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionNamed) ToPdfObject() core.PdfObject {
+	d := a.toPdfObject()
+	d.Set("N", core.MakeName(a.N))
+	return d
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionSubmitForm) ToPdfObject() core.PdfObject {
+	d := a.toPdfObject()
+	url := core.MakeDict()
+	url.Set("FS", core.MakeName("URL"))
+	url.Set("F", core.MakeString(a.URL))
+	d.Set("F", url)
+	setActionFields(d, a.Fields, a.Flags)
+	return d
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionResetForm) ToPdfObject() core.PdfObject {
+	d := a.toPdfObject()
+	setActionFields(d, a.Fields, a.Flags)
+	return d
+}
+
+// setActionFields sets the Fields and Flags entries of the form action dictionary 'd'.
+func setActionFields(d *core.PdfObjectDictionary, fields []core.PdfObject, flags int) {
+	if len(fields) > 0 {
+		d.Set("Fields", core.MakeArray(fields...))
+	} else {
+		d.Remove("Fields")
+	}
+	if flags != 0 {
+		d.Set("Flags", core.MakeInteger(int64(flags)))
+	} else {
+		d.Remove("Flags")
+	}
+}
+
+// loadAdditionalActions loads the additional-actions dictionary 'obj' by trigger event, such as
+// E (enter) or K (keystroke). The invalid actions are skipped.
+func loadAdditionalActions(obj core.PdfObject) (map[string]*PdfAction, error) {
+	actions := map[string]*PdfAction{}
+	if obj == nil {
+		return actions, nil
+	}
+	aa, ok := core.GetDict(obj)
+	if !ok {
+		return nil, fmt.Errorf("invalid additional actions type: %T", obj)
+	}
+	for _, trigger := range aa.Keys() {
+		action, err := NewPdfActionFromObject(aa.Get(trigger))
+		if err != nil {
+			common.Log.Debug("Invalid additional action %s: %v", trigger, err)
+			continue
+		}
+		actions[string(trigger)] = action
+	}
+	return actions, nil
+}
+
+// setAdditionalAction sets the action of the trigger event 'trigger' of the additional-actions
+// dictionary 'obj' and returns the dictionary, which is created if 'obj' is nil. The action is
+// removed if 'action' is nil.
+func setAdditionalAction(obj core.PdfObject, trigger string, action *PdfAction) core.PdfObject {
+	aa, ok := core.GetDict(obj)
+	if !ok {
+		aa = core.MakeDict()
+		obj = aa
+	}
+	if action == nil {
+		aa.Remove(core.PdfObjectName(trigger))
+	} else {
+		aa.Set(core.PdfObjectName(trigger), action.ToPdfObject())
+	}
+	return obj
+}
+
+// GetAction returns the action of the link annotation, nil if not set.
+func (link *PdfAnnotationLink) GetAction() (*PdfAction, error) {
+	if link.A == nil {
+		return nil, nil
+	}
+	return NewPdfActionFromObject(link.A)
+}
+
+// SetAction sets the action of the link annotation.
+func (link *PdfAnnotationLink) SetAction(action *PdfAction) {
+	if action == nil {
+		link.A = nil
+		return
+	}
+	link.A = action.ToPdfObject()
+}
+
+// GetAction returns the action of the outline item, nil if not set.
+func (oi *PdfOutlineItem) GetAction() (*PdfAction, error) {
+	if oi.A == nil {
+		return nil, nil
+	}
+	return NewPdfActionFromObject(oi.A)
+}
+
+// SetAction sets the action of the outline item.
+func (oi *PdfOutlineItem) SetAction(action *PdfAction) {
+	if action == nil {
+		oi.A = nil
+		return
+	}
+	oi.A = action.ToPdfObject()
+}
+
+// GetAdditionalActions returns the additional actions of the widget annotation by trigger event,
+// such as E (cursor enters) or Fo (receives focus).
+func (widget *PdfAnnotationWidget) GetAdditionalActions() (map[string]*PdfAction, error) {
+	return loadAdditionalActions(widget.AA)
+}
+
+// SetAdditionalAction sets the additional action of the widget annotation for the trigger event
+// 'trigger', a nil action removes it.
+func (widget *PdfAnnotationWidget) SetAdditionalAction(trigger string, action *PdfAction) {
+	widget.AA = setAdditionalAction(widget.AA, trigger, action)
+}
+
+// GetAdditionalActions returns the additional actions of the form field by trigger event: K
+// (keystroke), F (format), V (validate) and C (calculate).
+func (f *PdfField) GetAdditionalActions() (map[string]*PdfAction, error) {
+	return loadAdditionalActions(f.AA)
+}
+
+// SetAdditionalAction sets the additional action of the form field for the trigger event
+// 'trigger', a nil action removes it.
+func (f *PdfField) SetAdditionalAction(trigger string, action *PdfAction) {
+	f.AA = setAdditionalAction(f.AA, trigger, action)
+}
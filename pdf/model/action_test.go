@@ -0,0 +1,256 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+func TestActionChain(t *testing.T) {
+	uri := NewPdfActionURI("https://example.com/?q=1")
+	js := NewPdfActionJavaScript("app.alert('Hello');")
+	named := NewPdfActionNamed("NextPage")
+	uri.Next = []*PdfAction{js.PdfAction, named.PdfAction}
+	launch := NewPdfActionLaunch(NewPdfFileSpec("report.pdf", nil))
+	newWindow := true
+	launch.NewWindow = &newWindow
+	named.Next = []*PdfAction{launch.PdfAction}
+
+	w := NewPdfWriter()
+	page := NewPdfPage()
+	link := NewPdfAnnotationLink()
+	link.Rect = core.MakeArray(core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(10), core.MakeInteger(10))
+	link.SetAction(uri.PdfAction)
+	page.SetAnnotations([]*PdfAnnotation{link.PdfAnnotation})
+	require.NoError(t, w.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	page, err = reader.GetPage(1)
+	require.NoError(t, err)
+	annotations, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+	link, ok := annotations[0].GetContext().(*PdfAnnotationLink)
+	require.True(t, ok)
+
+	action, err := link.GetAction()
+	require.NoError(t, err)
+	require.Equal(t, ActionTypeURI, action.S)
+	readURI, ok := action.GetContext().(*PdfActionURI)
+	require.True(t, ok)
+	require.Equal(t, "https://example.com/?q=1", readURI.URI)
+	require.False(t, readURI.IsMap)
+
+	require.Len(t, action.Next, 2)
+	readJS, ok := action.Next[0].GetContext().(*PdfActionJavaScript)
+	require.True(t, ok)
+	require.Equal(t, "app.alert('Hello');", readJS.JS)
+	readNamed, ok := action.Next[1].GetContext().(*PdfActionNamed)
+	require.True(t, ok)
+	require.Equal(t, "NextPage", readNamed.N)
+	require.Len(t, readNamed.Next, 1)
+	readLaunch, ok := readNamed.Next[0].GetContext().(*PdfActionLaunch)
+	require.True(t, ok)
+	require.Equal(t, "report.pdf", readLaunch.F.Filename)
+	require.True(t, *readLaunch.NewWindow)
+}
+
+func TestActionFromObject(t *testing.T) {
+	// Looping chains are cut.
+	first := core.MakeDict()
+	first.Set("S", core.MakeName("GoTo"))
+	first.Set("D", core.MakeString("chapter1"))
+	second := core.MakeDict()
+	second.Set("S", core.MakeName("GoToE"))
+	second.Set("Next", first)
+	first.Set("Next", core.MakeArray(second))
+
+	action, err := NewPdfActionFromObject(first)
+	require.NoError(t, err)
+	goTo, ok := action.GetContext().(*PdfActionGoTo)
+	require.True(t, ok)
+	require.Equal(t, "chapter1", goTo.D.(*core.PdfObjectString).Str())
+	require.Len(t, action.Next, 1)
+	require.Equal(t, PdfActionType("GoToE"), action.Next[0].S)
+	require.Nil(t, action.Next[0].GetContext())
+	require.Empty(t, action.Next[0].Next)
+
+	// Unsupported actions are kept as is.
+	second.Set("T", core.MakeDict())
+	obj, ok := action.Next[0].ToPdfObject().(*core.PdfObjectDictionary)
+	require.True(t, ok)
+	require.NotNil(t, obj.Get("T"))
+
+	// The dictionaries read are not modified and the indirect actions of the chain are kept.
+	uri := core.MakeDict()
+	uri.Set("S", core.MakeName("URI"))
+	uri.Set("URI", core.MakeString("https://example.com"))
+	goToDict := core.MakeDict()
+	goToDict.Set("S", core.MakeName("GoTo"))
+	goToDict.Set("D", core.MakeString("chapter1"))
+	goToDict.Set("Next", core.MakeIndirectObject(uri))
+	action, err = NewPdfActionFromObject(goToDict)
+	require.NoError(t, err)
+	d, ok := action.ToPdfObject().(*core.PdfObjectDictionary)
+	require.True(t, ok)
+	require.Equal(t, "Action", d.Get("Type").String())
+	next, ok := d.Get("Next").(*core.PdfIndirectObject)
+	require.True(t, ok, "next %T", d.Get("Next"))
+	nextDict, ok := core.GetDict(next)
+	require.True(t, ok)
+	require.Equal(t, "https://example.com", nextDict.Get("URI").(*core.PdfObjectString).Str())
+	require.Nil(t, goToDict.Get("Type"))
+	require.Nil(t, uri.Get("Type"))
+
+	_, err = NewPdfActionFromObject(core.MakeDict())
+	require.Error(t, err)
+}
+
+func TestFormActions(t *testing.T) {
+	submit := NewPdfActionSubmitForm("https://example.com/submit")
+	submit.Fields = []core.PdfObject{core.MakeString("name"), core.MakeString("email")}
+	submit.Flags = SubmitFormFlagExportFormat | SubmitFormFlagGetMethod
+	reset := NewPdfActionResetForm()
+	reset.Flags = ResetFormFlagExclude
+
+	widget := NewPdfAnnotationWidget()
+	widget.SetAdditionalAction("U", submit.PdfAction)
+	widget.SetAdditionalAction("D", reset.PdfAction)
+	widget.SetAdditionalAction("E", NewPdfActionJavaScript("x").PdfAction)
+	widget.SetAdditionalAction("E", nil)
+
+	actions, err := widget.GetAdditionalActions()
+	require.NoError(t, err)
+	require.Len(t, actions, 2)
+	readSubmit, ok := actions["U"].GetContext().(*PdfActionSubmitForm)
+	require.True(t, ok)
+	require.Equal(t, "https://example.com/submit", readSubmit.URL)
+	require.Len(t, readSubmit.Fields, 2)
+	require.Equal(t, SubmitFormFlagExportFormat|SubmitFormFlagGetMethod, readSubmit.Flags)
+	readReset, ok := actions["D"].GetContext().(*PdfActionResetForm)
+	require.True(t, ok)
+	require.Empty(t, readReset.Fields)
+	require.Equal(t, ResetFormFlagExclude, readReset.Flags)
+}
+
+func TestOpenAction(t *testing.T) {
+	w := NewPdfWriter()
+	require.NoError(t, w.AddPage(NewPdfPage()))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	action, err := reader.GetOpenAction()
+	require.NoError(t, err)
+	require.Nil(t, action)
+
+	// Open destinations are returned as go-to actions.
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	reader.catalog.Set("OpenAction", core.MakeArray(page.GetPageAsIndirectObject(), core.MakeName("Fit")))
+	action, err = reader.GetOpenAction()
+	require.NoError(t, err)
+	goTo, ok := action.GetContext().(*PdfActionGoTo)
+	require.True(t, ok)
+	dest, err := reader.ResolveDestination(goTo.D)
+	require.NoError(t, err)
+	require.Equal(t, 1, dest.PageNumber)
+	require.Equal(t, DestinationFit, dest.Type)
+}
+
+func TestOutlineItemAction(t *testing.T) {
+	item := NewPdfOutlineItem()
+	item.Title = core.MakeString("Chapter")
+	item.SetAction(NewPdfActionURI("https://example.com").PdfAction)
+	outline := NewPdfOutline()
+	outline.First = &item.PdfOutlineTreeNode
+	outline.Last = &item.PdfOutlineTreeNode
+	item.Parent = &outline.PdfOutlineTreeNode
+
+	w := NewPdfWriter()
+	require.NoError(t, w.AddPage(NewPdfPage()))
+	w.AddOutlineTree(&outline.PdfOutlineTreeNode)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	nodes, _, err := reader.GetOutlinesFlattened()
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	readItem, ok := nodes[0].context.(*PdfOutlineItem)
+	require.True(t, ok)
+	action, err := readItem.GetAction()
+	require.NoError(t, err)
+	uri, ok := action.GetContext().(*PdfActionURI)
+	require.True(t, ok)
+	require.Equal(t, "https://example.com", uri.URI)
+
+	item.SetAction(nil)
+	action, err = item.GetAction()
+	require.NoError(t, err)
+	require.Nil(t, action)
+}
+
+func TestGoToActionPageNumbers(t *testing.T) {
+	w := NewPdfWriter()
+	for i := 0; i < 3; i++ {
+		page := NewPdfPage()
+		if i == 0 {
+			// Link to the second page, then to the third one.
+			goTo := NewPdfActionGoTo(NewPdfDestinationFit(2).ToPdfObject())
+			goTo.Next = []*PdfAction{NewPdfActionGoTo(NewPdfDestinationFit(3).ToPdfObject()).PdfAction}
+			link := NewPdfAnnotationLink()
+			link.Rect = core.MakeArray(core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(10), core.MakeInteger(10))
+			link.SetAction(goTo.PdfAction)
+			page.SetAnnotations([]*PdfAnnotation{link.PdfAnnotation})
+		}
+		require.NoError(t, w.AddPage(page))
+	}
+	w.SetOpenAction(NewPdfActionGoTo(NewPdfDestinationXYZ(3, 0, 700, 0).ToPdfObject()).PdfAction)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	destPage := func(action *PdfAction) *core.PdfIndirectObject {
+		goTo, ok := action.GetContext().(*PdfActionGoTo)
+		require.True(t, ok)
+		arr, ok := core.GetArray(goTo.D)
+		require.True(t, ok)
+		page, ok := core.GetIndirect(arr.Get(0))
+		require.True(t, ok, "page %T", arr.Get(0))
+		return page
+	}
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	annotations, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+	link, ok := annotations[0].GetContext().(*PdfAnnotationLink)
+	require.True(t, ok)
+	action, err := link.GetAction()
+	require.NoError(t, err)
+	page2, err := reader.GetPage(2)
+	require.NoError(t, err)
+	page3, err := reader.GetPage(3)
+	require.NoError(t, err)
+	require.True(t, page2.GetPageAsIndirectObject() == destPage(action))
+	require.Len(t, action.Next, 1)
+	require.True(t, page3.GetPageAsIndirectObject() == destPage(action.Next[0]))
+
+	action, err = reader.GetOpenAction()
+	require.NoError(t, err)
+	require.True(t, page3.GetPageAsIndirectObject() == destPage(action))
+}
@@ -26,6 +26,12 @@ type PdfAppender struct {
 	Reader   *PdfReader
 	pages    []*PdfPage
 	acroForm *PdfAcroForm
+	info     *PdfInfo
+
+	// Embedded files, loaded when modified.
+	embeddedFiles *embeddedFiles
+	// Page labels, nil if not modified.
+	pageLabels *PdfNumberTree
 
 	xrefs          core.XrefTable
 	greatestObjNum int
@@ -193,7 +199,8 @@ func (a *PdfAppender) addNewObjects(obj core.PdfObject) {
 			// Check if data has changed.
 			if streamObj, err := a.roReader.parser.LookupByReference(v.PdfObjectReference); err == nil {
 				var isNotChanged bool
-				if stream, ok := core.GetStream(streamObj); ok && bytes.Equal(stream.Stream, v.Stream) {
+				stream, ok := core.GetStream(streamObj)
+				if ok && stream.LoadStream() == nil && v.LoadStream() == nil && bytes.Equal(stream.Stream, v.Stream) {
 					isNotChanged = true
 				}
 				if dict, ok := core.GetDict(streamObj); isNotChanged && ok {
@@ -454,6 +461,56 @@ func (a *PdfAppender) ReplaceAcroForm(acroForm *PdfAcroForm) {
 	a.acroForm = acroForm
 }
 
+// SetDocInfo sets the document information dictionary of the appended revision.
+func (a *PdfAppender) SetDocInfo(info *PdfInfo) {
+	a.info = info
+}
+
+// loadEmbeddedFiles loads the embedded files of the original document to be modified.
+func (a *PdfAppender) loadEmbeddedFiles() error {
+	if a.embeddedFiles != nil {
+		return nil
+	}
+	var tree core.PdfObject
+	if names, ok := core.GetDict(a.roReader.catalog.Get("Names")); ok {
+		tree = names.Get("EmbeddedFiles")
+	}
+	files, err := loadEmbeddedFiles(tree)
+	if err != nil {
+		return err
+	}
+	a.embeddedFiles = files
+	return nil
+}
+
+// AddEmbeddedFile adds the document level embedded file 'fs' with the name 'name', replacing the
+// file with the same name if any.
+func (a *PdfAppender) AddEmbeddedFile(name string, fs *PdfFileSpec) error {
+	if err := a.loadEmbeddedFiles(); err != nil {
+		return err
+	}
+	return a.embeddedFiles.add(name, fs)
+}
+
+// RemoveEmbeddedFile removes the document level embedded file with the name 'name'.
+func (a *PdfAppender) RemoveEmbeddedFile(name string) error {
+	if err := a.loadEmbeddedFiles(); err != nil {
+		return err
+	}
+	return a.embeddedFiles.remove(name)
+}
+
+// SetPageLabels replaces the page label ranges of the document, no ranges removes the page labels.
+// The page labels of the original document are kept otherwise.
+func (a *PdfAppender) SetPageLabels(ranges []*PdfPageLabelRange) error {
+	tree, err := newPageLabelsTree(ranges)
+	if err != nil {
+		return err
+	}
+	a.pageLabels = tree
+	return nil
+}
+
 // Write writes the Appender output to io.Writer.
 // It can only be called once and further invocations will result in an error.
 func (a *PdfAppender) Write(w io.Writer) error {
@@ -504,6 +561,20 @@ func (a *PdfAppender) Write(w io.Writer) error {
 		}
 	}
 
+	// Embedded files.
+	if a.embeddedFiles != nil {
+		for _, obj := range a.embeddedFiles.updateCatalog(writer.catalog) {
+			a.addNewObjects(obj)
+		}
+	}
+
+	// Page labels, the original labels are kept if not set.
+	if a.pageLabels != nil {
+		if obj := setCatalogPageLabels(writer.catalog, a.pageLabels); obj != nil {
+			a.addNewObjects(obj)
+		}
+	}
+
 	inheritedFields := []core.PdfObjectName{"Resources", "MediaBox", "CropBox", "Rotate"}
 
 	for _, p := range a.pages {
@@ -549,6 +620,9 @@ func (a *PdfAppender) Write(w io.Writer) error {
 	if a.acroForm != nil {
 		writer.SetForms(a.acroForm)
 	}
+	if a.info != nil {
+		writer.SetDocInfo(a.info)
+	}
 
 	if _, err := a.rs.Seek(0, io.SeekStart); err != nil {
 		return err
@@ -600,7 +674,8 @@ func (a *PdfAppender) Write(w io.Writer) error {
 		return err
 	}
 
-	if len(a.newObjects) == 0 {
+	// Nothing to update.
+	if len(a.newObjects) == 0 && a.info == nil && a.pageLabels == nil {
 		return nil
 	}
 
@@ -0,0 +1,190 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+// PdfDestinationType is the type of an explicit destination, which determines how the page is
+// displayed (Table 151 - Destination syntax).
+type PdfDestinationType string
+
+// Destination types.
+const (
+	DestinationXYZ   PdfDestinationType = "XYZ"
+	DestinationFit   PdfDestinationType = "Fit"
+	DestinationFitH  PdfDestinationType = "FitH"
+	DestinationFitV  PdfDestinationType = "FitV"
+	DestinationFitR  PdfDestinationType = "FitR"
+	DestinationFitB  PdfDestinationType = "FitB"
+	DestinationFitBH PdfDestinationType = "FitBH"
+	DestinationFitBV PdfDestinationType = "FitBV"
+)
+
+// destinationParams are the names of the parameters of the destination types, in order.
+var destinationParams = map[PdfDestinationType][]string{
+	DestinationXYZ:   {"Left", "Top", "Zoom"},
+	DestinationFit:   nil,
+	DestinationFitH:  {"Top"},
+	DestinationFitV:  {"Left"},
+	DestinationFitR:  {"Left", "Bottom", "Right", "Top"},
+	DestinationFitB:  nil,
+	DestinationFitBH: {"Top"},
+	DestinationFitBV: {"Left"},
+}
+
+// maxDestinationDepth limits the indirections (names, GoTo actions) followed when resolving a
+// destination.
+const maxDestinationDepth = 8
+
+// PdfDestination represents an explicit destination (section 12.3.2.2 Explicit Destinations),
+// a page and the position and zoom of the view of the page. The parameters which are not
+// relevant for the type, or which are left unchanged (null), are nil.
+type PdfDestination struct {
+	Type PdfDestinationType
+
+	// Page is the page object of the destination, nil if the page is given by its number.
+	Page *core.PdfIndirectObject
+	// PageNumber is the number of the page, starting at 1, 0 if unknown. When writing, it is
+	// only used if Page is nil, and refers to the pages of the PdfWriter in local destinations.
+	PageNumber int
+
+	Left   *float64
+	Bottom *float64
+	Right  *float64
+	Top    *float64
+	Zoom   *float64
+}
+
+// NewPdfDestinationXYZ returns a new destination displaying the page 'pageNumber' with the
+// coordinates (left, top) at the upper left corner of the window, magnified by 'zoom'
+// (0 to keep the current zoom).
+func NewPdfDestinationXYZ(pageNumber int, left, top, zoom float64) *PdfDestination {
+	return &PdfDestination{Type: DestinationXYZ, PageNumber: pageNumber, Left: &left, Top: &top, Zoom: &zoom}
+}
+
+// NewPdfDestinationFit returns a new destination displaying the page 'pageNumber' entirely.
+func NewPdfDestinationFit(pageNumber int) *PdfDestination {
+	return &PdfDestination{Type: DestinationFit, PageNumber: pageNumber}
+}
+
+// NewPdfDestinationFitH returns a new destination displaying the page 'pageNumber' with the
+// coordinate 'top' at the top of the window and the width of the page fitting the window.
+func NewPdfDestinationFitH(pageNumber int, top float64) *PdfDestination {
+	return &PdfDestination{Type: DestinationFitH, PageNumber: pageNumber, Top: &top}
+}
+
+// NewPdfDestinationFitV returns a new destination displaying the page 'pageNumber' with the
+// coordinate 'left' at the left edge of the window and the height of the page fitting the window.
+func NewPdfDestinationFitV(pageNumber int, left float64) *PdfDestination {
+	return &PdfDestination{Type: DestinationFitV, PageNumber: pageNumber, Left: &left}
+}
+
+// NewPdfDestinationFitR returns a new destination displaying the rectangle (left, bottom,
+// right, top) of the page 'pageNumber' entirely.
+func NewPdfDestinationFitR(pageNumber int, left, bottom, right, top float64) *PdfDestination {
+	return &PdfDestination{Type: DestinationFitR, PageNumber: pageNumber, Left: &left, Bottom: &bottom,
+		Right: &right, Top: &top}
+}
+
+// NewPdfDestinationFromObject loads the explicit destination 'obj', which is a destination
+// array or a dictionary with a destination array in its D entry. The page number is only set for
+// the pages given by their index (remote destinations), the page objects are resolved with
+// PdfReader.ResolveDestination.
+func NewPdfDestinationFromObject(obj core.PdfObject) (*PdfDestination, error) {
+	if dict, ok := core.GetDict(obj); ok {
+		obj = dict.Get("D")
+	}
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		return nil, fmt.Errorf("invalid destination type: %T", obj)
+	}
+	if arr.Len() < 2 {
+		return nil, errors.New("destination array too short")
+	}
+
+	dest := &PdfDestination{}
+	switch t := arr.Get(0).(type) {
+	case *core.PdfIndirectObject:
+		dest.Page = t
+	case *core.PdfObjectReference:
+		page, ok := core.GetIndirect(t)
+		if !ok {
+			return nil, errors.New("invalid destination page reference")
+		}
+		dest.Page = page
+	default:
+		// The pages of remote destinations are given by their index.
+		index, ok := core.GetIntVal(t)
+		if !ok {
+			return nil, fmt.Errorf("invalid destination page type: %T", t)
+		}
+		dest.PageNumber = index + 1
+	}
+
+	typ, ok := core.GetNameVal(arr.Get(1))
+	if !ok {
+		return nil, fmt.Errorf("invalid destination type: %T", arr.Get(1))
+	}
+	dest.Type = PdfDestinationType(typ)
+	params, ok := destinationParams[dest.Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported destination type: %s", typ)
+	}
+	for i, name := range params {
+		if i+2 >= arr.Len() {
+			break
+		}
+		val, err := core.GetNumberAsFloat(core.TraceToDirectObject(arr.Get(i + 2)))
+		if err != nil {
+			// Null parameters keep the current value.
+			continue
+		}
+		*dest.param(name) = &val
+	}
+	return dest, nil
+}
+
+// param returns the field of the parameter 'name'.
+func (dest *PdfDestination) param(name string) **float64 {
+	switch name {
+	case "Left":
+		return &dest.Left
+	case "Bottom":
+		return &dest.Bottom
+	case "Right":
+		return &dest.Right
+	case "Top":
+		return &dest.Top
+	}
+	return &dest.Zoom
+}
+
+// ToPdfObject returns the destination array. The page is referred to by its index if the page
+// object is not set, as in remote destinations. The index is replaced by the page object when the
+// array is written by PdfWriter as the destination of a link annotation, an outline item or a
+// go-to action.
+func (dest *PdfDestination) ToPdfObject() core.PdfObject {
+	arr := core.MakeArray()
+	if dest.Page != nil {
+		arr.Append(dest.Page)
+	} else {
+		arr.Append(core.MakeInteger(int64(dest.PageNumber - 1)))
+	}
+	arr.Append(core.MakeName(string(dest.Type)))
+	for _, name := range destinationParams[dest.Type] {
+		if val := *dest.param(name); val != nil {
+			arr.Append(core.MakeFloat(*val))
+		} else {
+			arr.Append(core.MakeNull())
+		}
+	}
+	return arr
+}
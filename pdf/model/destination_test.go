@@ -0,0 +1,183 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+func TestNamedDestinations(t *testing.T) {
+	w := NewPdfWriter()
+	for i := 0; i < 3; i++ {
+		page := NewPdfPage()
+		if i == 0 {
+			// Links to a named destination and with a GoTo action.
+			link := NewPdfAnnotationLink()
+			link.Rect = core.MakeArray(core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(10), core.MakeInteger(10))
+			link.Dest = core.MakeString("chapter2")
+			action := NewPdfAnnotationLink()
+			action.Rect = link.Rect
+			goTo := core.MakeDict()
+			goTo.Set("S", core.MakeName("GoTo"))
+			goTo.Set("D", core.MakeString("figure"))
+			action.A = goTo
+			page.SetAnnotations([]*PdfAnnotation{link.PdfAnnotation, action.PdfAnnotation})
+		}
+		require.NoError(t, w.AddPage(page))
+	}
+	require.NoError(t, w.AddNamedDestination("chapter2", NewPdfDestinationXYZ(2, 72, 700, 0)))
+	require.NoError(t, w.AddNamedDestination("figure", NewPdfDestinationFitR(3, 10, 20, 300, 400)))
+	require.NoError(t, w.AddNamedDestination("missing", NewPdfDestinationFit(1)))
+	fitH := NewPdfDestinationFitH(1, 0)
+	fitH.Top = nil
+	require.NoError(t, w.AddNamedDestination("missing", fitH))
+	require.Error(t, w.AddNamedDestination("", fitH))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	dests, err := reader.GetNamedDestinations()
+	require.NoError(t, err)
+	require.Len(t, dests, 3)
+
+	dest := dests["chapter2"]
+	require.Equal(t, DestinationXYZ, dest.Type)
+	require.Equal(t, 2, dest.PageNumber)
+	require.Equal(t, 72.0, *dest.Left)
+	require.Equal(t, 700.0, *dest.Top)
+	require.Equal(t, 0.0, *dest.Zoom)
+	require.Nil(t, dest.Bottom)
+	dest = dests["missing"]
+	require.Equal(t, DestinationFitH, dest.Type)
+	require.Equal(t, 1, dest.PageNumber)
+	require.Nil(t, dest.Top)
+
+	// Destinations of the link annotations.
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	annotations, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 2)
+	link, ok := annotations[0].GetContext().(*PdfAnnotationLink)
+	require.True(t, ok)
+	dest, err = reader.ResolveDestination(link.Dest)
+	require.NoError(t, err)
+	require.Equal(t, 2, dest.PageNumber)
+	page2, err := reader.GetPage(2)
+	require.NoError(t, err)
+	require.Equal(t, page2.GetPageAsIndirectObject(), dest.Page)
+	link, ok = annotations[1].GetContext().(*PdfAnnotationLink)
+	require.True(t, ok)
+	dest, err = reader.ResolveDestination(link.A)
+	require.NoError(t, err)
+	require.Equal(t, DestinationFitR, dest.Type)
+	require.Equal(t, 3, dest.PageNumber)
+	require.Equal(t, []float64{10, 20, 300, 400}, []float64{*dest.Left, *dest.Bottom, *dest.Right, *dest.Top})
+
+	// Explicit destinations referring to the pages by index, as written by the outlines.
+	dest, err = reader.ResolveDestination(NewOutlineDest(2, 50, 60).ToPdfObject())
+	require.NoError(t, err)
+	require.Equal(t, 3, dest.PageNumber)
+	require.Equal(t, 50.0, *dest.Left)
+
+	_, err = reader.ResolveDestination(core.MakeString("unknown"))
+	require.Error(t, err)
+	_, err = reader.ResolveDestination(core.MakeArray(core.MakeInteger(5), core.MakeName("Fit")))
+	require.Error(t, err)
+}
+
+func TestDestinationFromObject(t *testing.T) {
+	dest, err := NewPdfDestinationFromObject(core.MakeArray(core.MakeInteger(0), core.MakeName("XYZ"), core.MakeNull(),
+		core.MakeFloat(500)))
+	require.NoError(t, err)
+	require.Equal(t, DestinationXYZ, dest.Type)
+	require.Equal(t, 1, dest.PageNumber)
+	require.Nil(t, dest.Left)
+	require.Equal(t, 500.0, *dest.Top)
+	require.Nil(t, dest.Zoom)
+
+	// Written with null parameters.
+	arr, ok := dest.ToPdfObject().(*core.PdfObjectArray)
+	require.True(t, ok)
+	require.Equal(t, "[0 /XYZ null 500 null]", arr.WriteString())
+
+	_, err = NewPdfDestinationFromObject(core.MakeArray(core.MakeInteger(0), core.MakeName("Unknown")))
+	require.Error(t, err)
+	_, err = NewPdfDestinationFromObject(core.MakeName("chapter"))
+	require.Error(t, err)
+}
+
+func TestWriterDestinationPageNumbers(t *testing.T) {
+	w := NewPdfWriter()
+	for i := 0; i < 3; i++ {
+		page := NewPdfPage()
+		if i == 0 {
+			link := NewPdfAnnotationLink()
+			link.Rect = core.MakeArray(core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(10), core.MakeInteger(10))
+			link.Dest = NewPdfDestinationFit(2).ToPdfObject()
+			page.SetAnnotations([]*PdfAnnotation{link.PdfAnnotation})
+		}
+		require.NoError(t, w.AddPage(page))
+	}
+	item := NewPdfOutlineItem()
+	item.Title = core.MakeString("Chapter")
+	item.Dest = NewPdfDestinationXYZ(3, 72, 700, 0).ToPdfObject()
+	outline := NewPdfOutline()
+	outline.First = &item.PdfOutlineTreeNode
+	outline.Last = &item.PdfOutlineTreeNode
+	item.Parent = &outline.PdfOutlineTreeNode
+	w.AddOutlineTree(&outline.PdfOutlineTreeNode)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	destPage := func(obj core.PdfObject) *core.PdfIndirectObject {
+		arr, ok := core.GetArray(obj)
+		require.True(t, ok)
+		page, ok := core.GetIndirect(arr.Get(0))
+		require.True(t, ok, "page %T", arr.Get(0))
+		return page
+	}
+
+	// The page numbers of the link destinations are written as page objects.
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	annotations, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+	link, ok := annotations[0].GetContext().(*PdfAnnotationLink)
+	require.True(t, ok)
+	page2, err := reader.GetPage(2)
+	require.NoError(t, err)
+	require.True(t, page2.GetPageAsIndirectObject() == destPage(link.Dest))
+
+	// As are the ones of the outline items.
+	nodes, _, err := reader.GetOutlinesFlattened()
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	readItem, ok := nodes[0].context.(*PdfOutlineItem)
+	require.True(t, ok)
+	page3, err := reader.GetPage(3)
+	require.NoError(t, err)
+	require.True(t, page3.GetPageAsIndirectObject() == destPage(readItem.Dest))
+
+	// Pages out of the document are not written.
+	w = NewPdfWriter()
+	page = NewPdfPage()
+	link = NewPdfAnnotationLink()
+	link.Rect = core.MakeArray(core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(10), core.MakeInteger(10))
+	link.Dest = NewPdfDestinationFit(2).ToPdfObject()
+	page.SetAnnotations([]*PdfAnnotation{link.PdfAnnotation})
+	require.NoError(t, w.AddPage(page))
+	require.Error(t, w.Write(&buf))
+}
@@ -0,0 +1,337 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+// PdfAFRelationship specifies the relationship between an associated file and the PDF component
+// which refers to it (PDF/A-3, ISO 32000-2 section 14.13).
+type PdfAFRelationship string
+
+// Associated file relationships.
+const (
+	AFRelationshipSource           PdfAFRelationship = "Source"
+	AFRelationshipData             PdfAFRelationship = "Data"
+	AFRelationshipAlternative      PdfAFRelationship = "Alternative"
+	AFRelationshipSupplement       PdfAFRelationship = "Supplement"
+	AFRelationshipEncryptedPayload PdfAFRelationship = "EncryptedPayload"
+	AFRelationshipFormData         PdfAFRelationship = "FormData"
+	AFRelationshipSchema           PdfAFRelationship = "Schema"
+	AFRelationshipUnspecified      PdfAFRelationship = "Unspecified"
+)
+
+// PdfEmbeddedFile represents an embedded file stream (section 7.11.4 Embedded File Streams).
+type PdfEmbeddedFile struct {
+	// Content is the uncompressed content of the file.
+	Content []byte
+	// Subtype is the MIME type of the file, e.g. "application/pdf".
+	Subtype string
+
+	// File parameters (Params).
+	Size         int
+	CreationDate *PdfDate
+	ModifiedDate *PdfDate
+	// CheckSum is the MD5 digest of the file content.
+	CheckSum []byte
+}
+
+// NewPdfEmbeddedFile returns a new embedded file with the content 'content' of the MIME type
+// 'mimeType'. The size and checksum are computed from the content.
+func NewPdfEmbeddedFile(content []byte, mimeType string) *PdfEmbeddedFile {
+	sum := md5.Sum(content)
+	return &PdfEmbeddedFile{
+		Content:  content,
+		Subtype:  mimeType,
+		Size:     len(content),
+		CheckSum: sum[:],
+	}
+}
+
+// NewPdfEmbeddedFileFromFile returns a new embedded file with the content of the file at 'path'.
+// The MIME type is determined from the file extension and the modification date is the one of
+// the file.
+func NewPdfEmbeddedFileFromFile(path string) (*PdfEmbeddedFile, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	ef := NewPdfEmbeddedFile(content, mime.TypeByExtension(filepath.Ext(path)))
+	modDate, err := NewPdfDateFromTime(stat.ModTime())
+	if err != nil {
+		return nil, err
+	}
+	ef.ModifiedDate = &modDate
+	return ef, nil
+}
+
+// newPdfEmbeddedFileFromStream loads the embedded file from the stream 'stream'.
+func newPdfEmbeddedFileFromStream(stream *core.PdfObjectStream) (*PdfEmbeddedFile, error) {
+	content, err := core.DecodeStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	ef := &PdfEmbeddedFile{Content: content}
+	ef.Subtype, _ = core.GetNameVal(stream.Get("Subtype"))
+
+	if params, ok := core.GetDict(stream.Get("Params")); ok {
+		if size, ok := core.GetIntVal(params.Get("Size")); ok {
+			ef.Size = size
+		}
+		if str, ok := core.GetString(params.Get("CreationDate")); ok {
+			if date, err := NewPdfDate(str.Str()); err == nil {
+				ef.CreationDate = &date
+			} else {
+				common.Log.Debug("Invalid embedded file CreationDate: %v", err)
+			}
+		}
+		if str, ok := core.GetString(params.Get("ModDate")); ok {
+			if date, err := NewPdfDate(str.Str()); err == nil {
+				ef.ModifiedDate = &date
+			} else {
+				common.Log.Debug("Invalid embedded file ModDate: %v", err)
+			}
+		}
+		if str, ok := core.GetString(params.Get("CheckSum")); ok {
+			ef.CheckSum = str.Bytes()
+		}
+	}
+	return ef, nil
+}
+
+// IsCheckSumValid checks whether the content matches the checksum. Files without checksum are
+// considered valid.
+func (ef *PdfEmbeddedFile) IsCheckSumValid() bool {
+	if len(ef.CheckSum) == 0 {
+		return true
+	}
+	sum := md5.Sum(ef.Content)
+	return bytes.Equal(sum[:], ef.CheckSum)
+}
+
+// ToPdfObject returns the embedded file stream, the content is compressed with the Flate filter.
+func (ef *PdfEmbeddedFile) ToPdfObject() core.PdfObject {
+	encoder := core.NewFlateEncoder()
+	stream, err := core.MakeStream(ef.Content, encoder)
+	if err != nil {
+		common.Log.Debug("ERROR: Failed to encode embedded file: %v", err)
+		stream, _ = core.MakeStream(ef.Content, core.NewRawEncoder())
+	}
+	stream.Set("Type", core.MakeName("EmbeddedFile"))
+	if ef.Subtype != "" {
+		stream.Set("Subtype", core.MakeName(ef.Subtype))
+	}
+
+	params := core.MakeDict()
+	size := ef.Size
+	if size == 0 {
+		size = len(ef.Content)
+	}
+	params.Set("Size", core.MakeInteger(int64(size)))
+	if ef.CreationDate != nil {
+		params.Set("CreationDate", ef.CreationDate.ToPdfObject())
+	}
+	if ef.ModifiedDate != nil {
+		params.Set("ModDate", ef.ModifiedDate.ToPdfObject())
+	}
+	if len(ef.CheckSum) > 0 {
+		params.Set("CheckSum", core.MakeHexString(string(ef.CheckSum)))
+	}
+	stream.Set("Params", params)
+	return stream
+}
+
+// PdfFileSpec represents a file specification dictionary (section 7.11.3 File Specification
+// Dictionaries), typically referring to an embedded file.
+type PdfFileSpec struct {
+	// Filename is the name of the file (UF or F entries).
+	Filename string
+	// Description is the description of the file (Desc).
+	Description string
+	// AFRelationship is the relationship of an associated file (PDF/A-3), empty if the file is
+	// not associated with the document.
+	AFRelationship PdfAFRelationship
+	// EmbeddedFile is the embedded file stream (EF), nil for references to external files.
+	EmbeddedFile *PdfEmbeddedFile
+}
+
+// NewPdfFileSpec returns a new file specification of the file 'filename' embedding the file 'ef'.
+func NewPdfFileSpec(filename string, ef *PdfEmbeddedFile) *PdfFileSpec {
+	return &PdfFileSpec{
+		Filename:     filename,
+		EmbeddedFile: ef,
+	}
+}
+
+// NewPdfFileSpecFromObject loads the file specification 'obj', which can be a string or a file
+// specification dictionary, such as the FS entry of a file attachment annotation.
+func NewPdfFileSpecFromObject(obj core.PdfObject) (*PdfFileSpec, error) {
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectString:
+		return &PdfFileSpec{Filename: t.Decoded()}, nil
+	case *core.PdfObjectDictionary:
+		fs := &PdfFileSpec{}
+		if str, ok := core.GetString(t.Get("UF")); ok {
+			fs.Filename = str.Decoded()
+		} else if str, ok := core.GetString(t.Get("F")); ok {
+			fs.Filename = str.Decoded()
+		}
+		if str, ok := core.GetString(t.Get("Desc")); ok {
+			fs.Description = str.Decoded()
+		}
+		if name, ok := core.GetNameVal(t.Get("AFRelationship")); ok {
+			fs.AFRelationship = PdfAFRelationship(name)
+		}
+
+		if ef, ok := core.GetDict(t.Get("EF")); ok {
+			// The embedded file of the UF entry is preferred.
+			stream, ok := core.GetStream(ef.Get("UF"))
+			if !ok {
+				stream, ok = core.GetStream(ef.Get("F"))
+			}
+			if ok {
+				var err error
+				fs.EmbeddedFile, err = newPdfEmbeddedFileFromStream(stream)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		return fs, nil
+	}
+	return nil, fmt.Errorf("invalid file specification type: %T", obj)
+}
+
+// ToPdfObject returns a new indirect object containing the file specification dictionary.
+func (fs *PdfFileSpec) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("Filespec"))
+	dict.Set("F", makeTextString(fs.Filename))
+	dict.Set("UF", core.MakeEncodedString(fs.Filename, true))
+	if fs.Description != "" {
+		dict.Set("Desc", makeTextString(fs.Description))
+	}
+	if fs.AFRelationship != "" {
+		dict.Set("AFRelationship", core.MakeName(string(fs.AFRelationship)))
+	}
+	if fs.EmbeddedFile != nil {
+		stream := fs.EmbeddedFile.ToPdfObject()
+		ef := core.MakeDict()
+		ef.Set("F", stream)
+		ef.Set("UF", stream)
+		dict.Set("EF", ef)
+	}
+	return core.MakeIndirectObject(dict)
+}
+
+// embeddedFiles is the name tree of the embedded files of a document.
+type embeddedFiles struct {
+	tree *PdfNameTree
+	// af marks the associated files added.
+	af map[string]bool
+	// removed contains the replaced and removed values.
+	removed []core.PdfObject
+}
+
+// loadEmbeddedFiles loads the embedded files of the name tree 'obj', nil for a new tree.
+func loadEmbeddedFiles(obj core.PdfObject) (*embeddedFiles, error) {
+	e := &embeddedFiles{tree: NewPdfNameTree(), af: map[string]bool{}}
+	if obj != nil {
+		tree, err := NewPdfNameTreeFromObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		e.tree = tree
+	}
+	return e, nil
+}
+
+// add adds the file 'fs' with the name 'name', replacing the file with the same name if any.
+func (e *embeddedFiles) add(name string, fs *PdfFileSpec) error {
+	if name == "" {
+		return errors.New("empty embedded file name")
+	}
+	if fs == nil {
+		return errors.New("nil file specification")
+	}
+	if old, ok := e.tree.Get(name); ok {
+		e.removed = append(e.removed, old)
+	}
+	obj := fs.ToPdfObject()
+	if err := e.tree.Set(name, obj); err != nil {
+		return err
+	}
+	e.af[name] = fs.AFRelationship != ""
+	return nil
+}
+
+// remove removes the file with the name 'name'.
+func (e *embeddedFiles) remove(name string) error {
+	old, ok := e.tree.Get(name)
+	if !ok {
+		return fmt.Errorf("embedded file %q not found", name)
+	}
+	if _, err := e.tree.Remove(name); err != nil {
+		return err
+	}
+	e.removed = append(e.removed, old)
+	delete(e.af, name)
+	return nil
+}
+
+// isRemoved checks whether the value 'obj' has been replaced or removed.
+func (e *embeddedFiles) isRemoved(obj core.PdfObject) bool {
+	obj = core.ResolveReference(obj)
+	for _, removed := range e.removed {
+		if core.ResolveReference(removed) == obj {
+			return true
+		}
+	}
+	return false
+}
+
+// updateCatalog sets the name tree of the embedded files in the Names dictionary of the catalog
+// 'catalog' and updates the associated files (AF) of the catalog. The existing Names dictionary
+// and AF array are replaced by new objects, which are returned.
+func (e *embeddedFiles) updateCatalog(catalog *core.PdfObjectDictionary) []core.PdfObject {
+	names := setCatalogNameTree(catalog, "EmbeddedFiles", e.tree.ToPdfObject())
+
+	af := core.MakeArray()
+	if orig, ok := core.GetArray(catalog.Get("AF")); ok {
+		for _, obj := range orig.Elements() {
+			if !e.isRemoved(obj) {
+				af.Append(obj)
+			}
+		}
+	}
+	e.tree.Walk(func(name string, value core.PdfObject) error {
+		if e.af[name] {
+			af.Append(value)
+		}
+		return nil
+	})
+	if af.Len() > 0 {
+		catalog.Set("AF", af)
+	} else {
+		catalog.Remove("AF")
+	}
+	return []core.PdfObject{names, af}
+}
@@ -0,0 +1,99 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+func TestEmbeddedFiles(t *testing.T) {
+	modDate, err := NewPdfDateFromTime(time.Date(2019, 6, 1, 10, 30, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	csv := NewPdfEmbeddedFile([]byte("a,b\n1,2\n"), "text/csv")
+	csv.ModifiedDate = &modDate
+	data := NewPdfFileSpec("data.csv", csv)
+	data.Description = "Source data"
+	data.AFRelationship = AFRelationshipSource
+	notes := NewPdfFileSpec("notes.txt", NewPdfEmbeddedFile([]byte("Some notes"), "text/plain"))
+
+	w := NewPdfWriter()
+	require.NoError(t, w.AddPage(NewPdfPage()))
+	require.NoError(t, w.AddEmbeddedFile("notes.txt", notes))
+	require.NoError(t, w.AddEmbeddedFile("data.csv", data))
+	require.NoError(t, w.AddEmbeddedFile("removed.txt", notes))
+	require.NoError(t, w.RemoveEmbeddedFile("removed.txt"))
+	require.Error(t, w.RemoveEmbeddedFile("missing.txt"))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	files, err := reader.GetEmbeddedFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	fs := files["data.csv"]
+	require.NotNil(t, fs)
+	require.Equal(t, "data.csv", fs.Filename)
+	require.Equal(t, "Source data", fs.Description)
+	require.Equal(t, AFRelationshipSource, fs.AFRelationship)
+	require.NotNil(t, fs.EmbeddedFile)
+	require.Equal(t, "a,b\n1,2\n", string(fs.EmbeddedFile.Content))
+	require.Equal(t, "text/csv", fs.EmbeddedFile.Subtype)
+	require.Equal(t, 8, fs.EmbeddedFile.Size)
+	require.True(t, fs.EmbeddedFile.IsCheckSumValid())
+	require.Len(t, fs.EmbeddedFile.CheckSum, 16)
+	require.NotNil(t, fs.EmbeddedFile.ModifiedDate)
+	require.True(t, modDate.ToGoTime().Equal(fs.EmbeddedFile.ModifiedDate.ToGoTime()))
+	require.Equal(t, "Some notes", string(files["notes.txt"].EmbeddedFile.Content))
+
+	// The associated files of the catalog.
+	af, ok := core.GetArray(reader.catalog.Get("AF"))
+	require.True(t, ok)
+	require.Equal(t, 1, af.Len())
+
+	// Replace the notes and remove the data in an incremental update.
+	appender, err := NewPdfAppender(reader)
+	require.NoError(t, err)
+	notes = NewPdfFileSpec("notes.txt", NewPdfEmbeddedFile([]byte("Updated notes"), "text/plain"))
+	require.NoError(t, appender.AddEmbeddedFile("notes.txt", notes))
+	require.NoError(t, appender.AddEmbeddedFile("image.png", NewPdfFileSpec("image.png",
+		NewPdfEmbeddedFile([]byte{0x89, 'P', 'N', 'G'}, "image/png"))))
+	require.NoError(t, appender.RemoveEmbeddedFile("data.csv"))
+	var appended bytes.Buffer
+	require.NoError(t, appender.Write(&appended))
+
+	reader, err = NewPdfReader(bytes.NewReader(appended.Bytes()))
+	require.NoError(t, err)
+	files, err = reader.GetEmbeddedFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.Equal(t, "Updated notes", string(files["notes.txt"].EmbeddedFile.Content))
+	require.Equal(t, []byte{0x89, 'P', 'N', 'G'}, files["image.png"].EmbeddedFile.Content)
+	require.Nil(t, reader.catalog.Get("AF"))
+}
+
+func TestFileSpecFromAnnotation(t *testing.T) {
+	annotation := NewPdfAnnotationFileAttachment()
+	annotation.FS = NewPdfFileSpec("attachment.txt", NewPdfEmbeddedFile([]byte("Attached"), "text/plain")).ToPdfObject()
+
+	fs, err := NewPdfFileSpecFromObject(annotation.FS)
+	require.NoError(t, err)
+	require.Equal(t, "attachment.txt", fs.Filename)
+	require.Equal(t, "Attached", string(fs.EmbeddedFile.Content))
+
+	fs, err = NewPdfFileSpecFromObject(core.MakeString("external.pdf"))
+	require.NoError(t, err)
+	require.Equal(t, "external.pdf", fs.Filename)
+	require.Nil(t, fs.EmbeddedFile)
+}
@@ -320,6 +320,7 @@ func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool) (*PdfFont,
 
 			simplefont.charWidths = std.charWidths
 			simplefont.fontMetrics = std.fontMetrics
+			simplefont.std14Descriptor = std.std14Descriptor
 		} else {
 			simplefont, err = newSimpleFontFromPdfObject(d, base, nil)
 			if err != nil {
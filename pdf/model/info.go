@@ -0,0 +1,205 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+// PdfInfoTrapped specifies whether the document has been modified to include trapping information
+// (section 14.11.6 Trapping Support).
+type PdfInfoTrapped string
+
+const (
+	// TrappedTrue indicates that the document has been fully trapped.
+	TrappedTrue PdfInfoTrapped = "True"
+	// TrappedFalse indicates that the document has not yet been trapped.
+	TrappedFalse PdfInfoTrapped = "False"
+	// TrappedUnknown indicates that it is unknown whether the document has been trapped.
+	TrappedUnknown PdfInfoTrapped = "Unknown"
+)
+
+// standardInfoKeys are the keys of the document information dictionary defined by the standard.
+var standardInfoKeys = map[core.PdfObjectName]struct{}{
+	"Title":        {},
+	"Author":       {},
+	"Subject":      {},
+	"Keywords":     {},
+	"Creator":      {},
+	"Producer":     {},
+	"CreationDate": {},
+	"ModDate":      {},
+	"Trapped":      {},
+}
+
+// PdfInfo represents the document information dictionary (section 14.3.3 Document Information
+// Dictionary). It contains the metadata of the document and can be read with
+// PdfReader.GetPdfInfo and written with PdfWriter.SetDocInfo.
+type PdfInfo struct {
+	Title        *core.PdfObjectString
+	Author       *core.PdfObjectString
+	Subject      *core.PdfObjectString
+	Keywords     *core.PdfObjectString
+	Creator      *core.PdfObjectString
+	Producer     *core.PdfObjectString
+	CreationDate *PdfDate
+	ModifiedDate *PdfDate
+	Trapped      *core.PdfObjectName
+
+	// Custom keys, not defined by the standard.
+	customInfo *core.PdfObjectDictionary
+}
+
+// NewPdfInfo returns a new empty document information dictionary.
+func NewPdfInfo() *PdfInfo {
+	return &PdfInfo{customInfo: core.MakeDict()}
+}
+
+// NewPdfInfoFromObject loads the document information dictionary from the object 'obj'.
+// The dates which cannot be parsed are ignored.
+func NewPdfInfoFromObject(obj core.PdfObject) (*PdfInfo, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return nil, fmt.Errorf("invalid info dictionary type: %T", obj)
+	}
+
+	info := NewPdfInfo()
+	info.Title, _ = core.GetString(dict.Get("Title"))
+	info.Author, _ = core.GetString(dict.Get("Author"))
+	info.Subject, _ = core.GetString(dict.Get("Subject"))
+	info.Keywords, _ = core.GetString(dict.Get("Keywords"))
+	info.Creator, _ = core.GetString(dict.Get("Creator"))
+	info.Producer, _ = core.GetString(dict.Get("Producer"))
+
+	if str, ok := core.GetString(dict.Get("CreationDate")); ok {
+		date, err := NewPdfDate(str.Str())
+		if err != nil {
+			common.Log.Debug("Invalid CreationDate: %v", err)
+		} else {
+			info.CreationDate = &date
+		}
+	}
+	if str, ok := core.GetString(dict.Get("ModDate")); ok {
+		date, err := NewPdfDate(str.Str())
+		if err != nil {
+			common.Log.Debug("Invalid ModDate: %v", err)
+		} else {
+			info.ModifiedDate = &date
+		}
+	}
+
+	switch t := core.ResolveReference(dict.Get("Trapped")).(type) {
+	case *core.PdfObjectName:
+		info.Trapped = t
+	case *core.PdfObjectBool:
+		// Some writers use a boolean value.
+		if *t {
+			info.Trapped = core.MakeName(string(TrappedTrue))
+		} else {
+			info.Trapped = core.MakeName(string(TrappedFalse))
+		}
+	}
+
+	for _, key := range dict.Keys() {
+		if _, ok := standardInfoKeys[key]; ok {
+			continue
+		}
+		info.customInfo.Set(key, dict.Get(key))
+	}
+
+	return info, nil
+}
+
+// SetTrapped sets the trapped state of the document.
+func (info *PdfInfo) SetTrapped(trapped PdfInfoTrapped) {
+	info.Trapped = core.MakeName(string(trapped))
+}
+
+// GetTrapped returns the trapped state of the document, TrappedUnknown if not set.
+func (info *PdfInfo) GetTrapped() PdfInfoTrapped {
+	if info.Trapped == nil {
+		return TrappedUnknown
+	}
+	return PdfInfoTrapped(*info.Trapped)
+}
+
+// SetCustomInfo sets the custom key 'name' to the text string 'value'. An error is returned if
+// 'name' is one of the standard keys, which are set through the fields of PdfInfo.
+func (info *PdfInfo) SetCustomInfo(name core.PdfObjectName, value string) error {
+	if _, ok := standardInfoKeys[name]; ok {
+		return fmt.Errorf("cannot set standard key %s as custom info", name)
+	}
+	if len(name) == 0 {
+		return errors.New("empty custom info key")
+	}
+	if info.customInfo == nil {
+		info.customInfo = core.MakeDict()
+	}
+	info.customInfo.Set(name, makeTextString(value))
+	return nil
+}
+
+// CustomInfo returns the value of the custom key 'name' or nil if not set or not a string.
+func (info *PdfInfo) CustomInfo(name string) *core.PdfObjectString {
+	if info.customInfo == nil {
+		return nil
+	}
+	str, _ := core.GetString(info.customInfo.Get(core.PdfObjectName(name)))
+	return str
+}
+
+// CustomKeys returns the sorted custom keys of the document information dictionary.
+func (info *PdfInfo) CustomKeys() []string {
+	if info.customInfo == nil {
+		return nil
+	}
+	var keys []string
+	for _, key := range info.customInfo.Keys() {
+		keys = append(keys, string(key))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ToPdfObject implements interface PdfModel.
+func (info *PdfInfo) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.SetIfNotNil("Title", info.Title)
+	dict.SetIfNotNil("Author", info.Author)
+	dict.SetIfNotNil("Subject", info.Subject)
+	dict.SetIfNotNil("Keywords", info.Keywords)
+	dict.SetIfNotNil("Creator", info.Creator)
+	dict.SetIfNotNil("Producer", info.Producer)
+	if info.CreationDate != nil {
+		dict.Set("CreationDate", info.CreationDate.ToPdfObject())
+	}
+	if info.ModifiedDate != nil {
+		dict.Set("ModDate", info.ModifiedDate.ToPdfObject())
+	}
+	dict.SetIfNotNil("Trapped", info.Trapped)
+
+	if info.customInfo != nil {
+		for _, key := range info.customInfo.Keys() {
+			dict.Set(key, info.customInfo.Get(key))
+		}
+	}
+	return dict
+}
+
+// makeTextString returns a text string object encoding 's' in UTF-16BE if it is not ASCII.
+func makeTextString(s string) *core.PdfObjectString {
+	for _, r := range s {
+		if r > 0x7f {
+			return core.MakeEncodedString(s, true)
+		}
+	}
+	return core.MakeString(s)
+}
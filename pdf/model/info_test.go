@@ -0,0 +1,107 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+func TestPdfInfoReadWrite(t *testing.T) {
+	creationDate, err := NewPdfDateFromTime(time.Date(2019, 3, 14, 15, 9, 26, 0, time.UTC))
+	require.NoError(t, err)
+
+	info := NewPdfInfo()
+	info.Title = core.MakeString("Document title")
+	info.Author = core.MakeEncodedString("Jón Jónsson", true)
+	info.Producer = core.MakeString("Test producer")
+	info.CreationDate = &creationDate
+	info.SetTrapped(TrappedFalse)
+	require.NoError(t, info.SetCustomInfo("Department", "Sales"))
+	require.NoError(t, info.SetCustomInfo("Reviewer", "Ásta"))
+	require.Error(t, info.SetCustomInfo("Title", "Custom title"))
+
+	// Package level metadata is not used by the writers with document information.
+	SetPdfTitle("Global title")
+	defer SetPdfTitle("")
+
+	page := NewPdfPage()
+	w := NewPdfWriter()
+	w.SetDocInfo(info)
+	require.NoError(t, w.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	checkInfo := func(data []byte, title string) *PdfReader {
+		reader, err := NewPdfReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		readInfo, err := reader.GetPdfInfo()
+		require.NoError(t, err)
+
+		require.Equal(t, title, readInfo.Title.Decoded())
+		require.Equal(t, "Jón Jónsson", readInfo.Author.Decoded())
+		require.Equal(t, "Test producer", readInfo.Producer.Decoded())
+		require.Nil(t, readInfo.Subject)
+		require.NotNil(t, readInfo.CreationDate)
+		require.True(t, creationDate.ToGoTime().Equal(readInfo.CreationDate.ToGoTime()))
+		require.Equal(t, TrappedFalse, readInfo.GetTrapped())
+		require.Equal(t, []string{"Department", "Reviewer"}, readInfo.CustomKeys())
+		require.Equal(t, "Sales", readInfo.CustomInfo("Department").Decoded())
+		require.Equal(t, "Ásta", readInfo.CustomInfo("Reviewer").Decoded())
+		return reader
+	}
+	reader := checkInfo(buf.Bytes(), "Document title")
+
+	// Update the title in an incremental update.
+	readInfo, err := reader.GetPdfInfo()
+	require.NoError(t, err)
+	readInfo.Title = core.MakeString("Updated title")
+	appender, err := NewPdfAppender(reader)
+	require.NoError(t, err)
+	appender.AddPages(page)
+	appender.SetDocInfo(readInfo)
+	var appended bytes.Buffer
+	require.NoError(t, appender.Write(&appended))
+	checkInfo(appended.Bytes(), "Updated title")
+}
+
+func TestPdfInfoTrapped(t *testing.T) {
+	info, err := NewPdfInfoFromObject(core.MakeDict())
+	require.NoError(t, err)
+	require.Equal(t, TrappedUnknown, info.GetTrapped())
+
+	dict := core.MakeDict()
+	dict.Set("Trapped", core.MakeBool(true))
+	info, err = NewPdfInfoFromObject(dict)
+	require.NoError(t, err)
+	require.Equal(t, TrappedTrue, info.GetTrapped())
+
+	_, err = NewPdfInfoFromObject(core.MakeInteger(1))
+	require.Error(t, err)
+}
+
+func TestSetDocInfoNil(t *testing.T) {
+	info := NewPdfInfo()
+	info.Title = core.MakeString("Title")
+	w := NewPdfWriter()
+	w.SetDocInfo(info)
+	w.SetDocInfo(nil)
+	require.NoError(t, w.AddPage(NewPdfPage()))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	readInfo, err := reader.GetPdfInfo()
+	require.NoError(t, err)
+	require.Nil(t, readInfo.Title)
+	require.Equal(t, getPdfCreator(), readInfo.Creator.Decoded())
+}
@@ -26,6 +26,10 @@ func (c *CompressStreams) Optimize(objects []core.PdfObject) (optimizedObjects [
 		if _, found := core.GetName(stream.PdfObjectDictionary.Get("Filter")); found {
 			continue
 		}
+		if name, found := core.GetName(stream.PdfObjectDictionary.Get("Type")); found && *name == "Metadata" {
+			// Keep the XMP metadata readable by the tools not parsing PDF.
+			continue
+		}
 		encoder := core.NewFlateEncoder() // Most mainstream compressor and probably most robust.
 		var data []byte
 		data, err = encoder.EncodeBytes(stream.Stream)
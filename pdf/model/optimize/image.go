@@ -50,12 +50,18 @@ func findImages(objects []core.PdfObject) []*imageInfo {
 			continue
 		}
 		img := &imageInfo{BitsPerComponent: 8, Stream: stream}
-		if img.ColorSpace, err = model.DetermineColorspaceNameFromPdfObject(stream.PdfObjectDictionary.Get("ColorSpace")); err != nil {
-			common.Log.Error("Error determine color space %s", err)
-			continue
-		}
-		if val, ok := core.GetIntVal(stream.PdfObjectDictionary.Get("BitsPerComponent")); ok {
-			img.BitsPerComponent = val
+		if isJPXImage(stream) {
+			if !setJPXImageInfo(img) {
+				continue
+			}
+		} else {
+			if img.ColorSpace, err = model.DetermineColorspaceNameFromPdfObject(stream.PdfObjectDictionary.Get("ColorSpace")); err != nil {
+				common.Log.Error("Error determine color space %s", err)
+				continue
+			}
+			if val, ok := core.GetIntVal(stream.PdfObjectDictionary.Get("BitsPerComponent")); ok {
+				img.BitsPerComponent = val
+			}
 		}
 		if val, ok := core.GetIntVal(stream.PdfObjectDictionary.Get("Width")); ok {
 			img.Width = val
@@ -78,6 +84,53 @@ func findImages(objects []core.PdfObject) []*imageInfo {
 	return images
 }
 
+// isJPXImage checks whether the image stream is JPX (JPEG 2000) encoded.
+func isJPXImage(stream *core.PdfObjectStream) bool {
+	filter := core.TraceToDirectObject(stream.PdfObjectDictionary.Get("Filter"))
+	if arr, ok := filter.(*core.PdfObjectArray); ok && arr.Len() > 0 {
+		filter = core.TraceToDirectObject(arr.Get(arr.Len() - 1))
+	}
+	name, ok := core.GetName(filter)
+	return ok && string(*name) == core.StreamEncodingFilterNameJPX
+}
+
+// setJPXImageInfo sets the colorspace and bits per component of a JPX image, which can be given by the
+// image data only. Returns false if the image cannot be optimized.
+func setJPXImageInfo(img *imageInfo) bool {
+	if smask, ok := core.GetIntVal(img.Stream.PdfObjectDictionary.Get("SMaskInData")); ok && smask != 0 {
+		common.Log.Debug("Optimization of JPX images with soft mask in data is not supported")
+		return false
+	}
+	encoder, err := core.NewEncoderFromStream(img.Stream)
+	if err != nil {
+		common.Log.Debug("Error get encoder for the JPX image stream %s", err)
+		return false
+	}
+	jpx, ok := encoder.(*core.JPXEncoder)
+	if !ok {
+		common.Log.Debug("Optimization of JPX images with multiple filters is not supported")
+		return false
+	}
+	img.BitsPerComponent = jpx.BitsPerComponent
+	if csObj := img.Stream.PdfObjectDictionary.Get("ColorSpace"); csObj != nil {
+		if img.ColorSpace, err = model.DetermineColorspaceNameFromPdfObject(csObj); err != nil {
+			common.Log.Error("Error determine color space %s", err)
+			return false
+		}
+		return true
+	}
+	switch jpx.ColorComponents {
+	case 1:
+		img.ColorSpace = "DeviceGray"
+	case 3:
+		img.ColorSpace = "DeviceRGB"
+	default:
+		common.Log.Warning("Optimization is not supported for JPX images with %d color components", jpx.ColorComponents)
+		return false
+	}
+	return true
+}
+
 // Optimize optimizes PDF objects to decrease PDF size.
 func (i *Image) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfObject, err error) {
 	if i.ImageQuality <= 0 {
@@ -126,6 +179,12 @@ func (i *Image) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfO
 		newStream.PdfObjectDictionary.Merge(stream.PdfObjectDictionary)
 		fn := core.PdfObjectName(encoder.GetFilterName())
 		newStream.PdfObjectDictionary.Set(core.PdfObjectName("Filter"), &fn)
+		if isJPXImage(stream) {
+			// The colorspace and bits per component of JPX images can be given by the image data only.
+			newStream.PdfObjectDictionary.Set("ColorSpace", core.MakeName(string(img.ColorSpace)))
+			newStream.PdfObjectDictionary.Set("BitsPerComponent", core.MakeInteger(int64(img.BitsPerComponent)))
+			newStream.PdfObjectDictionary.Remove("SMaskInData")
+		}
 		ln := core.PdfObjectInteger(int64(len(streamData)))
 		newStream.PdfObjectDictionary.Set(core.PdfObjectName("Length"), &ln)
 		replaceTable[stream] = newStream
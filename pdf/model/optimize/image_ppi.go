@@ -61,6 +61,12 @@ func scaleImage(stream *core.PdfObjectStream, scale float64) error {
 		return err
 	}
 
+	// JPX images cannot be encoded: re-encode them with the DCT filter.
+	if _, ok := xImg.Filter.(*core.JPXEncoder); ok {
+		xImg.Filter = core.NewDCTEncoder()
+		xImg.SMaskInData = nil
+	}
+
 	// Update image encoder
 	encoderParams := core.MakeDict()
 	encoderParams.Set("ColorComponents", core.MakeInteger(int64(i.ColorComponents)))
@@ -0,0 +1,174 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+// PdfPageLabelStyle is the numbering style of the page labels (Table 159 - Entries in a page
+// label dictionary).
+type PdfPageLabelStyle string
+
+// Page label numbering styles.
+const (
+	// PageLabelNone labels the pages with the prefix only.
+	PageLabelNone         PdfPageLabelStyle = ""
+	PageLabelDecimal      PdfPageLabelStyle = "D"
+	PageLabelRomanUpper   PdfPageLabelStyle = "R"
+	PageLabelRomanLower   PdfPageLabelStyle = "r"
+	PageLabelLettersUpper PdfPageLabelStyle = "A"
+	PageLabelLettersLower PdfPageLabelStyle = "a"
+)
+
+// PdfPageLabelRange represents a range of pages labeled with the same style and prefix (section
+// 12.4.2 Page Labels). The range extends to the first page of the next range.
+type PdfPageLabelRange struct {
+	// PageIndex is the index of the first page of the range, starting at 0.
+	PageIndex int
+	Style     PdfPageLabelStyle
+	Prefix    string
+	// Start is the numeric value of the label of the first page of the range, 1 if not set.
+	Start int
+}
+
+// NewPdfPageLabelRange returns a new page label range starting at the page 'pageIndex' (starting
+// at 0) with the numbering style 'style', the prefix 'prefix' and the first numeric value 'start'.
+func NewPdfPageLabelRange(pageIndex int, style PdfPageLabelStyle, prefix string, start int) *PdfPageLabelRange {
+	return &PdfPageLabelRange{
+		PageIndex: pageIndex,
+		Style:     style,
+		Prefix:    prefix,
+		Start:     start,
+	}
+}
+
+// newPdfPageLabelRangeFromObject loads the page label dictionary 'obj' of the range starting at
+// the page 'pageIndex'.
+func newPdfPageLabelRangeFromObject(pageIndex int, obj core.PdfObject) (*PdfPageLabelRange, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return nil, fmt.Errorf("invalid page label type: %T", obj)
+	}
+	r := &PdfPageLabelRange{PageIndex: pageIndex}
+	if style, ok := core.GetNameVal(dict.Get("S")); ok {
+		r.Style = PdfPageLabelStyle(style)
+	}
+	if prefix, ok := core.GetString(dict.Get("P")); ok {
+		r.Prefix = prefix.Decoded()
+	}
+	if start, ok := core.GetIntVal(dict.Get("St")); ok {
+		r.Start = start
+	}
+	return r, nil
+}
+
+// Label returns the label of the page 'offset' of the range (0 for the first page).
+func (r *PdfPageLabelRange) Label(offset int) string {
+	start := r.Start
+	if start < 1 {
+		start = 1
+	}
+	num := start + offset
+
+	var label string
+	switch r.Style {
+	case PageLabelDecimal:
+		label = strconv.Itoa(num)
+	case PageLabelRomanUpper:
+		label = formatRoman(num)
+	case PageLabelRomanLower:
+		label = strings.ToLower(formatRoman(num))
+	case PageLabelLettersUpper:
+		label = formatLetters(num)
+	case PageLabelLettersLower:
+		label = strings.ToLower(formatLetters(num))
+	case PageLabelNone:
+	default:
+		common.Log.Debug("Unsupported page label style: %s", r.Style)
+		label = strconv.Itoa(num)
+	}
+	return r.Prefix + label
+}
+
+// ToPdfObject returns the page label dictionary of the range.
+func (r *PdfPageLabelRange) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("PageLabel"))
+	if r.Style != PageLabelNone {
+		dict.Set("S", core.MakeName(string(r.Style)))
+	}
+	if r.Prefix != "" {
+		dict.Set("P", makeTextString(r.Prefix))
+	}
+	if r.Start > 1 {
+		dict.Set("St", core.MakeInteger(int64(r.Start)))
+	}
+	return dict
+}
+
+// formatRoman returns the number 'num' in upper case roman numerals.
+func formatRoman(num int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var b strings.Builder
+	for i, value := range values {
+		for num >= value {
+			b.WriteString(symbols[i])
+			num -= value
+		}
+	}
+	return b.String()
+}
+
+// formatLetters returns the number 'num' in upper case letters: A to Z for 1 to 26, AA to ZZ
+// for 27 to 52 and so on.
+func formatLetters(num int) string {
+	if num < 1 {
+		return ""
+	}
+	letter := string(rune('A' + (num-1)%26))
+	return strings.Repeat(letter, (num-1)/26+1)
+}
+
+// newPageLabelsTree returns the number tree of the page label ranges 'ranges', which must start
+// at distinct pages. An empty tree is returned for no ranges.
+func newPageLabelsTree(ranges []*PdfPageLabelRange) (*PdfNumberTree, error) {
+	tree := NewPdfNumberTree()
+	for _, r := range ranges {
+		if r == nil {
+			return nil, errors.New("nil page label range")
+		}
+		if r.PageIndex < 0 {
+			return nil, fmt.Errorf("invalid page label range index: %d", r.PageIndex)
+		}
+		if _, ok := tree.Get(r.PageIndex); ok {
+			return nil, fmt.Errorf("duplicate page label range index: %d", r.PageIndex)
+		}
+		if err := tree.Set(r.PageIndex, r.ToPdfObject()); err != nil {
+			return nil, err
+		}
+	}
+	return tree, nil
+}
+
+// setCatalogPageLabels sets the PageLabels entry of the catalog 'catalog' to the number tree
+// 'tree', the entry is removed for an empty tree. The root of the tree is returned, nil if removed.
+func setCatalogPageLabels(catalog *core.PdfObjectDictionary, tree *PdfNumberTree) core.PdfObject {
+	if tree.Len() == 0 {
+		catalog.Remove("PageLabels")
+		return nil
+	}
+	obj := tree.ToPdfObject()
+	catalog.Set("PageLabels", obj)
+	return obj
+}
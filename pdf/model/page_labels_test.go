@@ -0,0 +1,145 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+func TestPageLabelFormat(t *testing.T) {
+	testcases := []struct {
+		style  PdfPageLabelStyle
+		start  int
+		labels []string
+	}{
+		{PageLabelDecimal, 0, []string{"1", "2", "3"}},
+		{PageLabelRomanLower, 3, []string{"iii", "iv", "v"}},
+		{PageLabelRomanUpper, 1989, []string{"MCMLXXXIX", "MCMXC", "MCMXCI"}},
+		{PageLabelLettersUpper, 25, []string{"Y", "Z", "AA"}},
+		{PageLabelLettersLower, 52, []string{"zz", "aaa", "bbb"}},
+		{PageLabelNone, 0, []string{"", "", ""}},
+	}
+	for _, tcase := range testcases {
+		r := NewPdfPageLabelRange(0, tcase.style, "", tcase.start)
+		for i, label := range tcase.labels {
+			require.Equal(t, label, r.Label(i))
+		}
+	}
+	r := NewPdfPageLabelRange(0, PageLabelDecimal, "A-", 0)
+	require.Equal(t, "A-2", r.Label(1))
+}
+
+func TestPageLabelsReadWrite(t *testing.T) {
+	w := NewPdfWriter()
+	for i := 0; i < 7; i++ {
+		require.NoError(t, w.AddPage(NewPdfPage()))
+	}
+	require.Error(t, w.SetPageLabels([]*PdfPageLabelRange{
+		NewPdfPageLabelRange(0, PageLabelDecimal, "", 1),
+		NewPdfPageLabelRange(0, PageLabelRomanLower, "", 1),
+	}))
+	require.NoError(t, w.SetPageLabels([]*PdfPageLabelRange{
+		NewPdfPageLabelRange(5, PageLabelDecimal, "A-", 1),
+		NewPdfPageLabelRange(0, PageLabelRomanLower, "", 1),
+		NewPdfPageLabelRange(2, PageLabelDecimal, "", 1),
+		NewPdfPageLabelRange(4, PageLabelNone, "Index", 0),
+	}))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	labels, err := reader.GetPageLabels()
+	require.NoError(t, err)
+	expected := []string{"i", "ii", "1", "2", "Index", "A-1", "A-2"}
+	require.Equal(t, expected, labels)
+	ranges, err := reader.GetPageLabelRanges()
+	require.NoError(t, err)
+	require.Len(t, ranges, 4)
+	require.Equal(t, &PdfPageLabelRange{PageIndex: 5, Style: PageLabelDecimal, Prefix: "A-"}, ranges[3])
+
+	// The page labels are kept by incremental updates.
+	appender, err := NewPdfAppender(reader)
+	require.NoError(t, err)
+	appender.SetDocInfo(NewPdfInfo())
+	var appended bytes.Buffer
+	require.NoError(t, appender.Write(&appended))
+	reader, err = NewPdfReader(bytes.NewReader(appended.Bytes()))
+	require.NoError(t, err)
+	labels, err = reader.GetPageLabels()
+	require.NoError(t, err)
+	require.Equal(t, expected, labels)
+
+	// Replaced and removed.
+	appender, err = NewPdfAppender(reader)
+	require.NoError(t, err)
+	require.NoError(t, appender.SetPageLabels([]*PdfPageLabelRange{
+		NewPdfPageLabelRange(0, PageLabelLettersUpper, "", 1),
+	}))
+	appended.Reset()
+	require.NoError(t, appender.Write(&appended))
+	reader, err = NewPdfReader(bytes.NewReader(appended.Bytes()))
+	require.NoError(t, err)
+	labels, err = reader.GetPageLabels()
+	require.NoError(t, err)
+	require.Equal(t, []string{"A", "B", "C", "D", "E", "F", "G"}, labels)
+
+	appender, err = NewPdfAppender(reader)
+	require.NoError(t, err)
+	require.NoError(t, appender.SetPageLabels(nil))
+	appended.Reset()
+	require.NoError(t, appender.Write(&appended))
+	reader, err = NewPdfReader(bytes.NewReader(appended.Bytes()))
+	require.NoError(t, err)
+	ranges, err = reader.GetPageLabelRanges()
+	require.NoError(t, err)
+	require.Nil(t, ranges)
+	labels, err = reader.GetPageLabels()
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2", "3", "4", "5", "6", "7"}, labels)
+}
+
+func TestPageLabelsInvalid(t *testing.T) {
+	w := NewPdfWriter()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, w.AddPage(NewPdfPage()))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	// Unsorted keys, a negative key and a key beyond the last page.
+	labelDict := func(style, prefix string) *core.PdfObjectDictionary {
+		dict := core.MakeDict()
+		dict.Set("S", core.MakeName(style))
+		if prefix != "" {
+			dict.Set("P", core.MakeString(prefix))
+		}
+		return dict
+	}
+	tree := core.MakeDict()
+	tree.Set("Nums", core.MakeArray(
+		core.MakeInteger(2), labelDict("r", ""),
+		core.MakeInteger(-1), labelDict("A", ""),
+		core.MakeInteger(0), labelDict("D", "x"),
+		core.MakeInteger(9), labelDict("A", ""),
+	))
+	reader.catalog.Set("PageLabels", tree)
+
+	ranges, err := reader.GetPageLabelRanges()
+	require.NoError(t, err)
+	require.Len(t, ranges, 3)
+	require.Equal(t, []int{0, 2, 9}, []int{ranges[0].PageIndex, ranges[1].PageIndex, ranges[2].PageIndex})
+	labels, err := reader.GetPageLabels()
+	require.NoError(t, err)
+	require.Equal(t, []string{"x1", "x2", "i", "ii"}, labels)
+}
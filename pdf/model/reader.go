@@ -6,9 +6,13 @@
 package model
 
 import (
+	"crypto"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/unidoc/unidoc/common"
@@ -46,34 +50,7 @@ type PdfReader struct {
 // Alternatively a lazy-loading reader can be created with NewPdfReaderLazy which loads only references,
 // and references are loaded from disk into memory on an as-needed basis.
 func NewPdfReader(rs io.ReadSeeker) (*PdfReader, error) {
-	pdfReader := &PdfReader{
-		rs:           rs,
-		traversed:    map[core.PdfObject]struct{}{},
-		modelManager: newModelManager(),
-		isLazy:       false,
-	}
-
-	// Create the parser, loads the cross reference table and trailer.
-	parser, err := core.NewParser(rs)
-	if err != nil {
-		return nil, err
-	}
-	pdfReader.parser = parser
-
-	isEncrypted, err := pdfReader.IsEncrypted()
-	if err != nil {
-		return nil, err
-	}
-
-	// Load pdf doc structure if not encrypted.
-	if !isEncrypted {
-		err = pdfReader.loadStructure()
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return pdfReader, nil
+	return NewPdfReaderWithOpts(rs, nil)
 }
 
 // NewPdfReaderLazy creates a new PdfReader for `rs` in lazy-loading mode. The difference
@@ -82,11 +59,33 @@ func NewPdfReader(rs io.ReadSeeker) (*PdfReader, error) {
 // Note that it may make sense to use the lazy-load reader when processing only parts of files,
 // rather than loading entire file into memory. Example: splitting a few pages from a large PDF file.
 func NewPdfReaderLazy(rs io.ReadSeeker) (*PdfReader, error) {
+	return NewPdfReaderWithOpts(rs, &ReaderOpts{LazyLoad: true})
+}
+
+// ReaderOpts defines options for creating PdfReader instances.
+type ReaderOpts struct {
+	// LazyLoad enables the lazy-loading mode, see NewPdfReaderLazy.
+	LazyLoad bool
+
+	// CacheLimit bounds the estimated memory size of the objects cached by the parser to the specified
+	// number of bytes. The least recently used objects are evicted and loaded from the file again when
+	// needed, and the stream data stays on disk until decoded or loaded by core.PdfObjectStream.LoadStream.
+	// Evicted objects are loaded as new objects, see core.PdfParser.SetCacheLimit. Intended for very
+	// large files, implies LazyLoad. Zero means no limit.
+	CacheLimit int64
+}
+
+// NewPdfReaderWithOpts creates a new PdfReader for `rs` with the specified options `opts`.
+// Nil options are the same as the default options of NewPdfReader.
+func NewPdfReaderWithOpts(rs io.ReadSeeker, opts *ReaderOpts) (*PdfReader, error) {
+	if opts == nil {
+		opts = &ReaderOpts{}
+	}
 	pdfReader := &PdfReader{
 		rs:           rs,
 		traversed:    map[core.PdfObject]struct{}{},
 		modelManager: newModelManager(),
-		isLazy:       true,
+		isLazy:       opts.LazyLoad || opts.CacheLimit > 0,
 	}
 
 	// Create the parser, loads the cross reference table and trailer.
@@ -94,6 +93,7 @@ func NewPdfReaderLazy(rs io.ReadSeeker) (*PdfReader, error) {
 	if err != nil {
 		return nil, err
 	}
+	parser.SetCacheLimit(opts.CacheLimit)
 	pdfReader.parser = parser
 
 	isEncrypted, err := pdfReader.IsEncrypted()
@@ -122,6 +122,12 @@ func (r *PdfReader) IsEncrypted() (bool, error) {
 	return r.parser.IsEncrypted()
 }
 
+// GetRepairReport returns the report of the repairs made when reading a damaged PDF file.
+// In lazy-loading mode, the objects are parsed when needed and the report may grow while reading.
+func (r *PdfReader) GetRepairReport() core.RepairReport {
+	return r.parser.GetRepairReport()
+}
+
 // GetEncryptionMethod returns a descriptive information string about the encryption method used.
 func (r *PdfReader) GetEncryptionMethod() string {
 	crypter := r.parser.GetCrypter()
@@ -149,6 +155,37 @@ func (r *PdfReader) Decrypt(password []byte) (bool, error) {
 	return true, nil
 }
 
+// DecryptWithCertificate decrypts the PDF file encrypted by the public-key security handler with
+// the recipient's certificate and private key. Returns true if successful, false otherwise.
+// The permissions granted to the recipient are returned by GetAccessPermissions.
+func (r *PdfReader) DecryptWithCertificate(cert *x509.Certificate, pkey crypto.PrivateKey) (bool, error) {
+	success, err := r.parser.DecryptWithCertificate(cert, pkey)
+	if err != nil {
+		return false, err
+	}
+	if !success {
+		return false, nil
+	}
+
+	err = r.loadStructure()
+	if err != nil {
+		common.Log.Debug("ERROR: Fail to load structure (%s)", err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetAccessPermissions returns the access permissions of the decrypted PDF file. The unencrypted
+// files grant all permissions.
+func (r *PdfReader) GetAccessPermissions() security.Permissions {
+	crypter := r.parser.GetCrypter()
+	if crypter == nil {
+		return security.PermOwner
+	}
+	return crypter.GetAccessPermissions()
+}
+
 // CheckAccessRights checks access rights and permissions for a specified password.  If either user/owner
 // password is specified,  full rights are granted, otherwise the access rights are specified by the
 // Permissions flag.
@@ -681,7 +718,9 @@ func (r *PdfReader) PageFromIndirectObject(ind *core.PdfIndirectObject) (*PdfPag
 	}
 
 	for i, pageind := range r.pageList {
-		if pageind == ind {
+		// The page objects are compared by object number as well, since the parser can load an
+		// object again as a new object when its cache is limited.
+		if pageind == ind || (ind != nil && ind.ObjectNumber > 0 && pageind.ObjectNumber == ind.ObjectNumber) {
 			return r.PageList[i], i + 1, nil
 		}
 	}
@@ -745,6 +784,266 @@ func (r *PdfReader) GetIndirectObjectByNumber(number int) (core.PdfObject, error
 	return obj, err
 }
 
+// GetPdfInfo returns the document information dictionary of the PDF.
+// An error is returned if the trailer has no valid Info dictionary.
+func (r *PdfReader) GetPdfInfo() (*PdfInfo, error) {
+	trailerDict, err := r.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	infoDict, ok := core.GetDict(trailerDict.Get("Info"))
+	if !ok {
+		return nil, errors.New("missing info dictionary")
+	}
+	return NewPdfInfoFromObject(infoDict)
+}
+
+// GetXMPMetadata returns the XMP metadata of the document catalog, nil if the document has no
+// metadata stream.
+func (r *PdfReader) GetXMPMetadata() (*XMPMetadata, error) {
+	stream, ok := core.GetStream(r.catalog.Get("Metadata"))
+	if !ok {
+		return nil, nil
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	return NewXMPMetadataFromBytes(data)
+}
+
+// GetNameTree returns the name tree 'name' of the Names dictionary of the catalog, such as Dests
+// or EmbeddedFiles, nil if the document does not have the tree.
+func (r *PdfReader) GetNameTree(name core.PdfObjectName) (*PdfNameTree, error) {
+	names, ok := core.GetDict(r.catalog.Get("Names"))
+	if !ok || names.Get(name) == nil {
+		return nil, nil
+	}
+	return NewPdfNameTreeFromObject(names.Get(name))
+}
+
+// GetEmbeddedFiles returns the document level embedded files (file attachments) of the
+// EmbeddedFiles name tree by name.
+func (r *PdfReader) GetEmbeddedFiles() (map[string]*PdfFileSpec, error) {
+	files := map[string]*PdfFileSpec{}
+	tree, err := r.GetNameTree("EmbeddedFiles")
+	if err != nil || tree == nil {
+		return files, err
+	}
+	err = tree.Walk(func(name string, value core.PdfObject) error {
+		fs, err := NewPdfFileSpecFromObject(value)
+		if err != nil {
+			return err
+		}
+		files[name] = fs
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// GetNamedDestinations returns the named destinations of the document by name, from the Dests
+// name tree and from the Dests dictionary of the catalog (PDF 1.1). The destinations which cannot
+// be resolved are skipped.
+func (r *PdfReader) GetNamedDestinations() (map[string]*PdfDestination, error) {
+	dests := map[string]*PdfDestination{}
+	if dict, ok := core.GetDict(r.catalog.Get("Dests")); ok {
+		for _, name := range dict.Keys() {
+			dest, err := r.resolveDestination(dict.Get(name), 1)
+			if err != nil {
+				common.Log.Debug("Invalid named destination %s: %v", name, err)
+				continue
+			}
+			dests[string(name)] = dest
+		}
+	}
+
+	tree, err := r.GetNameTree("Dests")
+	if err != nil || tree == nil {
+		return dests, err
+	}
+	err = tree.Walk(func(name string, value core.PdfObject) error {
+		dest, err := r.resolveDestination(value, 1)
+		if err != nil {
+			common.Log.Debug("Invalid named destination %q: %v", name, err)
+			return nil
+		}
+		dests[name] = dest
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dests, nil
+}
+
+// ResolveDestination resolves the destination 'obj', such as the Dest entry of an outline item
+// or of a link annotation, to an explicit destination with the page number of its page. The
+// destination can be explicit, a name (Dests dictionary), a string (Dests name tree) or a GoTo
+// action.
+func (r *PdfReader) ResolveDestination(obj core.PdfObject) (*PdfDestination, error) {
+	return r.resolveDestination(obj, 0)
+}
+
+func (r *PdfReader) resolveDestination(obj core.PdfObject, depth int) (*PdfDestination, error) {
+	if depth > maxDestinationDepth {
+		return nil, errors.New("destination nested too deeply")
+	}
+
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectName:
+		dests, ok := core.GetDict(r.catalog.Get("Dests"))
+		if !ok || dests.Get(*t) == nil {
+			return nil, fmt.Errorf("named destination %s not found", *t)
+		}
+		return r.resolveDestination(dests.Get(*t), depth+1)
+	case *core.PdfObjectString:
+		name := t.Decoded()
+		tree, err := r.GetNameTree("Dests")
+		if err != nil {
+			return nil, err
+		}
+		if tree != nil {
+			if value, ok := tree.Get(name); ok {
+				return r.resolveDestination(value, depth+1)
+			}
+		}
+		// Some documents refer to the Dests dictionary with strings.
+		if dests, ok := core.GetDict(r.catalog.Get("Dests")); ok && dests.Get(core.PdfObjectName(name)) != nil {
+			return r.resolveDestination(dests.Get(core.PdfObjectName(name)), depth+1)
+		}
+		return nil, fmt.Errorf("named destination %q not found", name)
+	case *core.PdfObjectDictionary:
+		// A GoTo action or the dictionary of a named destination.
+		if s, ok := core.GetNameVal(t.Get("S")); ok && s != "GoTo" {
+			return nil, fmt.Errorf("unsupported destination action: %s", s)
+		}
+		return r.resolveDestination(t.Get("D"), depth+1)
+	case *core.PdfObjectArray:
+		dest, err := NewPdfDestinationFromObject(t)
+		if err != nil {
+			return nil, err
+		}
+		if dest.Page != nil {
+			_, num, err := r.PageFromIndirectObject(dest.Page)
+			if err != nil {
+				return nil, err
+			}
+			dest.PageNumber = num
+		} else if dest.PageNumber < 1 || dest.PageNumber > len(r.PageList) {
+			return nil, fmt.Errorf("destination page index out of range: %d", dest.PageNumber-1)
+		} else {
+			// Page indices are used for local destinations by some producers.
+			dest.Page = r.pageList[dest.PageNumber-1]
+		}
+		return dest, nil
+	}
+	return nil, fmt.Errorf("invalid destination type: %T", obj)
+}
+
+// GetPageLabelRanges returns the page label ranges of the PageLabels number tree of the catalog,
+// ordered by their first page, nil if the document has no page labels.
+func (r *PdfReader) GetPageLabelRanges() ([]*PdfPageLabelRange, error) {
+	obj := r.catalog.Get("PageLabels")
+	if obj == nil {
+		return nil, nil
+	}
+	tree, err := NewPdfNumberTreeFromObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	var ranges []*PdfPageLabelRange
+	err = tree.Walk(func(num int, value core.PdfObject) error {
+		if num < 0 {
+			common.Log.Debug("Invalid page label range page index %d", num)
+			return nil
+		}
+		labelRange, err := newPdfPageLabelRangeFromObject(num, value)
+		if err != nil {
+			common.Log.Debug("Invalid page label range %d: %v", num, err)
+			return nil
+		}
+		ranges = append(ranges, labelRange)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The keys of number trees are sorted, but not in all files.
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].PageIndex < ranges[j].PageIndex
+	})
+	return ranges, nil
+}
+
+// GetPageLabels returns the labels of the pages by page index (starting at 0). The pages which
+// are not in a page label range, such as the pages of documents without page labels, are labeled
+// with their page number.
+func (r *PdfReader) GetPageLabels() ([]string, error) {
+	ranges, err := r.GetPageLabelRanges()
+	if err != nil {
+		return nil, err
+	}
+	labels := make([]string, len(r.pageList))
+	for i := range labels {
+		labels[i] = strconv.Itoa(i + 1)
+	}
+	for i, labelRange := range ranges {
+		if labelRange.PageIndex < 0 || labelRange.PageIndex >= len(labels) {
+			continue
+		}
+		end := len(labels)
+		if i+1 < len(ranges) && ranges[i+1].PageIndex < end {
+			end = ranges[i+1].PageIndex
+		}
+		for j := labelRange.PageIndex; j < end; j++ {
+			labels[j] = labelRange.Label(j - labelRange.PageIndex)
+		}
+	}
+	return labels, nil
+}
+
+// GetOpenAction returns the action performed when the document is opened (OpenAction entry of the
+// catalog), nil if not set. An open destination is returned as a go-to action.
+func (r *PdfReader) GetOpenAction() (*PdfAction, error) {
+	obj := r.catalog.Get("OpenAction")
+	if obj == nil {
+		return nil, nil
+	}
+	if arr, ok := core.GetArray(obj); ok {
+		return NewPdfActionGoTo(arr).PdfAction, nil
+	}
+	return NewPdfActionFromObject(obj)
+}
+
+// GetPageMode returns the page mode of the document when opened, PageModeUseNone if not set.
+func (r *PdfReader) GetPageMode() PdfPageMode {
+	if mode, ok := core.GetNameVal(r.catalog.Get("PageMode")); ok {
+		return PdfPageMode(mode)
+	}
+	return PageModeUseNone
+}
+
+// GetPageLayout returns the page layout of the document when opened, PageLayoutSinglePage if not
+// set.
+func (r *PdfReader) GetPageLayout() PdfPageLayout {
+	if layout, ok := core.GetNameVal(r.catalog.Get("PageLayout")); ok {
+		return PdfPageLayout(layout)
+	}
+	return PageLayoutSinglePage
+}
+
+// GetViewerPreferences returns the viewer preferences of the document, nil if not set.
+func (r *PdfReader) GetViewerPreferences() (*PdfViewerPreferences, error) {
+	obj := r.catalog.Get("ViewerPreferences")
+	if obj == nil {
+		return nil, nil
+	}
+	return NewPdfViewerPreferencesFromObject(obj)
+}
+
 // GetTrailer returns the PDF's trailer dictionary.
 func (r *PdfReader) GetTrailer() (*core.PdfObjectDictionary, error) {
 	trailerDict := r.parser.GetTrailer()
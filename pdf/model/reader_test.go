@@ -8,6 +8,7 @@ package model
 import (
 	"bytes"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -47,3 +48,136 @@ func TestReaderLazy(t *testing.T) {
 	err = writer.Write(&buf)
 	require.NoError(t, err)
 }
+
+// Tests that the reader with a limited parser cache resolves the pages of destinations and
+// structure elements loaded again as new objects after their eviction.
+func TestReaderCacheLimit(t *testing.T) {
+	const numPages = 40
+
+	// JBIG2 image with a repeated symbol stored in a compressed JBIG2Globals stream.
+	const width, height = 40, 8
+	imgData := bytes.Repeat([]byte{0xff}, 5*height)
+	for x := 1; x+2 < width; x += 4 {
+		for y := 2; y < 6; y++ {
+			imgData[y*5+x/8] &^= 0x80 >> uint(x%8)
+			imgData[y*5+(x+1)/8] &^= 0x80 >> uint((x+1)%8)
+		}
+	}
+	jbig2 := core.NewJBIG2Encoder()
+	jbig2.Width = width
+	jbig2.Height = height
+	require.NoError(t, jbig2.EncodeGlobals(core.JBIG2Image{Width: width, Height: height, Data: imgData}))
+	encoded, err := jbig2.EncodeBytes(imgData)
+	require.NoError(t, err)
+	imgDict := jbig2.MakeStreamDict()
+	decodeParams, ok := core.GetDict(imgDict.Get("DecodeParms"))
+	require.True(t, ok)
+	globals, ok := core.GetStream(decodeParams.Get("JBIG2Globals"))
+	require.True(t, ok)
+	globals, err = core.MakeStream(globals.Stream, core.NewFlateEncoder())
+	require.NoError(t, err)
+	decodeParams.Set("JBIG2Globals", globals)
+	imgDict.Set("Type", core.MakeName("XObject"))
+	imgDict.Set("Subtype", core.MakeName("Image"))
+	imgDict.Set("Width", core.MakeInteger(width))
+	imgDict.Set("Height", core.MakeInteger(height))
+	imgDict.Set("ColorSpace", core.MakeName("DeviceGray"))
+	imgDict.Set("BitsPerComponent", core.MakeInteger(1))
+	imgStream := &core.PdfObjectStream{PdfObjectDictionary: imgDict, Stream: encoded}
+
+	// DCT image, the header of which is read when creating the encoder.
+	dct := core.NewDCTEncoder()
+	dct.Width = width
+	dct.Height = height
+	dct.ColorComponents = 1
+	dct.BitsPerComponent = 8
+	encoded, err = dct.EncodeBytes(bytes.Repeat([]byte{0x80}, width*height))
+	require.NoError(t, err)
+	dctDict := dct.MakeStreamDict()
+	dctDict.Set("Type", core.MakeName("XObject"))
+	dctDict.Set("Subtype", core.MakeName("Image"))
+	dctDict.Set("Width", core.MakeInteger(width))
+	dctDict.Set("Height", core.MakeInteger(height))
+	dctDict.Set("ColorSpace", core.MakeName("DeviceGray"))
+	dctDict.Set("BitsPerComponent", core.MakeInteger(8))
+	dctStream := &core.PdfObjectStream{PdfObjectDictionary: dctDict, Stream: encoded}
+
+	w := NewPdfWriter()
+	root := core.MakeDict()
+	rootObj := core.MakeIndirectObject(root)
+	kids := core.MakeArray()
+	parentTree := NewPdfNumberTree()
+	for i := 0; i < numPages; i++ {
+		page := NewPdfPage()
+		page.StructParents = core.MakeInteger(int64(i))
+		require.NoError(t, page.SetContentStreams([]string{"/P <</MCID 0>> BDC EMC"}, core.NewFlateEncoder()))
+		if i == numPages-1 {
+			require.NoError(t, page.Resources.SetXObjectByName("Im1", imgStream))
+			require.NoError(t, page.Resources.SetXObjectByName("Im2", dctStream))
+		}
+		require.NoError(t, w.AddPage(page))
+
+		elem := core.MakeDict()
+		elem.Set("S", core.MakeName("P"))
+		elem.Set("P", rootObj)
+		elem.Set("Pg", page.GetPageAsIndirectObject())
+		elem.Set("K", core.MakeInteger(0))
+		elemObj := core.MakeIndirectObject(elem)
+		kids.Append(elemObj)
+		require.NoError(t, parentTree.Set(i, core.MakeArray(elemObj)))
+	}
+	require.NoError(t, w.AddNamedDestination("target", NewPdfDestinationFit(30)))
+	root.Set("Type", core.MakeName("StructTreeRoot"))
+	root.Set("K", kids)
+	root.Set("ParentTree", parentTree.ToPdfObject())
+	w.catalog.Set("StructTreeRoot", rootObj)
+	require.NoError(t, w.addObjects(rootObj))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReaderWithOpts(bytes.NewReader(buf.Bytes()), &ReaderOpts{CacheLimit: 2048})
+	require.NoError(t, err)
+	dests, err := reader.GetNamedDestinations()
+	require.NoError(t, err)
+	require.Contains(t, dests, "target")
+	require.Equal(t, 30, dests["target"].PageNumber)
+
+	tree, err := reader.GetStructTreeRoot()
+	require.NoError(t, err)
+	require.Len(t, tree.K, numPages)
+	for i, elem := range tree.K {
+		require.Equal(t, i+1, elem.PageNumber)
+		require.Len(t, elem.Kids, 1)
+		require.Equal(t, i+1, elem.Kids[0].PageNumber)
+		require.True(t, elem == tree.GetMarkedContentElem(i, 0), "page %d", i+1)
+	}
+
+	// The images are decoded with the data read on demand.
+	page, err := reader.GetPage(numPages)
+	require.NoError(t, err)
+	ximg, err := page.Resources.GetXObjectImageByName("Im1")
+	require.NoError(t, err)
+	require.NotNil(t, ximg)
+	img, err := ximg.ToImage()
+	require.NoError(t, err)
+	require.Equal(t, imgData, img.Data)
+	ximg, err = page.Resources.GetXObjectImageByName("Im2")
+	require.NoError(t, err)
+	require.NotNil(t, ximg)
+	img, err = ximg.ToImage()
+	require.NoError(t, err)
+	require.Len(t, img.Data, width*height)
+
+	// The content streams read on demand are written with their data.
+	w = NewPdfWriter()
+	require.NoError(t, w.AddPage(page))
+	buf.Reset()
+	require.NoError(t, w.Write(&buf))
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	page, err = reader.GetPage(1)
+	require.NoError(t, err)
+	contents, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(contents, "/P <</MCID 0>> BDC EMC"), contents)
+}
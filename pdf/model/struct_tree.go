@@ -0,0 +1,747 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+// maxStructDepth limits the depth of the structure tree to avoid endless recursion.
+const maxStructDepth = 256
+
+// PdfStructTreeRoot represents the structure tree root of a tagged document (section 14.7.2
+// Structure Hierarchy).
+type PdfStructTreeRoot struct {
+	// K contains the top level structure elements.
+	K []*PdfStructElem
+	// RoleMap maps the custom structure types to standard structure types.
+	RoleMap map[string]string
+	// ClassMap maps the attribute class names to their attribute objects.
+	ClassMap map[string][]*PdfStructAttributes
+	// ParentTree maps the StructParents keys of the pages and the StructParent keys of the
+	// objects to their structure elements.
+	ParentTree *PdfNumberTree
+	// ParentTreeNextKey is the next key available for the parent tree.
+	ParentTreeNextKey int
+	// IDTree maps the element identifiers to the structure elements.
+	IDTree *PdfNameTree
+
+	// elems maps the loaded structure element objects to their elements.
+	elems map[structElemKey]*PdfStructElem
+}
+
+// structElemKey identifies a structure element object: by object number if the element is an
+// indirect object, as the parser can load an object again as a new object when its cache is
+// limited, or by dictionary otherwise.
+type structElemKey struct {
+	num  int64
+	dict *core.PdfObjectDictionary
+}
+
+// getStructElemKey returns the key of the structure element object 'obj'.
+func getStructElemKey(obj core.PdfObject) (structElemKey, bool) {
+	switch t := obj.(type) {
+	case *core.PdfObjectReference:
+		return structElemKey{num: t.ObjectNumber}, true
+	case *core.PdfIndirectObject:
+		if t.ObjectNumber > 0 {
+			return structElemKey{num: t.ObjectNumber}, true
+		}
+	}
+	d, ok := core.GetDict(obj)
+	if !ok {
+		return structElemKey{}, false
+	}
+	return structElemKey{dict: d}, true
+}
+
+// PdfStructElem represents a structure element (Table 323 - Entries in a structure element
+// dictionary).
+type PdfStructElem struct {
+	// S is the structure type, such as P or H1, which can be mapped by the role map.
+	S string
+	// Parent is the parent element, nil for the top level elements.
+	Parent *PdfStructElem
+	// ID is the element identifier.
+	ID string
+	// Page is the page containing the content of the element (Pg), nil if not set.
+	Page *core.PdfIndirectObject
+	// PageNumber is the number of the page, starting at 1, 0 if not set.
+	PageNumber int
+	// Kids are the kids of the element: structure elements and content items.
+	Kids []*PdfStructKid
+	// Attributes are the attribute objects of the element (A).
+	Attributes []*PdfStructAttributes
+	// Classes are the attribute classes of the element (C).
+	Classes []string
+
+	Title      string
+	Lang       string
+	Alt        string
+	Expansion  string
+	ActualText string
+
+	container *core.PdfObjectDictionary
+}
+
+// PdfStructKid is a kid of a structure element: a structure element, a marked-content sequence
+// or an object, such as an annotation.
+type PdfStructKid struct {
+	// Elem is the structure element, nil for the content items.
+	Elem *PdfStructElem
+	// MCID is the marked-content identifier of the marked-content sequences, -1 for the other
+	// kids.
+	MCID int
+	// Stream is the content stream containing the marked-content sequence when it is not the
+	// content of the page, such as a form XObject (Stm).
+	Stream core.PdfObject
+	// Obj is the referenced object of the object references (OBJR).
+	Obj core.PdfObject
+	// Page is the page of the content item, inherited from the element if not set.
+	Page *core.PdfIndirectObject
+	// PageNumber is the number of the page, starting at 1, 0 if unknown.
+	PageNumber int
+}
+
+// PdfStructAttributes represents an attribute object (section 14.7.5 Structure Attributes).
+type PdfStructAttributes struct {
+	// O is the owner of the attributes, such as Layout, Table or List.
+	O string
+	// Attributes are the attributes of the owner by name.
+	Attributes *core.PdfObjectDictionary
+}
+
+// NewPdfStructTreeRoot returns a new empty structure tree.
+func NewPdfStructTreeRoot() *PdfStructTreeRoot {
+	return &PdfStructTreeRoot{
+		RoleMap:  map[string]string{},
+		ClassMap: map[string][]*PdfStructAttributes{},
+		elems:    map[structElemKey]*PdfStructElem{},
+	}
+}
+
+// NewPdfStructElem returns a new structure element of type 'typ', such as P or H1.
+func NewPdfStructElem(typ string) *PdfStructElem {
+	return &PdfStructElem{S: typ}
+}
+
+// AddKid appends the top level structure element 'elem' to the tree.
+func (root *PdfStructTreeRoot) AddKid(elem *PdfStructElem) {
+	elem.Parent = nil
+	root.K = append(root.K, elem)
+}
+
+// AddKid appends the structure element 'kid' to the kids of the element.
+func (elem *PdfStructElem) AddKid(kid *PdfStructElem) {
+	kid.Parent = elem
+	elem.Kids = append(elem.Kids, &PdfStructKid{Elem: kid, MCID: -1, Page: kid.Page, PageNumber: kid.PageNumber})
+}
+
+// AddMarkedContent appends the marked-content sequence 'mcid' of the content of the page 'page'
+// to the kids of the element.
+func (elem *PdfStructElem) AddMarkedContent(page *core.PdfIndirectObject, mcid int) {
+	elem.Kids = append(elem.Kids, &PdfStructKid{MCID: mcid, Page: page})
+}
+
+// AddObject appends the object reference to 'obj' on the page 'page', such as an annotation, to
+// the kids of the element.
+func (elem *PdfStructElem) AddObject(page *core.PdfIndirectObject, obj core.PdfObject) {
+	elem.Kids = append(elem.Kids, &PdfStructKid{MCID: -1, Obj: obj, Page: page})
+}
+
+// IsMarkedContent returns true if the kid is a marked-content sequence.
+func (kid *PdfStructKid) IsMarkedContent() bool {
+	return kid.Elem == nil && kid.MCID >= 0
+}
+
+// IsObject returns true if the kid is an object reference.
+func (kid *PdfStructKid) IsObject() bool {
+	return kid.Elem == nil && kid.Obj != nil
+}
+
+// IsTagged checks whether the document is a tagged document (Marked entry of the MarkInfo
+// dictionary of the catalog).
+func (r *PdfReader) IsTagged() bool {
+	markInfo, ok := core.GetDict(r.catalog.Get("MarkInfo"))
+	if !ok {
+		return false
+	}
+	marked, ok := core.TraceToDirectObject(markInfo.Get("Marked")).(*core.PdfObjectBool)
+	return ok && bool(*marked)
+}
+
+// GetStructTreeRoot returns the structure tree of a tagged document, nil if the document has no
+// structure tree.
+func (r *PdfReader) GetStructTreeRoot() (*PdfStructTreeRoot, error) {
+	obj := r.catalog.Get("StructTreeRoot")
+	if obj == nil {
+		return nil, nil
+	}
+	d, ok := core.GetDict(obj)
+	if !ok {
+		return nil, fmt.Errorf("invalid structure tree root type: %T", obj)
+	}
+
+	root := NewPdfStructTreeRoot()
+	if roleMap, ok := core.GetDict(d.Get("RoleMap")); ok {
+		for _, key := range roleMap.Keys() {
+			if role, ok := core.GetNameVal(roleMap.Get(key)); ok {
+				root.RoleMap[string(key)] = role
+			}
+		}
+	}
+	if classMap, ok := core.GetDict(d.Get("ClassMap")); ok {
+		for _, key := range classMap.Keys() {
+			root.ClassMap[string(key)] = loadStructAttributes(classMap.Get(key))
+		}
+	}
+	if obj := d.Get("ParentTree"); obj != nil {
+		tree, err := NewPdfNumberTreeFromObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		root.ParentTree = tree
+	}
+	root.ParentTreeNextKey, _ = core.GetIntVal(d.Get("ParentTreeNextKey"))
+	if obj := d.Get("IDTree"); obj != nil {
+		tree, err := NewPdfNameTreeFromObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		root.IDTree = tree
+	}
+
+	// The page numbers of the content items are looked up by page object number.
+	pageNumbers := make(map[int64]int, len(r.pageList))
+	for i, page := range r.pageList {
+		pageNumbers[page.ObjectNumber] = i + 1
+	}
+	kids, err := r.loadStructKids(root, d.Get("K"), nil, nil, pageNumbers, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, kid := range kids {
+		if kid.Elem != nil {
+			root.K = append(root.K, kid.Elem)
+		}
+	}
+	return root, nil
+}
+
+// loadStructKids loads the kids 'obj' (K entry) of the element 'parent' on the page 'page'.
+// 'pageNumbers' maps the object numbers of the pages to their page numbers.
+func (r *PdfReader) loadStructKids(root *PdfStructTreeRoot, obj core.PdfObject, parent *PdfStructElem,
+	page *core.PdfIndirectObject, pageNumbers map[int64]int, depth int) ([]*PdfStructKid, error) {
+	if depth > maxStructDepth {
+		return nil, errors.New("structure tree too deep")
+	}
+
+	var objs []core.PdfObject
+	if arr, ok := core.GetArray(obj); ok {
+		objs = arr.Elements()
+	} else if obj != nil {
+		objs = []core.PdfObject{obj}
+	}
+
+	var kids []*PdfStructKid
+	for _, obj := range objs {
+		if mcid, ok := core.GetIntVal(obj); ok {
+			kids = append(kids, &PdfStructKid{MCID: mcid, Page: page, PageNumber: structPageNumber(page, pageNumbers)})
+			continue
+		}
+		d, ok := core.GetDict(obj)
+		if !ok {
+			common.Log.Debug("Invalid structure kid type: %T", obj)
+			continue
+		}
+
+		kid := &PdfStructKid{MCID: -1, Page: page}
+		if pg, ok := core.GetIndirect(d.Get("Pg")); ok {
+			kid.Page = pg
+		}
+		typ, _ := core.GetNameVal(d.Get("Type"))
+		switch {
+		case typ == "MCR":
+			kid.MCID, _ = core.GetIntVal(d.Get("MCID"))
+			kid.Stream = d.Get("Stm")
+		case typ == "OBJR":
+			kid.Obj = d.Get("Obj")
+		case d.Get("S") != nil:
+			key, _ := getStructElemKey(obj)
+			if _, ok := root.elems[key]; ok {
+				common.Log.Debug("ERROR: Structure element referenced twice - skipping")
+				continue
+			}
+			elem, err := r.loadStructElem(root, key, d, parent, pageNumbers, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			kid.Elem = elem
+			kid.Page = elem.Page
+		default:
+			common.Log.Debug("Invalid structure kid: %s", d)
+			continue
+		}
+		kid.PageNumber = structPageNumber(kid.Page, pageNumbers)
+		kids = append(kids, kid)
+	}
+	return kids, nil
+}
+
+// loadStructElem loads the structure element 'd' with the key 'key' of the parent 'parent'.
+func (r *PdfReader) loadStructElem(root *PdfStructTreeRoot, key structElemKey, d *core.PdfObjectDictionary,
+	parent *PdfStructElem, pageNumbers map[int64]int, depth int) (*PdfStructElem, error) {
+	elem := &PdfStructElem{Parent: parent, container: d}
+	root.elems[key] = elem
+
+	elem.S, _ = core.GetNameVal(d.Get("S"))
+	if str, ok := core.GetString(d.Get("ID")); ok {
+		elem.ID = str.Str()
+	}
+	elem.Page, _ = core.GetIndirect(d.Get("Pg"))
+	if elem.Page == nil && parent != nil {
+		// Content items without page are on the page of the closest ancestor with a page.
+		elem.Page = parent.Page
+	}
+	elem.PageNumber = structPageNumber(elem.Page, pageNumbers)
+	elem.Attributes = loadStructAttributes(d.Get("A"))
+	switch c := core.TraceToDirectObject(d.Get("C")).(type) {
+	case *core.PdfObjectName:
+		elem.Classes = []string{string(*c)}
+	case *core.PdfObjectArray:
+		for _, obj := range c.Elements() {
+			if name, ok := core.GetNameVal(obj); ok {
+				elem.Classes = append(elem.Classes, name)
+			}
+		}
+	}
+	for key, field := range map[core.PdfObjectName]*string{
+		"T":          &elem.Title,
+		"Lang":       &elem.Lang,
+		"Alt":        &elem.Alt,
+		"E":          &elem.Expansion,
+		"ActualText": &elem.ActualText,
+	} {
+		if str, ok := core.GetString(d.Get(key)); ok {
+			*field = str.Decoded()
+		}
+	}
+
+	kids, err := r.loadStructKids(root, d.Get("K"), elem, elem.Page, pageNumbers, depth)
+	if err != nil {
+		return nil, err
+	}
+	elem.Kids = kids
+	return elem, nil
+}
+
+// structPageNumber returns the page number of the page 'page' in 'pageNumbers', 0 if unknown.
+func structPageNumber(page *core.PdfIndirectObject, pageNumbers map[int64]int) int {
+	if page == nil {
+		return 0
+	}
+	return pageNumbers[page.ObjectNumber]
+}
+
+// loadStructAttributes loads the attribute objects 'obj', an attribute object or an array of
+// attribute objects with their revision numbers.
+func loadStructAttributes(obj core.PdfObject) []*PdfStructAttributes {
+	var objs []core.PdfObject
+	if arr, ok := core.GetArray(obj); ok {
+		objs = arr.Elements()
+	} else if obj != nil {
+		objs = []core.PdfObject{obj}
+	}
+
+	var attrs []*PdfStructAttributes
+	for _, obj := range objs {
+		var d *core.PdfObjectDictionary
+		switch t := core.TraceToDirectObject(obj).(type) {
+		case *core.PdfObjectDictionary:
+			d = t
+		case *core.PdfObjectStream:
+			d = t.PdfObjectDictionary
+		default:
+			// Revision numbers.
+			continue
+		}
+		owner, _ := core.GetNameVal(d.Get("O"))
+		attrs = append(attrs, &PdfStructAttributes{O: owner, Attributes: d})
+	}
+	return attrs
+}
+
+// Walk calls 'fn' for each structure element of the tree in depth first order with the depth of
+// the element (0 for the top level elements), stopping at the first error, which is returned.
+func (root *PdfStructTreeRoot) Walk(fn func(elem *PdfStructElem, depth int) error) error {
+	var walk func(elems []*PdfStructElem, depth int) error
+	walk = func(elems []*PdfStructElem, depth int) error {
+		for _, elem := range elems {
+			if err := fn(elem, depth); err != nil {
+				return err
+			}
+			if err := walk(elem.GetKidElems(), depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(root.K, 0)
+}
+
+// ResolveRole returns the standard structure type of the structure type 'typ' by following the
+// role map.
+func (root *PdfStructTreeRoot) ResolveRole(typ string) string {
+	for i := 0; i < len(root.RoleMap); i++ {
+		role, ok := root.RoleMap[typ]
+		if !ok {
+			break
+		}
+		typ = role
+	}
+	return typ
+}
+
+// GetMarkedContentElem returns the structure element of the marked-content sequence 'mcid' of
+// the content stream with the StructParents key 'structParents', such as a page, nil if not found.
+func (root *PdfStructTreeRoot) GetMarkedContentElem(structParents, mcid int) *PdfStructElem {
+	if root.ParentTree == nil || mcid < 0 {
+		return nil
+	}
+	obj, ok := root.ParentTree.Get(structParents)
+	if !ok {
+		return nil
+	}
+	arr, ok := core.GetArray(obj)
+	if !ok || mcid >= arr.Len() {
+		return nil
+	}
+	return root.getElem(arr.Get(mcid))
+}
+
+// GetObjectElem returns the structure element of the object with the StructParent key
+// 'structParent', such as an annotation, nil if not found.
+func (root *PdfStructTreeRoot) GetObjectElem(structParent int) *PdfStructElem {
+	if root.ParentTree == nil {
+		return nil
+	}
+	obj, ok := root.ParentTree.Get(structParent)
+	if !ok {
+		return nil
+	}
+	return root.getElem(obj)
+}
+
+// GetElemByID returns the structure element with the identifier 'id', nil if not found.
+func (root *PdfStructTreeRoot) GetElemByID(id string) *PdfStructElem {
+	if root.IDTree == nil {
+		return nil
+	}
+	obj, ok := root.IDTree.Get(id)
+	if !ok {
+		return nil
+	}
+	return root.getElem(obj)
+}
+
+// getElem returns the loaded structure element of the object 'obj'.
+func (root *PdfStructTreeRoot) getElem(obj core.PdfObject) *PdfStructElem {
+	key, ok := getStructElemKey(obj)
+	if !ok {
+		return nil
+	}
+	return root.elems[key]
+}
+
+// GetKidElems returns the kids of the element which are structure elements.
+func (elem *PdfStructElem) GetKidElems() []*PdfStructElem {
+	var elems []*PdfStructElem
+	for _, kid := range elem.Kids {
+		if kid.Elem != nil {
+			elems = append(elems, kid.Elem)
+		}
+	}
+	return elems
+}
+
+// GetAttribute returns the attribute 'name' of the element, from its attribute objects and then
+// from its attribute classes in the class map of 'root', nil if not found.
+func (elem *PdfStructElem) GetAttribute(root *PdfStructTreeRoot, name core.PdfObjectName) core.PdfObject {
+	for _, attrs := range elem.Attributes {
+		if obj := attrs.Attributes.Get(name); obj != nil {
+			return obj
+		}
+	}
+	if root == nil {
+		return nil
+	}
+	for _, class := range elem.Classes {
+		for _, attrs := range root.ClassMap[class] {
+			if obj := attrs.Attributes.Get(name); obj != nil {
+				return obj
+			}
+		}
+	}
+	return nil
+}
+
+// GetContainingPdfObject returns the structure element dictionary.
+func (elem *PdfStructElem) GetContainingPdfObject() core.PdfObject {
+	return elem.container
+}
+
+// toPdfObject returns the structure tree root object for the document pages 'pages'. The parent
+// tree and the ID tree are rebuilt from the elements, setting the StructParents entries of the
+// pages and the StructParent entries of the referenced objects. The pages of the elements and
+// content items are given either by page object or by page number.
+func (root *PdfStructTreeRoot) toPdfObject(pages []*core.PdfIndirectObject) (*core.PdfIndirectObject, error) {
+	resolvePage := func(page *core.PdfIndirectObject, number int) *core.PdfIndirectObject {
+		if page == nil && number >= 1 && number <= len(pages) {
+			return pages[number-1]
+		}
+		return page
+	}
+
+	rootDict := core.MakeDict()
+	rootObj := core.MakeIndirectObject(rootDict)
+	built := map[*PdfStructElem]bool{}
+	// Elements of the marked-content sequences by page and MCID.
+	mcids := map[*core.PdfIndirectObject]map[int]core.PdfObject{}
+	// Referenced objects and their elements.
+	var objrs []*core.PdfObjectDictionary
+	var objrElems []core.PdfObject
+	ids := NewPdfNameTree()
+
+	var build func(elem *PdfStructElem, parent core.PdfObject, depth int) (core.PdfObject, error)
+	build = func(elem *PdfStructElem, parent core.PdfObject, depth int) (core.PdfObject, error) {
+		if depth > maxStructDepth {
+			return nil, errors.New("structure tree too deep")
+		}
+		if built[elem] {
+			return nil, errors.New("structure element added twice")
+		}
+		built[elem] = true
+
+		d := core.MakeDict()
+		obj := core.MakeIndirectObject(d)
+		page := resolvePage(elem.Page, elem.PageNumber)
+		if page == nil {
+			// Use the page of the first content item.
+			for _, kid := range elem.Kids {
+				if kid.Elem == nil {
+					if page = resolvePage(kid.Page, kid.PageNumber); page != nil {
+						break
+					}
+				}
+			}
+		}
+		d.Set("S", core.MakeName(elem.S))
+		d.Set("P", parent)
+		if elem.ID != "" {
+			d.Set("ID", core.MakeString(elem.ID))
+			if err := ids.Set(elem.ID, obj); err != nil {
+				return nil, err
+			}
+		}
+		if page != nil {
+			d.Set("Pg", page)
+		}
+
+		var kids []core.PdfObject
+		for _, kid := range elem.Kids {
+			kidPage := resolvePage(kid.Page, kid.PageNumber)
+			switch {
+			case kid.Elem != nil:
+				kidObj, err := build(kid.Elem, obj, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				kids = append(kids, kidObj)
+			case kid.IsObject():
+				objr := core.MakeDict()
+				objr.Set("Type", core.MakeName("OBJR"))
+				if kidPage != page {
+					objr.Set("Pg", kidPage)
+				}
+				objr.Set("Obj", kid.Obj)
+				kids = append(kids, objr)
+				if od, ok := core.GetDict(kid.Obj); ok {
+					objrs = append(objrs, od)
+					objrElems = append(objrElems, obj)
+				}
+			case kid.IsMarkedContent():
+				if kid.Stream == nil && kidPage != nil {
+					if mcids[kidPage] == nil {
+						mcids[kidPage] = map[int]core.PdfObject{}
+					}
+					mcids[kidPage][kid.MCID] = obj
+				}
+				if kid.Stream == nil && kidPage == page {
+					kids = append(kids, core.MakeInteger(int64(kid.MCID)))
+					continue
+				}
+				mcr := core.MakeDict()
+				mcr.Set("Type", core.MakeName("MCR"))
+				if kidPage != page {
+					mcr.Set("Pg", kidPage)
+				}
+				if kid.Stream != nil {
+					mcr.Set("Stm", kid.Stream)
+				}
+				mcr.Set("MCID", core.MakeInteger(int64(kid.MCID)))
+				kids = append(kids, mcr)
+			}
+		}
+		switch len(kids) {
+		case 0:
+		case 1:
+			d.Set("K", kids[0])
+		default:
+			d.Set("K", core.MakeArray(kids...))
+		}
+
+		if len(elem.Attributes) > 0 {
+			d.Set("A", structAttributesObject(elem.Attributes))
+		}
+		switch len(elem.Classes) {
+		case 0:
+		case 1:
+			d.Set("C", core.MakeName(elem.Classes[0]))
+		default:
+			classes := core.MakeArray()
+			for _, class := range elem.Classes {
+				classes.Append(core.MakeName(class))
+			}
+			d.Set("C", classes)
+		}
+		for _, entry := range []struct {
+			key core.PdfObjectName
+			val string
+		}{
+			{"T", elem.Title},
+			{"Lang", elem.Lang},
+			{"Alt", elem.Alt},
+			{"E", elem.Expansion},
+			{"ActualText", elem.ActualText},
+		} {
+			if entry.val != "" {
+				d.Set(entry.key, makeTextString(entry.val))
+			}
+		}
+		return obj, nil
+	}
+
+	var kids []core.PdfObject
+	for _, elem := range root.K {
+		obj, err := build(elem, rootObj, 0)
+		if err != nil {
+			return nil, err
+		}
+		kids = append(kids, obj)
+	}
+
+	// The keys of the pages come first, in page order, then the keys of the objects.
+	parentTree := NewPdfNumberTree()
+	key := 0
+	for _, page := range pages {
+		elems, ok := mcids[page]
+		if !ok {
+			continue
+		}
+		max := -1
+		for mcid := range elems {
+			if mcid > max {
+				max = mcid
+			}
+		}
+		arr := core.MakeArray()
+		for mcid := 0; mcid <= max; mcid++ {
+			if obj, ok := elems[mcid]; ok {
+				arr.Append(obj)
+			} else {
+				arr.Append(core.MakeNull())
+			}
+		}
+		if err := parentTree.Set(key, arr); err != nil {
+			return nil, err
+		}
+		if d, ok := core.GetDict(page); ok {
+			d.Set("StructParents", core.MakeInteger(int64(key)))
+		}
+		key++
+	}
+	for i, d := range objrs {
+		if err := parentTree.Set(key, objrElems[i]); err != nil {
+			return nil, err
+		}
+		d.Set("StructParent", core.MakeInteger(int64(key)))
+		key++
+	}
+
+	rootDict.Set("Type", core.MakeName("StructTreeRoot"))
+	switch len(kids) {
+	case 0:
+	case 1:
+		rootDict.Set("K", kids[0])
+	default:
+		rootDict.Set("K", core.MakeArray(kids...))
+	}
+	rootDict.Set("ParentTree", parentTree.ToPdfObject())
+	rootDict.Set("ParentTreeNextKey", core.MakeInteger(int64(key)))
+	if ids.Len() > 0 {
+		rootDict.Set("IDTree", ids.ToPdfObject())
+	}
+	if len(root.RoleMap) > 0 {
+		roleMap := core.MakeDict()
+		var keys []string
+		for typ := range root.RoleMap {
+			keys = append(keys, typ)
+		}
+		sort.Strings(keys)
+		for _, typ := range keys {
+			roleMap.Set(core.PdfObjectName(typ), core.MakeName(root.RoleMap[typ]))
+		}
+		rootDict.Set("RoleMap", roleMap)
+	}
+	if len(root.ClassMap) > 0 {
+		classMap := core.MakeDict()
+		var keys []string
+		for class := range root.ClassMap {
+			keys = append(keys, class)
+		}
+		sort.Strings(keys)
+		for _, class := range keys {
+			classMap.Set(core.PdfObjectName(class), structAttributesObject(root.ClassMap[class]))
+		}
+		rootDict.Set("ClassMap", classMap)
+	}
+	return rootObj, nil
+}
+
+// structAttributesObject returns the attribute object of 'attrs' if there is only one, or an
+// array of attribute objects otherwise.
+func structAttributesObject(attrs []*PdfStructAttributes) core.PdfObject {
+	var objs []core.PdfObject
+	for _, a := range attrs {
+		d := core.MakeDict()
+		if a.Attributes != nil {
+			d.Merge(a.Attributes)
+		}
+		if a.O != "" {
+			d.Set("O", core.MakeName(a.O))
+		}
+		objs = append(objs, d)
+	}
+	if len(objs) == 1 {
+		return objs[0]
+	}
+	return core.MakeArray(objs...)
+}
@@ -0,0 +1,255 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+func TestStructTreeRead(t *testing.T) {
+	w := NewPdfWriter()
+	var pages []*core.PdfIndirectObject
+	for i := 0; i < 2; i++ {
+		page := NewPdfPage()
+		page.StructParents = core.MakeInteger(int64(i))
+		require.NoError(t, w.AddPage(page))
+		pages = append(pages, page.GetPageAsIndirectObject())
+	}
+	link := NewPdfAnnotationLink().ToPdfObject()
+
+	// Document
+	//   MyHeading (mapped to H1): MCID 0
+	//   P (class and attributes): MCID 1, MCID 0 on page 2
+	//   Link: MCID 1 on page 2, link annotation
+	root := core.MakeDict()
+	rootObj := core.MakeIndirectObject(root)
+	doc := core.MakeDict()
+	doc.Set("S", core.MakeName("Document"))
+	doc.Set("P", rootObj)
+	doc.Set("Pg", pages[0])
+	docObj := core.MakeIndirectObject(doc)
+
+	heading := core.MakeDict()
+	heading.Set("S", core.MakeName("MyHeading"))
+	heading.Set("P", docObj)
+	heading.Set("ID", core.MakeString("h-1"))
+	heading.Set("Alt", core.MakeEncodedString("Überschrift", true))
+	heading.Set("K", core.MakeInteger(0))
+	headingObj := core.MakeIndirectObject(heading)
+
+	mcr := core.MakeDict()
+	mcr.Set("Type", core.MakeName("MCR"))
+	mcr.Set("Pg", pages[1])
+	mcr.Set("MCID", core.MakeInteger(0))
+	attrs := core.MakeDict()
+	attrs.Set("O", core.MakeName("Layout"))
+	attrs.Set("TextAlign", core.MakeName("Center"))
+	para := core.MakeDict()
+	para.Set("S", core.MakeName("P"))
+	para.Set("P", docObj)
+	para.Set("K", core.MakeArray(core.MakeInteger(1), mcr))
+	para.Set("A", core.MakeArray(attrs, core.MakeInteger(0)))
+	para.Set("C", core.MakeName("Body"))
+	para.Set("ActualText", core.MakeString("Paragraph"))
+	paraObj := core.MakeIndirectObject(para)
+
+	objr := core.MakeDict()
+	objr.Set("Type", core.MakeName("OBJR"))
+	objr.Set("Obj", link)
+	linkElem := core.MakeDict()
+	linkElem.Set("S", core.MakeName("Link"))
+	linkElem.Set("P", docObj)
+	linkElem.Set("Pg", pages[1])
+	linkElem.Set("K", core.MakeArray(core.MakeInteger(1), objr))
+	linkElemObj := core.MakeIndirectObject(linkElem)
+	doc.Set("K", core.MakeArray(headingObj, paraObj, linkElemObj))
+
+	roleMap := core.MakeDict()
+	roleMap.Set("MyHeading", core.MakeName("Heading"))
+	roleMap.Set("Heading", core.MakeName("H1"))
+	classAttrs := core.MakeDict()
+	classAttrs.Set("O", core.MakeName("Layout"))
+	classAttrs.Set("SpaceBefore", core.MakeInteger(12))
+	classMap := core.MakeDict()
+	classMap.Set("Body", classAttrs)
+	parentTree := NewPdfNumberTree()
+	require.NoError(t, parentTree.Set(0, core.MakeArray(headingObj, paraObj)))
+	require.NoError(t, parentTree.Set(1, core.MakeArray(paraObj, linkElemObj)))
+	require.NoError(t, parentTree.Set(2, linkElemObj))
+	idTree := NewPdfNameTree()
+	require.NoError(t, idTree.Set("h-1", headingObj))
+
+	root.Set("Type", core.MakeName("StructTreeRoot"))
+	root.Set("K", docObj)
+	root.Set("RoleMap", roleMap)
+	root.Set("ClassMap", classMap)
+	root.Set("ParentTree", parentTree.ToPdfObject())
+	root.Set("ParentTreeNextKey", core.MakeInteger(3))
+	root.Set("IDTree", idTree.ToPdfObject())
+	w.catalog.Set("StructTreeRoot", rootObj)
+	markInfo := core.MakeDict()
+	markInfo.Set("Marked", core.MakeBool(true))
+	w.catalog.Set("MarkInfo", markInfo)
+	require.NoError(t, w.addObjects(rootObj))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.True(t, reader.IsTagged())
+	tree, err := reader.GetStructTreeRoot()
+	require.NoError(t, err)
+	require.NotNil(t, tree)
+	require.Equal(t, 3, tree.ParentTreeNextKey)
+
+	var types []string
+	var depths []int
+	require.NoError(t, tree.Walk(func(elem *PdfStructElem, depth int) error {
+		types = append(types, elem.S)
+		depths = append(depths, depth)
+		return nil
+	}))
+	require.Equal(t, []string{"Document", "MyHeading", "P", "Link"}, types)
+	require.Equal(t, []int{0, 1, 1, 1}, depths)
+
+	elems := tree.K[0].GetKidElems()
+	h := elems[0]
+	require.Equal(t, "H1", tree.ResolveRole(h.S))
+	require.Equal(t, "Überschrift", h.Alt)
+	require.Equal(t, "h-1", h.ID)
+	require.Equal(t, tree.K[0], h.Parent)
+	require.Equal(t, 1, h.PageNumber)
+	require.Len(t, h.Kids, 1)
+	require.True(t, h.Kids[0].IsMarkedContent())
+	require.Equal(t, 0, h.Kids[0].MCID)
+	require.Equal(t, 1, h.Kids[0].PageNumber)
+
+	p := elems[1]
+	require.Equal(t, "Paragraph", p.ActualText)
+	require.Len(t, p.Kids, 2)
+	require.Equal(t, 1, p.Kids[0].MCID)
+	require.Equal(t, 1, p.Kids[0].PageNumber)
+	require.Equal(t, 0, p.Kids[1].MCID)
+	require.Equal(t, 2, p.Kids[1].PageNumber)
+	require.Len(t, p.Attributes, 1)
+	require.Equal(t, "Layout", p.Attributes[0].O)
+	name, _ := core.GetNameVal(p.GetAttribute(tree, "TextAlign"))
+	require.Equal(t, "Center", name)
+	space, _ := core.GetIntVal(p.GetAttribute(tree, "SpaceBefore"))
+	require.Equal(t, 12, space)
+	require.Nil(t, p.GetAttribute(tree, "Missing"))
+
+	l := elems[2]
+	require.Equal(t, 2, l.PageNumber)
+	require.True(t, l.Kids[1].IsObject())
+	require.Equal(t, 2, l.Kids[1].PageNumber)
+
+	// Lookups by marked-content, object and identifier.
+	require.Equal(t, h, tree.GetMarkedContentElem(0, 0))
+	require.Equal(t, p, tree.GetMarkedContentElem(1, 0))
+	require.Equal(t, l, tree.GetMarkedContentElem(1, 1))
+	require.Nil(t, tree.GetMarkedContentElem(1, 2))
+	require.Equal(t, l, tree.GetObjectElem(2))
+	require.Equal(t, h, tree.GetElemByID("h-1"))
+
+	// Untagged documents.
+	w = NewPdfWriter()
+	require.NoError(t, w.AddPage(NewPdfPage()))
+	buf.Reset()
+	require.NoError(t, w.Write(&buf))
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.False(t, reader.IsTagged())
+	tree, err = reader.GetStructTreeRoot()
+	require.NoError(t, err)
+	require.Nil(t, tree)
+}
+
+func TestStructTreeWrite(t *testing.T) {
+	w := NewPdfWriter()
+	var pages []*core.PdfIndirectObject
+	for i := 0; i < 3; i++ {
+		page := NewPdfPage()
+		require.NoError(t, w.AddPage(page))
+		pages = append(pages, page.GetPageAsIndirectObject())
+	}
+	link := NewPdfAnnotationLink().ToPdfObject()
+
+	root := NewPdfStructTreeRoot()
+	root.RoleMap["Heading"] = "H1"
+	attrs := core.MakeDict()
+	attrs.Set("SpaceBefore", core.MakeInteger(12))
+	root.ClassMap["Body"] = []*PdfStructAttributes{{O: "Layout", Attributes: attrs}}
+	doc := NewPdfStructElem("Document")
+	root.AddKid(doc)
+	heading := NewPdfStructElem("Heading")
+	heading.ID = "h-1"
+	heading.Alt = "Überschrift"
+	heading.AddMarkedContent(pages[0], 0)
+	doc.AddKid(heading)
+	para := NewPdfStructElem("P")
+	para.Classes = []string{"Body"}
+	para.AddMarkedContent(pages[0], 1)
+	para.Kids = append(para.Kids, &PdfStructKid{MCID: 0, PageNumber: 3})
+	doc.AddKid(para)
+	linkElem := NewPdfStructElem("Link")
+	linkElem.AddMarkedContent(pages[2], 2)
+	linkElem.AddObject(pages[2], link)
+	doc.AddKid(linkElem)
+	w.SetStructTreeRoot(root)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.True(t, reader.IsTagged())
+	tree, err := reader.GetStructTreeRoot()
+	require.NoError(t, err)
+	require.Equal(t, 3, tree.ParentTreeNextKey)
+	require.Equal(t, "H1", tree.ResolveRole("Heading"))
+
+	// Only the pages with marked content have a parent tree key.
+	var keys []int
+	for i := 1; i <= 3; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		key, ok := core.GetIntVal(page.StructParents)
+		if !ok {
+			key = -1
+		}
+		keys = append(keys, key)
+	}
+	require.Equal(t, []int{0, -1, 1}, keys)
+
+	require.Len(t, tree.K, 1)
+	elems := tree.K[0].GetKidElems()
+	require.Len(t, elems, 3)
+	h, p, l := elems[0], elems[1], elems[2]
+	require.Equal(t, "Überschrift", h.Alt)
+	require.Equal(t, 1, h.PageNumber)
+	require.Equal(t, h, tree.GetElemByID("h-1"))
+	require.Equal(t, h, tree.GetMarkedContentElem(0, 0))
+	require.Equal(t, p, tree.GetMarkedContentElem(0, 1))
+	require.Equal(t, p, tree.GetMarkedContentElem(1, 0))
+	require.Nil(t, tree.GetMarkedContentElem(1, 1))
+	require.Equal(t, l, tree.GetMarkedContentElem(1, 2))
+	require.Equal(t, l, tree.GetObjectElem(2))
+
+	require.Len(t, p.Kids, 2)
+	require.Equal(t, 3, p.Kids[1].PageNumber)
+	space, _ := core.GetIntVal(p.GetAttribute(tree, "SpaceBefore"))
+	require.Equal(t, 12, space)
+	require.True(t, l.Kids[1].IsObject())
+	annot, ok := core.GetDict(l.Kids[1].Obj)
+	require.True(t, ok)
+	structParent, _ := core.GetIntVal(annot.Get("StructParent"))
+	require.Equal(t, 2, structParent)
+}
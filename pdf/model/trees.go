@@ -0,0 +1,469 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+// maxTreeDepth limits the depth of the name and number trees to avoid endless recursion.
+const maxTreeDepth = 32
+
+// treeNodeSize is the maximum number of entries of the leaves and of kids of the intermediate
+// nodes of the written trees.
+const treeNodeSize = 32
+
+// treeKey is a key of a name tree (name) or of a number tree (num).
+type treeKey struct {
+	name string
+	// encoded is the name encoded as a PDF string, which determines the order of the keys.
+	encoded string
+	num     int
+}
+
+// pdfTree is the common implementation of the name and number trees. The entries are read from
+// the root node in the file until the tree is modified, all the entries are then loaded.
+type pdfTree struct {
+	numbers bool
+	// root is the node in the file, nil for new trees.
+	root core.PdfObject
+	// Entries, valid if loaded.
+	loaded bool
+	keys   []treeKey
+	values map[treeKey]core.PdfObject
+}
+
+func (t *pdfTree) makeKey(name string, num int) treeKey {
+	if t.numbers {
+		return treeKey{num: num}
+	}
+	return treeKey{name: name, encoded: makeTextString(name).Str()}
+}
+
+// less compares the order of the keys.
+func (t *pdfTree) less(a, b treeKey) bool {
+	if t.numbers {
+		return a.num < b.num
+	}
+	return a.encoded < b.encoded
+}
+
+// entriesKey is the key of the entries array of the leaves.
+func (t *pdfTree) entriesKey() core.PdfObjectName {
+	if t.numbers {
+		return "Nums"
+	}
+	return "Names"
+}
+
+// parseKey parses the key 'obj' of an entries array, the raw string is returned for names.
+func (t *pdfTree) parseKey(obj core.PdfObject) (treeKey, string, bool) {
+	if t.numbers {
+		num, ok := core.GetIntVal(obj)
+		return treeKey{num: num}, "", ok
+	}
+	str, ok := core.GetString(obj)
+	if !ok {
+		return treeKey{}, "", false
+	}
+	return treeKey{name: str.Decoded(), encoded: str.Str()}, str.Str(), true
+}
+
+// walkNode calls 'fn' for each entry of the node 'obj' in order.
+func (t *pdfTree) walkNode(obj core.PdfObject, depth int, fn func(key treeKey, value core.PdfObject) error) error {
+	if depth > maxTreeDepth {
+		return errors.New("tree too deep")
+	}
+	node, ok := core.GetDict(obj)
+	if !ok {
+		return fmt.Errorf("invalid tree node type: %T", obj)
+	}
+	if entries, ok := core.GetArray(node.Get(t.entriesKey())); ok {
+		for i := 0; i+1 < entries.Len(); i += 2 {
+			key, _, ok := t.parseKey(entries.Get(i))
+			if !ok {
+				common.Log.Debug("Invalid tree key: %T", entries.Get(i))
+				continue
+			}
+			if err := fn(key, entries.Get(i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	if kids, ok := core.GetArray(node.Get("Kids")); ok {
+		for _, kid := range kids.Elements() {
+			if err := t.walkNode(kid, depth+1, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// load loads all the entries of the tree.
+func (t *pdfTree) load() error {
+	if t.loaded {
+		return nil
+	}
+	t.values = map[treeKey]core.PdfObject{}
+	t.keys = nil
+	if t.root != nil {
+		err := t.walkNode(t.root, 0, func(key treeKey, value core.PdfObject) error {
+			if !t.numbers {
+				// Normalize the encoding of the names, as written.
+				key = t.makeKey(key.name, 0)
+			}
+			if _, ok := t.values[key]; !ok {
+				t.keys = append(t.keys, key)
+			}
+			t.values[key] = value
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	sort.SliceStable(t.keys, func(i, j int) bool { return t.less(t.keys[i], t.keys[j]) })
+	t.loaded = true
+	return nil
+}
+
+// walk calls 'fn' for each entry of the tree in order.
+func (t *pdfTree) walk(fn func(key treeKey, value core.PdfObject) error) error {
+	if !t.loaded {
+		if t.root == nil {
+			return nil
+		}
+		return t.walkNode(t.root, 0, fn)
+	}
+	for _, key := range t.keys {
+		if err := fn(key, t.values[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// get returns the value of the key 'key'. The nodes of the file are searched using their limits.
+func (t *pdfTree) get(key treeKey) (core.PdfObject, bool) {
+	if t.loaded {
+		value, ok := t.values[key]
+		return value, ok
+	}
+	if t.root == nil {
+		return nil, false
+	}
+	if value, ok := t.search(t.root, key, 0); ok {
+		return value, true
+	}
+	if t.numbers {
+		return nil, false
+	}
+	// The names can be encoded differently in the file.
+	var found core.PdfObject
+	t.walkNode(t.root, 0, func(k treeKey, value core.PdfObject) error {
+		if found == nil && k.name == key.name {
+			found = value
+		}
+		return nil
+	})
+	return found, found != nil
+}
+
+// search searches the key 'key' in the node 'obj', only descending into the kids whose limits
+// contain the key.
+func (t *pdfTree) search(obj core.PdfObject, key treeKey, depth int) (core.PdfObject, bool) {
+	node, ok := core.GetDict(obj)
+	if !ok || depth > maxTreeDepth {
+		return nil, false
+	}
+	if entries, ok := core.GetArray(node.Get(t.entriesKey())); ok {
+		for i := 0; i+1 < entries.Len(); i += 2 {
+			k, raw, ok := t.parseKey(entries.Get(i))
+			if ok && (t.numbers && k.num == key.num || !t.numbers && raw == key.encoded) {
+				return entries.Get(i + 1), true
+			}
+		}
+	}
+	kids, ok := core.GetArray(node.Get("Kids"))
+	if !ok {
+		return nil, false
+	}
+	for _, kid := range kids.Elements() {
+		if kidDict, ok := core.GetDict(kid); ok {
+			if limits, ok := core.GetArray(kidDict.Get("Limits")); ok && limits.Len() == 2 {
+				low, _, okLow := t.parseKey(limits.Get(0))
+				high, _, okHigh := t.parseKey(limits.Get(1))
+				if okLow && okHigh && (t.less(key, low) || t.less(high, key)) {
+					continue
+				}
+			}
+		}
+		if value, ok := t.search(kid, key, depth+1); ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// set sets the value of the key 'key'.
+func (t *pdfTree) set(key treeKey, value core.PdfObject) error {
+	if err := t.load(); err != nil {
+		return err
+	}
+	if _, ok := t.values[key]; !ok {
+		i := sort.Search(len(t.keys), func(i int) bool { return !t.less(t.keys[i], key) })
+		t.keys = append(t.keys, treeKey{})
+		copy(t.keys[i+1:], t.keys[i:])
+		t.keys[i] = key
+	}
+	t.values[key] = value
+	return nil
+}
+
+// remove removes the key 'key', returns false if not found.
+func (t *pdfTree) remove(key treeKey) (bool, error) {
+	if err := t.load(); err != nil {
+		return false, err
+	}
+	if _, ok := t.values[key]; !ok {
+		return false, nil
+	}
+	delete(t.values, key)
+	for i, k := range t.keys {
+		if k == key {
+			t.keys = append(t.keys[:i], t.keys[i+1:]...)
+			break
+		}
+	}
+	return true, nil
+}
+
+// len returns the number of entries.
+func (t *pdfTree) len() int {
+	if t.loaded {
+		return len(t.keys)
+	}
+	n := 0
+	t.walk(func(treeKey, core.PdfObject) error {
+		n++
+		return nil
+	})
+	return n
+}
+
+// keyObject returns the PDF object of the key 'key'.
+func (t *pdfTree) keyObject(key treeKey) core.PdfObject {
+	if t.numbers {
+		return core.MakeInteger(int64(key.num))
+	}
+	return makeTextString(key.name)
+}
+
+// toPdfObject returns the root of a new balanced tree with the entries, the nodes other than
+// the root contain their limits. The tree is returned as is if not modified.
+func (t *pdfTree) toPdfObject() core.PdfObject {
+	if !t.loaded && t.root != nil {
+		return t.root
+	}
+	if err := t.load(); err != nil {
+		common.Log.Debug("ERROR: Failed to load tree: %v", err)
+	}
+
+	type node struct {
+		dict        *core.PdfObjectDictionary
+		first, last treeKey
+	}
+
+	// Leaves.
+	var level []node
+	for i := 0; i < len(t.keys); i += treeNodeSize {
+		end := i + treeNodeSize
+		if end > len(t.keys) {
+			end = len(t.keys)
+		}
+		entries := core.MakeArray()
+		for _, key := range t.keys[i:end] {
+			entries.Append(t.keyObject(key), t.values[key])
+		}
+		dict := core.MakeDict()
+		dict.Set(t.entriesKey(), entries)
+		level = append(level, node{dict: dict, first: t.keys[i], last: t.keys[end-1]})
+	}
+	if len(level) == 0 {
+		dict := core.MakeDict()
+		dict.Set(t.entriesKey(), core.MakeArray())
+		return core.MakeIndirectObject(dict)
+	}
+
+	// Intermediate nodes, up to the root.
+	for len(level) > 1 {
+		var parents []node
+		for i := 0; i < len(level); i += treeNodeSize {
+			end := i + treeNodeSize
+			if end > len(level) {
+				end = len(level)
+			}
+			kids := core.MakeArray()
+			for _, kid := range level[i:end] {
+				kid.dict.Set("Limits", core.MakeArray(t.keyObject(kid.first), t.keyObject(kid.last)))
+				kids.Append(core.MakeIndirectObject(kid.dict))
+			}
+			dict := core.MakeDict()
+			dict.Set("Kids", kids)
+			parents = append(parents, node{dict: dict, first: level[i].first, last: level[end-1].last})
+		}
+		level = parents
+	}
+	return core.MakeIndirectObject(level[0].dict)
+}
+
+// setCatalogNameTree sets the name tree 'tree' as the entry 'name' of the Names dictionary of
+// the catalog 'catalog'. The Names dictionary is replaced by a new dictionary, which is returned.
+func setCatalogNameTree(catalog *core.PdfObjectDictionary, name core.PdfObjectName, tree core.PdfObject) *core.PdfObjectDictionary {
+	names := core.MakeDict()
+	if orig, ok := core.GetDict(catalog.Get("Names")); ok {
+		for _, key := range orig.Keys() {
+			names.Set(key, orig.Get(key))
+		}
+	}
+	names.Set(name, tree)
+	catalog.Set("Names", names)
+	return names
+}
+
+// PdfNameTree represents a name tree (section 7.9.6 Name Trees), which maps text string keys to
+// objects, such as the named destinations or the embedded files.
+type PdfNameTree struct {
+	tree pdfTree
+}
+
+// NewPdfNameTree returns a new empty name tree.
+func NewPdfNameTree() *PdfNameTree {
+	return &PdfNameTree{tree: pdfTree{loaded: true, values: map[treeKey]core.PdfObject{}}}
+}
+
+// NewPdfNameTreeFromObject returns the name tree of the root node 'obj'. The nodes are loaded
+// when needed.
+func NewPdfNameTreeFromObject(obj core.PdfObject) (*PdfNameTree, error) {
+	if _, ok := core.GetDict(obj); !ok {
+		return nil, fmt.Errorf("invalid name tree root type: %T", obj)
+	}
+	return &PdfNameTree{tree: pdfTree{root: obj}}, nil
+}
+
+// Get returns the value of the key 'name'.
+func (t *PdfNameTree) Get(name string) (core.PdfObject, bool) {
+	return t.tree.get(t.tree.makeKey(name, 0))
+}
+
+// Set sets the value of the key 'name' to 'value'.
+func (t *PdfNameTree) Set(name string, value core.PdfObject) error {
+	return t.tree.set(t.tree.makeKey(name, 0), value)
+}
+
+// Remove removes the key 'name', returns false if not found.
+func (t *PdfNameTree) Remove(name string) (bool, error) {
+	return t.tree.remove(t.tree.makeKey(name, 0))
+}
+
+// Len returns the number of entries of the tree.
+func (t *PdfNameTree) Len() int {
+	return t.tree.len()
+}
+
+// Keys returns the keys of the tree in order.
+func (t *PdfNameTree) Keys() []string {
+	var keys []string
+	t.Walk(func(name string, value core.PdfObject) error {
+		keys = append(keys, name)
+		return nil
+	})
+	return keys
+}
+
+// Walk calls 'fn' for each entry of the tree in order, stopping at the first error, which is
+// returned.
+func (t *PdfNameTree) Walk(fn func(name string, value core.PdfObject) error) error {
+	return t.tree.walk(func(key treeKey, value core.PdfObject) error {
+		return fn(key.name, value)
+	})
+}
+
+// ToPdfObject returns the root node of the tree. The modified trees are balanced with the limits
+// of their nodes.
+func (t *PdfNameTree) ToPdfObject() core.PdfObject {
+	return t.tree.toPdfObject()
+}
+
+// PdfNumberTree represents a number tree (section 7.9.7 Number Trees), which maps integer keys to
+// objects, such as the page labels or the structure parent tree.
+type PdfNumberTree struct {
+	tree pdfTree
+}
+
+// NewPdfNumberTree returns a new empty number tree.
+func NewPdfNumberTree() *PdfNumberTree {
+	return &PdfNumberTree{tree: pdfTree{numbers: true, loaded: true, values: map[treeKey]core.PdfObject{}}}
+}
+
+// NewPdfNumberTreeFromObject returns the number tree of the root node 'obj'. The nodes are loaded
+// when needed.
+func NewPdfNumberTreeFromObject(obj core.PdfObject) (*PdfNumberTree, error) {
+	if _, ok := core.GetDict(obj); !ok {
+		return nil, fmt.Errorf("invalid number tree root type: %T", obj)
+	}
+	return &PdfNumberTree{tree: pdfTree{numbers: true, root: obj}}, nil
+}
+
+// Get returns the value of the key 'num'.
+func (t *PdfNumberTree) Get(num int) (core.PdfObject, bool) {
+	return t.tree.get(t.tree.makeKey("", num))
+}
+
+// Set sets the value of the key 'num' to 'value'.
+func (t *PdfNumberTree) Set(num int, value core.PdfObject) error {
+	return t.tree.set(t.tree.makeKey("", num), value)
+}
+
+// Remove removes the key 'num', returns false if not found.
+func (t *PdfNumberTree) Remove(num int) (bool, error) {
+	return t.tree.remove(t.tree.makeKey("", num))
+}
+
+// Len returns the number of entries of the tree.
+func (t *PdfNumberTree) Len() int {
+	return t.tree.len()
+}
+
+// Keys returns the keys of the tree in order.
+func (t *PdfNumberTree) Keys() []int {
+	var keys []int
+	t.Walk(func(num int, value core.PdfObject) error {
+		keys = append(keys, num)
+		return nil
+	})
+	return keys
+}
+
+// Walk calls 'fn' for each entry of the tree in order, stopping at the first error, which is
+// returned.
+func (t *PdfNumberTree) Walk(fn func(num int, value core.PdfObject) error) error {
+	return t.tree.walk(func(key treeKey, value core.PdfObject) error {
+		return fn(key.num, value)
+	})
+}
+
+// ToPdfObject returns the root node of the tree. The modified trees are balanced with the limits
+// of their nodes.
+func (t *PdfNumberTree) ToPdfObject() core.PdfObject {
+	return t.tree.toPdfObject()
+}
@@ -0,0 +1,153 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+// checkTreeLimits checks that the entries of the node 'obj' are within its limits and returns the
+// depth of the node.
+func checkTreeLimits(t *testing.T, obj core.PdfObject, root bool) int {
+	node, ok := core.GetDict(obj)
+	require.True(t, ok)
+	if root {
+		require.Nil(t, node.Get("Limits"))
+	} else {
+		limits, ok := core.GetArray(node.Get("Limits"))
+		require.True(t, ok)
+		require.Equal(t, 2, limits.Len())
+	}
+	kids, ok := core.GetArray(node.Get("Kids"))
+	if !ok {
+		entries, ok := core.GetArray(node.Get("Names"))
+		require.True(t, ok)
+		require.True(t, entries.Len() <= 2*treeNodeSize)
+		return 1
+	}
+	require.True(t, kids.Len() <= treeNodeSize)
+	depth := 0
+	for _, kid := range kids.Elements() {
+		_, ok := kid.(*core.PdfIndirectObject)
+		require.True(t, ok)
+		d := checkTreeLimits(t, kid, false)
+		if depth != 0 {
+			require.Equal(t, depth, d)
+		}
+		depth = d
+	}
+	return depth + 1
+}
+
+func TestNameTree(t *testing.T) {
+	tree := NewPdfNameTree()
+	for i := 2000; i > 0; i-- {
+		require.NoError(t, tree.Set(fmt.Sprintf("key%04d", i), core.MakeInteger(int64(i))))
+	}
+	require.NoError(t, tree.Set("Grüße", core.MakeString("unicode")))
+	removed, err := tree.Remove("key0002")
+	require.NoError(t, err)
+	require.True(t, removed)
+	removed, err = tree.Remove("key0002")
+	require.NoError(t, err)
+	require.False(t, removed)
+	require.Equal(t, 2000, tree.Len())
+
+	keys := tree.Keys()
+	// The keys are ordered by their encoded bytes, the UTF-16BE strings come last.
+	require.Equal(t, "key0001", keys[0])
+	require.Equal(t, "key0003", keys[1])
+	require.Equal(t, "key2000", keys[len(keys)-2])
+	require.Equal(t, "Grüße", keys[len(keys)-1])
+
+	root := tree.ToPdfObject()
+	require.Equal(t, 3, checkTreeLimits(t, root, true))
+
+	w := NewPdfWriter()
+	require.NoError(t, w.AddPage(NewPdfPage()))
+	w.SetNameTree("Custom", tree)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	read, err := reader.GetNameTree("Custom")
+	require.NoError(t, err)
+	require.NotNil(t, read)
+
+	// Lookups through the limits of the nodes.
+	value, ok := read.Get("key1234")
+	require.True(t, ok)
+	num, _ := core.GetIntVal(value)
+	require.Equal(t, 1234, num)
+	value, ok = read.Get("Grüße")
+	require.True(t, ok)
+	require.Equal(t, "unicode", value.(*core.PdfObjectString).Str())
+	_, ok = read.Get("key0002")
+	require.False(t, ok)
+	require.Equal(t, keys, read.Keys())
+
+	missing, err := reader.GetNameTree("Dests")
+	require.NoError(t, err)
+	require.Nil(t, missing)
+}
+
+func TestNameTreeFromObject(t *testing.T) {
+	// A tree with unsorted entries and a kid without limits.
+	leaf := core.MakeDict()
+	leaf.Set("Names", core.MakeArray(core.MakeString("b"), core.MakeInteger(2), core.MakeString("a"), core.MakeInteger(1)))
+	root := core.MakeDict()
+	root.Set("Kids", core.MakeArray(core.MakeIndirectObject(leaf)))
+
+	tree, err := NewPdfNameTreeFromObject(root)
+	require.NoError(t, err)
+	value, ok := tree.Get("a")
+	require.True(t, ok)
+	num, _ := core.GetIntVal(value)
+	require.Equal(t, 1, num)
+	require.Equal(t, 2, tree.Len())
+	require.Equal(t, root, tree.ToPdfObject())
+
+	require.NoError(t, tree.Set("c", core.MakeInteger(3)))
+	require.Equal(t, []string{"a", "b", "c"}, tree.Keys())
+	require.NotEqual(t, root, tree.ToPdfObject())
+
+	// Cyclic trees.
+	cyclic := core.MakeIndirectObject(nil)
+	dict := core.MakeDict()
+	dict.Set("Kids", core.MakeArray(cyclic))
+	cyclic.PdfObject = dict
+	tree, err = NewPdfNameTreeFromObject(cyclic)
+	require.NoError(t, err)
+	require.Error(t, tree.Walk(func(string, core.PdfObject) error { return nil }))
+
+	_, err = NewPdfNameTreeFromObject(core.MakeInteger(1))
+	require.Error(t, err)
+}
+
+func TestNumberTree(t *testing.T) {
+	tree := NewPdfNumberTree()
+	for _, num := range []int{10, 0, 5, 100} {
+		require.NoError(t, tree.Set(num, core.MakeString(fmt.Sprint(num))))
+	}
+	require.Equal(t, []int{0, 5, 10, 100}, tree.Keys())
+
+	obj := tree.ToPdfObject()
+	read, err := NewPdfNumberTreeFromObject(obj)
+	require.NoError(t, err)
+	value, ok := read.Get(10)
+	require.True(t, ok)
+	require.Equal(t, "10", value.(*core.PdfObjectString).Str())
+	_, ok = read.Get(11)
+	require.False(t, ok)
+	require.Equal(t, 4, read.Len())
+}
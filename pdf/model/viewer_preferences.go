@@ -0,0 +1,190 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"fmt"
+
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+// PdfPageMode specifies how the document is displayed when opened (PageMode entry of the catalog).
+type PdfPageMode string
+
+// Page modes.
+const (
+	PageModeUseNone        PdfPageMode = "UseNone"
+	PageModeUseOutlines    PdfPageMode = "UseOutlines"
+	PageModeUseThumbs      PdfPageMode = "UseThumbs"
+	PageModeFullScreen     PdfPageMode = "FullScreen"
+	PageModeUseOC          PdfPageMode = "UseOC"
+	PageModeUseAttachments PdfPageMode = "UseAttachments"
+)
+
+// PdfPageLayout specifies the page layout used when the document is opened (PageLayout entry of
+// the catalog).
+type PdfPageLayout string
+
+// Page layouts.
+const (
+	PageLayoutSinglePage     PdfPageLayout = "SinglePage"
+	PageLayoutOneColumn      PdfPageLayout = "OneColumn"
+	PageLayoutTwoColumnLeft  PdfPageLayout = "TwoColumnLeft"
+	PageLayoutTwoColumnRight PdfPageLayout = "TwoColumnRight"
+	PageLayoutTwoPageLeft    PdfPageLayout = "TwoPageLeft"
+	PageLayoutTwoPageRight   PdfPageLayout = "TwoPageRight"
+)
+
+// Values of the Direction entry of the viewer preferences.
+const (
+	DirectionL2R = "L2R"
+	DirectionR2L = "R2L"
+)
+
+// Values of the PrintScaling entry of the viewer preferences.
+const (
+	PrintScalingNone       = "None"
+	PrintScalingAppDefault = "AppDefault"
+)
+
+// Values of the Duplex entry of the viewer preferences.
+const (
+	DuplexSimplex       = "Simplex"
+	DuplexFlipShortEdge = "DuplexFlipShortEdge"
+	DuplexFlipLongEdge  = "DuplexFlipLongEdge"
+)
+
+// PdfViewerPreferences represents the viewer preferences dictionary of the catalog (section
+// 12.2 Viewer Preferences). The entries which are not set (nil or empty) take their default value.
+type PdfViewerPreferences struct {
+	HideToolbar     *bool
+	HideMenubar     *bool
+	HideWindowUI    *bool
+	FitWindow       *bool
+	CenterWindow    *bool
+	DisplayDocTitle *bool
+	// NonFullScreenPageMode is the page mode when exiting the full screen mode.
+	NonFullScreenPageMode PdfPageMode
+	// Direction is the reading order, DirectionL2R or DirectionR2L.
+	Direction string
+
+	// Page boundaries (MediaBox, CropBox, BleedBox, TrimBox or ArtBox) for viewing and printing.
+	ViewArea  string
+	ViewClip  string
+	PrintArea string
+	PrintClip string
+
+	// PrintScaling is the page scaling of the print dialog, PrintScalingNone or
+	// PrintScalingAppDefault.
+	PrintScaling string
+	// Duplex is the paper handling of the print dialog, such as DuplexFlipLongEdge.
+	Duplex            string
+	PickTrayByPDFSize *bool
+	// PrintPageRange contains the first and last pages (starting at 1) of the page ranges of the
+	// print dialog.
+	PrintPageRange []int
+	// NumCopies is the number of copies of the print dialog, 0 if not set.
+	NumCopies int
+}
+
+// NewPdfViewerPreferences returns a new viewer preferences dictionary with the default values.
+func NewPdfViewerPreferences() *PdfViewerPreferences {
+	return &PdfViewerPreferences{}
+}
+
+// NewPdfViewerPreferencesFromObject loads the viewer preferences dictionary 'obj'.
+func NewPdfViewerPreferencesFromObject(obj core.PdfObject) (*PdfViewerPreferences, error) {
+	d, ok := core.GetDict(obj)
+	if !ok {
+		return nil, fmt.Errorf("invalid viewer preferences type: %T", obj)
+	}
+	vp := &PdfViewerPreferences{}
+	for name, field := range vp.flags() {
+		if val, ok := core.TraceToDirectObject(d.Get(name)).(*core.PdfObjectBool); ok {
+			b := bool(*val)
+			*field = &b
+		}
+	}
+	for name, field := range vp.names() {
+		*field, _ = core.GetNameVal(d.Get(name))
+	}
+	if mode, ok := core.GetNameVal(d.Get("NonFullScreenPageMode")); ok {
+		vp.NonFullScreenPageMode = PdfPageMode(mode)
+	}
+	if arr, ok := core.GetArray(d.Get("PrintPageRange")); ok {
+		for _, obj := range arr.Elements() {
+			page, ok := core.GetIntVal(obj)
+			if !ok {
+				return nil, fmt.Errorf("invalid print page range type: %T", obj)
+			}
+			vp.PrintPageRange = append(vp.PrintPageRange, page)
+		}
+	}
+	vp.NumCopies, _ = core.GetIntVal(d.Get("NumCopies"))
+	return vp, nil
+}
+
+// Names of the boolean and name entries of the viewer preferences, in the written order.
+var (
+	viewerPreferencesFlags = []core.PdfObjectName{"HideToolbar", "HideMenubar", "HideWindowUI", "FitWindow",
+		"CenterWindow", "DisplayDocTitle", "PickTrayByPDFSize"}
+	viewerPreferencesNames = []core.PdfObjectName{"Direction", "ViewArea", "ViewClip", "PrintArea", "PrintClip",
+		"PrintScaling", "Duplex"}
+)
+
+// flags returns the boolean fields by name.
+func (vp *PdfViewerPreferences) flags() map[core.PdfObjectName]**bool {
+	return map[core.PdfObjectName]**bool{
+		"HideToolbar":       &vp.HideToolbar,
+		"HideMenubar":       &vp.HideMenubar,
+		"HideWindowUI":      &vp.HideWindowUI,
+		"FitWindow":         &vp.FitWindow,
+		"CenterWindow":      &vp.CenterWindow,
+		"DisplayDocTitle":   &vp.DisplayDocTitle,
+		"PickTrayByPDFSize": &vp.PickTrayByPDFSize,
+	}
+}
+
+// names returns the name fields by name.
+func (vp *PdfViewerPreferences) names() map[core.PdfObjectName]*string {
+	return map[core.PdfObjectName]*string{
+		"Direction":    &vp.Direction,
+		"ViewArea":     &vp.ViewArea,
+		"ViewClip":     &vp.ViewClip,
+		"PrintArea":    &vp.PrintArea,
+		"PrintClip":    &vp.PrintClip,
+		"PrintScaling": &vp.PrintScaling,
+		"Duplex":       &vp.Duplex,
+	}
+}
+
+// ToPdfObject returns the viewer preferences dictionary, only the entries which are set are
+// written.
+func (vp *PdfViewerPreferences) ToPdfObject() core.PdfObject {
+	d := core.MakeDict()
+	flags := vp.flags()
+	for _, name := range viewerPreferencesFlags {
+		if field := flags[name]; *field != nil {
+			d.Set(name, core.MakeBool(**field))
+		}
+	}
+	names := vp.names()
+	for _, name := range viewerPreferencesNames {
+		if field := names[name]; *field != "" {
+			d.Set(name, core.MakeName(*field))
+		}
+	}
+	if vp.NonFullScreenPageMode != "" {
+		d.Set("NonFullScreenPageMode", core.MakeName(string(vp.NonFullScreenPageMode)))
+	}
+	if len(vp.PrintPageRange) > 0 {
+		d.Set("PrintPageRange", core.MakeArrayFromIntegers(vp.PrintPageRange))
+	}
+	if vp.NumCopies > 0 {
+		d.Set("NumCopies", core.MakeInteger(int64(vp.NumCopies)))
+	}
+	return d
+}
@@ -0,0 +1,71 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestViewerPreferences(t *testing.T) {
+	w := NewPdfWriter()
+	for i := 0; i < 2; i++ {
+		require.NoError(t, w.AddPage(NewPdfPage()))
+	}
+	yes, no := true, false
+	prefs := NewPdfViewerPreferences()
+	prefs.HideToolbar = &yes
+	prefs.FitWindow = &no
+	prefs.DisplayDocTitle = &yes
+	prefs.Direction = DirectionR2L
+	prefs.PrintScaling = PrintScalingNone
+	prefs.Duplex = DuplexFlipLongEdge
+	prefs.NonFullScreenPageMode = PageModeUseOutlines
+	prefs.PrintPageRange = []int{1, 1, 2, 2}
+	prefs.NumCopies = 3
+	w.SetViewerPreferences(prefs)
+	w.SetPageMode(PageModeFullScreen)
+	w.SetPageLayout(PageLayoutTwoColumnLeft)
+	w.SetOpenAction(NewPdfActionJavaScript("app.alert('Hello');").PdfAction)
+	w.SetOpenDestination(NewPdfDestinationFitH(2, 500))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, PageModeFullScreen, reader.GetPageMode())
+	require.Equal(t, PageLayoutTwoColumnLeft, reader.GetPageLayout())
+
+	read, err := reader.GetViewerPreferences()
+	require.NoError(t, err)
+	require.Equal(t, prefs, read)
+
+	// The open destination replaced the action.
+	action, err := reader.GetOpenAction()
+	require.NoError(t, err)
+	goTo, ok := action.GetContext().(*PdfActionGoTo)
+	require.True(t, ok)
+	dest, err := reader.ResolveDestination(goTo.D)
+	require.NoError(t, err)
+	require.Equal(t, 2, dest.PageNumber)
+	require.Equal(t, DestinationFitH, dest.Type)
+	require.Equal(t, 500.0, *dest.Top)
+
+	// Defaults.
+	w = NewPdfWriter()
+	require.NoError(t, w.AddPage(NewPdfPage()))
+	buf.Reset()
+	require.NoError(t, w.Write(&buf))
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, PageModeUseNone, reader.GetPageMode())
+	require.Equal(t, PageLayoutSinglePage, reader.GetPageLayout())
+	read, err = reader.GetViewerPreferences()
+	require.NoError(t, err)
+	require.Nil(t, read)
+}
@@ -16,6 +16,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 
@@ -50,6 +51,8 @@ func getPdfAuthor() string {
 }
 
 // SetPdfAuthor sets the Author attribute of the output PDF.
+//
+// Deprecated: shared by all writers, not safe for concurrent use. Use PdfWriter.SetDocInfo instead.
 func SetPdfAuthor(author string) {
 	pdfAuthor = author
 }
@@ -59,6 +62,8 @@ func getPdfCreationDate() time.Time {
 }
 
 // SetPdfCreationDate sets the CreationDate attribute of the output PDF.
+//
+// Deprecated: shared by all writers, not safe for concurrent use. Use PdfWriter.SetDocInfo instead.
 func SetPdfCreationDate(creationDate time.Time) {
 	pdfCreationDate = creationDate
 }
@@ -73,6 +78,8 @@ func getPdfCreator() string {
 }
 
 // SetPdfCreator sets the Creator attribute of the output PDF.
+//
+// Deprecated: shared by all writers, not safe for concurrent use. Use PdfWriter.SetDocInfo instead.
 func SetPdfCreator(creator string) {
 	pdfCreator = creator
 }
@@ -82,6 +89,8 @@ func getPdfKeywords() string {
 }
 
 // SetPdfKeywords sets the Keywords attribute of the output PDF.
+//
+// Deprecated: shared by all writers, not safe for concurrent use. Use PdfWriter.SetDocInfo instead.
 func SetPdfKeywords(keywords string) {
 	pdfKeywords = keywords
 }
@@ -91,14 +100,22 @@ func getPdfModifiedDate() time.Time {
 }
 
 // SetPdfModifiedDate sets the ModDate attribute of the output PDF.
+//
+// Deprecated: shared by all writers, not safe for concurrent use. Use PdfWriter.SetDocInfo instead.
 func SetPdfModifiedDate(modifiedDate time.Time) {
-	pdfCreationDate = modifiedDate
+	pdfModifiedDate = modifiedDate
 }
 
 func getPdfProducer() string {
+	return licensedPdfProducer(pdfProducer)
+}
+
+// licensedPdfProducer returns the Producer attribute of the output PDF, which can be set to
+// 'producer' only with a license.
+func licensedPdfProducer(producer string) string {
 	licenseKey := license.GetLicenseKey()
-	if len(pdfProducer) > 0 && (licenseKey.IsLicensed() || flag.Lookup("test.v") != nil) {
-		return pdfProducer
+	if len(producer) > 0 && (licenseKey.IsLicensed() || flag.Lookup("test.v") != nil) {
+		return producer
 	}
 
 	// Return default.
@@ -106,6 +123,8 @@ func getPdfProducer() string {
 }
 
 // SetPdfProducer sets the Producer attribute of the output PDF.
+//
+// Deprecated: shared by all writers, not safe for concurrent use. Use PdfWriter.SetDocInfo instead.
 func SetPdfProducer(producer string) {
 	pdfProducer = producer
 }
@@ -115,6 +134,8 @@ func getPdfSubject() string {
 }
 
 // SetPdfSubject sets the Subject attribute of the output PDF.
+//
+// Deprecated: shared by all writers, not safe for concurrent use. Use PdfWriter.SetDocInfo instead.
 func SetPdfSubject(subject string) {
 	pdfSubject = subject
 }
@@ -124,10 +145,47 @@ func getPdfTitle() string {
 }
 
 // SetPdfTitle sets the Title attribute of the output PDF.
+//
+// Deprecated: shared by all writers, not safe for concurrent use. Use PdfWriter.SetDocInfo instead.
 func SetPdfTitle(title string) {
 	pdfTitle = title
 }
 
+// defaultPdfInfo returns the document information set by the package level functions, used by
+// the writers unless set per document with PdfWriter.SetDocInfo.
+func defaultPdfInfo() *PdfInfo {
+	info := NewPdfInfo()
+	metadata := []struct {
+		field **core.PdfObjectString
+		value string
+	}{
+		{&info.Producer, getPdfProducer()},
+		{&info.Creator, getPdfCreator()},
+		{&info.Author, getPdfAuthor()},
+		{&info.Subject, getPdfSubject()},
+		{&info.Title, getPdfTitle()},
+		{&info.Keywords, getPdfKeywords()},
+	}
+	for _, tuple := range metadata {
+		if tuple.value != "" {
+			*tuple.field = core.MakeString(tuple.value)
+		}
+	}
+
+	// Set creation and modified dates.
+	if creationDate := getPdfCreationDate(); !creationDate.IsZero() {
+		if cd, err := NewPdfDateFromTime(creationDate); err == nil {
+			info.CreationDate = &cd
+		}
+	}
+	if modifiedDate := getPdfModifiedDate(); !modifiedDate.IsZero() {
+		if md, err := NewPdfDateFromTime(modifiedDate); err == nil {
+			info.ModifiedDate = &md
+		}
+	}
+	return info
+}
+
 // PdfWriter handles outputing PDF content.
 type PdfWriter struct {
 	root        *core.PdfIndirectObject
@@ -141,6 +199,28 @@ type PdfWriter struct {
 	catalog     *core.PdfObjectDictionary
 	fields      []core.PdfObject
 	infoObj     *core.PdfIndirectObject
+	xmpMetadata *XMPMetadata
+	// Document information set by SetDocInfo, nil for the information set by the package
+	// level functions.
+	docInfo *PdfInfo
+
+	// Name trees of the Names dictionary of the catalog, set by SetNameTree.
+	nameTrees map[core.PdfObjectName]*PdfNameTree
+	// Open action or destination, page mode and layout and viewer preferences, if set.
+	openAction  *PdfAction
+	openDest    *PdfDestination
+	pageMode    PdfPageMode
+	pageLayout  PdfPageLayout
+	viewerPrefs *PdfViewerPreferences
+
+	// Page labels, nil if not set.
+	pageLabels *PdfNumberTree
+	// Structure tree of tagged documents, nil if not set.
+	structTreeRoot *PdfStructTreeRoot
+	// Named destinations added to the Dests name tree.
+	namedDests map[string]*PdfDestination
+	// Embedded files, nil if not set.
+	embeddedFiles *embeddedFiles
 
 	// Encryption
 	crypter     *core.PdfCrypt
@@ -188,35 +268,7 @@ func NewPdfWriter() PdfWriter {
 	w.minorVersion = 3
 
 	// Creation info.
-	infoDict := core.MakeDict()
-	metadata := []struct {
-		key   core.PdfObjectName
-		value string
-	}{
-		{"Producer", getPdfProducer()},
-		{"Creator", getPdfCreator()},
-		{"Author", getPdfAuthor()},
-		{"Subject", getPdfSubject()},
-		{"Title", getPdfTitle()},
-		{"Keywords", getPdfKeywords()},
-	}
-	for _, tuple := range metadata {
-		if tuple.value != "" {
-			infoDict.Set(tuple.key, core.MakeString(tuple.value))
-		}
-	}
-
-	// Set creation and modified dates.
-	if creationDate := getPdfCreationDate(); !creationDate.IsZero() {
-		if cd, err := NewPdfDateFromTime(creationDate); err == nil {
-			infoDict.Set("CreationDate", cd.ToPdfObject())
-		}
-	}
-	if modifiedDate := getPdfModifiedDate(); !modifiedDate.IsZero() {
-		if md, err := NewPdfDateFromTime(modifiedDate); err == nil {
-			infoDict.Set("ModDate", md.ToPdfObject())
-		}
-	}
+	infoDict := defaultPdfInfo().ToPdfObject()
 
 	infoObj := core.PdfIndirectObject{}
 	infoObj.PdfObject = infoDict
@@ -373,6 +425,256 @@ func (w *PdfWriter) SetVersion(majorVersion, minorVersion int) {
 	w.minorVersion = minorVersion
 }
 
+// SetDocInfo sets the document information dictionary of the output PDF, replacing the one set
+// by the package level functions such as SetPdfAuthor. The Producer can only be set with a license.
+// Nil info restores the information set by the package level functions.
+func (w *PdfWriter) SetDocInfo(info *PdfInfo) {
+	w.docInfo = info
+}
+
+// SetXMPMetadata sets the XMP metadata stream of the document catalog. The properties
+// corresponding to the document information dictionary are updated from it when writing.
+func (w *PdfWriter) SetXMPMetadata(metadata *XMPMetadata) {
+	w.xmpMetadata = metadata
+}
+
+// SetNameTree sets the name tree 'name' of the Names dictionary of the catalog, such as Dests.
+// The embedded files are set with AddEmbeddedFile.
+func (w *PdfWriter) SetNameTree(name core.PdfObjectName, tree *PdfNameTree) {
+	if w.nameTrees == nil {
+		w.nameTrees = map[core.PdfObjectName]*PdfNameTree{}
+	}
+	w.nameTrees[name] = tree
+}
+
+// SetOpenAction sets the action performed when the document is opened, such as a JavaScript
+// action. It replaces the open destination.
+func (w *PdfWriter) SetOpenAction(action *PdfAction) {
+	w.openAction = action
+	w.openDest = nil
+}
+
+// SetOpenDestination sets the destination displayed when the document is opened. It replaces the
+// open action.
+func (w *PdfWriter) SetOpenDestination(dest *PdfDestination) {
+	w.openDest = dest
+	w.openAction = nil
+}
+
+// SetPageMode sets how the document is displayed when opened, e.g. with the outlines visible.
+func (w *PdfWriter) SetPageMode(mode PdfPageMode) {
+	w.pageMode = mode
+}
+
+// SetPageLayout sets the page layout used when the document is opened.
+func (w *PdfWriter) SetPageLayout(layout PdfPageLayout) {
+	w.pageLayout = layout
+}
+
+// SetViewerPreferences sets the viewer preferences of the document.
+func (w *PdfWriter) SetViewerPreferences(prefs *PdfViewerPreferences) {
+	w.viewerPrefs = prefs
+}
+
+// SetStructTreeRoot sets the structure tree of the document, which is marked as tagged. The parent
+// tree is rebuilt when writing from the marked-content sequences of the elements, whose pages are
+// the pages of the writer.
+func (w *PdfWriter) SetStructTreeRoot(root *PdfStructTreeRoot) {
+	w.structTreeRoot = root
+}
+
+// SetPageLabels sets the page label ranges of the document, which must start at distinct pages.
+// The first range should start at the first page (index 0).
+func (w *PdfWriter) SetPageLabels(ranges []*PdfPageLabelRange) error {
+	tree, err := newPageLabelsTree(ranges)
+	if err != nil {
+		return err
+	}
+	w.pageLabels = tree
+	return nil
+}
+
+// AddNamedDestination adds the named destination 'name' to the Dests name tree, replacing the
+// destination with the same name if any. The destinations given by a page number refer to the
+// pages of the writer.
+func (w *PdfWriter) AddNamedDestination(name string, dest *PdfDestination) error {
+	if name == "" {
+		return errors.New("empty destination name")
+	}
+	if dest == nil {
+		return errors.New("nil destination")
+	}
+	if w.namedDests == nil {
+		w.namedDests = map[string]*PdfDestination{}
+	}
+	w.namedDests[name] = dest
+	return nil
+}
+
+// destinationObject returns the destination array of 'dest', the page number is resolved to the
+// page object.
+func (w *PdfWriter) destinationObject(dest *PdfDestination) (core.PdfObject, error) {
+	if dest.Page != nil {
+		return dest.ToPdfObject(), nil
+	}
+	pagesDict, ok := w.pages.PdfObject.(*core.PdfObjectDictionary)
+	if !ok {
+		return nil, errors.New("invalid Pages obj (not a dict)")
+	}
+	kids, ok := core.GetArray(pagesDict.Get("Kids"))
+	if !ok || dest.PageNumber < 1 || dest.PageNumber > kids.Len() {
+		return nil, fmt.Errorf("destination page %d not found", dest.PageNumber)
+	}
+	page, ok := kids.Get(dest.PageNumber - 1).(*core.PdfIndirectObject)
+	if !ok {
+		return nil, fmt.Errorf("destination page %d not found", dest.PageNumber)
+	}
+	resolved := *dest
+	resolved.Page = page
+	return resolved.ToPdfObject(), nil
+}
+
+// resolveDestinations replaces the page numbers of the destinations of the link annotations, the
+// outline items and the go-to actions by the page objects of the writer.
+func (w *PdfWriter) resolveDestinations() error {
+	visited := map[*core.PdfObjectDictionary]struct{}{}
+	if err := w.resolveActionDestinations(w.catalog.Get("OpenAction"), visited); err != nil {
+		return err
+	}
+
+	pagesDict, ok := core.GetDict(w.pages)
+	if !ok {
+		return errors.New("invalid Pages obj (not a dict)")
+	}
+	if kids, ok := core.GetArray(pagesDict.Get("Kids")); ok {
+		for _, page := range kids.Elements() {
+			pageDict, ok := core.GetDict(page)
+			if !ok {
+				continue
+			}
+			annots, ok := core.GetArray(pageDict.Get("Annots"))
+			if !ok {
+				continue
+			}
+			for _, annot := range annots.Elements() {
+				annotDict, ok := core.GetDict(annot)
+				if !ok {
+					continue
+				}
+				if err := w.resolveDestinationPage(annotDict, "Dest"); err != nil {
+					return err
+				}
+				if err := w.resolveActionDestinations(annotDict.Get("A"), visited); err != nil {
+					return err
+				}
+				if aa, ok := core.GetDict(annotDict.Get("AA")); ok {
+					for _, key := range aa.Keys() {
+						if err := w.resolveActionDestinations(aa.Get(key), visited); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+
+	outlines, ok := core.GetDict(w.catalog.Get("Outlines"))
+	if !ok {
+		return nil
+	}
+	items := []core.PdfObject{outlines.Get("First")}
+	for len(items) > 0 {
+		item, ok := core.GetDict(items[len(items)-1])
+		items = items[:len(items)-1]
+		if !ok {
+			continue
+		}
+		if _, ok := visited[item]; ok {
+			continue
+		}
+		visited[item] = struct{}{}
+		if err := w.resolveDestinationPage(item, "Dest"); err != nil {
+			return err
+		}
+		if err := w.resolveActionDestinations(item.Get("A"), visited); err != nil {
+			return err
+		}
+		items = append(items, item.Get("Next"), item.Get("First"))
+	}
+	return nil
+}
+
+// resolveActionDestinations replaces the page numbers of the destinations of the go-to actions of
+// the action chain 'obj'.
+func (w *PdfWriter) resolveActionDestinations(obj core.PdfObject, visited map[*core.PdfObjectDictionary]struct{}) error {
+	action, ok := core.GetDict(obj)
+	if !ok {
+		return nil
+	}
+	if _, ok := visited[action]; ok {
+		return nil
+	}
+	visited[action] = struct{}{}
+	if typ, _ := core.GetNameVal(action.Get("S")); typ == string(ActionTypeGoTo) {
+		if err := w.resolveDestinationPage(action, "D"); err != nil {
+			return err
+		}
+	}
+
+	switch next := core.TraceToDirectObject(action.Get("Next")).(type) {
+	case *core.PdfObjectDictionary:
+		return w.resolveActionDestinations(next, visited)
+	case *core.PdfObjectArray:
+		for _, obj := range next.Elements() {
+			if err := w.resolveActionDestinations(obj, visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveDestinationPage replaces the destination array of the entry 'key' of 'dict' if it refers
+// to the page by its number.
+func (w *PdfWriter) resolveDestinationPage(dict *core.PdfObjectDictionary, key core.PdfObjectName) error {
+	arr, ok := core.GetArray(dict.Get(key))
+	if !ok || arr.Len() == 0 {
+		// Named destination.
+		return nil
+	}
+	if _, ok := arr.Get(0).(*core.PdfObjectInteger); !ok {
+		return nil
+	}
+	dest, err := NewPdfDestinationFromObject(arr)
+	if err != nil {
+		return err
+	}
+	obj, err := w.destinationObject(dest)
+	if err != nil {
+		return err
+	}
+	dict.Set(key, obj)
+	return nil
+}
+
+// AddEmbeddedFile adds the document level embedded file 'fs' with the name 'name', replacing the
+// file with the same name if any. The files with an AFRelationship are also associated with the
+// document (AF entry of the catalog).
+func (w *PdfWriter) AddEmbeddedFile(name string, fs *PdfFileSpec) error {
+	if w.embeddedFiles == nil {
+		w.embeddedFiles, _ = loadEmbeddedFiles(nil)
+	}
+	return w.embeddedFiles.add(name, fs)
+}
+
+// RemoveEmbeddedFile removes the document level embedded file with the name 'name'.
+func (w *PdfWriter) RemoveEmbeddedFile(name string) error {
+	if w.embeddedFiles == nil {
+		return fmt.Errorf("embedded file %q not found", name)
+	}
+	return w.embeddedFiles.remove(name)
+}
+
 // SetOCProperties sets the optional content properties.
 func (w *PdfWriter) SetOCProperties(ocProperties core.PdfObject) error {
 	dict := w.catalog
@@ -778,6 +1080,16 @@ func (w *PdfWriter) updateObjectNumbers() {
 type EncryptOptions struct {
 	Permissions security.Permissions
 	Algorithm   EncryptionAlgorithm
+
+	// Recipients enables the public-key security handler. The document is encrypted for
+	// the recipients' certificates with the permissions of each recipient, the passwords
+	// and the Permissions are ignored.
+	Recipients []security.Recipient
+
+	// CryptFilters select the objects encrypted with the Algorithm, the others are stored unencrypted.
+	// Supported by the AES algorithms only. For example, setting IdentityStreams and IdentityStrings
+	// encrypts the embedded files only.
+	CryptFilters core.CryptFilterOptions
 }
 
 // EncryptionAlgorithm is used in EncryptOptions to change the default algorithm used to encrypt the document.
@@ -793,14 +1105,17 @@ const (
 )
 
 // Encrypt encrypts the output file with a specified user/owner password.
+// If the options specify the Recipients, the file is encrypted for them with the public-key security handler.
 func (w *PdfWriter) Encrypt(userPass, ownerPass []byte, options *EncryptOptions) error {
 	algo := RC4_128bit
 	if options != nil {
 		algo = options.Algorithm
 	}
 	perm := security.PermOwner
+	var filters *core.CryptFilterOptions
 	if options != nil {
 		perm = options.Permissions
+		filters = &options.CryptFilters
 	}
 
 	var cf crypt.Filter
@@ -814,7 +1129,16 @@ func (w *PdfWriter) Encrypt(userPass, ownerPass []byte, options *EncryptOptions)
 	default:
 		return fmt.Errorf("unsupported algorithm: %v", options.Algorithm)
 	}
-	crypter, info, err := core.PdfCryptNewEncrypt(cf, userPass, ownerPass, perm)
+	var (
+		crypter *core.PdfCrypt
+		info    *core.EncryptInfo
+		err     error
+	)
+	if options != nil && len(options.Recipients) > 0 {
+		crypter, info, err = core.PdfCryptNewEncryptPubKey(cf, options.Recipients, filters)
+	} else {
+		crypter, info, err = core.PdfCryptNewEncryptWithOptions(cf, userPass, ownerPass, perm, filters)
+	}
 	if err != nil {
 		return err
 	}
@@ -888,6 +1212,143 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		}
 	}
 
+	// Open action and viewer settings.
+	var openAction core.PdfObject
+	if w.openAction != nil {
+		openAction = w.openAction.ToPdfObject()
+	} else if w.openDest != nil {
+		obj, err := w.destinationObject(w.openDest)
+		if err != nil {
+			return err
+		}
+		openAction = obj
+	}
+	if openAction != nil {
+		w.catalog.Set("OpenAction", openAction)
+		if err := w.addObjects(openAction); err != nil {
+			return err
+		}
+	}
+	if err := w.resolveDestinations(); err != nil {
+		return err
+	}
+	if w.pageMode != "" {
+		w.catalog.Set("PageMode", core.MakeName(string(w.pageMode)))
+	}
+	if w.pageLayout != "" {
+		w.catalog.Set("PageLayout", core.MakeName(string(w.pageLayout)))
+	}
+	if w.viewerPrefs != nil {
+		w.catalog.Set("ViewerPreferences", w.viewerPrefs.ToPdfObject())
+	}
+
+	// Page labels.
+	if w.pageLabels != nil {
+		if obj := setCatalogPageLabels(w.catalog, w.pageLabels); obj != nil {
+			if err := w.addObjects(obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Structure tree.
+	if w.structTreeRoot != nil {
+		pagesDict, ok := core.GetDict(w.pages)
+		if !ok {
+			return errors.New("invalid Pages obj (not a dict)")
+		}
+		var pages []*core.PdfIndirectObject
+		if kids, ok := core.GetArray(pagesDict.Get("Kids")); ok {
+			for _, obj := range kids.Elements() {
+				if page, ok := obj.(*core.PdfIndirectObject); ok {
+					pages = append(pages, page)
+				}
+			}
+		}
+		root, err := w.structTreeRoot.toPdfObject(pages)
+		if err != nil {
+			return err
+		}
+		w.catalog.Set("StructTreeRoot", root)
+		markInfo := core.MakeDict()
+		markInfo.Set("Marked", core.MakeBool(true))
+		w.catalog.Set("MarkInfo", markInfo)
+		if err := w.addObjects(root); err != nil {
+			return err
+		}
+	}
+
+	// Named destinations.
+	if len(w.namedDests) > 0 {
+		tree := w.nameTrees["Dests"]
+		if tree == nil {
+			tree = NewPdfNameTree()
+			w.SetNameTree("Dests", tree)
+		}
+		for name, dest := range w.namedDests {
+			obj, err := w.destinationObject(dest)
+			if err != nil {
+				return err
+			}
+			if err := tree.Set(name, obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Name trees.
+	var treeNames []string
+	for name := range w.nameTrees {
+		treeNames = append(treeNames, string(name))
+	}
+	sort.Strings(treeNames)
+	for _, name := range treeNames {
+		names := setCatalogNameTree(w.catalog, core.PdfObjectName(name), w.nameTrees[core.PdfObjectName(name)].ToPdfObject())
+		if err := w.addObjects(names); err != nil {
+			return err
+		}
+	}
+
+	// Embedded files.
+	if w.embeddedFiles != nil {
+		for _, obj := range w.embeddedFiles.updateCatalog(w.catalog) {
+			if err := w.addObjects(obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Document information.
+	if w.docInfo != nil {
+		infoDict := w.docInfo.ToPdfObject().(*core.PdfObjectDictionary)
+		var producer string
+		if w.docInfo.Producer != nil {
+			producer = w.docInfo.Producer.Decoded()
+		}
+		if licensed := licensedPdfProducer(producer); licensed != producer {
+			infoDict.Set("Producer", core.MakeString(licensed))
+		}
+		w.infoObj.PdfObject = infoDict
+		if err := w.addObjects(infoDict); err != nil {
+			return err
+		}
+	}
+
+	// XMP metadata, kept in sync with the document information dictionary.
+	if w.xmpMetadata != nil {
+		info, err := NewPdfInfoFromObject(w.infoObj)
+		if err != nil {
+			return err
+		}
+		w.xmpMetadata.SetPdfInfo(info)
+		stream, err := w.xmpMetadata.ToPdfStream()
+		if err != nil {
+			return err
+		}
+		w.catalog.Set("Metadata", stream)
+		w.addObject(stream)
+	}
+
 	// Check pending objects prior to write.
 	for pendingObj, pendingObjDicts := range w.pendingObjects {
 		if !w.hasObject(pendingObj) {
@@ -907,6 +1368,15 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 	// Set version in the catalog.
 	w.catalog.Set("Version", core.MakeName(fmt.Sprintf("%d.%d", w.majorVersion, w.minorVersion)))
 
+	// Load the data of the streams read on demand from the source files before copying.
+	for _, obj := range w.objects {
+		if stream, ok := obj.(*core.PdfObjectStream); ok {
+			if err := stream.LoadStream(); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Make a copy of objects prior to optimizing as this can alter the objects.
 	// TODO: Copying wastes memory. Might be worth making user responsible for handling properly.
 	//       Is copy needed for optimization?
@@ -1026,7 +1496,12 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 				binary.Write(crossReferenceData, binary.BigEndian, uint16(ref.Index))
 			}
 		}
-		crossReferenceStream, err := core.MakeStream(crossReferenceData.Bytes(), core.NewFlateEncoder())
+		// The entries of the same field in the consecutive rows are similar, so the PNG Up
+		// predictor allows to compress the rows of 7 bytes better.
+		encoder := core.NewFlateEncoder()
+		encoder.Predictor = 12
+		encoder.Columns = 7
+		crossReferenceStream, err := core.MakeStream(crossReferenceData.Bytes(), encoder)
 		if err != nil {
 			return err
 		}
@@ -7,12 +7,20 @@ package model
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/core/security"
 )
 
 // Tests loading annotations from file, writing back out and reloading.
@@ -100,3 +108,77 @@ func TestReadWriteAnnotations(t *testing.T) {
 		checkAnnots(reader, false)
 	}
 }
+
+// Tests writing a document encrypted for the recipients' certificates and decrypting it.
+func TestEncryptPubKey(t *testing.T) {
+	newCertificate := func(name string) (*x509.Certificate, *rsa.PrivateKey) {
+		pkey, err := rsa.GenerateKey(rand.Reader, 1024)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(time.Now().UnixNano()),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		data, err := x509.CreateCertificate(rand.Reader, template, template, &pkey.PublicKey, pkey)
+		require.NoError(t, err)
+		cert, err := x509.ParseCertificate(data)
+		require.NoError(t, err)
+		return cert, pkey
+	}
+	owner, ownerKey := newCertificate("owner")
+	reader, readerKey := newCertificate("reader")
+	other, otherKey := newCertificate("other")
+
+	const content = "BT /F1 12 Tf 10 10 Td (Hello) Tj ET"
+	for _, algo := range []EncryptionAlgorithm{RC4_128bit, AES_128bit, AES_256bit} {
+		page := NewPdfPage()
+		require.NoError(t, page.AddContentStreamByString(content))
+		w := NewPdfWriter()
+		require.NoError(t, w.AddPage(page))
+		err := w.Encrypt(nil, nil, &EncryptOptions{
+			Algorithm: algo,
+			Recipients: []security.Recipient{
+				{Certificate: owner, Permissions: security.PermOwner},
+				{Certificate: reader, Permissions: security.PermPrinting},
+			},
+		})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, w.Write(&buf))
+
+		open := func(cert *x509.Certificate, pkey *rsa.PrivateKey) (*PdfReader, bool) {
+			r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			isEncrypted, err := r.IsEncrypted()
+			require.NoError(t, err)
+			require.True(t, isEncrypted)
+			ok, err := r.DecryptWithCertificate(cert, pkey)
+			require.NoError(t, err)
+			return r, ok
+		}
+
+		for _, c := range []struct {
+			cert  *x509.Certificate
+			pkey  *rsa.PrivateKey
+			perms security.Permissions
+		}{
+			{owner, ownerKey, security.PermOwner},
+			{reader, readerKey, security.PermPrinting},
+		} {
+			r, ok := open(c.cert, c.pkey)
+			require.True(t, ok, "algorithm %d", algo)
+			require.Equal(t, c.perms, r.GetAccessPermissions())
+
+			p, err := r.GetPage(1)
+			require.NoError(t, err)
+			str, err := p.GetAllContentStreams()
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(str, content), "algorithm %d", algo)
+		}
+
+		_, ok := open(other, otherKey)
+		require.False(t, ok)
+	}
+}
@@ -0,0 +1,576 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+// Namespaces of the XMP schemas commonly used in PDF documents.
+const (
+	// XMPNamespaceDC is the Dublin Core schema namespace (prefix dc).
+	XMPNamespaceDC = "http://purl.org/dc/elements/1.1/"
+	// XMPNamespaceXMP is the XMP basic schema namespace (prefix xmp).
+	XMPNamespaceXMP = "http://ns.adobe.com/xap/1.0/"
+	// XMPNamespaceXMPMM is the XMP media management schema namespace (prefix xmpMM).
+	XMPNamespaceXMPMM = "http://ns.adobe.com/xap/1.0/mm/"
+	// XMPNamespacePDF is the Adobe PDF schema namespace (prefix pdf).
+	XMPNamespacePDF = "http://ns.adobe.com/pdf/1.3/"
+	// XMPNamespacePDFAID is the PDF/A identification schema namespace (prefix pdfaid).
+	XMPNamespacePDFAID = "http://www.aiim.org/pdfa/ns/id/"
+)
+
+const (
+	xmpNamespaceRDF  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmpNamespaceMeta = "adobe:ns:meta/"
+	xmpNamespaceXML  = "http://www.w3.org/XML/1998/namespace"
+
+	// xmpDefaultLang is the language of the default item of language alternatives.
+	xmpDefaultLang = "x-default"
+	// xmpDateFormat is the format of the XMP dates.
+	xmpDateFormat = "2006-01-02T15:04:05-07:00"
+	// xmpPaddingSize is the size of the white space padding allowing in-place edits of the packet.
+	xmpPaddingSize = 2048
+)
+
+// xmpDefaultPrefixes are the prefixes of the well known namespaces.
+var xmpDefaultPrefixes = map[string]string{
+	XMPNamespaceDC:     "dc",
+	XMPNamespaceXMP:    "xmp",
+	XMPNamespaceXMPMM:  "xmpMM",
+	XMPNamespacePDF:    "pdf",
+	XMPNamespacePDFAID: "pdfaid",
+}
+
+// XMPPropertyType is the type of the value of a XMP property.
+type XMPPropertyType int
+
+// XMP property types.
+const (
+	// XMPSimple is a simple text value.
+	XMPSimple XMPPropertyType = iota
+	// XMPSeq is an ordered array (rdf:Seq).
+	XMPSeq
+	// XMPBag is an unordered array (rdf:Bag).
+	XMPBag
+	// XMPAlt is an array of alternatives (rdf:Alt), typically language alternatives.
+	XMPAlt
+	// XMPStruct is a structured value, which is kept as raw XML.
+	XMPStruct
+)
+
+// XMPItem is a text value of a XMP property with its optional language.
+type XMPItem struct {
+	Value string
+	Lang  string
+}
+
+// XMPProperty is a property of a XMP packet, identified by its schema namespace and name.
+type XMPProperty struct {
+	Namespace string
+	Name      string
+	Type      XMPPropertyType
+
+	// Items contains the value of a simple property or the items of an array.
+	Items []XMPItem
+
+	// Raw XML content and attributes of a structured value.
+	raw   string
+	attrs []xml.Attr
+}
+
+// Text returns the text of the property: the value of a simple property, the default language
+// item of a language alternative or the first item of an array. Structured values have no text.
+func (p *XMPProperty) Text() string {
+	if len(p.Items) == 0 {
+		return ""
+	}
+	if p.Type == XMPAlt {
+		for _, item := range p.Items {
+			if item.Lang == xmpDefaultLang {
+				return item.Value
+			}
+		}
+	}
+	return p.Items[0].Value
+}
+
+// Values returns the values of the items of the property.
+func (p *XMPProperty) Values() []string {
+	values := make([]string, len(p.Items))
+	for i, item := range p.Items {
+		values[i] = item.Value
+	}
+	return values
+}
+
+// XMPMetadata represents a XMP metadata packet, such as the document metadata stream referred by
+// the Metadata entry of the catalog (section 14.3.2 Metadata Streams).
+type XMPMetadata struct {
+	// prefixes maps the namespaces to their prefixes.
+	prefixes   map[string]string
+	properties []*XMPProperty
+	// about is the rdf:about attribute of the descriptions.
+	about string
+}
+
+// NewXMPMetadata returns a new empty XMP metadata packet.
+func NewXMPMetadata() *XMPMetadata {
+	m := &XMPMetadata{prefixes: map[string]string{}}
+	for ns, prefix := range xmpDefaultPrefixes {
+		m.prefixes[ns] = prefix
+	}
+	return m
+}
+
+// xmpNode is a XML element of a XMP packet.
+type xmpNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []xmpNode  `xml:",any"`
+	Text    string     `xml:",chardata"`
+	Inner   string     `xml:",innerxml"`
+}
+
+// attr returns the value of the attribute 'local' in the namespace 'ns'.
+func (n *xmpNode) attr(ns, local string) (string, bool) {
+	for _, attr := range n.Attrs {
+		if attr.Name.Space == ns && attr.Name.Local == local {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// is checks whether the element is 'local' in the namespace 'ns'.
+func (n *xmpNode) is(ns, local string) bool {
+	return n.XMLName.Space == ns && n.XMLName.Local == local
+}
+
+// NewXMPMetadataFromBytes parses the XMP packet 'data'. The properties of all the schemas are
+// loaded, the structured values are kept as is.
+func NewXMPMetadataFromBytes(data []byte) (*XMPMetadata, error) {
+	// Skip the byte order mark.
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var root xmpNode
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	rdf := findXMPNode(&root, xmpNamespaceRDF, "RDF")
+	if rdf == nil {
+		return nil, errors.New("rdf:RDF element not found")
+	}
+
+	m := NewXMPMetadata()
+	m.registerNamespaces(&root)
+	for i := range rdf.Nodes {
+		desc := &rdf.Nodes[i]
+		if !desc.is(xmpNamespaceRDF, "Description") {
+			common.Log.Debug("Unsupported XMP element: %s", desc.XMLName.Local)
+			continue
+		}
+		if about, ok := desc.attr(xmpNamespaceRDF, "about"); ok && about != "" {
+			m.about = about
+		}
+		for _, attr := range desc.Attrs {
+			switch attr.Name.Space {
+			case "", "xmlns", xmpNamespaceRDF, xmpNamespaceXML:
+				continue
+			}
+			m.SetText(attr.Name.Space, attr.Name.Local, attr.Value)
+		}
+		for j := range desc.Nodes {
+			m.SetProperty(newXMPPropertyFromNode(&desc.Nodes[j]))
+		}
+	}
+	return m, nil
+}
+
+// findXMPNode returns the first element 'local' in the namespace 'ns' in the tree of 'n'.
+func findXMPNode(n *xmpNode, ns, local string) *xmpNode {
+	if n.is(ns, local) {
+		return n
+	}
+	for i := range n.Nodes {
+		if found := findXMPNode(&n.Nodes[i], ns, local); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// registerNamespaces registers the namespace prefixes declared in the tree of 'n'.
+func (m *XMPMetadata) registerNamespaces(n *xmpNode) {
+	for _, attr := range n.Attrs {
+		if attr.Name.Space == "xmlns" {
+			m.RegisterNamespace(attr.Name.Local, attr.Value)
+		}
+	}
+	for i := range n.Nodes {
+		m.registerNamespaces(&n.Nodes[i])
+	}
+}
+
+// newXMPPropertyFromNode loads the property from the property element 'n'.
+func newXMPPropertyFromNode(n *xmpNode) *XMPProperty {
+	p := &XMPProperty{Namespace: n.XMLName.Space, Name: n.XMLName.Local}
+	lang, _ := n.attr(xmpNamespaceXML, "lang")
+	_, hasResource := n.attr(xmpNamespaceRDF, "resource")
+	parseType, _ := n.attr(xmpNamespaceRDF, "parseType")
+
+	switch {
+	case len(n.Nodes) == 0 && !hasResource && parseType == "":
+		p.Type = XMPSimple
+		p.Items = []XMPItem{{Value: n.Text, Lang: lang}}
+		return p
+	case len(n.Nodes) == 1 && parseType == "":
+		container := &n.Nodes[0]
+		switch {
+		case container.is(xmpNamespaceRDF, "Seq"):
+			p.Type = XMPSeq
+		case container.is(xmpNamespaceRDF, "Bag"):
+			p.Type = XMPBag
+		case container.is(xmpNamespaceRDF, "Alt"):
+			p.Type = XMPAlt
+		default:
+			return newXMPStructFromNode(p, n)
+		}
+		for _, li := range container.Nodes {
+			if !li.is(xmpNamespaceRDF, "li") || len(li.Nodes) > 0 {
+				// Array of structured values.
+				return newXMPStructFromNode(p, n)
+			}
+			lang, _ := li.attr(xmpNamespaceXML, "lang")
+			p.Items = append(p.Items, XMPItem{Value: li.Text, Lang: lang})
+		}
+		return p
+	}
+	return newXMPStructFromNode(p, n)
+}
+
+// newXMPStructFromNode sets the property 'p' to the structured value of the property element 'n'.
+func newXMPStructFromNode(p *XMPProperty, n *xmpNode) *XMPProperty {
+	p.Type = XMPStruct
+	p.Items = nil
+	p.raw = n.Inner
+	for _, attr := range n.Attrs {
+		if attr.Name.Space != "xmlns" {
+			p.attrs = append(p.attrs, attr)
+		}
+	}
+	return p
+}
+
+// RegisterNamespace registers 'prefix' as the prefix of the namespace 'ns' used when writing
+// the packet. Custom schemas should be registered before setting their properties.
+func (m *XMPMetadata) RegisterNamespace(prefix, ns string) {
+	if prefix == "" || ns == "" || ns == xmpNamespaceRDF || ns == xmpNamespaceMeta || ns == xmpNamespaceXML {
+		return
+	}
+	m.prefixes[ns] = prefix
+}
+
+// Properties returns the properties of the packet.
+func (m *XMPMetadata) Properties() []*XMPProperty {
+	return m.properties
+}
+
+// GetProperty returns the property 'name' of the schema 'ns', nil if not set.
+func (m *XMPMetadata) GetProperty(ns, name string) *XMPProperty {
+	for _, p := range m.properties {
+		if p.Namespace == ns && p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// SetProperty sets the property 'p', replacing the property with the same name if any.
+func (m *XMPMetadata) SetProperty(p *XMPProperty) {
+	for i, prop := range m.properties {
+		if prop.Namespace == p.Namespace && prop.Name == p.Name {
+			m.properties[i] = p
+			return
+		}
+	}
+	m.properties = append(m.properties, p)
+}
+
+// RemoveProperty removes the property 'name' of the schema 'ns'.
+func (m *XMPMetadata) RemoveProperty(ns, name string) {
+	for i, p := range m.properties {
+		if p.Namespace == ns && p.Name == name {
+			m.properties = append(m.properties[:i], m.properties[i+1:]...)
+			return
+		}
+	}
+}
+
+// GetText returns the text of the property 'name' of the schema 'ns' (see XMPProperty.Text).
+func (m *XMPMetadata) GetText(ns, name string) (string, bool) {
+	p := m.GetProperty(ns, name)
+	if p == nil || p.Type == XMPStruct {
+		return "", false
+	}
+	return p.Text(), true
+}
+
+// SetText sets the property 'name' of the schema 'ns' to the simple text 'value'.
+func (m *XMPMetadata) SetText(ns, name, value string) {
+	m.SetProperty(&XMPProperty{
+		Namespace: ns,
+		Name:      name,
+		Type:      XMPSimple,
+		Items:     []XMPItem{{Value: value}},
+	})
+}
+
+// SetArray sets the property 'name' of the schema 'ns' to an array of type 'typ' (XMPSeq, XMPBag
+// or XMPAlt) with the items 'values'.
+func (m *XMPMetadata) SetArray(ns, name string, typ XMPPropertyType, values []string) error {
+	if typ != XMPSeq && typ != XMPBag && typ != XMPAlt {
+		return fmt.Errorf("not an array type: %d", typ)
+	}
+	p := &XMPProperty{Namespace: ns, Name: name, Type: typ}
+	for _, value := range values {
+		p.Items = append(p.Items, XMPItem{Value: value})
+	}
+	m.SetProperty(p)
+	return nil
+}
+
+// SetLangAlt sets the item of the language 'lang' of the language alternative property 'name' of
+// the schema 'ns' to 'value'. The default language "x-default" is used if 'lang' is empty.
+func (m *XMPMetadata) SetLangAlt(ns, name, lang, value string) {
+	if lang == "" {
+		lang = xmpDefaultLang
+	}
+	p := m.GetProperty(ns, name)
+	if p == nil || p.Type != XMPAlt {
+		p = &XMPProperty{Namespace: ns, Name: name, Type: XMPAlt}
+		m.SetProperty(p)
+	}
+	for i, item := range p.Items {
+		if item.Lang == lang {
+			p.Items[i].Value = value
+			return
+		}
+	}
+	item := XMPItem{Value: value, Lang: lang}
+	if lang == xmpDefaultLang {
+		// The default item comes first.
+		p.Items = append([]XMPItem{item}, p.Items...)
+		return
+	}
+	p.Items = append(p.Items, item)
+}
+
+// GetPdfAID returns the PDF/A part and conformance level identifying the document, ok is false if
+// the identification is missing.
+func (m *XMPMetadata) GetPdfAID() (part int, conformance string, ok bool) {
+	text, ok := m.GetText(XMPNamespacePDFAID, "part")
+	if !ok {
+		return 0, "", false
+	}
+	part, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil {
+		return 0, "", false
+	}
+	conformance, _ = m.GetText(XMPNamespacePDFAID, "conformance")
+	return part, conformance, true
+}
+
+// SetPdfAID sets the PDF/A part and conformance level (e.g. 1 and "B" for PDF/A-1b) identifying
+// the document.
+func (m *XMPMetadata) SetPdfAID(part int, conformance string) {
+	m.SetText(XMPNamespacePDFAID, "part", strconv.Itoa(part))
+	if conformance != "" {
+		m.SetText(XMPNamespacePDFAID, "conformance", conformance)
+	} else {
+		m.RemoveProperty(XMPNamespacePDFAID, "conformance")
+	}
+}
+
+// SetPdfInfo sets the properties corresponding to the entries of the document information
+// dictionary 'info' (section 14.3.3, Table 317). The properties of the entries not in 'info'
+// are left unchanged.
+func (m *XMPMetadata) SetPdfInfo(info *PdfInfo) {
+	m.SetText(XMPNamespaceDC, "format", "application/pdf")
+	if info.Title != nil {
+		m.SetLangAlt(XMPNamespaceDC, "title", xmpDefaultLang, info.Title.Decoded())
+	}
+	if info.Author != nil {
+		m.SetArray(XMPNamespaceDC, "creator", XMPSeq, []string{info.Author.Decoded()})
+	}
+	if info.Subject != nil {
+		m.SetLangAlt(XMPNamespaceDC, "description", xmpDefaultLang, info.Subject.Decoded())
+	}
+	if info.Keywords != nil {
+		m.SetText(XMPNamespacePDF, "Keywords", info.Keywords.Decoded())
+	}
+	if info.Creator != nil {
+		m.SetText(XMPNamespaceXMP, "CreatorTool", info.Creator.Decoded())
+	}
+	if info.Producer != nil {
+		m.SetText(XMPNamespacePDF, "Producer", info.Producer.Decoded())
+	}
+	if info.CreationDate != nil {
+		m.SetText(XMPNamespaceXMP, "CreateDate", info.CreationDate.ToGoTime().Format(xmpDateFormat))
+	}
+	if info.ModifiedDate != nil {
+		date := info.ModifiedDate.ToGoTime().Format(xmpDateFormat)
+		m.SetText(XMPNamespaceXMP, "ModifyDate", date)
+		m.SetText(XMPNamespaceXMP, "MetadataDate", date)
+	}
+	if info.Trapped != nil {
+		m.SetText(XMPNamespacePDF, "Trapped", string(*info.Trapped))
+	}
+}
+
+// prefix returns the prefix of the namespace 'ns', registering a new prefix if needed.
+func (m *XMPMetadata) prefix(ns string) string {
+	switch ns {
+	case xmpNamespaceRDF:
+		return "rdf"
+	case xmpNamespaceXML:
+		return "xml"
+	}
+	if prefix, ok := m.prefixes[ns]; ok {
+		return prefix
+	}
+	prefix := fmt.Sprintf("ns%d", len(m.prefixes)+1)
+	m.prefixes[ns] = prefix
+	return prefix
+}
+
+// Bytes returns the serialized XMP packet.
+func (m *XMPMetadata) Bytes() ([]byte, error) {
+	// Namespaces declared by the description.
+	var namespaces []string
+	declared := map[string]bool{}
+	declare := func(ns string) {
+		if !declared[ns] && ns != xmpNamespaceRDF && ns != xmpNamespaceXML {
+			declared[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	hasStruct := false
+	for _, p := range m.properties {
+		if p.Namespace == "" || p.Name == "" {
+			return nil, fmt.Errorf("invalid XMP property %q in namespace %q", p.Name, p.Namespace)
+		}
+		declare(p.Namespace)
+		m.prefix(p.Namespace)
+		if p.Type == XMPStruct {
+			hasStruct = true
+			for _, attr := range p.attrs {
+				declare(attr.Name.Space)
+				m.prefix(attr.Name.Space)
+			}
+		}
+	}
+	if hasStruct {
+		// The structured values can use any of the registered namespaces.
+		var other []string
+		for ns := range m.prefixes {
+			other = append(other, ns)
+		}
+		sort.Strings(other)
+		for _, ns := range other {
+			declare(ns)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	buf.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	buf.WriteString(" <rdf:RDF xmlns:rdf=\"" + xmpNamespaceRDF + "\">\n")
+	buf.WriteString("  <rdf:Description rdf:about=\"")
+	xml.EscapeText(&buf, []byte(m.about))
+	buf.WriteString("\"")
+	for _, ns := range namespaces {
+		fmt.Fprintf(&buf, "\n    xmlns:%s=\"", m.prefix(ns))
+		xml.EscapeText(&buf, []byte(ns))
+		buf.WriteString("\"")
+	}
+	buf.WriteString(">\n")
+
+	for _, p := range m.properties {
+		name := m.prefix(p.Namespace) + ":" + p.Name
+		switch p.Type {
+		case XMPSimple:
+			buf.WriteString("   <" + name)
+			if len(p.Items) > 0 && p.Items[0].Lang != "" {
+				m.writeXMPAttr(&buf, xmpNamespaceXML, "lang", p.Items[0].Lang)
+			}
+			buf.WriteString(">")
+			xml.EscapeText(&buf, []byte(p.Text()))
+			buf.WriteString("</" + name + ">\n")
+		case XMPSeq, XMPBag, XMPAlt:
+			container := map[XMPPropertyType]string{XMPSeq: "rdf:Seq", XMPBag: "rdf:Bag", XMPAlt: "rdf:Alt"}[p.Type]
+			buf.WriteString("   <" + name + ">\n    <" + container + ">\n")
+			for _, item := range p.Items {
+				buf.WriteString("     <rdf:li")
+				if item.Lang != "" {
+					m.writeXMPAttr(&buf, xmpNamespaceXML, "lang", item.Lang)
+				}
+				buf.WriteString(">")
+				xml.EscapeText(&buf, []byte(item.Value))
+				buf.WriteString("</rdf:li>\n")
+			}
+			buf.WriteString("    </" + container + ">\n   </" + name + ">\n")
+		case XMPStruct:
+			buf.WriteString("   <" + name)
+			for _, attr := range p.attrs {
+				m.writeXMPAttr(&buf, attr.Name.Space, attr.Name.Local, attr.Value)
+			}
+			buf.WriteString(">" + p.raw + "</" + name + ">\n")
+		default:
+			return nil, fmt.Errorf("invalid XMP property type: %d", p.Type)
+		}
+	}
+
+	buf.WriteString("  </rdf:Description>\n </rdf:RDF>\n</x:xmpmeta>\n")
+	for i := 0; i < xmpPaddingSize/64; i++ {
+		buf.WriteString(strings.Repeat(" ", 63) + "\n")
+	}
+	buf.WriteString("<?xpacket end=\"w\"?>")
+	return buf.Bytes(), nil
+}
+
+// writeXMPAttr writes the attribute 'local' in the namespace 'ns' with the value 'value'.
+func (m *XMPMetadata) writeXMPAttr(buf *bytes.Buffer, ns, local, value string) {
+	buf.WriteString(" ")
+	if ns != "" {
+		buf.WriteString(m.prefix(ns) + ":")
+	}
+	buf.WriteString(local + "=\"")
+	xml.EscapeText(buf, []byte(value))
+	buf.WriteString("\"")
+}
+
+// ToPdfStream returns a new metadata stream containing the XMP packet.
+func (m *XMPMetadata) ToPdfStream() (*core.PdfObjectStream, error) {
+	data, err := m.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("Metadata"))
+	dict.Set("Subtype", core.MakeName("XML"))
+	dict.Set("Length", core.MakeInteger(int64(len(data))))
+	return &core.PdfObjectStream{PdfObjectDictionary: dict, Stream: data}, nil
+}
@@ -0,0 +1,193 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+const testXMPPacket = `<?xpacket begin="` + "\xef\xbb\xbf" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:pdf="http://ns.adobe.com/pdf/1.3/"
+    pdf:Producer="Test producer"/>
+  <rdf:Description rdf:about=""
+    xmlns:dc="http://purl.org/dc/elements/1.1/">
+   <dc:title>
+    <rdf:Alt>
+     <rdf:li xml:lang="x-default">Annual report</rdf:li>
+     <rdf:li xml:lang="de-DE">Jahresbericht</rdf:li>
+    </rdf:Alt>
+   </dc:title>
+   <dc:creator>
+    <rdf:Seq>
+     <rdf:li>Alice</rdf:li>
+     <rdf:li>Bob</rdf:li>
+    </rdf:Seq>
+   </dc:creator>
+   <dc:subject>
+    <rdf:Bag>
+     <rdf:li>finance &amp; reports</rdf:li>
+    </rdf:Bag>
+   </dc:subject>
+  </rdf:Description>
+  <rdf:Description rdf:about=""
+    xmlns:xmpMM="http://ns.adobe.com/xap/1.0/mm/"
+    xmlns:stEvt="http://ns.adobe.com/xap/1.0/sType/ResourceEvent#"
+    xmlns:acme="http://ns.acme.com/dam/1.0/">
+   <xmpMM:History>
+    <rdf:Seq>
+     <rdf:li rdf:parseType="Resource">
+      <stEvt:action>created</stEvt:action>
+     </rdf:li>
+    </rdf:Seq>
+   </xmpMM:History>
+   <acme:AssetID>A-1234</acme:AssetID>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+const testNamespaceACME = "http://ns.acme.com/dam/1.0/"
+
+func checkTestXMP(t *testing.T, m *XMPMetadata) {
+	text, ok := m.GetText(XMPNamespacePDF, "Producer")
+	require.True(t, ok)
+	require.Equal(t, "Test producer", text)
+
+	title := m.GetProperty(XMPNamespaceDC, "title")
+	require.NotNil(t, title)
+	require.Equal(t, XMPAlt, title.Type)
+	require.Equal(t, "Annual report", title.Text())
+	require.Equal(t, []XMPItem{{"Annual report", "x-default"}, {"Jahresbericht", "de-DE"}}, title.Items)
+
+	creator := m.GetProperty(XMPNamespaceDC, "creator")
+	require.NotNil(t, creator)
+	require.Equal(t, XMPSeq, creator.Type)
+	require.Equal(t, []string{"Alice", "Bob"}, creator.Values())
+
+	subject := m.GetProperty(XMPNamespaceDC, "subject")
+	require.NotNil(t, subject)
+	require.Equal(t, XMPBag, subject.Type)
+	require.Equal(t, []string{"finance & reports"}, subject.Values())
+
+	history := m.GetProperty(XMPNamespaceXMPMM, "History")
+	require.NotNil(t, history)
+	require.Equal(t, XMPStruct, history.Type)
+	require.Contains(t, history.raw, "<stEvt:action>created</stEvt:action>")
+
+	text, ok = m.GetText(testNamespaceACME, "AssetID")
+	require.True(t, ok)
+	require.Equal(t, "A-1234", text)
+}
+
+func TestXMPMetadataParse(t *testing.T) {
+	m, err := NewXMPMetadataFromBytes([]byte(testXMPPacket))
+	require.NoError(t, err)
+	checkTestXMP(t, m)
+
+	// Serialize and parse again.
+	data, err := m.Bytes()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(data), "<?xpacket begin="))
+	require.True(t, strings.HasSuffix(string(data), `<?xpacket end="w"?>`))
+	require.Contains(t, string(data), `xmlns:acme="`+testNamespaceACME+`"`)
+	m, err = NewXMPMetadataFromBytes(data)
+	require.NoError(t, err)
+	checkTestXMP(t, m)
+
+	_, err = NewXMPMetadataFromBytes([]byte("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\"/>"))
+	require.Error(t, err)
+}
+
+func TestXMPMetadataModify(t *testing.T) {
+	m := NewXMPMetadata()
+	m.RegisterNamespace("acme", testNamespaceACME)
+	m.SetText(testNamespaceACME, "AssetID", "B-42 <draft>")
+	require.NoError(t, m.SetArray(testNamespaceACME, "Tags", XMPBag, []string{"one", "two"}))
+	require.Error(t, m.SetArray(testNamespaceACME, "Tags", XMPSimple, nil))
+	m.SetLangAlt(XMPNamespaceDC, "title", "fr-FR", "Rapport")
+	m.SetLangAlt(XMPNamespaceDC, "title", "", "Report")
+	m.SetPdfAID(2, "B")
+	m.SetText("http://ns.example.com/unregistered/", "Value", "x")
+
+	data, err := m.Bytes()
+	require.NoError(t, err)
+	m, err = NewXMPMetadataFromBytes(data)
+	require.NoError(t, err)
+
+	text, _ := m.GetText(testNamespaceACME, "AssetID")
+	require.Equal(t, "B-42 <draft>", text)
+	require.Equal(t, []string{"one", "two"}, m.GetProperty(testNamespaceACME, "Tags").Values())
+	title := m.GetProperty(XMPNamespaceDC, "title")
+	require.Equal(t, []XMPItem{{"Report", "x-default"}, {"Rapport", "fr-FR"}}, title.Items)
+	part, conformance, ok := m.GetPdfAID()
+	require.True(t, ok)
+	require.Equal(t, 2, part)
+	require.Equal(t, "B", conformance)
+	text, _ = m.GetText("http://ns.example.com/unregistered/", "Value")
+	require.Equal(t, "x", text)
+
+	m.RemoveProperty(testNamespaceACME, "AssetID")
+	require.Nil(t, m.GetProperty(testNamespaceACME, "AssetID"))
+}
+
+func TestXMPMetadataWrite(t *testing.T) {
+	m, err := NewXMPMetadataFromBytes([]byte(testXMPPacket))
+	require.NoError(t, err)
+
+	modDate, err := NewPdfDateFromTime(time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	info := NewPdfInfo()
+	info.Title = core.MakeString("Quarterly report")
+	info.Author = core.MakeString("Carol")
+	info.ModifiedDate = &modDate
+
+	w := NewPdfWriter()
+	w.SetDocInfo(info)
+	w.SetXMPMetadata(m)
+	require.NoError(t, w.AddPage(NewPdfPage()))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	m, err = reader.GetXMPMetadata()
+	require.NoError(t, err)
+	require.NotNil(t, m)
+
+	// The properties are synchronized with the document information.
+	title := m.GetProperty(XMPNamespaceDC, "title")
+	require.Equal(t, []XMPItem{{"Quarterly report", "x-default"}, {"Jahresbericht", "de-DE"}}, title.Items)
+	require.Equal(t, []string{"Carol"}, m.GetProperty(XMPNamespaceDC, "creator").Values())
+	text, _ := m.GetText(XMPNamespaceXMP, "ModifyDate")
+	require.Equal(t, "2019-05-01T12:00:00+00:00", text)
+	text, _ = m.GetText(XMPNamespacePDF, "Producer")
+	readInfo, err := reader.GetPdfInfo()
+	require.NoError(t, err)
+	require.Equal(t, readInfo.Producer.Decoded(), text)
+	text, _ = m.GetText(testNamespaceACME, "AssetID")
+	require.Equal(t, "A-1234", text)
+
+	// Documents without metadata.
+	w = NewPdfWriter()
+	require.NoError(t, w.AddPage(NewPdfPage()))
+	buf.Reset()
+	require.NoError(t, w.Write(&buf))
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	m, err = reader.GetXMPMetadata()
+	require.NoError(t, err)
+	require.Nil(t, m)
+}
@@ -107,6 +107,10 @@ func NewXObjectFormFromStream(stream *core.PdfObjectStream) (*XObjectForm, error
 	form.OC = dict.Get("OC")
 	form.Name = dict.Get("Name")
 
+	// The data of streams read on demand is needed for writing the XObject.
+	if err := stream.LoadStream(); err != nil {
+		return nil, err
+	}
 	form.Stream = stream.Stream
 
 	return form, nil
@@ -410,13 +414,30 @@ func NewXObjectImageFromStream(stream *core.PdfObjectStream) (*XObjectImage, err
 			return nil, err
 		}
 		img.ColorSpace = cs
+	} else if jpx, ok := encoder.(*core.JPXEncoder); ok {
+		// JPX images may specify the colorspace in the image data only.
+		switch jpx.ColorComponents {
+		case 1:
+			img.ColorSpace = NewPdfColorspaceDeviceGray()
+		case 3:
+			img.ColorSpace = NewPdfColorspaceDeviceRGB()
+		case 4:
+			img.ColorSpace = NewPdfColorspaceDeviceCMYK()
+		default:
+			common.Log.Debug("JPX image with %d color components", jpx.ColorComponents)
+			return nil, errors.New("unsupported JPX colorspace")
+		}
 	} else {
 		// If not specified, assume gray..
 		common.Log.Debug("XObject Image colorspace not specified - assuming 1 color component")
 		img.ColorSpace = NewPdfColorspaceDeviceGray()
 	}
 
-	if obj := core.TraceToDirectObject(dict.Get("BitsPerComponent")); obj != nil {
+	if jpx, ok := encoder.(*core.JPXEncoder); ok {
+		// The bits per component of JPX images are determined by the image data.
+		iVal := int64(jpx.BitsPerComponent)
+		img.BitsPerComponent = &iVal
+	} else if obj := core.TraceToDirectObject(dict.Get("BitsPerComponent")); obj != nil {
 		iObj, ok := obj.(*core.PdfObjectInteger)
 		if !ok {
 			return nil, errors.New("invalid image height object")
@@ -441,6 +462,10 @@ func NewXObjectImageFromStream(stream *core.PdfObjectStream) (*XObjectImage, err
 	img.Metadata = dict.Get("Metadata")
 	img.OC = dict.Get("OC")
 
+	// The data of streams read on demand is needed for writing the XObject.
+	if err := stream.LoadStream(); err != nil {
+		return nil, err
+	}
 	img.Stream = stream.Stream
 
 	return img, nil
@@ -524,11 +549,25 @@ func (ximg *XObjectImage) ToImage() (*Image, error) {
 
 	image.ColorComponents = ximg.ColorSpace.GetNumComponents()
 
-	decoded, err := core.DecodeStream(ximg.primitive)
-	if err != nil {
-		return nil, err
+	if jpx, ok := ximg.Filter.(*core.JPXEncoder); ok {
+		// JPX images can contain the soft mask in the image data (SMaskInData).
+		decoded, alpha, err := jpx.DecodeBytesWithAlpha(ximg.primitive.Stream)
+		if err != nil {
+			return nil, err
+		}
+		image.Data = decoded
+		image.BitsPerComponent = int64(jpx.BitsPerComponent)
+		if alpha != nil {
+			image.alphaData = alpha
+			image.hasAlpha = true
+		}
+	} else {
+		decoded, err := core.DecodeStream(ximg.primitive)
+		if err != nil {
+			return nil, err
+		}
+		image.Data = decoded
 	}
-	image.Data = decoded
 
 	if ximg.Decode != nil {
 		darr, ok := ximg.Decode.(*core.PdfObjectArray)